@@ -1,15 +1,18 @@
 package file
 
 import (
-	"os"
+	"errors"
+	"net/http"
 	"strconv"
+	"template/internal/middleware"
 	"template/internal/models"
+	"template/internal/services/upload"
 	"template/pkg/common"
 
 	"github.com/gin-gonic/gin"
 )
 
-// DownloadFile 下载文件
+// DownloadFile 下载文件，?version=N 下载指定版本
 func DownloadFile(c *gin.Context) {
 	fileInfo, exists := c.Get("file_info")
 	if !exists {
@@ -17,24 +20,28 @@ func DownloadFile(c *gin.Context) {
 		return
 	}
 
-	file := fileInfo.(*models.UploadFile)
-
-	// 检查文件是否存在
-	if _, err := os.Stat(file.FilePath); os.IsNotExist(err) {
-		common.NotFound(c, "文件不存在")
+	file, ok := requestedVersion(c, fileInfo.(*models.UploadFile))
+	if !ok {
 		return
 	}
 
-	// 设置响应头
-	c.Header("Content-Type", file.MimeType)
-	c.Header("Content-Disposition", "attachment; filename=\""+file.Filename+"\"")
-	c.Header("Content-Length", strconv.FormatInt(file.FileSize, 10))
+	// 返回文件内容，支持断点续传
+	status, byteRange := serveFile(c, file, "attachment")
 
-	// 返回文件内容
-	c.File(file.FilePath)
+	// HEAD 请求和断点续传的后续分段不重复记录下载
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	if status == http.StatusOK || (status == http.StatusPartialContent && byteRange.Start == 0) {
+		var userID string
+		if user, err := middleware.GetUserFromContext(c); err == nil {
+			userID = user.UserID
+		}
+		upload.NotifyFileDownloaded(file, userID, c.ClientIP())
+	}
 }
 
-// PreviewFile 预览文件（在线查看）
+// PreviewFile 预览文件（在线查看），?version=N 预览指定版本
 func PreviewFile(c *gin.Context) {
 	fileInfo, exists := c.Get("file_info")
 	if !exists {
@@ -42,18 +49,43 @@ func PreviewFile(c *gin.Context) {
 		return
 	}
 
+	file, ok := requestedVersion(c, fileInfo.(*models.UploadFile))
+	if !ok {
+		return
+	}
+
+	// 返回文件内容，支持视频等媒体的拖动播放
+	serveFile(c, file, "inline")
+}
+
+// ThumbnailFile 获取图片缩略图，尺寸为配置中的缩略图长边像素数
+func ThumbnailFile(c *gin.Context) {
+	fileInfo, exists := c.Get("file_info")
+	if !exists {
+		common.ServerError(c, "无法获取文件信息")
+		return
+	}
+
 	file := fileInfo.(*models.UploadFile)
 
-	// 检查文件是否存在
-	if _, err := os.Stat(file.FilePath); os.IsNotExist(err) {
-		common.NotFound(c, "文件不存在")
+	size, err := strconv.Atoi(c.Param("size"))
+	if err != nil {
+		common.BadRequest(c, "缩略图尺寸无效")
 		return
 	}
 
-	// 设置预览响应头
-	c.Header("Content-Type", file.MimeType)
-	c.Header("Content-Disposition", "inline; filename=\""+file.Filename+"\"")
+	derivative, err := upload.GetThumbnail(file, size)
+	switch {
+	case errors.Is(err, upload.ErrThumbnailSize):
+		common.BadRequest(c, err.Error())
+		return
+	case errors.Is(err, upload.ErrThumbnailUnsupported), errors.Is(err, upload.ErrThumbnailUnavailable):
+		common.NotFound(c, err.Error())
+		return
+	case err != nil:
+		common.ServerError(c, err.Error())
+		return
+	}
 
-	// 返回文件内容
-	c.File(file.FilePath)
+	serveDerivative(c, file, derivative)
 }
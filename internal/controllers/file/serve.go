@@ -0,0 +1,170 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"template/internal/models"
+	"template/internal/services/upload"
+	"template/pkg/common"
+	"template/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveFile 通过存储接口输出文件内容，支持 Range 断点续传和 ETag/Last-Modified 缓存校验
+// 返回响应状态码和实际输出的范围（完整输出时为nil）
+func serveFile(c *gin.Context, file *models.UploadFile, disposition string) (int, *utils.ByteRange) {
+	etag := fileETag(file)
+	lastModified := fileLastModified(file)
+
+	c.Header("Accept-Ranges", "bytes")
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if utils.NotModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return http.StatusNotModified, nil
+	}
+
+	// 解析范围，If-Range 不满足时返回完整内容
+	var byteRange *utils.ByteRange
+	if header := c.GetHeader("Range"); header != "" && utils.IfRangeMatches(c.Request, etag, lastModified) {
+		var err error
+		byteRange, err = utils.ParseRange(header, file.FileSize)
+		if err != nil {
+			c.Header("Content-Range", fmt.Sprintf("bytes */%d", file.FileSize))
+			c.AbortWithStatus(http.StatusRequestedRangeNotSatisfiable)
+			return http.StatusRequestedRangeNotSatisfiable, nil
+		}
+	}
+
+	var obj io.ReadCloser
+	var err error
+	if byteRange != nil {
+		obj, err = upload.OpenStoredFileRange(file, byteRange.Start, byteRange.Length)
+	} else {
+		obj, err = upload.OpenStoredFile(file)
+	}
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			common.NotFound(c, "文件不存在")
+			return http.StatusNotFound, nil
+		}
+		common.ServerError(c, "读取文件失败")
+		return http.StatusInternalServerError, nil
+	}
+	defer obj.Close()
+
+	// 按记录的类型输出，禁止浏览器根据内容猜测类型
+	c.Header("Content-Type", file.MimeType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", disposition+"; filename=\""+file.Filename+"\"")
+
+	status := http.StatusOK
+	length := file.FileSize
+	if byteRange != nil {
+		status = http.StatusPartialContent
+		length = byteRange.Length
+		c.Header("Content-Range", fmt.Sprintf("bytes %d-%d/%d", byteRange.Start, byteRange.End(), file.FileSize))
+	}
+	c.Header("Content-Length", strconv.FormatInt(length, 10))
+	c.Status(status)
+
+	// 客户端中途断开时复制会失败，无需处理
+	if c.Request.Method != http.MethodHead {
+		io.Copy(c.Writer, obj)
+	}
+	return status, byteRange
+}
+
+// requestedVersion 按 version 查询参数返回文件的指定版本，没有该参数时返回当前版本
+// 版本号无效或不存在时输出错误响应并返回 false
+func requestedVersion(c *gin.Context, file *models.UploadFile) (*models.UploadFile, bool) {
+	param := c.Query("version")
+	if param == "" {
+		return file, true
+	}
+
+	version, err := strconv.Atoi(param)
+	if err != nil {
+		common.BadRequest(c, "版本号无效")
+		return nil, false
+	}
+	versioned, err := upload.GetFileAtVersion(file, version)
+	if err != nil {
+		if errors.Is(err, upload.ErrVersionNotFound) {
+			common.NotFound(c, err.Error())
+		} else {
+			common.ServerError(c, err.Error())
+		}
+		return nil, false
+	}
+	return versioned, true
+}
+
+// serveDerivative 输出派生文件（如缩略图）内容，支持 ETag/Last-Modified 缓存校验
+func serveDerivative(c *gin.Context, file *models.UploadFile, derivative *models.FileDerivative) {
+	etag := ""
+	if fileETag(file) != "" {
+		etag = strings.TrimSuffix(fileETag(file), `"`) + "-" + derivative.Kind + `"`
+		c.Header("ETag", etag)
+	}
+	lastModified := derivative.GetCreatedAt()
+	c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+
+	if utils.NotModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	obj, err := upload.OpenDerivative(derivative)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			common.NotFound(c, "文件不存在")
+			return
+		}
+		common.ServerError(c, "读取文件失败")
+		return
+	}
+	defer obj.Close()
+
+	c.Header("Content-Type", derivative.MimeType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Length", strconv.FormatInt(derivative.FileSize, 10))
+	c.Status(http.StatusOK)
+
+	if c.Request.Method != http.MethodHead {
+		io.Copy(c.Writer, obj)
+	}
+}
+
+// fileETag 使用文件内容哈希作为强ETag，旧文件没有SHA-256时使用MD5
+func fileETag(file *models.UploadFile) string {
+	hash := file.SHA256Hash
+	if hash == "" {
+		hash = file.MD5Hash
+	}
+	if hash == "" {
+		return ""
+	}
+	return `"` + hash + `"`
+}
+
+// fileLastModified 文件最后修改时间，取上传完成时间
+func fileLastModified(file *models.UploadFile) time.Time {
+	if file.UploadedAt != nil {
+		return *file.UploadedAt
+	}
+	return time.Time(file.UpdatedAt)
+}
@@ -0,0 +1,172 @@
+package rbac
+
+import (
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/models"
+	"template/internal/services/rbac"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListPermissions 获取全部权限
+func ListPermissions(c *gin.Context) {
+	perms, err := rbac.ListPermissions()
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items := make([]response.PermissionInfo, 0, len(perms))
+	for _, perm := range perms {
+		items = append(items, response.PermissionInfo{Code: perm.Code, Name: perm.Name})
+	}
+
+	errors.ResponseSuccess(c, items, "获取权限列表成功")
+}
+
+// ListRoles 获取全部角色
+func ListRoles(c *gin.Context) {
+	roles, err := rbac.ListRoles()
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, toRoleInfos(roles), "获取角色列表成功")
+}
+
+// GetRole 获取角色详情
+func GetRole(c *gin.Context) {
+	role, err := rbac.GetRole(c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	var info response.RoleInfo
+	errors.ResponseSuccess(c, info.FromModel(*role), "获取角色成功")
+}
+
+// CreateRole 创建角色
+func CreateRole(c *gin.Context) {
+	req, err := common.ValidateRequest[request.CreateRoleRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	role, err := rbac.CreateRole(req.Name, req.DisplayName, req.Description, req.Permissions)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	var info response.RoleInfo
+	errors.ResponseSuccess(c, info.FromModel(*role), "创建角色成功")
+}
+
+// UpdateRole 更新角色
+func UpdateRole(c *gin.Context) {
+	req, err := common.ValidateRequest[request.UpdateRoleRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	role, err := rbac.UpdateRole(c.Param("id"), req.DisplayName, req.Description, req.Permissions)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	var info response.RoleInfo
+	errors.ResponseSuccess(c, info.FromModel(*role), "更新角色成功")
+}
+
+// DeleteRole 删除角色
+func DeleteRole(c *gin.Context) {
+	if err := rbac.DeleteRole(c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "删除角色成功")
+}
+
+// GetUserRoles 获取用户的角色和生效的权限
+func GetUserRoles(c *gin.Context) {
+	userID := c.Param("id")
+
+	roles, err := rbac.GetUserRoles(userID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	authz, err := rbac.GetUserAuthorization(userID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.UserRolesResponse{
+		Roles:       toRoleInfos(roles),
+		Permissions: authz.Permissions,
+	}, "获取用户角色成功")
+}
+
+// SetUserRoles 设置用户的角色
+func SetUserRoles(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.SetUserRolesRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if err := rbac.SetUserRoles(claims.UserID, c.Param("id"), req.RoleIDs); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "设置用户角色成功")
+}
+
+// GetCurrentAuthorization 获取当前用户的角色和权限，供前端控制菜单和按钮
+func GetCurrentAuthorization(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	authz, err := rbac.GetUserAuthorization(claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.AuthorizationResponse{
+		Roles:       authz.Roles,
+		Permissions: authz.Permissions,
+	}, "获取权限成功")
+}
+
+// toRoleInfos 转换角色列表
+func toRoleInfos(roles []models.Role) []response.RoleInfo {
+	items := make([]response.RoleInfo, 0, len(roles))
+	for _, role := range roles {
+		var info response.RoleInfo
+		items = append(items, *info.FromModel(role))
+	}
+	return items
+}
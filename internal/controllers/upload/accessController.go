@@ -0,0 +1,146 @@
+package upload
+
+import (
+	"errors"
+
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/models"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateTemporaryAccess 为文件创建临时访问链接
+// @Summary 创建临时访问链接
+// @Description 生成带签名、有过期时间和使用次数限制的下载地址，不需要登录即可访问私有文件，可以限制允许访问的IP
+// @Tags 临时访问
+// @Accept json
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param request body request.CreateTempAccessRequest true "链接设置"
+// @Success 200 {object} response.TemporaryAccessInfo
+// @Failure 400,404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/temp-access [post]
+func CreateTemporaryAccess(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.CreateTempAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	file, err := uploadService.GetUserFile(c.Param("fileID"), user.UserID)
+	if err != nil {
+		handleTemporaryAccessError(c, err)
+		return
+	}
+	access, err := uploadService.CreateTemporaryAccess(file.ID.String(), user.UserID, &uploadService.TemporaryAccessOptions{
+		Purpose:    req.Purpose,
+		ExpiresAt:  req.ExpiresAt,
+		UsageLimit: req.UsageLimit,
+		AllowedIPs: req.AllowedIPs,
+	})
+	if err != nil {
+		handleTemporaryAccessError(c, err)
+		return
+	}
+
+	common.Success(c, newTemporaryAccessInfo(file, access), "创建临时访问链接成功")
+}
+
+// ListTemporaryAccesses 获取文件的临时访问链接
+// @Summary 临时访问链接列表
+// @Description 返回文件的全部临时访问链接，包括已过期和已停用的链接，最近创建的在前
+// @Tags 临时访问
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Success 200 {array} response.TemporaryAccessInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/temp-access [get]
+func ListTemporaryAccesses(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	file, err := uploadService.GetUserFile(c.Param("fileID"), user.UserID)
+	if err != nil {
+		handleTemporaryAccessError(c, err)
+		return
+	}
+	accesses, err := uploadService.ListTemporaryAccesses(file.ID.String(), user.UserID)
+	if err != nil {
+		handleTemporaryAccessError(c, err)
+		return
+	}
+
+	items := make([]response.TemporaryAccessInfo, 0, len(accesses))
+	for i := range accesses {
+		items = append(items, newTemporaryAccessInfo(file, &accesses[i]))
+	}
+
+	common.Success(c, items, "获取临时访问链接成功")
+}
+
+// RevokeTemporaryAccess 停用临时访问链接
+// @Summary 停用临时访问链接
+// @Description 停用后链接立即失效，不能重新启用
+// @Tags 临时访问
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param accessID path string true "链接ID"
+// @Success 200
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/temp-access/{accessID} [delete]
+func RevokeTemporaryAccess(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	if err := uploadService.RevokeTemporaryAccess(c.Param("fileID"), user.UserID, c.Param("accessID")); err != nil {
+		handleTemporaryAccessError(c, err)
+		return
+	}
+
+	common.Success(c, nil, "停用临时访问链接成功")
+}
+
+// handleTemporaryAccessError 文件或链接不存在时返回404，参数无效或有效链接过多时返回400
+func handleTemporaryAccessError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, uploadService.ErrFileNotFound), errors.Is(err, uploadService.ErrTemporaryAccessNotFound):
+		common.NotFound(c, err.Error())
+	case errors.Is(err, uploadService.ErrInvalidAccessExpiry),
+		errors.Is(err, uploadService.ErrInvalidAllowedIP),
+		errors.Is(err, uploadService.ErrTooManyTemporaryAccess):
+		common.BadRequest(c, err.Error())
+	default:
+		common.ServerError(c, err.Error())
+	}
+}
+
+// newTemporaryAccessInfo 构建临时访问链接信息
+func newTemporaryAccessInfo(file *models.UploadFile, access *models.TemporaryAccess) response.TemporaryAccessInfo {
+	return response.TemporaryAccessInfo{
+		AccessID:   access.ID.String(),
+		Purpose:    access.Purpose,
+		URL:        uploadService.TemporaryAccessURL(file, access),
+		ExpiresAt:  access.ExpiresAt,
+		UsageLimit: access.UsageLimit,
+		UsageCount: access.UsageCount,
+		AllowedIPs: uploadService.ParseAllowedIPs(access.AllowedIPs),
+		IsActive:   access.IsActive,
+		CreatedAt:  access.GetCreatedAt(),
+	}
+}
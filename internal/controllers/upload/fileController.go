@@ -0,0 +1,208 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/models"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMyFiles 分页获取当前用户的文件
+// @Summary 我的文件列表
+// @Description 获取当前用户已上传的文件，支持按类型、文件名和上传日期筛选及排序
+// @Tags 文件管理
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Param mimeType query string false "文件类型，image/* 匹配所有图片"
+// @Param keyword query string false "文件名关键词"
+// @Param startDate query string false "上传日期起始，如 2024-01-01"
+// @Param endDate query string false "上传日期截止，如 2024-01-31"
+// @Param sortBy query string false "排序字段：createdAt filename fileSize downloadCount"
+// @Param sortOrder query string false "排序方向：asc desc"
+// @Success 200 {object} common.PaginationResponse
+// @Router /upload/files [get]
+func ListMyFiles(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.ListFilesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	files, total, err := uploadService.SearchUserFiles(user.UserID, &req)
+	if err != nil {
+		common.ServerError(c, err.Error())
+		return
+	}
+
+	items := make([]response.FileInfo, 0, len(files))
+	for i := range files {
+		items = append(items, newFileInfo(&files[i]))
+	}
+
+	common.Success(c, common.NewPaginationResponse(items, &req.PaginationRequest, total), "获取文件列表成功")
+}
+
+// GetMyFile 获取当前用户的文件详情
+// @Summary 文件详情
+// @Tags 文件管理
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Success 200 {object} response.FileInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID} [get]
+func GetMyFile(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	file, err := uploadService.GetUserFile(c.Param("fileID"), user.UserID)
+	if err != nil {
+		handleFileError(c, err)
+		return
+	}
+
+	common.Success(c, newFileInfo(file), "获取文件详情成功")
+}
+
+// RenameMyFile 修改文件名
+// @Summary 修改文件名
+// @Description 修改文件名，扩展名不能修改
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param request body request.RenameFileRequest true "新文件名"
+// @Success 200 {object} response.FileInfo
+// @Failure 400,404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/name [put]
+func RenameMyFile(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.RenameFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	file, err := uploadService.RenameUserFile(c.Param("fileID"), user.UserID, req.Filename)
+	if err != nil {
+		handleFileError(c, err)
+		return
+	}
+
+	common.Success(c, newFileInfo(file), "修改文件名成功")
+}
+
+// UpdateMyFileSettings 设置文件是否公开
+// @Summary 设置文件可见性
+// @Description 公开文件任何人都可以访问，私有文件只有所有者和被授权的用户可以访问
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param request body request.UpdateFileSettingsRequest true "文件设置"
+// @Success 200 {object} response.FileInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/settings [put]
+func UpdateMyFileSettings(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.UpdateFileSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	file, err := uploadService.SetUserFileVisibility(c.Param("fileID"), user.UserID, *req.IsPublic)
+	if err != nil {
+		handleFileError(c, err)
+		return
+	}
+
+	common.Success(c, newFileInfo(file), "设置文件成功")
+}
+
+// DeleteMyFiles 批量删除当前用户的文件
+// @Summary 批量删除文件
+// @Description 将文件移入回收站，可以在回收站中恢复；不存在或不属于当前用户的文件记入 failed
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param request body request.BatchDeleteFilesRequest true "文件ID列表"
+// @Success 200 {object} response.BatchDeleteFilesResponse
+// @Router /upload/files/batch-delete [post]
+func DeleteMyFiles(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.BatchDeleteFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	deleted, failed := uploadService.DeleteUserFiles(user.UserID, req.FileIDs)
+
+	common.Success(c, response.BatchDeleteFilesResponse{Deleted: deleted, Failed: failed}, "删除文件完成")
+}
+
+// handleFileError 文件不存在时返回404，文件名无效时返回400
+func handleFileError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, uploadService.ErrFileNotFound):
+		common.NotFound(c, err.Error())
+	case errors.Is(err, uploadService.ErrInvalidFilename), errors.Is(err, uploadService.ErrFileExtensionChanged):
+		common.BadRequest(c, err.Error())
+	default:
+		common.ServerError(c, err.Error())
+	}
+}
+
+// newFileInfo 构建文件信息
+func newFileInfo(file *models.UploadFile) response.FileInfo {
+	fileID := file.ID.String()
+	return response.FileInfo{
+		FileID:        fileID,
+		FolderID:      file.FolderID,
+		Filename:      file.Filename,
+		FileSize:      file.FileSize,
+		MimeType:      file.MimeType,
+		Extension:     file.Extension,
+		SHA256Hash:    file.SHA256Hash,
+		Status:        file.UploadStatus,
+		Version:       file.Version,
+		IsPublic:      file.IsPublic,
+		DownloadCount: file.DownloadCount,
+		PreviewURL:    fmt.Sprintf("/files/preview/%s/%s", fileID, file.Filename),
+		DownloadURL:   fmt.Sprintf("/files/download/%s/%s", fileID, file.Filename),
+		CreatedAt:     file.GetCreatedAt(),
+		UploadedAt:    file.UploadedAt,
+	}
+}
@@ -0,0 +1,314 @@
+package upload
+
+import (
+	"errors"
+
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/models"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+	appErrors "template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateFolder 创建文件夹
+// @Summary 创建文件夹
+// @Description 在指定文件夹下创建子文件夹，同一文件夹下不能重名
+// @Tags 文件夹
+// @Accept json
+// @Produce json
+// @Param request body request.CreateFolderRequest true "文件夹信息"
+// @Success 200 {object} response.FolderInfo
+// @Failure 400,404,409 {object} errors.ErrorResponse
+// @Router /upload/folders [post]
+func CreateFolder(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	folder, err := uploadService.CreateFolder(user.UserID, req.Name, req.ParentID)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+
+	common.Success(c, newFolderInfo(folder), "创建文件夹成功")
+}
+
+// GetFolder 获取文件夹详情
+// @Summary 文件夹详情
+// @Description 返回文件夹信息和从根目录到该文件夹的路径
+// @Tags 文件夹
+// @Produce json
+// @Param folderID path string true "文件夹ID"
+// @Success 200 {object} response.FolderInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/folders/{folderID} [get]
+func GetFolder(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	folder, err := uploadService.GetUserFolder(c.Param("folderID"), user.UserID)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+	path, err := uploadService.GetFolderBreadcrumbs(folder)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+
+	info := newFolderInfo(folder)
+	info.Breadcrumbs = make([]response.Breadcrumb, 0, len(path))
+	for _, f := range path {
+		info.Breadcrumbs = append(info.Breadcrumbs, response.Breadcrumb{FolderID: f.ID.String(), Name: f.Name})
+	}
+
+	common.Success(c, info, "获取文件夹详情成功")
+}
+
+// ListFolderChildren 获取文件夹内容
+// @Summary 文件夹内容
+// @Description 返回文件夹下的全部子文件夹和分页的文件，不传 folderID 时查询根目录
+// @Tags 文件夹
+// @Produce json
+// @Param folderID query string false "文件夹ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} response.FolderChildrenResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/folders/children [get]
+func ListFolderChildren(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.ListFolderChildrenRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	children, err := uploadService.ListFolderChildren(user.UserID, req.FolderID, &req.PaginationRequest)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+
+	folders := make([]response.FolderInfo, 0, len(children.Folders))
+	for i := range children.Folders {
+		folders = append(folders, newFolderInfo(&children.Folders[i]))
+	}
+	files := make([]response.FileInfo, 0, len(children.Files))
+	for i := range children.Files {
+		files = append(files, newFileInfo(&children.Files[i]))
+	}
+
+	common.Success(c, response.FolderChildrenResponse{
+		Folders: folders,
+		Files:   common.NewPaginationResponse(files, &req.PaginationRequest, children.Total),
+	}, "获取文件夹内容成功")
+}
+
+// RenameFolder 修改文件夹名称
+// @Summary 修改文件夹名称
+// @Tags 文件夹
+// @Accept json
+// @Produce json
+// @Param folderID path string true "文件夹ID"
+// @Param request body request.RenameFolderRequest true "新名称"
+// @Success 200 {object} response.FolderInfo
+// @Failure 400,404,409 {object} errors.ErrorResponse
+// @Router /upload/folders/{folderID}/name [put]
+func RenameFolder(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.RenameFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	folder, err := uploadService.RenameFolder(c.Param("folderID"), user.UserID, req.Name)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+
+	common.Success(c, newFolderInfo(folder), "修改文件夹名称成功")
+}
+
+// MoveFolder 移动文件夹
+// @Summary 移动文件夹
+// @Description 将文件夹连同其中的内容移到另一个文件夹下，不能移到自身或子文件夹中
+// @Tags 文件夹
+// @Accept json
+// @Produce json
+// @Param folderID path string true "文件夹ID"
+// @Param request body request.MoveFolderRequest true "目标文件夹"
+// @Success 200 {object} response.FolderInfo
+// @Failure 400,404,409 {object} errors.ErrorResponse
+// @Router /upload/folders/{folderID}/parent [put]
+func MoveFolder(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.MoveFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	folder, err := uploadService.MoveFolder(c.Param("folderID"), user.UserID, req.ParentID)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+
+	common.Success(c, newFolderInfo(folder), "移动文件夹成功")
+}
+
+// UpdateFolderSettings 设置文件夹是否公开
+// @Summary 设置文件夹可见性
+// @Description 私有文件夹及其子文件夹中的文件都按私有文件处理，文件夹的授权对其中的文件同样有效
+// @Tags 文件夹
+// @Accept json
+// @Produce json
+// @Param folderID path string true "文件夹ID"
+// @Param request body request.UpdateFileSettingsRequest true "文件夹设置"
+// @Success 200 {object} response.FolderInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/folders/{folderID}/settings [put]
+func UpdateFolderSettings(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.UpdateFileSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	folder, err := uploadService.SetFolderVisibility(c.Param("folderID"), user.UserID, *req.IsPublic)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+
+	common.Success(c, newFolderInfo(folder), "设置文件夹成功")
+}
+
+// DeleteFolder 删除文件夹
+// @Summary 删除文件夹
+// @Description 删除文件夹和全部子文件夹，其中的文件移入回收站
+// @Tags 文件夹
+// @Produce json
+// @Param folderID path string true "文件夹ID"
+// @Success 200
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/folders/{folderID} [delete]
+func DeleteFolder(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	deleted, err := uploadService.DeleteFolder(c.Param("folderID"), user.UserID)
+	if err != nil {
+		handleFolderError(c, err)
+		return
+	}
+
+	common.Success(c, gin.H{"deletedFiles": deleted}, "删除文件夹成功")
+}
+
+// MoveMyFile 将文件移到文件夹
+// @Summary 移动文件
+// @Tags 文件夹
+// @Accept json
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param request body request.MoveFileRequest true "目标文件夹"
+// @Success 200 {object} response.FileInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/folder [put]
+func MoveMyFile(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.MoveFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	file, err := uploadService.MoveUserFile(c.Param("fileID"), user.UserID, req.FolderID)
+	if err != nil {
+		if errors.Is(err, uploadService.ErrFileNotFound) {
+			handleFileError(c, err)
+			return
+		}
+		handleFolderError(c, err)
+		return
+	}
+
+	common.Success(c, newFileInfo(file), "移动文件成功")
+}
+
+// handleFolderError 文件夹不存在时返回404，重名时返回409，名称无效或移动位置无效时返回400
+func handleFolderError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, uploadService.ErrFolderNotFound):
+		common.NotFound(c, err.Error())
+	case errors.Is(err, uploadService.ErrFolderNameExists):
+		common.HandleError(c, appErrors.New(appErrors.CodeConflict, err.Error()))
+	case errors.Is(err, uploadService.ErrInvalidFolderName),
+		errors.Is(err, uploadService.ErrFolderCycle),
+		errors.Is(err, uploadService.ErrFolderTooDeep):
+		common.BadRequest(c, err.Error())
+	default:
+		common.ServerError(c, err.Error())
+	}
+}
+
+// newFolderInfo 构建文件夹信息
+func newFolderInfo(folder *models.Folder) response.FolderInfo {
+	return response.FolderInfo{
+		FolderID:  folder.ID.String(),
+		ParentID:  folder.ParentID,
+		Name:      folder.Name,
+		IsPublic:  folder.IsPublic,
+		CreatedAt: folder.GetCreatedAt(),
+	}
+}
@@ -0,0 +1,117 @@
+package upload
+
+import (
+	"errors"
+
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/models"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListQuarantinedFiles 分页获取被隔离的文件
+// @Summary 隔离文件列表
+// @Description 获取安全扫描发现威胁而被隔离的文件，按上传时间排序
+// @Tags 文件上传
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} common.PaginationResponse
+// @Router /upload/quarantine [get]
+func ListQuarantinedFiles(c *gin.Context) {
+	var req request.ListQuarantinedFilesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	files, total, err := uploadService.ListQuarantinedFiles(&req.PaginationRequest)
+	if err != nil {
+		common.ServerError(c, err.Error())
+		return
+	}
+
+	items := make([]response.QuarantinedFileInfo, 0, len(files))
+	for i := range files {
+		items = append(items, newQuarantinedFileInfo(&files[i]))
+	}
+
+	common.Success(c, common.NewPaginationResponse(items, &req.PaginationRequest, total), "获取隔离文件成功")
+}
+
+// ReleaseQuarantinedFile 解除文件隔离
+// @Summary 解除文件隔离
+// @Description 管理员确认文件安全后解除隔离，文件恢复为可访问
+// @Tags 文件上传
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Success 200 {object} response.QuarantinedFileInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/quarantine/{fileID}/release [post]
+func ReleaseQuarantinedFile(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	file, err := uploadService.ReleaseQuarantinedFile(c.Param("fileID"), user.UserID)
+	if err != nil {
+		handleQuarantineError(c, err)
+		return
+	}
+
+	common.Success(c, newQuarantinedFileInfo(file), "已解除隔离")
+}
+
+// DeleteQuarantinedFile 删除被隔离的文件
+// @Summary 删除隔离文件
+// @Description 彻底删除被隔离的文件记录，内容不再被引用时一并删除存储
+// @Tags 文件上传
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Success 200
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/quarantine/{fileID} [delete]
+func DeleteQuarantinedFile(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	if err := uploadService.DeleteQuarantinedFile(c.Param("fileID"), user.UserID); err != nil {
+		handleQuarantineError(c, err)
+		return
+	}
+
+	common.SuccessWithMessage(c, "隔离文件已删除")
+}
+
+// handleQuarantineError 文件不存在或未被隔离时返回404
+func handleQuarantineError(c *gin.Context, err error) {
+	if errors.Is(err, uploadService.ErrNotQuarantined) {
+		common.NotFound(c, err.Error())
+		return
+	}
+	common.ServerError(c, err.Error())
+}
+
+// newQuarantinedFileInfo 构建隔离文件信息
+func newQuarantinedFileInfo(file *models.UploadFile) response.QuarantinedFileInfo {
+	return response.QuarantinedFileInfo{
+		FileID:        file.ID.String(),
+		Filename:      file.Filename,
+		FileSize:      file.FileSize,
+		MimeType:      file.MimeType,
+		SHA256Hash:    file.SHA256Hash,
+		UserID:        file.UserID,
+		ScanSignature: file.ScanSignature,
+		UploadedAt:    file.UploadedAt,
+		ScannedAt:     file.ScannedAt,
+	}
+}
@@ -0,0 +1,136 @@
+package upload
+
+import (
+	"errors"
+
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuota 获取当前用户的存储用量和配额
+// @Summary 存储配额
+// @Description 获取当前用户已使用、上传中预留的空间和配额
+// @Tags 文件上传
+// @Produce json
+// @Success 200 {object} response.QuotaResponse
+// @Router /upload/quota [get]
+func GetQuota(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	quota, err := uploadService.GetQuota(user.UserID)
+	if err != nil {
+		common.ServerError(c, err.Error())
+		return
+	}
+
+	common.Success(c, newQuotaResponse(quota), "获取存储配额成功")
+}
+
+// GetUserQuota 获取用户的存储用量和配额
+// @Summary 查询用户配额
+// @Tags 文件上传
+// @Produce json
+// @Param userID path string true "用户ID"
+// @Success 200 {object} response.QuotaResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/quota/users/{userID} [get]
+func GetUserQuota(c *gin.Context) {
+	quota, err := uploadService.GetUserQuota(c.Param("userID"))
+	if err != nil {
+		handleQuotaError(c, err)
+		return
+	}
+
+	common.Success(c, newQuotaResponse(quota), "获取存储配额成功")
+}
+
+// SetUserQuota 为用户单独设置配额
+// @Summary 设置用户配额
+// @Description quotaBytes 为0表示不限制，为空时恢复使用角色配额
+// @Tags 文件上传
+// @Accept json
+// @Produce json
+// @Param userID path string true "用户ID"
+// @Param request body request.SetUserQuotaRequest true "配额"
+// @Success 200 {object} response.QuotaResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/quota/users/{userID} [put]
+func SetUserQuota(c *gin.Context) {
+	var req request.SetUserQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	quota, err := uploadService.SetUserQuota(c.Param("userID"), req.QuotaBytes)
+	if err != nil {
+		handleQuotaError(c, err)
+		return
+	}
+
+	common.Success(c, newQuotaResponse(quota), "设置配额成功")
+}
+
+// RecomputeUserQuota 按文件记录重新统计用户的用量
+// @Summary 重新统计用户用量
+// @Tags 文件上传
+// @Produce json
+// @Param userID path string true "用户ID"
+// @Success 200 {object} response.QuotaResponse
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/quota/users/{userID}/recompute [post]
+func RecomputeUserQuota(c *gin.Context) {
+	quota, err := uploadService.RecomputeQuota(c.Param("userID"))
+	if err != nil {
+		handleQuotaError(c, err)
+		return
+	}
+
+	common.Success(c, newQuotaResponse(quota), "重新统计用量成功")
+}
+
+// RecomputeAllQuotas 按文件记录重新统计所有用户的用量
+// @Summary 重新统计所有用户用量
+// @Tags 文件上传
+// @Produce json
+// @Success 200
+// @Router /upload/quota/recompute [post]
+func RecomputeAllQuotas(c *gin.Context) {
+	count, err := uploadService.RecomputeAllQuotas()
+	if err != nil {
+		common.ServerError(c, err.Error())
+		return
+	}
+
+	common.Success(c, gin.H{"userCount": count}, "重新统计用量成功")
+}
+
+// handleQuotaError 用户不存在时返回404
+func handleQuotaError(c *gin.Context, err error) {
+	if errors.Is(err, uploadService.ErrQuotaUserNotFound) {
+		common.NotFound(c, err.Error())
+		return
+	}
+	common.ServerError(c, err.Error())
+}
+
+// newQuotaResponse 构建存储用量和配额响应
+func newQuotaResponse(quota *uploadService.QuotaInfo) response.QuotaResponse {
+	return response.QuotaResponse{
+		UserID:         quota.UserID,
+		UsedBytes:      quota.UsedBytes,
+		ReservedBytes:  quota.ReservedBytes,
+		LimitBytes:     quota.LimitBytes,
+		RemainingBytes: quota.RemainingBytes(),
+		IsCustom:       quota.IsCustom,
+	}
+}
@@ -0,0 +1,133 @@
+package upload
+
+import (
+	"errors"
+
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTrash 分页获取当前用户回收站中的文件
+// @Summary 回收站文件列表
+// @Description 返回已删除但尚未彻底删除的文件，最近删除的在前；超过保留时间的文件由定时任务彻底删除
+// @Tags 回收站
+// @Produce json
+// @Param page query int false "页码"
+// @Param page_size query int false "每页数量"
+// @Success 200 {object} common.PaginationResponse
+// @Router /upload/trash [get]
+func ListTrash(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req common.PaginationRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	files, total, err := uploadService.ListTrash(user.UserID, &req)
+	if err != nil {
+		common.ServerError(c, err.Error())
+		return
+	}
+
+	retention := uploadService.TrashRetention()
+	items := make([]response.TrashedFileInfo, 0, len(files))
+	for i := range files {
+		deletedAt := files[i].DeletedAt.Time
+		items = append(items, response.TrashedFileInfo{
+			FileInfo:  newFileInfo(&files[i]),
+			DeletedAt: deletedAt,
+			ExpiresAt: deletedAt.Add(retention),
+		})
+	}
+
+	common.Success(c, common.NewPaginationResponse(items, &req, total), "获取回收站文件成功")
+}
+
+// RestoreTrash 批量恢复回收站中的文件
+// @Summary 恢复文件
+// @Description 将文件恢复到原来的文件夹，文件夹已被删除时恢复到根目录；不在回收站中或不属于当前用户的文件记入 failed
+// @Tags 回收站
+// @Accept json
+// @Produce json
+// @Param request body request.RestoreTrashRequest true "文件ID列表"
+// @Success 200 {object} response.RestoreTrashResponse
+// @Router /upload/trash/restore [post]
+func RestoreTrash(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.RestoreTrashRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.BadRequest(c, "参数错误")
+		return
+	}
+
+	restored, failed := uploadService.RestoreTrashedFiles(user.UserID, req.FileIDs)
+
+	common.Success(c, response.RestoreTrashResponse{Restored: restored, Failed: failed}, "恢复文件完成")
+}
+
+// PurgeTrashedFile 彻底删除回收站中的文件
+// @Summary 彻底删除文件
+// @Description 删除文件记录及其分享、授权和历史版本，文件内容不再被引用时一并删除存储
+// @Tags 回收站
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Success 200
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/trash/{fileID} [delete]
+func PurgeTrashedFile(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	if err := uploadService.PurgeTrashedFile(c.Param("fileID"), user.UserID); err != nil {
+		if errors.Is(err, uploadService.ErrTrashedFileNotFound) {
+			common.NotFound(c, err.Error())
+			return
+		}
+		common.ServerError(c, err.Error())
+		return
+	}
+
+	common.Success(c, nil, "彻底删除文件成功")
+}
+
+// EmptyTrash 清空当前用户的回收站
+// @Summary 清空回收站
+// @Description 彻底删除回收站中的全部文件
+// @Tags 回收站
+// @Produce json
+// @Success 200
+// @Router /upload/trash [delete]
+func EmptyTrash(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	purged, err := uploadService.EmptyTrash(user.UserID)
+	if err != nil {
+		common.ServerError(c, err.Error())
+		return
+	}
+
+	common.Success(c, gin.H{"purged": purged}, "清空回收站成功")
+}
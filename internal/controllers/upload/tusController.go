@@ -0,0 +1,248 @@
+package upload
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"template/internal/middleware"
+	uploadService "template/internal/services/upload"
+	"template/pkg/upload"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tusExposeHeaders 浏览器客户端需要读取的tus响应头
+const tusExposeHeaders = "Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Tus-Checksum-Algorithm, " +
+	"Location, Upload-Offset, Upload-Length, Upload-Metadata, Upload-Expires"
+
+// TusOptions 查询服务端支持的tus协议版本和扩展
+// @Summary tus能力查询
+// @Description 返回支持的tus协议版本、扩展、最大文件大小和校验算法
+// @Tags 文件上传
+// @Success 204
+// @Router /upload/tus [options]
+func TusOptions(c *gin.Context) {
+	setTusHeaders(c)
+	c.Header("Tus-Version", upload.TusVersion)
+	c.Header("Tus-Extension", upload.TusExtensions)
+	c.Header("Tus-Max-Size", strconv.FormatInt(upload.NewDefaultConfig().MaxFileSize, 10))
+	c.Header("Tus-Checksum-Algorithm", upload.TusChecksumAlgorithms)
+	c.Status(http.StatusNoContent)
+}
+
+// TusCreate 创建tus上传
+// @Summary 创建tus上传
+// @Description 通过 Upload-Length 声明文件大小，Upload-Metadata 中的 filename 指定文件名，Location 响应头返回上传地址
+// @Tags 文件上传
+// @Param Tus-Resumable header string true "协议版本" default(1.0.0)
+// @Param Upload-Length header int true "文件大小"
+// @Param Upload-Metadata header string true "元数据，如 filename <Base64编码的文件名>，可选 folderID <Base64编码的文件夹ID>"
+// @Success 201
+// @Failure 400,404,412,413 {string} string
+// @Router /upload/tus [post]
+func TusCreate(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		tusError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		tusError(c, http.StatusBadRequest, "Upload-Length 无效")
+		return
+	}
+	if length > upload.NewDefaultConfig().MaxFileSize {
+		tusError(c, http.StatusRequestEntityTooLarge, "文件大小超出限制")
+		return
+	}
+
+	info, err := uploadService.CreateTusUpload(user.UserID, length, c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, uploadService.ErrQuotaExceeded), errors.Is(err, uploadService.ErrFileTooLarge):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, uploadService.ErrFolderNotFound):
+			status = http.StatusNotFound
+		}
+		tusError(c, status, err.Error())
+		return
+	}
+
+	c.Header("Location", c.Request.URL.Path+"/"+info.File.ID.String())
+	setTusUploadHeaders(c, info)
+	c.Status(http.StatusCreated)
+}
+
+// TusHead 查询tus上传的进度
+// @Summary 查询tus上传进度
+// @Description Upload-Offset 响应头返回已上传的字节数，客户端从该位置继续上传
+// @Tags 文件上传
+// @Param fileID path string true "文件ID"
+// @Param Tus-Resumable header string true "协议版本" default(1.0.0)
+// @Success 200
+// @Failure 404,410,412 {string} string
+// @Router /upload/tus/{fileID} [head]
+func TusHead(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		tusError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	info, err := uploadService.GetTusUpload(c.Param("fileID"), user.UserID)
+	if err != nil {
+		handleTusError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Length", strconv.FormatInt(info.File.FileSize, 10))
+	if info.Metadata != "" {
+		c.Header("Upload-Metadata", info.Metadata)
+	}
+	setTusUploadHeaders(c, info)
+	c.Status(http.StatusOK)
+}
+
+// TusPatch 从 Upload-Offset 开始写入上传内容
+// @Summary 写入tus上传内容
+// @Description 请求体为从 Upload-Offset 开始的文件内容，可以通过 Upload-Checksum 校验本次写入的内容，写满后自动合并为完整文件
+// @Tags 文件上传
+// @Accept application/offset+octet-stream
+// @Param fileID path string true "文件ID"
+// @Param Tus-Resumable header string true "协议版本" default(1.0.0)
+// @Param Upload-Offset header int true "写入位置"
+// @Param Upload-Checksum header string false "校验和，如 sha1 <Base64编码的摘要>"
+// @Success 204
+// @Failure 400,404,409,410,412,413,415,423,460 {string} string
+// @Router /upload/tus/{fileID} [patch]
+func TusPatch(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		tusError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if c.ContentType() != upload.TusOffsetOctetStream {
+		tusError(c, http.StatusUnsupportedMediaType, "Content-Type 必须为 "+upload.TusOffsetOctetStream)
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		tusError(c, http.StatusBadRequest, "Upload-Offset 无效")
+		return
+	}
+
+	var checksum *upload.TusChecksum
+	if header := c.GetHeader("Upload-Checksum"); header != "" {
+		if checksum, err = upload.ParseTusChecksum(header); err != nil {
+			tusError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	info, err := uploadService.WriteTusUpload(c.Param("fileID"), user.UserID, offset, c.Request.Body, checksum)
+	if err != nil {
+		handleTusError(c, err)
+		return
+	}
+
+	setTusUploadHeaders(c, info)
+	c.Status(http.StatusNoContent)
+}
+
+// TusDelete 终止tus上传
+// @Summary 终止tus上传
+// @Description 删除上传的文件记录和已上传的内容
+// @Tags 文件上传
+// @Param fileID path string true "文件ID"
+// @Param Tus-Resumable header string true "协议版本" default(1.0.0)
+// @Success 204
+// @Failure 404,412,423 {string} string
+// @Router /upload/tus/{fileID} [delete]
+func TusDelete(c *gin.Context) {
+	if !checkTusResumable(c) {
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		tusError(c, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	if err := uploadService.TerminateTusUpload(c.Param("fileID"), user.UserID); err != nil {
+		handleTusError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkTusResumable 设置tus公共响应头，并检查客户端使用的协议版本
+func checkTusResumable(c *gin.Context) bool {
+	setTusHeaders(c)
+	if c.GetHeader("Tus-Resumable") != upload.TusVersion {
+		c.Header("Tus-Version", upload.TusVersion)
+		tusError(c, http.StatusPreconditionFailed, "不支持的tus协议版本")
+		return false
+	}
+	return true
+}
+
+// setTusHeaders 设置tus公共响应头
+func setTusHeaders(c *gin.Context) {
+	c.Header("Tus-Resumable", upload.TusVersion)
+	c.Header("Access-Control-Expose-Headers", tusExposeHeaders)
+}
+
+// setTusUploadHeaders 设置上传进度和过期时间响应头
+func setTusUploadHeaders(c *gin.Context, info *uploadService.TusUploadInfo) {
+	c.Header("Upload-Offset", strconv.FormatInt(info.Offset, 10))
+	if info.ExpiresAt != nil {
+		c.Header("Upload-Expires", info.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+// handleTusError 按tus协议把服务层错误转换为状态码
+func handleTusError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, uploadService.ErrTusNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, uploadService.ErrTusGone):
+		status = http.StatusGone
+	case errors.Is(err, uploadService.ErrTusOffsetMismatch), errors.Is(err, uploadService.ErrUploadNotMerging):
+		status = http.StatusConflict
+	case errors.Is(err, uploadService.ErrTusChecksumMismatch):
+		status = upload.StatusChecksumMismatch
+	case errors.Is(err, uploadService.ErrTusTooLarge):
+		status = http.StatusRequestEntityTooLarge
+	case errors.Is(err, uploadService.ErrTusLocked):
+		status = http.StatusLocked
+	case errors.Is(err, upload.ErrContentTypeMismatch):
+		status = http.StatusUnsupportedMediaType
+	}
+	tusError(c, status, err.Error())
+}
+
+// tusError tus客户端按状态码处理错误，响应体为纯文本的错误信息
+func tusError(c *gin.Context, status int, message string) {
+	c.Header("Cache-Control", "no-store")
+	c.String(status, message)
+}
@@ -1,12 +1,14 @@
 package upload
 
 import (
+	"errors"
 	"strconv"
 
 	"template/internal/dto/request"
 	"template/internal/middleware"
 	uploadService "template/internal/services/upload"
 	"template/pkg/common"
+	appErrors "template/pkg/errors"
 	"template/pkg/upload"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +22,7 @@ import (
 // @Produce json
 // @Param file formData file true "上传的文件"
 // @Param description formData string false "文件描述"
+// @Param folderID formData string false "目标文件夹ID，为空时上传到根目录"
 // @Success 200 {object} response.SimpleUploadResponse
 // @Failure 400 {object} errors.ErrorResponse
 // @Router /upload/simple [post]
@@ -46,9 +49,9 @@ func SimpleUpload(c *gin.Context) {
 	}
 
 	// 4. 调用服务层处理上传
-	result, err := uploadService.SimpleUpload(file, user.UserID)
+	result, err := uploadService.SimpleUpload(file, user.UserID, req.FolderID)
 	if err != nil {
-		common.ServerError(c, err.Error())
+		handleUploadError(c, err)
 		return
 	}
 
@@ -86,11 +89,13 @@ func InitChunkUpload(c *gin.Context) {
 		req.Filename,
 		req.FileSize,
 		req.MD5Hash,
+		req.SHA256Hash,
 		req.ChunkSize,
 		user.UserID,
+		req.FolderID,
 	)
 	if err != nil {
-		common.ServerError(c, err.Error())
+		handleUploadError(c, err)
 		return
 	}
 
@@ -145,7 +150,7 @@ func UploadChunk(c *gin.Context) {
 	// 4. 调用服务层处理分片上传
 	result, err := uploadService.UploadChunk(fileID, chunkIndex, md5Hash, chunk)
 	if err != nil {
-		common.ServerError(c, err.Error())
+		handleUploadError(c, err)
 		return
 	}
 
@@ -181,7 +186,7 @@ func MergeChunks(c *gin.Context) {
 	// 3. 调用服务层合并分片
 	result, err := uploadService.MergeChunks(req.FileID)
 	if err != nil {
-		common.ServerError(c, err.Error())
+		handleUploadError(c, err)
 		return
 	}
 
@@ -189,6 +194,33 @@ func MergeChunks(c *gin.Context) {
 	common.Success(c, result, "分片合并成功")
 }
 
+// AbortChunkUpload 终止分片上传
+// @Summary 终止分片上传
+// @Description 终止未完成的分片上传，删除已上传的分片和文件记录，释放预留的存储空间
+// @Tags 文件上传
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Success 200
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/chunk/{fileID} [delete]
+func AbortChunkUpload(c *gin.Context) {
+	// 1. 验证用户登录
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	// 2. 调用服务层终止上传
+	if err := uploadService.AbortChunkUpload(c.Param("fileID"), user.UserID); err != nil {
+		handleUploadError(c, err)
+		return
+	}
+
+	// 3. 返回成功响应
+	common.SuccessWithMessage(c, "分片上传已终止")
+}
+
 // GetUploadProgress 获取上传进度
 // @Summary 获取上传进度
 // @Description 获取文件上传进度信息
@@ -246,3 +278,24 @@ func GetUploadConfig(c *gin.Context) {
 
 	common.Success(c, result, "获取上传配置成功")
 }
+
+// handleUploadError 文件不合要求、内容校验失败属于请求错误，分片未传完时返回409，其他错误按服务器错误返回
+func handleUploadError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, upload.ErrContentTypeMismatch),
+		errors.Is(err, uploadService.ErrInvalidFilename),
+		errors.Is(err, uploadService.ErrFileTooLarge),
+		errors.Is(err, uploadService.ErrUnsupportedFileType),
+		errors.Is(err, uploadService.ErrChunkDigestMismatch),
+		errors.Is(err, uploadService.ErrDigestMismatch):
+		common.BadRequest(c, err.Error())
+	case errors.Is(err, uploadService.ErrUploadNotFound), errors.Is(err, uploadService.ErrFolderNotFound):
+		common.NotFound(c, err.Error())
+	case errors.Is(err, uploadService.ErrChunksIncomplete), errors.Is(err, uploadService.ErrUploadNotMerging):
+		common.HandleError(c, appErrors.New(appErrors.CodeConflict, err.Error()))
+	case errors.Is(err, uploadService.ErrQuotaExceeded):
+		common.HandleError(c, appErrors.New(appErrors.CodeQuotaExceeded, err.Error()))
+	default:
+		common.ServerError(c, err.Error())
+	}
+}
@@ -0,0 +1,189 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/models"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+	appErrors "template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadFileVersion 上传文件的新版本
+// @Summary 上传新版本
+// @Description 替换文件内容，文件ID、文件名和分享链接不变，原内容保存为历史版本；扩展名必须与文件名一致
+// @Tags 文件版本
+// @Accept multipart/form-data
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param file formData file true "新版本的文件"
+// @Success 200 {object} response.FileInfo
+// @Failure 400,404,409,413 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/versions [post]
+func UploadFileVersion(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		common.BadRequest(c, "未找到上传文件")
+		return
+	}
+
+	file, err := uploadService.UploadFileVersion(c.Param("fileID"), user.UserID, header)
+	if err != nil {
+		handleVersionError(c, err)
+		return
+	}
+
+	common.Success(c, newFileInfo(file), "上传新版本成功")
+}
+
+// ListFileVersions 获取文件的版本列表
+// @Summary 版本列表
+// @Description 返回当前版本和全部历史版本，按版本号从新到旧排序
+// @Tags 文件版本
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Success 200 {array} response.FileVersionInfo
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/versions [get]
+func ListFileVersions(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	file, versions, err := uploadService.ListFileVersions(c.Param("fileID"), user.UserID)
+	if err != nil {
+		handleVersionError(c, err)
+		return
+	}
+
+	items := make([]response.FileVersionInfo, 0, len(versions)+1)
+	items = append(items, response.FileVersionInfo{
+		Version:     file.Version,
+		FileSize:    file.FileSize,
+		MimeType:    file.MimeType,
+		SHA256Hash:  file.SHA256Hash,
+		UploadedBy:  file.UserID,
+		UploadedAt:  file.UploadedAt,
+		IsCurrent:   true,
+		DownloadURL: versionDownloadURL(file, file.Version),
+	})
+	for _, version := range versions {
+		items = append(items, newFileVersionInfo(file, &version))
+	}
+
+	common.Success(c, items, "获取版本列表成功")
+}
+
+// RestoreFileVersion 恢复历史版本
+// @Summary 恢复历史版本
+// @Description 以历史版本的内容创建新的当前版本，原当前内容保存为历史版本
+// @Tags 文件版本
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param version path int true "版本号"
+// @Success 200 {object} response.FileInfo
+// @Failure 400,404,409,413 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/versions/{version}/restore [post]
+func RestoreFileVersion(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		common.BadRequest(c, "版本号无效")
+		return
+	}
+
+	file, err := uploadService.RestoreFileVersion(c.Param("fileID"), user.UserID, version)
+	if err != nil {
+		handleVersionError(c, err)
+		return
+	}
+
+	common.Success(c, newFileInfo(file), "恢复版本成功")
+}
+
+// PruneFileVersions 删除旧的历史版本
+// @Summary 删除旧版本
+// @Description 只保留最新的 keep 个历史版本，不传 keep 时使用配置的保留数；删除的版本释放存储配额
+// @Tags 文件版本
+// @Accept json
+// @Produce json
+// @Param fileID path string true "文件ID"
+// @Param request body request.PruneFileVersionsRequest false "保留数"
+// @Success 200
+// @Failure 404 {object} errors.ErrorResponse
+// @Router /upload/files/{fileID}/versions/prune [post]
+func PruneFileVersions(c *gin.Context) {
+	user, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		common.Unauthorized(c, err.Error())
+		return
+	}
+
+	var req request.PruneFileVersionsRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			common.BadRequest(c, "参数错误")
+			return
+		}
+	}
+
+	pruned, err := uploadService.PruneFileVersions(c.Param("fileID"), user.UserID, req.Keep)
+	if err != nil {
+		handleVersionError(c, err)
+		return
+	}
+
+	common.Success(c, gin.H{"pruned": pruned}, "删除历史版本成功")
+}
+
+// handleVersionError 文件或版本不存在时返回404，文件不能修改版本或已被其他请求修改时返回409，其余同上传错误
+func handleVersionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, uploadService.ErrFileNotFound), errors.Is(err, uploadService.ErrVersionNotFound):
+		common.NotFound(c, err.Error())
+	case errors.Is(err, uploadService.ErrFileExtensionChanged):
+		common.BadRequest(c, err.Error())
+	case errors.Is(err, uploadService.ErrFileNotCompleted), errors.Is(err, uploadService.ErrFileVersionChanged):
+		common.HandleError(c, appErrors.New(appErrors.CodeConflict, err.Error()))
+	default:
+		handleUploadError(c, err)
+	}
+}
+
+// newFileVersionInfo 构建历史版本信息
+func newFileVersionInfo(file *models.UploadFile, version *models.FileVersion) response.FileVersionInfo {
+	return response.FileVersionInfo{
+		Version:     version.Version,
+		FileSize:    version.FileSize,
+		MimeType:    version.MimeType,
+		SHA256Hash:  version.SHA256Hash,
+		UploadedBy:  version.UploadedBy,
+		UploadedAt:  version.UploadedAt,
+		DownloadURL: versionDownloadURL(file, version.Version),
+	}
+}
+
+// versionDownloadURL 文件指定版本的下载地址
+func versionDownloadURL(file *models.UploadFile, version int) string {
+	return fmt.Sprintf("/files/download/%s/%s?version=%d", file.ID.String(), file.Filename, version)
+}
@@ -0,0 +1,80 @@
+package user
+
+import (
+	"fmt"
+	"log"
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportAccountData 导出当前用户的个人数据（zip压缩包）
+func ExportAccountData(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	export, err := user.BuildUserExport(claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-export-%s.zip", export.Username, time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(200)
+
+	// 响应头已发送，出错时只能中断连接
+	if err := export.WriteZip(c.Writer); err != nil {
+		log.Printf("导出用户数据失败 %s: %v", claims.UserID, err)
+		c.Abort()
+	}
+}
+
+// SendDeleteAccountCode 发送注销账号验证码
+func SendDeleteAccountCode(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.SendDeleteAccountCode(claims.UserID); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "验证码已发送至当前邮箱")
+}
+
+// DeleteAccount 注销当前账号
+func DeleteAccount(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.DeleteAccountRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	purgeAt, err := user.DeleteAccount(claims.UserID, req.Code)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.DeleteAccountResponse{PurgeAt: purgeAt}, "账号已注销")
+}
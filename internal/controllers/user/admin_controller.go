@@ -0,0 +1,212 @@
+package user
+
+import (
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/services/rbac"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UnlockUser 解除用户的登录锁定
+func UnlockUser(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.UnlockAccount(claims.UserID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "已解除登录锁定")
+}
+
+// ListUsers 分页查询用户
+func ListUsers(c *gin.Context) {
+	req, err := common.ValidateQuery[request.AdminListUsersRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	filter := user.UserFilter{
+		Keyword: req.Keyword,
+		Status:  req.Status,
+		Role:    req.Role,
+		Deleted: req.Deleted,
+	}
+
+	users, total, err := user.ListUsers(filter, &req.PaginationRequest)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items := make([]response.AdminUserInfo, 0, len(users))
+	for _, u := range users {
+		items = append(items, toAdminUserInfo(u))
+	}
+
+	errors.ResponseSuccess(c, common.NewPaginationResponse(items, &req.PaginationRequest, total), "获取用户列表成功")
+}
+
+// GetUser 获取用户详情
+func GetUser(c *gin.Context) {
+	u, err := user.GetManagedUser(c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	info := toAdminUserInfo(*u)
+	if authz, err := rbac.GetUserAuthorization(u.ID); err == nil {
+		info.Roles = authz.Roles
+	}
+
+	errors.ResponseSuccess(c, info, "获取用户信息成功")
+}
+
+// CreateUser 创建用户
+func CreateUser(c *gin.Context) {
+	req, err := common.ValidateRequest[request.AdminCreateUserRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	u, err := user.CreateUserByAdmin(req.Username, req.Email, req.Password)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, toAdminUserInfo(*u), "创建用户成功")
+}
+
+// DisableUser 禁用用户
+func DisableUser(c *gin.Context) {
+	setUserStatus(c, common.UserStatusDisabled, "已禁用用户")
+}
+
+// EnableUser 启用用户
+func EnableUser(c *gin.Context) {
+	setUserStatus(c, common.UserStatusNormal, "已启用用户")
+}
+
+// DeleteUser 删除用户（软删除）
+func DeleteUser(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.DeleteUserByAdmin(claims.UserID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "删除用户成功")
+}
+
+// RestoreUser 恢复已删除的用户
+func RestoreUser(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.RestoreUser(claims.UserID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "恢复用户成功")
+}
+
+// ResetUserPassword 强制重置用户密码
+func ResetUserPassword(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.AdminResetPasswordRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	tempPassword, err := user.ForcePasswordReset(claims.UserID, c.Param("id"), req.Password)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.AdminResetPasswordResponse{TempPassword: tempPassword}, "密码已重置，用户下次登录后需要修改密码")
+}
+
+// ChangeUserRole 修改用户角色（超级管理员）
+func ChangeUserRole(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.ChangeUserRoleRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if err := user.ChangeUserRole(claims.UserID, c.Param("id"), req.Role); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "修改用户角色成功")
+}
+
+// setUserStatus 修改用户状态
+func setUserStatus(c *gin.Context, status int, message string) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.SetUserStatus(claims.UserID, c.Param("id"), status); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, message)
+}
+
+// toAdminUserInfo 转换管理员查看的用户信息
+func toAdminUserInfo(u user.ManagedUser) response.AdminUserInfo {
+	return response.AdminUserInfo{
+		ID:                     u.ID,
+		Username:               u.Username,
+		Email:                  u.Email,
+		Avatar:                 u.Avatar,
+		Bio:                    u.Bio,
+		Status:                 u.Status,
+		Role:                   u.Role,
+		TwoFactorEnabled:       u.TwoFactorEnabled,
+		PasswordChangeRequired: u.PasswordChangeRequired,
+		Deleted:                u.DeletedAt != nil,
+		CreatedAt:              u.CreatedAt,
+		UpdatedAt:              u.UpdatedAt,
+	}
+}
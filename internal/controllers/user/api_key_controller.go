@@ -0,0 +1,78 @@
+package user
+
+import (
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListAPIKeys 获取当前用户的API密钥列表
+func ListAPIKeys(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	keys, err := user.ListAPIKeys(claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items := make([]response.APIKeyInfo, 0, len(keys))
+	for _, key := range keys {
+		var info response.APIKeyInfo
+		items = append(items, *info.FromModel(key))
+	}
+
+	errors.ResponseSuccess(c, items, "获取API密钥列表成功")
+}
+
+// CreateAPIKey 创建API密钥
+func CreateAPIKey(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.CreateAPIKeyRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	key, plaintext, err := user.CreateAPIKey(claims.UserID, req.Name, req.Scopes, req.ExpiresInDays)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	var info response.APIKeyInfo
+	errors.ResponseSuccess(c, response.CreateAPIKeyResponse{
+		APIKeyInfo: *info.FromModel(*key),
+		Key:        plaintext,
+	}, "API密钥创建成功，请立即保存，密钥只显示一次")
+}
+
+// RevokeAPIKey 删除API密钥
+func RevokeAPIKey(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.RevokeAPIKey(claims.UserID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "API密钥已删除")
+}
@@ -0,0 +1,222 @@
+package user
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/services/user"
+	"template/pkg/config"
+	"template/pkg/errors"
+	"template/pkg/oauth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oauthBindingCookie 保存浏览器绑定值的Cookie名称，回调时与授权状态一起校验
+const oauthBindingCookie = "oauth_binding"
+
+// ListOAuthProviders 获取可用的第三方登录方式
+func ListOAuthProviders(c *gin.Context) {
+	providers := oauth.ListProviders()
+
+	items := make([]response.OAuthProviderInfo, 0, len(providers))
+	for _, p := range providers {
+		items = append(items, response.OAuthProviderInfo{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+		})
+	}
+
+	errors.ResponseSuccess(c, items, "获取登录方式成功")
+}
+
+// OAuthLogin 跳转到第三方授权页面
+func OAuthLogin(c *gin.Context) {
+	provider := c.Param("provider")
+
+	authorization, err := user.BeginOAuth(provider, oauthRedirectURI(c, provider), "")
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	setOAuthBindingCookie(c, authorization.Binding, int(user.OAuthStateTTL.Seconds()))
+	c.Redirect(http.StatusFound, authorization.AuthURL)
+}
+
+// OAuthLink 为当前用户绑定第三方账号，返回授权地址由前端跳转
+// 浏览器绑定Cookie随本次响应写入，前端跨域调用时需要携带凭证（credentials: include）
+func OAuthLink(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	provider := c.Param("provider")
+
+	authorization, err := user.BeginOAuth(provider, oauthRedirectURI(c, provider), claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	setOAuthBindingCookie(c, authorization.Binding, int(user.OAuthStateTTL.Seconds()))
+	errors.ResponseSuccess(c, response.OAuthAuthorizeResponse{AuthURL: authorization.AuthURL}, "请跳转到授权页面")
+}
+
+// OAuthCallback 第三方授权回调
+// 配置了前端跳转地址时，结果通过URL片段传递给前端；否则直接返回JSON
+func OAuthCallback(c *gin.Context) {
+	// 绑定值只能使用一次，无论成功与否都清除
+	binding, _ := c.Cookie(oauthBindingCookie)
+	setOAuthBindingCookie(c, "", -1)
+
+	if providerErr := c.Query("error"); providerErr != "" {
+		oauthFail(c, errors.New(errors.CodeThirdPartyService, "第三方授权失败: "+providerErr))
+		return
+	}
+
+	client := user.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+
+	result, err := user.CompleteOAuth(c.Param("provider"), c.Query("state"), binding, c.Query("code"), client)
+	if err != nil {
+		oauthFail(c, err)
+		return
+	}
+
+	frontend := config.GetConfig().OAuth.FrontendRedirect
+
+	switch {
+	case result.Linked:
+		if frontend != "" {
+			redirectWithFragment(c, frontend, url.Values{"linked": {"1"}})
+			return
+		}
+		errors.ResponseSuccess(c, nil, "绑定成功")
+
+	case result.Login.TwoFactorRequired:
+		if frontend != "" {
+			redirectWithFragment(c, frontend, url.Values{
+				"two_factor_required": {"1"},
+				"challenge_token":     {result.Login.ChallengeToken},
+			})
+			return
+		}
+		errors.ResponseSuccess(c, response.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    result.Login.ChallengeToken,
+			ExpiresAt:         result.Login.ChallengeExpiresAt,
+		}, "请输入两步验证码")
+
+	default:
+		if frontend != "" {
+			tokens := result.Login.Tokens
+			redirectWithFragment(c, frontend, url.Values{
+				"token":              {tokens.AccessToken},
+				"expires_at":         {strconv.FormatInt(tokens.AccessExpiresAt.Unix(), 10)},
+				"refresh_token":      {tokens.RefreshToken},
+				"refresh_expires_at": {strconv.FormatInt(tokens.RefreshExpiresAt.Unix(), 10)},
+			})
+			return
+		}
+		errors.ResponseSuccess(c, buildLoginResponse(result.Login), "登录成功")
+	}
+}
+
+// ListIdentities 获取当前用户绑定的第三方账号
+func ListIdentities(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	identities, err := user.ListIdentities(claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items := make([]response.IdentityInfo, 0, len(identities))
+	for _, identity := range identities {
+		var info response.IdentityInfo
+		items = append(items, *info.FromModel(identity))
+	}
+
+	errors.ResponseSuccess(c, items, "获取绑定信息成功")
+}
+
+// UnlinkIdentity 解绑第三方账号
+func UnlinkIdentity(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.UnlinkIdentity(claims.UserID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "解绑成功")
+}
+
+// oauthFail 回调失败时的处理
+func oauthFail(c *gin.Context, err error) {
+	if frontend := config.GetConfig().OAuth.FrontendRedirect; frontend != "" {
+		message := err.Error()
+		if appErr, ok := err.(*errors.Error); ok {
+			message = appErr.Message
+		}
+		redirectWithFragment(c, frontend, url.Values{"error": {message}})
+		return
+	}
+	errors.HandleError(c, err)
+}
+
+// redirectWithFragment 跳转到前端地址，参数放在URL片段中，避免令牌出现在服务端日志和Referer里
+func redirectWithFragment(c *gin.Context, target string, values url.Values) {
+	c.Redirect(http.StatusFound, strings.SplitN(target, "#", 2)[0]+"#"+values.Encode())
+}
+
+// oauthRedirectURI 计算回调地址
+func oauthRedirectURI(c *gin.Context, provider string) string {
+	cfg := config.GetConfig().OAuth
+	if providerCfg, ok := cfg.Providers[provider]; ok && providerCfg.RedirectURL != "" {
+		return providerCfg.RedirectURL
+	}
+
+	base := strings.TrimRight(cfg.RedirectBaseURL, "/")
+	if base == "" {
+		scheme := "http"
+		if isHTTPSRequest(c) {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+
+	// 当前路由为 .../oauth/:provider 或 .../oauth/:provider/link
+	path := strings.TrimSuffix(c.FullPath(), "/link")
+	path = strings.Replace(path, ":provider", url.PathEscape(provider), 1)
+	return base + path + "/callback"
+}
+
+// setOAuthBindingCookie 写入浏览器绑定Cookie，maxAge小于0时删除
+// 使用 SameSite=Lax，第三方授权页面跳转回来的顶级导航请求会携带该Cookie
+func setOAuthBindingCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthBindingCookie, value, maxAge, "/", "", isHTTPSRequest(c), true)
+}
+
+// isHTTPSRequest 判断请求是否通过HTTPS访问（包括反向代理转发的HTTPS请求）
+func isHTTPSRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,66 @@
+package user
+
+import (
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/services/user"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListSessions 获取当前用户的登录会话列表
+func ListSessions(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	sessions, err := user.ListSessions(claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items := make([]response.SessionInfo, 0, len(sessions))
+	for _, session := range sessions {
+		var info response.SessionInfo
+		items = append(items, *info.FromModel(session, claims.SessionID))
+	}
+
+	errors.ResponseSuccess(c, items, "获取会话列表成功")
+}
+
+// RevokeSession 吊销指定的登录会话
+func RevokeSession(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	if err := user.RevokeSession(claims.UserID, c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "会话已注销")
+}
+
+// RevokeOtherSessions 吊销除当前会话外的所有登录会话
+func RevokeOtherSessions(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	count, err := user.RevokeOtherSessions(claims.UserID, claims.SessionID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.CountResponse{Count: count}, "其他会话已全部注销")
+}
@@ -0,0 +1,146 @@
+package user
+
+import (
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyTwoFactorLogin 提交两步验证码完成登录
+func VerifyTwoFactorLogin(c *gin.Context) {
+	req, err := common.ValidateRequest[request.TwoFactorLoginRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	client := user.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Device:    req.Device,
+	}
+
+	result, err := user.VerifyTwoFactorLogin(req.ChallengeToken, req.Code, client)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, buildLoginResponse(result), "登录成功")
+}
+
+// SetupTwoFactor 生成两步验证密钥
+func SetupTwoFactor(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	setup, err := user.SetupTwoFactor(claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.TwoFactorSetupResponse{
+		Secret:    setup.Secret,
+		URI:       setup.URI,
+		QRCode:    setup.URI,
+		ExpiresAt: setup.ExpiresAt,
+	}, "请使用验证器App扫描二维码")
+}
+
+// EnableTwoFactor 确认验证码并启用两步验证
+func EnableTwoFactor(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.TwoFactorCodeRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	codes, err := user.EnableTwoFactor(claims.UserID, req.Code)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.RecoveryCodesResponse{RecoveryCodes: codes}, "两步验证已启用，请妥善保存恢复码")
+}
+
+// DisableTwoFactor 关闭两步验证
+func DisableTwoFactor(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.DisableTwoFactorRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	if err := user.DisableTwoFactor(claims.UserID, req.Password, req.Code); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "两步验证已关闭")
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func RegenerateRecoveryCodes(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.TwoFactorCodeRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	codes, err := user.RegenerateRecoveryCodes(claims.UserID, req.Code)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.RecoveryCodesResponse{RecoveryCodes: codes}, "恢复码已重新生成")
+}
+
+// GetTwoFactorStatus 获取两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	status, err := user.GetTwoFactorStatus(claims.UserID)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, response.TwoFactorStatusResponse{
+		Enabled:                status.Enabled,
+		Required:               status.Required,
+		RemainingRecoveryCodes: status.RemainingRecoveryCodes,
+	}, "获取两步验证状态成功")
+}
@@ -1,8 +1,10 @@
 package user
 
 import (
+	"strconv"
 	"template/internal/dto/request"
 	"template/internal/dto/response"
+	"template/internal/middleware"
 	"template/internal/services/user"
 	"template/pkg/common"
 	"template/pkg/errors"
@@ -36,30 +38,110 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	userInfo, token, expiresAt, err := user.Login(req.Account, req.Password)
+	client := user.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Device:    req.Device,
+	}
+
+	result, err := user.Login(req.Account, req.Password, client)
 	if err != nil {
+		setRetryAfter(c, err)
 		errors.HandleError(c, err)
 		return
 	}
 
+	// 已启用两步验证，需要继续提交验证码
+	if result.TwoFactorRequired {
+		errors.ResponseSuccess(c, response.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    result.ChallengeToken,
+			ExpiresAt:         result.ChallengeExpiresAt,
+		}, "请输入两步验证码")
+		return
+	}
+
+	errors.ResponseSuccess(c, buildLoginResponse(result), "登录成功")
+}
+
+// buildLoginResponse 构造登录响应
+func buildLoginResponse(result *user.LoginResult) response.LoginResponse {
 	// 安全地获取用户信息
-	username, _ := userInfo["username"].(string)
-	email, _ := userInfo["email"].(string)
-	avatar, _ := userInfo["avatar"].(string)
-	status, _ := userInfo["status"].(int)
-
-	resp := response.LoginResponse{
-		Token:     token,
-		ExpiresAt: expiresAt,
+	username, _ := result.UserInfo["username"].(string)
+	email, _ := result.UserInfo["email"].(string)
+	avatar, _ := result.UserInfo["avatar"].(string)
+	status, _ := result.UserInfo["status"].(int)
+	passwordChangeRequired, _ := result.UserInfo["password_change_required"].(bool)
+
+	return response.LoginResponse{
+		Token:            result.Tokens.AccessToken,
+		ExpiresAt:        result.Tokens.AccessExpiresAt,
+		RefreshToken:     result.Tokens.RefreshToken,
+		RefreshExpiresAt: result.Tokens.RefreshExpiresAt,
 		User: response.UserInfo{
 			Username: username,
 			Email:    email,
 			Avatar:   avatar,
 			Status:   status,
 		},
+		TwoFactorSetupRequired: result.TwoFactorSetupRequired,
+		PasswordChangeRequired: passwordChangeRequired,
+	}
+}
+
+// setRetryAfter 登录被限制时设置 Retry-After 响应头
+func setRetryAfter(c *gin.Context, err error) {
+	appErr, ok := err.(*errors.Error)
+	if !ok || appErr.Code != errors.CodeRateLimited {
+		return
+	}
+	if seconds, ok := appErr.Metadata["retry_after"].(int); ok {
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
+}
+
+// RefreshToken 刷新访问令牌
+func RefreshToken(c *gin.Context) {
+	req, err := common.ValidateRequest[request.RefreshTokenRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	tokens, err := user.RefreshToken(req.RefreshToken)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	resp := response.TokenResponse{
+		Token:            tokens.AccessToken,
+		ExpiresAt:        tokens.AccessExpiresAt,
+		RefreshToken:     tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
+
+	errors.ResponseSuccess(c, resp, "刷新令牌成功")
+}
+
+// Logout 退出登录
+func Logout(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	// 请求体可选，客户端可一并提交刷新令牌使其失效
+	var req request.LogoutRequest
+	_ = c.ShouldBindJSON(&req)
+
+	if err := user.Logout(claims, req.RefreshToken); err != nil {
+		errors.HandleError(c, err)
+		return
 	}
 
-	errors.ResponseSuccess(c, resp, "登录成功")
+	errors.ResponseSuccess(c, nil, "退出登录成功")
 }
 
 // GetUserInfo 获取用户信息
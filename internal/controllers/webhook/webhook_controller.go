@@ -0,0 +1,153 @@
+package webhook
+
+import (
+	"template/internal/dto/request"
+	"template/internal/dto/response"
+	"template/internal/middleware"
+	"template/internal/models"
+	"template/internal/services/webhook"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListEvents 获取可订阅的事件类型
+func ListEvents(c *gin.Context) {
+	errors.ResponseSuccess(c, common.WebhookEvents, "获取事件类型成功")
+}
+
+// ListWebhooks 获取全部Webhook
+func ListWebhooks(c *gin.Context) {
+	endpoints, err := webhook.ListEndpoints()
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items := make([]response.WebhookInfo, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		var info response.WebhookInfo
+		items = append(items, *info.FromModel(endpoint))
+	}
+
+	errors.ResponseSuccess(c, items, "获取Webhook列表成功")
+}
+
+// GetWebhook 获取Webhook详情
+func GetWebhook(c *gin.Context) {
+	endpoint, err := webhook.GetEndpoint(c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	var info response.WebhookInfo
+	errors.ResponseSuccess(c, info.FromModel(*endpoint), "获取Webhook成功")
+}
+
+// CreateWebhook 创建Webhook，签名密钥只返回这一次
+func CreateWebhook(c *gin.Context) {
+	claims, err := middleware.GetUserFromContext(c)
+	if err != nil {
+		errors.HandleError(c, errors.New(errors.CodeUnauthorized, err.Error()))
+		return
+	}
+
+	req, err := common.ValidateRequest[request.CreateWebhookRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	endpoint, err := webhook.CreateEndpoint(claims.UserID, req.URL, req.Description, req.Events)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, toSecretResponse(endpoint), "创建Webhook成功")
+}
+
+// UpdateWebhook 更新Webhook
+func UpdateWebhook(c *gin.Context) {
+	req, err := common.ValidateRequest[request.UpdateWebhookRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	endpoint, err := webhook.UpdateEndpoint(c.Param("id"), req.URL, req.Description, req.Events, req.IsActive)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	var info response.WebhookInfo
+	errors.ResponseSuccess(c, info.FromModel(*endpoint), "更新Webhook成功")
+}
+
+// RotateWebhookSecret 重新生成签名密钥
+func RotateWebhookSecret(c *gin.Context) {
+	endpoint, err := webhook.RotateSecret(c.Param("id"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, toSecretResponse(endpoint), "签名密钥已重新生成")
+}
+
+// DeleteWebhook 删除Webhook
+func DeleteWebhook(c *gin.Context) {
+	if err := webhook.DeleteEndpoint(c.Param("id")); err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	errors.ResponseSuccess(c, nil, "删除Webhook成功")
+}
+
+// ListDeliveries 分页获取Webhook的投递记录
+func ListDeliveries(c *gin.Context) {
+	req, err := common.ValidateQuery[request.ListWebhookDeliveriesRequest](c)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	deliveries, total, err := webhook.ListDeliveries(c.Param("id"), req.Status, &req.PaginationRequest)
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	items := make([]response.WebhookDeliveryInfo, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		var info response.WebhookDeliveryInfo
+		items = append(items, *info.FromModel(delivery))
+	}
+
+	errors.ResponseSuccess(c, common.NewPaginationResponse(items, &req.PaginationRequest, total), "获取投递记录成功")
+}
+
+// Redeliver 重新投递
+func Redeliver(c *gin.Context) {
+	delivery, err := webhook.Redeliver(c.Param("id"), c.Param("deliveryId"))
+	if err != nil {
+		errors.HandleError(c, err)
+		return
+	}
+
+	var info response.WebhookDeliveryInfo
+	errors.ResponseSuccess(c, info.FromModel(*delivery), "已加入投递队列")
+}
+
+// toSecretResponse 转换为包含签名密钥的响应
+func toSecretResponse(endpoint *models.WebhookEndpoint) response.WebhookSecretResponse {
+	var info response.WebhookInfo
+	return response.WebhookSecretResponse{
+		WebhookInfo: *info.FromModel(*endpoint),
+		Secret:      endpoint.Secret,
+	}
+}
@@ -0,0 +1,26 @@
+package cron
+
+import (
+	"log"
+	"template/internal/services/user"
+	"template/pkg/logger"
+)
+
+// registerAccountCleanupTask 注册注销账号清理任务
+func registerAccountCleanupTask() {
+	// 每小时匿名化一次保留期已过的注销账号
+	_, err := cronManager.AddFunc("0 0 * * * *", func() {
+		count, err := user.PurgeDeletedAccounts()
+		if err != nil {
+			logger.Error("清理注销账号失败: %v", err)
+			return
+		}
+		if count > 0 {
+			logger.Info("已匿名化 %d 个注销账号", count)
+		}
+	})
+
+	if err != nil {
+		log.Printf("注册注销账号清理任务失败: %v", err)
+	}
+}
@@ -26,6 +26,21 @@ func registerTasks() {
 	// 注册示例任务
 	registerExampleTask()
 
+	// 注册注销账号清理任务
+	registerAccountCleanupTask()
+
+	// 注册Webhook投递任务
+	registerWebhookDeliveryTask()
+
+	// 注册待扫描文件的安全扫描任务
+	registerPendingScanTask()
+
+	// 注册过期上传清理任务
+	registerStaleUploadCleanupTask()
+
+	// 注册回收站清理任务
+	registerTrashPurgeTask()
+
 	// 在这里注册其他定时任务
 	// registerOtherTask()
 }
@@ -0,0 +1,26 @@
+package cron
+
+import (
+	"log"
+	"template/internal/services/upload"
+	"template/pkg/logger"
+)
+
+// registerPendingScanTask 注册待扫描文件的重新扫描任务
+func registerPendingScanTask() {
+	// 每分钟扫描一次仍处于待扫描状态的文件（扫描器暂时不可用或服务重启时遗留）
+	_, err := cronManager.AddFunc("0 * * * * *", func() {
+		count, err := upload.ScanPendingFiles()
+		if err != nil {
+			logger.Error("扫描待扫描文件失败: %v", err)
+			return
+		}
+		if count > 0 {
+			logger.Info("已完成 %d 个待扫描文件的安全扫描", count)
+		}
+	})
+
+	if err != nil {
+		log.Printf("注册文件安全扫描任务失败: %v", err)
+	}
+}
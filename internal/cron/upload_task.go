@@ -0,0 +1,45 @@
+package cron
+
+import (
+	"log"
+	"template/internal/services/upload"
+	"template/pkg/logger"
+)
+
+// registerStaleUploadCleanupTask 注册过期上传清理任务
+func registerStaleUploadCleanupTask() {
+	// 每10分钟清理一次超过保留时间没有写入的分片上传和tus上传
+	_, err := cronManager.AddFunc("0 */10 * * * *", func() {
+		result, err := upload.CleanupStaleUploads()
+		if err != nil {
+			logger.Error("清理过期上传失败: %v", err)
+			return
+		}
+		if result.Count > 0 {
+			logger.Info("已清理 %d 个过期的上传，释放 %d 字节", result.Count, result.FreedBytes)
+		}
+	})
+
+	if err != nil {
+		log.Printf("注册过期上传清理任务失败: %v", err)
+	}
+}
+
+// registerTrashPurgeTask 注册回收站清理任务
+func registerTrashPurgeTask() {
+	// 每小时彻底删除一次在回收站中超过保留时间的文件
+	_, err := cronManager.AddFunc("0 0 * * * *", func() {
+		purged, err := upload.PurgeExpiredTrash()
+		if err != nil {
+			logger.Error("清理回收站失败: %v", err)
+			return
+		}
+		if purged > 0 {
+			logger.Info("已彻底删除 %d 个回收站中过期的文件", purged)
+		}
+	})
+
+	if err != nil {
+		log.Printf("注册回收站清理任务失败: %v", err)
+	}
+}
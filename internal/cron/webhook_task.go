@@ -0,0 +1,16 @@
+package cron
+
+import (
+	"log"
+	"template/internal/services/webhook"
+)
+
+// registerWebhookDeliveryTask 注册Webhook投递任务
+func registerWebhookDeliveryTask() {
+	// 每10秒投递一次到期的记录（包括等待重试的记录）
+	_, err := cronManager.AddFunc("*/10 * * * * *", webhook.ProcessDueDeliveries)
+
+	if err != nil {
+		log.Printf("注册Webhook投递任务失败: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package request
+
+import (
+	"template/internal/dto"
+	"template/pkg/common"
+)
+
+// AdminListUsersRequest 管理员查询用户列表请求
+type AdminListUsersRequest struct {
+	common.PaginationRequest
+	Keyword string `form:"keyword" binding:"omitempty,max=100"`
+	Status  int    `form:"status" binding:"omitempty,oneof=1 2 3"`
+	Role    int    `form:"role" binding:"omitempty,oneof=1 2 3"`
+	Deleted bool   `form:"deleted"` // 只查询已删除的用户
+}
+
+// GetValidationMessages 获取验证消息
+func (r *AdminListUsersRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Page.min":     "页码必须大于0",
+		"PageSize.min": "每页数量必须大于0",
+		"Keyword.max":  "搜索关键词不能超过100个字符",
+		"Status.oneof": "无效的用户状态",
+		"Role.oneof":   "无效的用户角色",
+	}
+}
+
+// AdminCreateUserRequest 管理员创建用户请求
+type AdminCreateUserRequest struct {
+	dto.BaseRequest
+	Username string `json:"username" binding:"required,min=2,max=20"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=6,max=20"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *AdminCreateUserRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Username.required": "用户名不能为空",
+		"Username.min":      "用户名长度不能小于2个字符",
+		"Username.max":      "用户名长度不能超过20个字符",
+		"Email.required":    "邮箱不能为空",
+		"Email.email":       "请输入有效的邮箱地址",
+		"Password.required": "密码不能为空",
+		"Password.min":      "密码长度不能小于6个字符",
+		"Password.max":      "密码长度不能超过20个字符",
+	}
+}
+
+// AdminResetPasswordRequest 管理员重置用户密码请求，密码为空时自动生成临时密码
+type AdminResetPasswordRequest struct {
+	dto.BaseRequest
+	Password string `json:"password" binding:"omitempty,min=6,max=20"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *AdminResetPasswordRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Password.min": "密码长度不能小于6个字符",
+		"Password.max": "密码长度不能超过20个字符",
+	}
+}
+
+// ChangeUserRoleRequest 修改用户角色请求
+type ChangeUserRoleRequest struct {
+	dto.BaseRequest
+	Role int `json:"role" binding:"required,oneof=1 2 3"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *ChangeUserRoleRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Role.required": "角色不能为空",
+		"Role.oneof":    "无效的用户角色",
+	}
+}
@@ -16,16 +16,15 @@ type CreateShareLinkRequest struct {
 
 // UpdateFileSettingsRequest 更新文件设置请求
 type UpdateFileSettingsRequest struct {
-	IsPublic *bool `json:"isPublic"` // 是否公开
+	IsPublic *bool `json:"isPublic" binding:"required"` // 是否公开
 }
 
 // CreateTempAccessRequest 创建临时访问请求
 type CreateTempAccessRequest struct {
-	FileID     string    `json:"fileID" binding:"required"`    // 文件ID
-	Purpose    string    `json:"purpose" binding:"required"`   // 用途说明
-	ExpiresAt  time.Time `json:"expiresAt" binding:"required"` // 过期时间
-	UsageLimit int       `json:"usageLimit"`                   // 使用次数限制
-	AllowedIPs []string  `json:"allowedIPs"`                   // 允许的IP地址
+	Purpose    string    `json:"purpose" binding:"required,max=64"`                   // 用途说明
+	ExpiresAt  time.Time `json:"expiresAt"`                                           // 过期时间，不传时有效期为1小时
+	UsageLimit *int      `json:"usageLimit" binding:"omitempty,min=0,max=10000"`      // 使用次数限制，不传时为1，0表示不限制
+	AllowedIPs []string  `json:"allowedIPs" binding:"omitempty,max=20,dive,required"` // 允许的IP地址或CIDR网段
 }
 
 // GrantFilePermissionRequest 授权文件权限请求
@@ -0,0 +1,55 @@
+package request
+
+import "template/internal/dto"
+
+// CreateRoleRequest 创建角色请求
+type CreateRoleRequest struct {
+	dto.BaseRequest
+	Name        string   `json:"name" binding:"required,min=2,max=50"`
+	DisplayName string   `json:"displayName" binding:"required,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *CreateRoleRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Name.required":        "角色标识不能为空",
+		"Name.min":             "角色标识至少2个字符",
+		"Name.max":             "角色标识不能超过50个字符",
+		"DisplayName.required": "角色名称不能为空",
+		"DisplayName.max":      "角色名称不能超过50个字符",
+		"Description.max":      "角色描述不能超过255个字符",
+	}
+}
+
+// UpdateRoleRequest 更新角色请求，权限列表为全量替换
+type UpdateRoleRequest struct {
+	dto.BaseRequest
+	DisplayName string   `json:"displayName" binding:"required,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *UpdateRoleRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"DisplayName.required": "角色名称不能为空",
+		"DisplayName.max":      "角色名称不能超过50个字符",
+		"Description.max":      "角色描述不能超过255个字符",
+	}
+}
+
+// SetUserRolesRequest 设置用户角色请求，角色列表为全量替换
+type SetUserRolesRequest struct {
+	dto.BaseRequest
+	RoleIDs []string `json:"roleIds" binding:"required,min=1"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *SetUserRolesRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"RoleIDs.required": "请选择角色",
+		"RoleIDs.min":      "请至少选择一个角色",
+	}
+}
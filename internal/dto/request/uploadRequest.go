@@ -1,18 +1,27 @@
 package request
 
+import (
+	"time"
+
+	"template/pkg/common"
+)
+
 // SimpleUploadRequest 简单上传请求
 type SimpleUploadRequest struct {
 	// 文件通过 multipart/form-data 上传，这里不需要定义文件字段
 	// 但可以添加其他参数
 	Description string `form:"description" json:"description"` // 文件描述
+	FolderID    string `form:"folderID" json:"folderID"`       // 目标文件夹ID（可选，为空时上传到根目录）
 }
 
 // ChunkUploadInitRequest 分片上传初始化请求
 type ChunkUploadInitRequest struct {
-	Filename  string `json:"filename" binding:"required" validate:"min=1,max=255"` // 文件名
-	FileSize  int64  `json:"fileSize" binding:"required" validate:"min=1"`         // 文件总大小
-	MD5Hash   string `json:"md5Hash" binding:"required" validate:"len=32"`         // 文件MD5哈希
-	ChunkSize int64  `json:"chunkSize" binding:"required" validate:"min=1024"`     // 分片大小
+	Filename   string `json:"filename" binding:"required" validate:"min=1,max=255"` // 文件名
+	FileSize   int64  `json:"fileSize" binding:"required" validate:"min=1"`         // 文件总大小
+	MD5Hash    string `json:"md5Hash" binding:"required" validate:"len=32"`         // 文件MD5哈希
+	SHA256Hash string `json:"sha256Hash" binding:"omitempty,len=64,hexadecimal"`    // 文件SHA-256哈希（可选，合并时校验）
+	ChunkSize  int64  `json:"chunkSize" binding:"required" validate:"min=1024"`     // 分片大小
+	FolderID   string `json:"folderID"`                                             // 目标文件夹ID（可选，为空时上传到根目录）
 }
 
 // ChunkUploadRequest 分片上传请求
@@ -34,3 +43,71 @@ type UploadConfigRequest struct {
 	AllowedMimeTypes map[string]bool `json:"allowedMimeTypes"` // 允许的MIME类型
 	ChunkSize        int64           `json:"chunkSize"`        // 分片大小
 }
+
+// ListQuarantinedFilesRequest 查询隔离文件请求
+type ListQuarantinedFilesRequest struct {
+	common.PaginationRequest
+}
+
+// SetUserQuotaRequest 设置用户配额请求
+type SetUserQuotaRequest struct {
+	QuotaBytes *int64 `json:"quotaBytes" binding:"omitempty,min=0"` // 配额（字节），0表示不限制，为空时恢复使用角色配额
+}
+
+// ListFilesRequest 查询我的文件列表请求
+type ListFilesRequest struct {
+	common.PaginationRequest
+	MimeType  string     `form:"mimeType" binding:"omitempty,max=100"`                                       // 文件类型，如 image/png，image/* 匹配所有图片
+	Keyword   string     `form:"keyword" binding:"omitempty,max=100"`                                        // 文件名搜索关键词
+	StartDate *time.Time `form:"startDate" time_format:"2006-01-02"`                                         // 上传日期起始（包含）
+	EndDate   *time.Time `form:"endDate" time_format:"2006-01-02"`                                           // 上传日期截止（包含）
+	SortBy    string     `form:"sortBy" binding:"omitempty,oneof=createdAt filename fileSize downloadCount"` // 排序字段，默认 createdAt
+	SortOrder string     `form:"sortOrder" binding:"omitempty,oneof=asc desc"`                               // 排序方向，默认 desc
+}
+
+// RenameFileRequest 修改文件名请求
+type RenameFileRequest struct {
+	Filename string `json:"filename" binding:"required,max=255"` // 新文件名，扩展名不能修改
+}
+
+// BatchDeleteFilesRequest 批量删除文件请求
+type BatchDeleteFilesRequest struct {
+	FileIDs []string `json:"fileIDs" binding:"required,min=1,max=100,dive,required"` // 文件ID列表
+}
+
+// RestoreTrashRequest 恢复回收站文件请求
+type RestoreTrashRequest struct {
+	FileIDs []string `json:"fileIDs" binding:"required,min=1,max=100,dive,required"` // 文件ID列表
+}
+
+// PruneFileVersionsRequest 删除历史版本请求
+type PruneFileVersionsRequest struct {
+	Keep *int `json:"keep" binding:"omitempty,min=0"` // 保留的历史版本数，为空时使用配置的保留数
+}
+
+// MoveFileRequest 移动文件请求
+type MoveFileRequest struct {
+	FolderID string `json:"folderID"` // 目标文件夹ID，为空时移到根目录
+}
+
+// CreateFolderRequest 创建文件夹请求
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required,max=255"` // 文件夹名称
+	ParentID string `json:"parentID"`                        // 上级文件夹ID，为空时创建在根目录
+}
+
+// RenameFolderRequest 修改文件夹名称请求
+type RenameFolderRequest struct {
+	Name string `json:"name" binding:"required,max=255"` // 新名称
+}
+
+// MoveFolderRequest 移动文件夹请求
+type MoveFolderRequest struct {
+	ParentID string `json:"parentID"` // 新的上级文件夹ID，为空时移到根目录
+}
+
+// ListFolderChildrenRequest 查询文件夹内容请求
+type ListFolderChildrenRequest struct {
+	common.PaginationRequest
+	FolderID string `form:"folderID"` // 文件夹ID，为空时查询根目录
+}
@@ -7,6 +7,7 @@ type LoginRequest struct {
 	dto.BaseRequest
 	Account  string `json:"account" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Device   string `json:"device,omitempty" binding:"omitempty,max=100"` // 设备名称（可选）
 }
 
 // GetValidationMessages 获取验证错误信息
@@ -14,6 +15,7 @@ func (r *LoginRequest) GetValidationMessages() map[string]string {
 	return map[string]string{
 		"Account.required":  "请输入账户名或邮箱",
 		"Password.required": "请输入密码",
+		"Device.max":        "设备名称不能超过100个字符",
 	}
 }
 
@@ -110,3 +112,104 @@ func (r *ChangePasswordRequest) GetValidationMessages() map[string]string {
 		"NewPassword.max":      "密码长度不能大于20位",
 	}
 }
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	dto.BaseRequest
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *RefreshTokenRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"RefreshToken.required": "刷新令牌不能为空",
+	}
+}
+
+// LogoutRequest 退出登录请求
+type LogoutRequest struct {
+	dto.BaseRequest
+	RefreshToken string `json:"refreshToken,omitempty"`
+}
+
+// TwoFactorLoginRequest 两步验证登录请求
+type TwoFactorLoginRequest struct {
+	dto.BaseRequest
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required,max=20"`
+	Device         string `json:"device,omitempty" binding:"omitempty,max=100"` // 设备名称（可选）
+}
+
+// GetValidationMessages 获取验证消息
+func (r *TwoFactorLoginRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"ChallengeToken.required": "挑战令牌不能为空",
+		"Code.required":           "请输入两步验证码或恢复码",
+		"Code.max":                "验证码格式不正确",
+		"Device.max":              "设备名称不能超过100个字符",
+	}
+}
+
+// TwoFactorCodeRequest 两步验证码请求
+type TwoFactorCodeRequest struct {
+	dto.BaseRequest
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *TwoFactorCodeRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Code.required": "请输入两步验证码",
+		"Code.len":      "两步验证码长度必须为6位",
+	}
+}
+
+// DisableTwoFactorRequest 关闭两步验证请求
+type DisableTwoFactorRequest struct {
+	dto.BaseRequest
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,max=20"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *DisableTwoFactorRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Password.required": "密码不能为空",
+		"Code.required":     "请输入两步验证码或恢复码",
+		"Code.max":          "验证码格式不正确",
+	}
+}
+
+// CreateAPIKeyRequest 创建API密钥请求
+type CreateAPIKeyRequest struct {
+	dto.BaseRequest
+	Name          string   `json:"name" binding:"required,max=50"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=3650"` // 有效天数，为空表示永不过期
+}
+
+// GetValidationMessages 获取验证消息
+func (r *CreateAPIKeyRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Name.required":     "密钥名称不能为空",
+		"Name.max":          "密钥名称不能超过50个字符",
+		"Scopes.required":   "请选择授权范围",
+		"Scopes.min":        "请至少选择一个授权范围",
+		"ExpiresInDays.min": "有效天数不能小于1天",
+		"ExpiresInDays.max": "有效天数不能超过3650天",
+	}
+}
+
+// DeleteAccountRequest 注销账号请求
+type DeleteAccountRequest struct {
+	dto.BaseRequest
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *DeleteAccountRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Code.required": "验证码不能为空",
+		"Code.len":      "验证码长度必须为6位",
+	}
+}
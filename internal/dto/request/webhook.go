@@ -0,0 +1,62 @@
+package request
+
+import (
+	"template/internal/dto"
+	"template/pkg/common"
+)
+
+// CreateWebhookRequest 创建Webhook请求
+type CreateWebhookRequest struct {
+	dto.BaseRequest
+	URL         string   `json:"url" binding:"required,url,max=500"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description string   `json:"description" binding:"max=255"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *CreateWebhookRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"URL.required":    "接收地址不能为空",
+		"URL.url":         "接收地址格式不正确",
+		"URL.max":         "接收地址不能超过500个字符",
+		"Events.required": "请选择订阅的事件",
+		"Events.min":      "请至少选择一个事件",
+		"Description.max": "描述不能超过255个字符",
+	}
+}
+
+// UpdateWebhookRequest 更新Webhook请求
+type UpdateWebhookRequest struct {
+	dto.BaseRequest
+	URL         string   `json:"url" binding:"required,url,max=500"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Description string   `json:"description" binding:"max=255"`
+	IsActive    bool     `json:"isActive"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *UpdateWebhookRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"URL.required":    "接收地址不能为空",
+		"URL.url":         "接收地址格式不正确",
+		"URL.max":         "接收地址不能超过500个字符",
+		"Events.required": "请选择订阅的事件",
+		"Events.min":      "请至少选择一个事件",
+		"Description.max": "描述不能超过255个字符",
+	}
+}
+
+// ListWebhookDeliveriesRequest 查询投递记录请求
+type ListWebhookDeliveriesRequest struct {
+	common.PaginationRequest
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded failed"`
+}
+
+// GetValidationMessages 获取验证消息
+func (r *ListWebhookDeliveriesRequest) GetValidationMessages() map[string]string {
+	return map[string]string{
+		"Page.min":     "页码必须大于0",
+		"PageSize.min": "每页数量必须大于0",
+		"Status.oneof": "无效的投递状态",
+	}
+}
@@ -0,0 +1,23 @@
+package response
+
+// AdminUserInfo 管理员查看的用户信息
+type AdminUserInfo struct {
+	ID                     string   `json:"id"`
+	Username               string   `json:"username"`
+	Email                  string   `json:"email"`
+	Avatar                 string   `json:"avatar"`
+	Bio                    string   `json:"bio"`
+	Status                 int      `json:"status"`
+	Role                   int      `json:"role"`
+	Roles                  []string `json:"roles,omitempty"` // 分配的角色标识，仅详情接口返回
+	TwoFactorEnabled       bool     `json:"two_factor_enabled"`
+	PasswordChangeRequired bool     `json:"password_change_required"`
+	Deleted                bool     `json:"deleted"`
+	CreatedAt              string   `json:"created_at"`
+	UpdatedAt              string   `json:"updated_at"`
+}
+
+// AdminResetPasswordResponse 管理员重置密码响应
+type AdminResetPasswordResponse struct {
+	TempPassword string `json:"temp_password,omitempty"` // 自动生成的临时密码，只返回这一次
+}
@@ -0,0 +1,53 @@
+package response
+
+import (
+	"template/internal/models"
+	"time"
+)
+
+// PermissionInfo 权限信息
+type PermissionInfo struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+// RoleInfo 角色信息
+type RoleInfo struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	DisplayName string           `json:"display_name"`
+	Description string           `json:"description"`
+	IsSystem    bool             `json:"is_system"`
+	Permissions []PermissionInfo `json:"permissions"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// FromModel 从角色模型转换
+func (r *RoleInfo) FromModel(role models.Role) *RoleInfo {
+	perms := make([]PermissionInfo, 0, len(role.Permissions))
+	for _, perm := range role.Permissions {
+		perms = append(perms, PermissionInfo{Code: perm.Code, Name: perm.Name})
+	}
+
+	return &RoleInfo{
+		ID:          role.ID.String(),
+		Name:        role.Name,
+		DisplayName: role.DisplayName,
+		Description: role.Description,
+		IsSystem:    role.IsSystem,
+		Permissions: perms,
+		CreatedAt:   time.Time(role.CreatedAt),
+	}
+}
+
+// UserRolesResponse 用户角色和最终生效的权限
+type UserRolesResponse struct {
+	Roles       []RoleInfo `json:"roles"`
+	Permissions []string   `json:"permissions"`
+}
+
+// AuthorizationResponse 当前用户的角色和权限
+type AuthorizationResponse struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
@@ -1,6 +1,10 @@
 package response
 
-import "time"
+import (
+	"time"
+
+	"template/pkg/common"
+)
 
 // SimpleUploadResponse 简单上传响应
 type SimpleUploadResponse struct {
@@ -11,6 +15,7 @@ type SimpleUploadResponse struct {
 	MimeType   string    `json:"mimeType"`   // 文件类型
 	Extension  string    `json:"extension"`  // 文件扩展名
 	MD5Hash    string    `json:"md5Hash"`    // 文件MD5哈希
+	SHA256Hash string    `json:"sha256Hash"` // 文件SHA-256哈希
 	FilePath   string    `json:"filePath"`   // 文件访问路径
 	UploadedAt time.Time `json:"uploadedAt"` // 上传时间
 }
@@ -41,6 +46,7 @@ type ChunkMergeResponse struct {
 	MimeType   string    `json:"mimeType"`   // 文件类型
 	Extension  string    `json:"extension"`  // 文件扩展名
 	MD5Hash    string    `json:"md5Hash"`    // 文件MD5哈希
+	SHA256Hash string    `json:"sha256Hash"` // 文件SHA-256哈希
 	FilePath   string    `json:"filePath"`   // 文件访问路径
 	UploadedAt time.Time `json:"uploadedAt"` // 上传完成时间
 }
@@ -62,3 +68,111 @@ type UploadProgressResponse struct {
 	Progress      float64 `json:"progress"`      // 上传进度百分比
 	Status        int     `json:"status"`        // 上传状态
 }
+
+// QuarantinedFileInfo 被隔离的文件信息
+type QuarantinedFileInfo struct {
+	FileID        string     `json:"fileID"`        // 文件ID
+	Filename      string     `json:"filename"`      // 原始文件名
+	FileSize      int64      `json:"fileSize"`      // 文件大小
+	MimeType      string     `json:"mimeType"`      // 文件类型
+	SHA256Hash    string     `json:"sha256Hash"`    // 文件SHA-256哈希
+	UserID        string     `json:"userID"`        // 上传用户ID
+	ScanSignature string     `json:"scanSignature"` // 扫描发现的威胁名称
+	UploadedAt    *time.Time `json:"uploadedAt"`    // 上传完成时间
+	ScannedAt     *time.Time `json:"scannedAt"`     // 扫描完成时间
+}
+
+// QuotaResponse 存储用量和配额响应
+type QuotaResponse struct {
+	UserID         string `json:"userID"`         // 用户ID
+	UsedBytes      int64  `json:"usedBytes"`      // 已上传文件占用的空间
+	ReservedBytes  int64  `json:"reservedBytes"`  // 上传中的文件预留的空间
+	LimitBytes     int64  `json:"limitBytes"`     // 配额，0表示不限制
+	RemainingBytes int64  `json:"remainingBytes"` // 剩余可用空间，-1表示不限制
+	IsCustom       bool   `json:"isCustom"`       // 是否是为用户单独设置的配额
+}
+
+// FileInfo 文件信息
+type FileInfo struct {
+	FileID        string     `json:"fileID"`        // 文件ID
+	FolderID      string     `json:"folderID"`      // 所在文件夹ID，空表示根目录
+	Filename      string     `json:"filename"`      // 文件名
+	FileSize      int64      `json:"fileSize"`      // 文件大小
+	MimeType      string     `json:"mimeType"`      // 文件类型
+	Extension     string     `json:"extension"`     // 文件扩展名
+	SHA256Hash    string     `json:"sha256Hash"`    // 文件SHA-256哈希
+	Status        int        `json:"status"`        // 上传状态
+	Version       int        `json:"version"`       // 当前版本号
+	IsPublic      bool       `json:"isPublic"`      // 是否公开
+	DownloadCount int        `json:"downloadCount"` // 下载次数
+	PreviewURL    string     `json:"previewURL"`    // 预览地址
+	DownloadURL   string     `json:"downloadURL"`   // 下载地址
+	CreatedAt     time.Time  `json:"createdAt"`     // 创建时间
+	UploadedAt    *time.Time `json:"uploadedAt"`    // 上传完成时间
+}
+
+// FileVersionInfo 文件版本信息
+type FileVersionInfo struct {
+	Version     int        `json:"version"`     // 版本号
+	FileSize    int64      `json:"fileSize"`    // 文件大小
+	MimeType    string     `json:"mimeType"`    // 文件类型
+	SHA256Hash  string     `json:"sha256Hash"`  // 文件SHA-256哈希
+	UploadedBy  string     `json:"uploadedBy"`  // 上传者ID
+	UploadedAt  *time.Time `json:"uploadedAt"`  // 上传时间
+	IsCurrent   bool       `json:"isCurrent"`   // 是否为当前版本
+	DownloadURL string     `json:"downloadURL"` // 下载地址
+}
+
+// BatchDeleteFilesResponse 批量删除文件响应
+type BatchDeleteFilesResponse struct {
+	Deleted []string `json:"deleted"` // 已删除的文件ID
+	Failed  []string `json:"failed"`  // 不存在或删除失败的文件ID
+}
+
+// TrashedFileInfo 回收站中的文件信息
+type TrashedFileInfo struct {
+	FileInfo
+	DeletedAt time.Time `json:"deletedAt"` // 删除时间
+	ExpiresAt time.Time `json:"expiresAt"` // 彻底删除时间
+}
+
+// RestoreTrashResponse 恢复回收站文件响应
+type RestoreTrashResponse struct {
+	Restored []string `json:"restored"` // 已恢复的文件ID
+	Failed   []string `json:"failed"`   // 不在回收站中或恢复失败的文件ID
+}
+
+// TemporaryAccessInfo 临时访问链接信息
+type TemporaryAccessInfo struct {
+	AccessID   string    `json:"accessID"`   // 链接ID
+	Purpose    string    `json:"purpose"`    // 用途说明
+	URL        string    `json:"url"`        // 带签名的下载地址
+	ExpiresAt  time.Time `json:"expiresAt"`  // 过期时间
+	UsageLimit int       `json:"usageLimit"` // 使用次数限制，0表示不限制
+	UsageCount int       `json:"usageCount"` // 已使用次数
+	AllowedIPs []string  `json:"allowedIPs"` // 允许的IP地址或CIDR网段
+	IsActive   bool      `json:"isActive"`   // 是否有效
+	CreatedAt  time.Time `json:"createdAt"`  // 创建时间
+}
+
+// FolderInfo 文件夹信息
+type FolderInfo struct {
+	FolderID    string       `json:"folderID"`              // 文件夹ID
+	ParentID    string       `json:"parentID"`              // 上级文件夹ID，空表示根目录
+	Name        string       `json:"name"`                  // 文件夹名称
+	IsPublic    bool         `json:"isPublic"`              // 是否公开
+	CreatedAt   time.Time    `json:"createdAt"`             // 创建时间
+	Breadcrumbs []Breadcrumb `json:"breadcrumbs,omitempty"` // 从根目录到当前文件夹的路径
+}
+
+// Breadcrumb 文件夹路径中的一级
+type Breadcrumb struct {
+	FolderID string `json:"folderID"` // 文件夹ID
+	Name     string `json:"name"`     // 文件夹名称
+}
+
+// FolderChildrenResponse 文件夹内容响应
+type FolderChildrenResponse struct {
+	Folders []FolderInfo               `json:"folders"` // 全部子文件夹
+	Files   *common.PaginationResponse `json:"files"`   // 分页的文件
+}
@@ -8,9 +8,28 @@ import (
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	User      UserInfo  `json:"user"`
+	Token                  string    `json:"token"`
+	ExpiresAt              time.Time `json:"expires_at"`
+	RefreshToken           string    `json:"refresh_token"`
+	RefreshExpiresAt       time.Time `json:"refresh_expires_at"`
+	User                   UserInfo  `json:"user"`
+	TwoFactorSetupRequired bool      `json:"two_factor_setup_required,omitempty"` // 账号角色要求启用两步验证
+	PasswordChangeRequired bool      `json:"password_change_required,omitempty"`  // 管理员重置了密码，需要先修改密码
+}
+
+// TwoFactorChallengeResponse 需要两步验证时的登录响应
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TokenResponse 令牌刷新响应
+type TokenResponse struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 // UserInfo 用户信息
@@ -56,3 +75,121 @@ type RegisterResponse struct {
 	Message string   `json:"message"`
 	User    UserInfo `json:"user"`
 }
+
+// SessionInfo 登录会话信息
+type SessionInfo struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"` // 是否为当前请求所在的会话
+}
+
+// FromModel 从会话模型转换
+func (s *SessionInfo) FromModel(session models.UserSession, currentSessionID string) *SessionInfo {
+	return &SessionInfo{
+		ID:         session.ID.String(),
+		Device:     session.Device,
+		IP:         session.IP,
+		UserAgent:  session.UserAgent,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		CreatedAt:  time.Time(session.CreatedAt),
+		Current:    session.ID.String() == currentSessionID,
+	}
+}
+
+// TwoFactorSetupResponse 两步验证绑定信息
+type TwoFactorSetupResponse struct {
+	Secret    string    `json:"secret"`
+	URI       string    `json:"uri"`        // otpauth URI
+	QRCode    string    `json:"qr_code"`    // 二维码内容，前端直接生成二维码即可
+	ExpiresAt time.Time `json:"expires_at"` // 需要在此之前完成确认
+}
+
+// RecoveryCodesResponse 恢复码响应，恢复码只在生成时返回一次
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatusResponse 两步验证状态
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"` // 当前角色是否被要求启用
+	RemainingRecoveryCodes int64 `json:"remaining_recovery_codes"`
+}
+
+// OAuthProviderInfo 第三方登录方式
+type OAuthProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OAuthAuthorizeResponse 第三方授权地址
+type OAuthAuthorizeResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+// IdentityInfo 已绑定的第三方身份
+type IdentityInfo struct {
+	ID          string     `json:"id"`
+	Provider    string     `json:"provider"`
+	Email       string     `json:"email"`
+	Name        string     `json:"name"`
+	AvatarURL   string     `json:"avatar_url"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// FromModel 从第三方身份模型转换
+func (i *IdentityInfo) FromModel(identity models.UserIdentity) *IdentityInfo {
+	return &IdentityInfo{
+		ID:          identity.ID.String(),
+		Provider:    identity.Provider,
+		Email:       identity.Email,
+		Name:        identity.Name,
+		AvatarURL:   identity.AvatarURL,
+		LastLoginAt: identity.LastLoginAt,
+		CreatedAt:   time.Time(identity.CreatedAt),
+	}
+}
+
+// APIKeyInfo API密钥信息
+type APIKeyInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// FromModel 从API密钥模型转换
+func (k *APIKeyInfo) FromModel(key models.APIKey) *APIKeyInfo {
+	return &APIKeyInfo{
+		ID:         key.ID.String(),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		LastUsedIP: key.LastUsedIP,
+		CreatedAt:  time.Time(key.CreatedAt),
+	}
+}
+
+// CreateAPIKeyResponse 创建API密钥响应，明文密钥只返回这一次
+type CreateAPIKeyResponse struct {
+	APIKeyInfo
+	Key string `json:"key"`
+}
+
+// DeleteAccountResponse 注销账号响应
+type DeleteAccountResponse struct {
+	PurgeAt time.Time `json:"purge_at"` // 在此之前可以联系管理员恢复账号，之后个人信息将被匿名化
+}
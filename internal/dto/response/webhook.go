@@ -0,0 +1,72 @@
+package response
+
+import (
+	"template/internal/models"
+	"time"
+)
+
+// WebhookInfo Webhook信息
+type WebhookInfo struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description"`
+	IsActive    bool      `json:"is_active"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// FromModel 从Webhook模型转换
+func (w *WebhookInfo) FromModel(endpoint models.WebhookEndpoint) *WebhookInfo {
+	return &WebhookInfo{
+		ID:          endpoint.ID.String(),
+		URL:         endpoint.URL,
+		Events:      endpoint.EventList(),
+		Description: endpoint.Description,
+		IsActive:    endpoint.IsActive,
+		CreatedBy:   endpoint.CreatedBy,
+		CreatedAt:   time.Time(endpoint.CreatedAt),
+	}
+}
+
+// WebhookSecretResponse 包含签名密钥的Webhook信息，只在创建和重新生成密钥时返回
+type WebhookSecretResponse struct {
+	WebhookInfo
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveryInfo Webhook投递记录
+type WebhookDeliveryInfo struct {
+	ID             string     `json:"id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus int        `json:"response_status"`
+	ResponseBody   string     `json:"response_body"`
+	Error          string     `json:"error"`
+	DurationMs     int64      `json:"duration_ms"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// FromModel 从投递记录模型转换
+func (d *WebhookDeliveryInfo) FromModel(delivery models.WebhookDelivery) *WebhookDeliveryInfo {
+	return &WebhookDeliveryInfo{
+		ID:             delivery.ID.String(),
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		Error:          delivery.Error,
+		DurationMs:     delivery.DurationMs,
+		CreatedAt:      time.Time(delivery.CreatedAt),
+	}
+}
@@ -3,7 +3,10 @@ package middleware
 import (
 	"errors"
 	"strings"
+	"template/internal/services/rbac"
+	"template/internal/services/user"
 	"template/pkg/common"
+	"template/pkg/config"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +16,16 @@ const (
 	ContextPayloadKey = "payload"
 )
 
+// passwordChangeExemptRoutes 管理员重置密码后、用户修改密码前仍然可以访问的接口（请求方法和路由后缀）
+var passwordChangeExemptRoutes = []struct {
+	method string
+	suffix string
+}{
+	{"POST", "/change-password"},
+	{"POST", "/logout"},
+	{"GET", "/info"},
+}
+
 // GetUserFromContext 从上下文中获取用户信息
 func GetUserFromContext(c *gin.Context) (*common.JWTClaims, error) {
 	value, exists := c.Get(ContextPayloadKey)
@@ -27,46 +40,149 @@ func GetUserFromContext(c *gin.Context) (*common.JWTClaims, error) {
 }
 
 // RequireAuth 基础认证中间件，验证用户是否登录
-func RequireAuth() gin.HandlerFunc {
+// 同时接受登录令牌和API密钥（X-API-Key 请求头或 Bearer pat_...）。
+// API密钥只能访问声明了授权范围的路由，且必须拥有全部所需范围；登录令牌不受授权范围限制。
+func RequireAuth(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 从请求头获取token
-		token := c.GetHeader("Authorization")
-		// 如果请求头中不存在，则从查询参数获取
-		if token == "" {
-			token = c.Query("token")
+		if !checkAuth(c, scopes...) {
+			return
 		}
+		c.Next()
+	}
+}
 
-		// 去掉可能存在的Bearer前缀
-		token = strings.TrimPrefix(token, "Bearer ")
+// checkAuth 验证登录凭证并写入用户信息，失败时写入响应并中止请求
+// 其他权限中间件在此基础上继续校验，不能直接调用 RequireAuth()(c)，否则后续处理函数会在校验完成前执行
+func checkAuth(c *gin.Context, scopes ...string) bool {
+	claims, message := authenticate(c)
+	if claims == nil {
+		common.Unauthorized(c, message)
+		c.Abort()
+		return false
+	}
 
-		if token == "" {
-			common.Unauthorized(c, "未提供有效的认证凭证")
-			c.Abort()
-			return
-		}
+	if claims.PasswordChangeRequired && !isPasswordChangeExempt(c) {
+		common.Forbidden(c, "管理员已重置您的密码，请先修改密码")
+		c.Abort()
+		return false
+	}
 
-		// 验证JWT令牌
-		claims, err := common.ParseToken(token)
-		if err != nil {
-			common.Unauthorized(c, "认证凭证无效或已过期")
+	if claims.IsAPIKey() {
+		if len(scopes) == 0 {
+			common.Forbidden(c, "该接口不支持使用API密钥访问")
 			c.Abort()
-			return
+			return false
 		}
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				common.Forbidden(c, "API密钥缺少授权范围: "+scope)
+				c.Abort()
+				return false
+			}
+		}
+	}
+
+	setAuthContext(c, claims)
+	return true
+}
 
-		// 将用户信息存储到上下文中
-		c.Set(ContextPayloadKey, claims)
-		// 同时设置user_id便于控制器直接获取
-		c.Set("user_id", claims.UserID)
+// OptionalAuth 可选认证中间件，携带有效凭证时写入用户信息，否则按匿名用户继续处理
+// API密钥需要拥有全部指定的授权范围才会被识别
+func OptionalAuth(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := authenticate(c)
+		// 需要修改密码的用户按匿名用户处理
+		if claims != nil && !claims.PasswordChangeRequired {
+			for _, scope := range scopes {
+				if !claims.HasScope(scope) {
+					c.Next()
+					return
+				}
+			}
+			setAuthContext(c, claims)
+		}
 		c.Next()
 	}
 }
 
+// isPasswordChangeExempt 当前路由是否允许需要修改密码的用户访问
+func isPasswordChangeExempt(c *gin.Context) bool {
+	path := c.FullPath()
+	for _, route := range passwordChangeExemptRoutes {
+		if c.Request.Method == route.method && strings.HasSuffix(path, route.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate 解析请求中的认证凭证，失败时返回提示信息
+func authenticate(c *gin.Context) (*common.JWTClaims, string) {
+	// API密钥可以通过独立的请求头传递
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		return authenticateAPIKey(c, apiKey)
+	}
+
+	// 从请求头获取token
+	token := c.GetHeader("Authorization")
+	// 如果请求头中不存在，则从查询参数获取
+	if token == "" {
+		token = c.Query("token")
+	}
+
+	// 去掉可能存在的Bearer前缀
+	token = strings.TrimPrefix(token, "Bearer ")
+
+	if token == "" {
+		return nil, "未提供有效的认证凭证"
+	}
+
+	if strings.HasPrefix(token, common.APIKeyPrefix) {
+		return authenticateAPIKey(c, token)
+	}
+
+	// 验证JWT令牌
+	claims, err := common.ParseToken(token)
+	if err != nil || !claims.IsAccessToken() {
+		return nil, "认证凭证无效或已过期"
+	}
+
+	// 检查令牌是否已被吊销（退出登录、修改密码等）
+	if common.IsTokenRevoked(claims) {
+		return nil, "认证凭证已失效，请重新登录"
+	}
+
+	// 检查令牌所属的登录会话是否已被吊销
+	if claims.SessionID != "" && !user.ValidateSession(claims.SessionID) {
+		return nil, "登录会话已失效，请重新登录"
+	}
+
+	claims.AuthType = common.AuthTypeJWT
+	return claims, ""
+}
+
+// authenticateAPIKey 校验API密钥
+func authenticateAPIKey(c *gin.Context, apiKey string) (*common.JWTClaims, string) {
+	claims, err := user.AuthenticateAPIKey(apiKey, c.ClientIP())
+	if err != nil {
+		return nil, "API密钥无效或已过期"
+	}
+	return claims, ""
+}
+
+// setAuthContext 将用户信息写入上下文
+func setAuthContext(c *gin.Context, claims *common.JWTClaims) {
+	// 将用户信息存储到上下文中
+	c.Set(ContextPayloadKey, claims)
+	// 同时设置user_id便于控制器直接获取
+	c.Set("user_id", claims.UserID)
+}
+
 // RequireSuperAuth 超级管理员权限中间件
 func RequireSuperAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 先验证基本认证
-		RequireAuth()(c)
-		if c.IsAborted() {
+		if !checkAuth(c) {
 			return
 		}
 
@@ -79,12 +195,16 @@ func RequireSuperAuth() gin.HandlerFunc {
 		}
 
 		// 检查是否是超级管理员
-		if claims.Role != common.UserRoleSuperAdmin {
+		if !rbac.HasRole(claims.UserID, common.RoleNameSuperAdmin) {
 			common.Forbidden(c, "需要超级管理员权限")
 			c.Abort()
 			return
 		}
 
+		if !checkTwoFactorPolicy(c, claims) {
+			return
+		}
+
 		c.Next()
 	}
 }
@@ -93,8 +213,7 @@ func RequireSuperAuth() gin.HandlerFunc {
 func RequireAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 先验证基本认证
-		RequireAuth()(c)
-		if c.IsAborted() {
+		if !checkAuth(c) {
 			return
 		}
 
@@ -107,12 +226,101 @@ func RequireAdmin() gin.HandlerFunc {
 		}
 
 		// 检查是否是管理员或超级管理员
-		if claims.Role != common.UserRoleAdmin && claims.Role != common.UserRoleSuperAdmin {
+		if !rbac.HasRole(claims.UserID, common.RoleNameAdmin, common.RoleNameSuperAdmin) {
 			common.Forbidden(c, "需要管理员权限")
 			c.Abort()
 			return
 		}
 
+		if !checkTwoFactorPolicy(c, claims) {
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// RequirePermission 权限中间件，要求当前用户拥有全部指定权限
+// 用户权限来自其角色，查询结果有缓存，角色变更时会主动清除
+// 可以挂在已经完成认证的路由组（如管理员路由组）下，此时不会重复认证
+func RequirePermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// 先验证基本认证
+		if _, err := GetUserFromContext(c); err != nil && !checkAuth(c) {
+			return
+		}
+
+		// 获取用户信息
+		claims, err := GetUserFromContext(c)
+		if err != nil {
+			common.Unauthorized(c, err.Error())
+			c.Abort()
+			return
+		}
+
+		// 管理权限只能通过登录令牌使用
+		if claims.IsAPIKey() {
+			common.Forbidden(c, "该接口不支持使用API密钥访问")
+			c.Abort()
+			return
+		}
+
+		if !checkPermissions(c, claims.UserID, perms) {
+			return
+		}
+
+		if !checkTwoFactorPolicy(c, claims) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireUserPermission 业务权限中间件，要求当前用户拥有全部指定权限
+// 与 RequirePermission 不同，API密钥按其所属用户的权限检查；需要挂在 RequireAuth 之后，由其校验授权范围
+func RequireUserPermission(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := GetUserFromContext(c)
+		if err != nil {
+			common.Unauthorized(c, err.Error())
+			c.Abort()
+			return
+		}
+
+		if !checkPermissions(c, claims.UserID, perms) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// checkPermissions 检查用户是否拥有全部指定权限，失败时写入响应并中止请求
+func checkPermissions(c *gin.Context, userID string, perms []string) bool {
+	authz, err := rbac.GetUserAuthorization(userID)
+	if err != nil {
+		common.Forbidden(c, "获取用户权限失败")
+		c.Abort()
+		return false
+	}
+
+	for _, perm := range perms {
+		if !authz.HasPermission(perm) {
+			common.Forbidden(c, "缺少权限: "+perm)
+			c.Abort()
+			return false
+		}
+	}
+	return true
+}
+
+// checkTwoFactorPolicy 检查两步验证策略，要求启用两步验证的角色必须使用通过两步验证的令牌
+func checkTwoFactorPolicy(c *gin.Context, claims *common.JWTClaims) bool {
+	if !config.GetConfig().Security.IsTwoFactorRequired(claims.Role) || claims.TwoFactor {
+		return true
+	}
+
+	common.Forbidden(c, "该操作要求启用两步验证，请启用后重新登录")
+	c.Abort()
+	return false
+}
@@ -60,7 +60,7 @@ func CORSMiddleware() gin.HandlerFunc {
 		if len(cfg.AllowedMethods) > 0 {
 			c.Writer.Header().Set("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
 		} else {
-			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
 		}
 
 		// 设置是否允许携带凭证
@@ -73,8 +73,8 @@ func CORSMiddleware() gin.HandlerFunc {
 			c.Writer.Header().Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
 		}
 
-		// 对于预检请求，直接返回成功
-		if c.Request.Method == "OPTIONS" {
+		// 对于预检请求，直接返回成功；其他 OPTIONS 请求（如tus的能力查询）交给路由处理
+		if c.Request.Method == "OPTIONS" && c.Request.Header.Get("Access-Control-Request-Method") != "" {
 			c.AbortWithStatus(200)
 			return
 		}
@@ -1,10 +1,15 @@
 package middleware
 
 import (
+	"net/http"
 	"template/internal/models"
 	"template/internal/repositories/upload"
+	"template/internal/services/rbac"
+	uploadService "template/internal/services/upload"
 	"template/pkg/common"
+	"template/pkg/constants"
 	"template/pkg/database"
+	"template/pkg/utils"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -35,8 +40,30 @@ func FileAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 检查文件是否公开
-		if file.IsPublic {
+		// 只提供上传完成且通过安全扫描的文件，文件所有者和管理员也不例外
+		if file.UploadStatus != constants.UploadStatusCompleted {
+			switch file.UploadStatus {
+			case constants.UploadStatusPendingScan:
+				common.Forbidden(c, "文件正在进行安全扫描，请稍后再试")
+			case constants.UploadStatusQuarantined:
+				common.Forbidden(c, "文件未通过安全扫描，已被隔离")
+			default:
+				common.NotFound(c, "文件不存在")
+			}
+			c.Abort()
+			return
+		}
+
+		// 文件夹中的文件继承各级文件夹的可见性和授权
+		var folderIDs []string
+		if file.FolderID != "" {
+			if folder, err := uploadRepo.GetFolderByID(file.FolderID); err == nil {
+				folderIDs = folder.PathIDs()
+			}
+		}
+
+		// 检查文件是否公开，任一级文件夹为私有时文件按私有文件处理
+		if file.IsPublic && uploadRepo.IsFolderPathPublic(folderIDs) {
 			go incrementFileDownloadCount(fileID)
 			c.Set("file_info", file)
 			c.Set("access_type", "public")
@@ -44,6 +71,27 @@ func FileAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// 带签名的临时访问链接不需要登录，从头读取内容的请求在校验通过后占用一次使用次数
+		if c.Query("signature") != "" {
+			consume := temporaryAccessConsumes(c, file)
+			if err := uploadService.UseTemporaryAccess(fileID, c.Request.URL.Query(), c.ClientIP(), consume); err != nil {
+				if uploadService.IsTemporaryAccessDenied(err) {
+					common.Forbidden(c, err.Error())
+				} else {
+					common.ServerError(c, err.Error())
+				}
+				c.Abort()
+				return
+			}
+			if consume {
+				go incrementFileDownloadCount(fileID)
+			}
+			c.Set("file_info", file)
+			c.Set("access_type", "temporary")
+			c.Next()
+			return
+		}
+
 		// 私有文件需要登录验证
 		user, err := GetUserFromContext(c)
 		if err != nil {
@@ -52,7 +100,9 @@ func FileAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		if hasPrivateFilePermission(user.UserID, file) {
+		// 拥有 file:read 权限的用户（登录令牌）可以访问所有文件
+		if hasPrivateFilePermission(user.UserID, file, folderIDs) ||
+			(!user.IsAPIKey() && rbac.HasPermission(user.UserID, common.PermFileRead)) {
 			go incrementFileDownloadCount(fileID)
 			c.Set("file_info", file)
 			c.Set("current_user", user)
@@ -66,24 +116,42 @@ func FileAuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-func hasPrivateFilePermission(userID string, file *models.UploadFile) bool {
+// temporaryAccessConsumes 与下载记录的规则一致，只有从头开始读取内容的GET请求占用临时访问链接的使用次数
+// HEAD 请求和断点续传的后续分段只校验链接，避免下载工具探测或分段下载耗尽次数
+func temporaryAccessConsumes(c *gin.Context, file *models.UploadFile) bool {
+	if c.Request.Method != http.MethodGet {
+		return false
+	}
+	header := c.GetHeader("Range")
+	if header == "" {
+		return true
+	}
+	byteRange, err := utils.ParseRange(header, file.FileSize)
+	return err == nil && byteRange.Start == 0
+}
+
+// hasPrivateFilePermission 检查用户是否拥有文件或其所在任一级文件夹的有效授权
+func hasPrivateFilePermission(userID string, file *models.UploadFile, folderIDs []string) bool {
 	if file.UserID == userID {
 		return true
 	}
 
 	db := database.GetDB()
-	var permission models.FilePermission
-	err := db.Where("file_id = ? AND user_id = ? AND is_active = ?", file.ID, userID, true).First(&permission).Error
-	if err == nil {
-		if permission.ExpiresAt == nil || permission.ExpiresAt.After(time.Now()) {
-			return true
-		}
+	query := db.Where("user_id = ? AND is_active = ?", userID, true).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now())
+	if len(folderIDs) > 0 {
+		query = query.Where("file_id = ? OR folder_id IN ?", file.ID, folderIDs)
+	} else {
+		query = query.Where("file_id = ?", file.ID)
 	}
-	
-	return false
+	var permission models.FilePermission
+	return query.First(&permission).Error == nil
 }
 
 func incrementFileDownloadCount(fileID string) {
 	db := database.GetDB()
+	if db == nil {
+		return
+	}
 	db.Model(&models.UploadFile{}).Where("id = ?", fileID).UpdateColumn("download_count", gorm.Expr("download_count + 1"))
 }
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey 用户创建的API密钥（个人访问令牌），只保存哈希值
+type APIKey struct {
+	BaseModel
+	UserID     string     `gorm:"size:36;not null;index" json:"user_id"` // 所属用户ID
+	Name       string     `gorm:"size:50;not null" json:"name"`          // 密钥名称
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`        // 密钥前几位，用于在列表中识别
+	KeyHash    string     `gorm:"size:64;not null;uniqueIndex" json:"-"` // 密钥SHA-256哈希
+	Scopes     string     `gorm:"size:255;not null" json:"scopes"`       // 授权范围，逗号分隔
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at"`               // 过期时间，为空表示永不过期
+	LastUsedAt *time.Time `json:"last_used_at"`                          // 最后使用时间
+	LastUsedIP string     `gorm:"size:64" json:"last_used_ip"`           // 最后使用IP
+}
+
+// ScopeList 获取授权范围列表
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// IsExpired 是否已过期
+func (k *APIKey) IsExpired() bool {
+	return k.ExpiresAt != nil && k.ExpiresAt.Before(time.Now())
+}
@@ -0,0 +1,24 @@
+package models
+
+// Role 角色
+type Role struct {
+	BaseModel
+	Name        string       `gorm:"size:50;not null;uniqueIndex" json:"name"`                // 角色标识
+	DisplayName string       `gorm:"size:50;not null" json:"display_name"`                    // 显示名称
+	Description string       `gorm:"size:255" json:"description"`                             // 描述
+	IsSystem    bool         `gorm:"default:false" json:"is_system"`                          // 是否是系统内置角色，内置角色不能删除
+	Permissions []Permission `gorm:"many2many:role_permission;" json:"permissions,omitempty"` // 角色拥有的权限
+}
+
+// Permission 权限
+type Permission struct {
+	BaseModel
+	Code string `gorm:"size:100;not null;uniqueIndex" json:"code"` // 权限标识，如 file:delete
+	Name string `gorm:"size:100;not null" json:"name"`             // 权限名称
+}
+
+// UserRole 用户与角色的关联
+type UserRole struct {
+	UserID string `gorm:"size:36;primaryKey" json:"user_id"`
+	RoleID string `gorm:"size:36;primaryKey;index" json:"role_id"`
+}
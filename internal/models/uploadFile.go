@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 )
 
@@ -14,16 +15,50 @@ type UploadFile struct {
 	MimeType      string     `gorm:"not null" json:"mimeType"`          // 文件MIME类型
 	Extension     string     `gorm:"not null" json:"extension"`         // 文件扩展名
 	MD5Hash       string     `gorm:"not null;index" json:"md5Hash"`     // 文件MD5哈希
-	UploadStatus  int        `gorm:"default:1" json:"uploadStatus"`     // 上传状态: 1-进行中 2-完成 3-失败
+	SHA256Hash    string     `gorm:"index" json:"sha256Hash"`           // 文件SHA-256哈希，合并或保存时由服务端计算
+	UploadStatus  int        `gorm:"default:1" json:"uploadStatus"`     // 上传状态: 1-进行中 2-完成 3-失败 4-待扫描 5-已隔离
 	ChunkTotal    int        `gorm:"default:1" json:"chunkTotal"`       // 总分片数
 	ChunkUploaded int        `gorm:"default:0" json:"chunkUploaded"`    // 已上传分片数
 	UserID        string     `gorm:"index" json:"userID"`               // 上传用户ID
+	FolderID      string     `gorm:"size:36;index" json:"folderID"`     // 所在文件夹ID，为空表示根目录
+	BlobID        string     `gorm:"index" json:"blobID"`               // 引用的文件内容ID，为空表示独占存储（上传中或旧记录）
+	Version       int        `gorm:"not null;default:1" json:"version"` // 当前版本号，每次上传新版本或恢复历史版本时加1
 	UploadedAt    *time.Time `json:"uploadedAt"`                        // 上传完成时间
+	ScannedAt     *time.Time `json:"scannedAt"`                         // 安全扫描完成时间
+	ScanSignature string     `json:"scanSignature"`                     // 安全扫描发现的威胁名称
 	// 文件访问控制
 	IsPublic      bool `gorm:"default:false" json:"isPublic"`  // 是否公开（只有true/false）
 	DownloadCount int  `gorm:"default:0" json:"downloadCount"` // 文件总下载次数（统计用）
 }
 
+// FileBlob 文件内容模型，相同内容（按SHA-256识别）的文件记录共享同一份存储
+// 每个引用它的文件记录占一个引用计数，最后一个引用释放时删除存储的内容
+type FileBlob struct {
+	BaseModel
+	SHA256Hash string `gorm:"index" json:"sha256Hash"`                         // 内容SHA-256哈希，旧版本创建的记录为空
+	MD5Hash    string `gorm:"not null;index:idx_file_blob_md5" json:"md5Hash"` // 内容MD5哈希
+	StoredName string `gorm:"not null;unique" json:"storedName"`               // 存储的文件名
+	FilePath   string `gorm:"not null" json:"filePath"`                        // 文件存储路径
+	FileSize   int64  `gorm:"not null" json:"fileSize"`                        // 文件大小(字节)
+	RefCount   int    `gorm:"not null;default:0" json:"refCount"`              // 引用计数
+}
+
+// FileVersion 文件的历史版本，上传新版本或恢复旧版本时保存被替换的内容，创建后不再修改
+// 每个历史版本占用文件内容的一个引用，并计入文件所有者的存储用量
+type FileVersion struct {
+	BaseModel
+	FileID     string     `gorm:"size:36;not null;uniqueIndex:idx_file_version" json:"fileID"` // 文件ID
+	Version    int        `gorm:"not null;uniqueIndex:idx_file_version" json:"version"`        // 版本号
+	BlobID     string     `gorm:"size:36;not null;index" json:"blobID"`                        // 引用的文件内容ID
+	FilePath   string     `gorm:"not null" json:"-"`                                           // 文件存储路径
+	FileSize   int64      `gorm:"not null" json:"fileSize"`                                    // 文件大小(字节)
+	MimeType   string     `gorm:"not null" json:"mimeType"`                                    // 文件MIME类型
+	MD5Hash    string     `json:"md5Hash"`                                                     // 文件MD5哈希
+	SHA256Hash string     `json:"sha256Hash"`                                                  // 文件SHA-256哈希
+	UploadedBy string     `gorm:"size:36;index" json:"uploadedBy"`                             // 上传该版本的用户ID
+	UploadedAt *time.Time `json:"uploadedAt"`                                                  // 该版本的上传时间
+}
+
 // ChunkInfo 分片上传信息模型
 type ChunkInfo struct {
 	BaseModel
@@ -35,6 +70,53 @@ type ChunkInfo struct {
 	IsUploaded bool   `gorm:"default:false" json:"isUploaded"` // 是否已上传
 }
 
+// TusUpload tus协议上传信息，文件内容以分片形式保存，传完后合并为普通文件
+type TusUpload struct {
+	BaseModel
+	FileID    string    `gorm:"not null;uniqueIndex" json:"fileID"` // 文件ID
+	Metadata  string    `json:"metadata"`                           // 创建上传时的 Upload-Metadata 原文
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`    // 未完成的上传的过期时间
+}
+
+// Folder 用户的虚拟文件夹，只用于组织文件记录，不影响文件的存储位置
+// Path 为从根目录到自身的文件夹ID路径（物化路径），如 /{祖先ID}/{自身ID}/，用于查询子孙文件夹和面包屑
+type Folder struct {
+	BaseModel
+	UserID   string `gorm:"size:36;not null;index" json:"userID"` // 所有者ID
+	ParentID string `gorm:"size:36;index" json:"parentID"`        // 上级文件夹ID，为空表示位于根目录
+	Name     string `gorm:"size:255;not null" json:"name"`        // 文件夹名称，同一文件夹下不能重复
+	Path     string `gorm:"size:1024;not null;index" json:"path"` // 物化路径
+	IsPublic bool   `gorm:"default:true" json:"isPublic"`         // 是否公开，设为私有时其中的文件都按私有文件处理
+}
+
+// PathIDs 从根目录到自身的文件夹ID列表
+func (f *Folder) PathIDs() []string {
+	return strings.FieldsFunc(f.Path, func(r rune) bool { return r == '/' })
+}
+
+// FileDerivative 由文件生成的派生文件（如图片缩略图），随文件记录一起删除
+type FileDerivative struct {
+	BaseModel
+	FileID   string `gorm:"not null;uniqueIndex:idx_file_derivative_kind" json:"fileID"`       // 文件ID
+	Kind     string `gorm:"size:32;not null;uniqueIndex:idx_file_derivative_kind" json:"kind"` // 派生类型，如 thumb_256
+	Status   int    `gorm:"not null" json:"status"`                                            // 状态: 1-可用 2-生成失败
+	FilePath string `json:"-"`                                                                 // 存储路径
+	FileSize int64  `json:"fileSize"`                                                          // 文件大小(字节)
+	MimeType string `json:"mimeType"`                                                          // MIME类型
+	Width    int    `json:"width"`                                                             // 宽度(像素)
+	Height   int    `json:"height"`                                                            // 高度(像素)
+	Error    string `json:"error"`                                                             // 生成失败的原因
+}
+
+// UserQuota 用户存储用量和单独设置的配额
+// 用量按用户的文件记录统计（包括上传中预留的空间，不包括上传失败的记录），与文件内容是否被共享无关
+type UserQuota struct {
+	BaseModel
+	UserID     string `gorm:"size:36;not null;uniqueIndex" json:"userID"` // 用户ID
+	UsedBytes  int64  `gorm:"not null;default:0" json:"usedBytes"`        // 已占用的字节数
+	QuotaBytes *int64 `json:"quotaBytes"`                                 // 单独设置的配额（字节），为空时使用角色配额，0表示不限制
+}
+
 // FileShare 文件分享模型（分享功能独立于文件的公开/私有状态）
 type FileShare struct {
 	BaseModel
@@ -56,7 +138,8 @@ type FileShare struct {
 // FilePermission 文件访问权限模型（用于私有文件的权限管理）
 type FilePermission struct {
 	BaseModel
-	FileID     string     `gorm:"not null;index" json:"fileID"`     // 文件ID
+	FileID     string     `gorm:"not null;index" json:"fileID"`     // 文件ID，授权文件夹时为空
+	FolderID   string     `gorm:"size:36;index" json:"folderID"`    // 文件夹ID，授权后可以访问文件夹及其子文件夹中的文件
 	UserID     string     `gorm:"not null;index" json:"userID"`     // 被授权用户ID
 	Permission string     `gorm:"default:'read'" json:"permission"` // 权限类型: read(查看), download(下载), manage(管理)
 	GrantedBy  string     `gorm:"not null" json:"grantedBy"`        // 授权者ID
@@ -2,6 +2,7 @@ package models
 
 import (
 	"template/pkg/common"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -16,6 +17,16 @@ type User struct {
 	Bio      string `gorm:"size:500" json:"bio"`
 	Status   int    `gorm:"default:1" json:"status"` // 1:正常 2:禁用 3:删除
 	Role     int    `gorm:"default:3" json:"role"`   // 1:超级管理员 2:管理员 3:普通用户
+
+	// 两步验证
+	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"` // 是否启用TOTP两步验证
+	TwoFactorSecret  string `gorm:"size:64" json:"-"`                        // TOTP密钥（base32）
+
+	PasswordChangeRequired bool `gorm:"default:false" json:"password_change_required"` // 管理员重置密码后，要求用户登录后修改密码
+
+	// 账号注销
+	DeletionRequestedAt *time.Time `gorm:"index" json:"deletion_requested_at"` // 用户申请注销的时间
+	AnonymizedAt        *time.Time `json:"anonymized_at"`                      // 个人信息匿名化的时间，匿名化后无法恢复
 }
 
 // TableName 指定表名
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserIdentity 用户绑定的第三方身份
+type UserIdentity struct {
+	BaseModel
+	UserID      string     `gorm:"size:36;not null;index" json:"user_id"`                                      // 用户ID
+	Provider    string     `gorm:"size:50;not null;uniqueIndex:idx_identity_provider_subject" json:"provider"` // 提供方名称
+	Subject     string     `gorm:"size:255;not null;uniqueIndex:idx_identity_provider_subject" json:"subject"` // 提供方内的用户标识
+	Email       string     `gorm:"size:100" json:"email"`                                                      // 提供方返回的邮箱
+	Name        string     `gorm:"size:100" json:"name"`                                                       // 提供方返回的昵称
+	AvatarURL   string     `gorm:"size:500" json:"avatar_url"`                                                 // 提供方返回的头像
+	LastLoginAt *time.Time `json:"last_login_at"`                                                              // 最后一次通过该身份登录的时间
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// UserRecoveryCode 两步验证恢复码，每个恢复码只能使用一次
+type UserRecoveryCode struct {
+	BaseModel
+	UserID   string     `gorm:"size:36;not null;index" json:"user_id"` // 用户ID
+	CodeHash string     `gorm:"size:64;not null" json:"-"`             // 恢复码SHA-256哈希
+	UsedAt   *time.Time `json:"used_at"`                               // 使用时间，为空表示未使用
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// UserSession 用户登录会话模型，每次登录创建一条记录，对应一台设备
+type UserSession struct {
+	BaseModel
+	UserID     string     `gorm:"size:36;not null;index" json:"user_id"` // 用户ID
+	Device     string     `gorm:"size:100" json:"device"`                // 设备名称
+	IP         string     `gorm:"size:64" json:"ip"`                     // 登录IP
+	UserAgent  string     `gorm:"size:500" json:"user_agent"`            // 浏览器UA
+	LastSeenAt time.Time  `json:"last_seen_at"`                          // 最后活跃时间
+	ExpiresAt  time.Time  `gorm:"index" json:"expires_at"`               // 过期时间（随刷新令牌延长）
+	RevokedAt  *time.Time `json:"revoked_at"`                            // 吊销时间，为空表示有效
+}
+
+// IsActive 会话是否仍然有效
+func (s *UserSession) IsActive() bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook投递状态
+const (
+	WebhookDeliveryPending   = "pending"   // 等待投递或等待重试
+	WebhookDeliverySucceeded = "succeeded" // 投递成功
+	WebhookDeliveryFailed    = "failed"    // 重试次数用尽，投递失败
+)
+
+// WebhookEndpoint Webhook订阅
+type WebhookEndpoint struct {
+	BaseModel
+	URL         string `gorm:"size:500;not null" json:"url"`    // 接收地址
+	Secret      string `gorm:"size:100;not null" json:"-"`      // 签名密钥
+	Events      string `gorm:"size:500;not null" json:"events"` // 订阅的事件类型，逗号分隔
+	Description string `gorm:"size:255" json:"description"`     // 描述
+	IsActive    bool   `gorm:"default:true" json:"is_active"`   // 是否启用
+	CreatedBy   string `gorm:"size:36" json:"created_by"`       // 创建者ID
+}
+
+// EventList 获取订阅的事件列表
+func (e *WebhookEndpoint) EventList() []string {
+	if e.Events == "" {
+		return []string{}
+	}
+	return strings.Split(e.Events, ",")
+}
+
+// Subscribes 是否订阅了指定事件
+func (e *WebhookEndpoint) Subscribes(event string) bool {
+	for _, ev := range e.EventList() {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery Webhook投递记录，同时作为持久化的投递队列
+type WebhookDelivery struct {
+	BaseModel
+	EndpointID     string     `gorm:"size:36;not null;index" json:"endpoint_id"` // 订阅ID
+	EventID        string     `gorm:"size:36;not null;index" json:"event_id"`    // 事件ID，重新投递时保持不变，接收方可据此去重
+	EventType      string     `gorm:"size:50;not null" json:"event_type"`        // 事件类型
+	Payload        string     `gorm:"type:text;not null" json:"payload"`         // 请求体
+	Status         string     `gorm:"size:20;not null;index" json:"status"`      // 投递状态
+	Attempts       int        `gorm:"default:0" json:"attempts"`                 // 已投递次数
+	NextAttemptAt  *time.Time `gorm:"index" json:"next_attempt_at"`              // 下次投递时间
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`                           // 最后投递时间
+	ResponseStatus int        `json:"response_status"`                           // 最后一次响应状态码
+	ResponseBody   string     `gorm:"type:text" json:"response_body"`            // 最后一次响应内容（截断）
+	Error          string     `gorm:"size:500" json:"error"`                     // 最后一次错误信息
+	DurationMs     int64      `json:"duration_ms"`                               // 最后一次请求耗时（毫秒）
+}
@@ -0,0 +1,66 @@
+package upload
+
+import (
+	"time"
+
+	"template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CreateTemporaryAccess 创建临时访问记录
+// 使用次数限制为0（不限制）时字段默认值会覆盖零值，需要在同一事务中单独写入
+func (r *UploadRepository) CreateTemporaryAccess(access *models.TemporaryAccess) error {
+	usageLimit := access.UsageLimit
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(access).Error; err != nil {
+			return err
+		}
+		if usageLimit != 0 {
+			return nil
+		}
+		access.UsageLimit = 0
+		return tx.Model(access).UpdateColumn("usage_limit", 0).Error
+	})
+}
+
+// GetTemporaryAccessByToken 根据访问令牌获取临时访问记录
+func (r *UploadRepository) GetTemporaryAccessByToken(token string) (*models.TemporaryAccess, error) {
+	var access models.TemporaryAccess
+	err := r.db.Where("access_token = ?", token).First(&access).Error
+	return &access, err
+}
+
+// GetFileTemporaryAccesses 获取文件的全部临时访问记录，最近创建的在前
+func (r *UploadRepository) GetFileTemporaryAccesses(fileID string) ([]models.TemporaryAccess, error) {
+	var accesses []models.TemporaryAccess
+	err := r.db.Where("file_id = ?", fileID).Order("created_at DESC").Find(&accesses).Error
+	return accesses, err
+}
+
+// DeactivateTemporaryAccess 停用文件的临时访问记录，记录不存在或已停用时返回 false
+func (r *UploadRepository) DeactivateTemporaryAccess(accessID, fileID string) (bool, error) {
+	result := r.db.Model(&models.TemporaryAccess{}).
+		Where("id = ? AND file_id = ? AND is_active = ?", accessID, fileID, true).
+		Update("is_active", false)
+	return result.RowsAffected > 0, result.Error
+}
+
+// ConsumeTemporaryAccess 占用一次临时访问的使用次数
+// 在同一条更新语句中检查是否有效、未过期且未用完，并发请求不会超出使用次数限制，使用次数为0表示不限制
+func (r *UploadRepository) ConsumeTemporaryAccess(accessID string, now time.Time) (bool, error) {
+	result := r.db.Model(&models.TemporaryAccess{}).
+		Where("id = ? AND is_active = ? AND expires_at > ?", accessID, true, now).
+		Where("usage_limit = 0 OR usage_count < usage_limit").
+		UpdateColumn("usage_count", gorm.Expr("usage_count + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountValidTemporaryAccesses 统计文件未停用且未过期的临时访问记录数
+func (r *UploadRepository) CountValidTemporaryAccesses(fileID string, now time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.TemporaryAccess{}).
+		Where("file_id = ? AND is_active = ? AND expires_at > ?", fileID, true, now).
+		Count(&count).Error
+	return count, err
+}
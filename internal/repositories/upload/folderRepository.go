@@ -0,0 +1,162 @@
+package upload
+
+import (
+	"strings"
+
+	"template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateFolder 创建文件夹
+func (r *UploadRepository) CreateFolder(folder *models.Folder) error {
+	return r.db.Create(folder).Error
+}
+
+// GetFolderByID 根据ID获取文件夹
+func (r *UploadRepository) GetFolderByID(folderID string) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.db.Where("id = ?", folderID).First(&folder).Error
+	return &folder, err
+}
+
+// GetFoldersByIDs 根据ID列表获取文件夹
+func (r *UploadRepository) GetFoldersByIDs(folderIDs []string) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := r.db.Where("id IN ?", folderIDs).Find(&folders).Error
+	return folders, err
+}
+
+// GetChildFolders 获取用户在 parentID 下的子文件夹，parentID 为空表示根目录
+func (r *UploadRepository) GetChildFolders(userID, parentID string) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := r.db.Where("user_id = ? AND parent_id = ?", userID, parentID).Order("name").Find(&folders).Error
+	return folders, err
+}
+
+// GetFolderSubtree 获取文件夹及其全部子孙文件夹
+func (r *UploadRepository) GetFolderSubtree(folder *models.Folder) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := r.db.Where("user_id = ? AND path LIKE ?", folder.UserID, folder.Path+"%").Find(&folders).Error
+	return folders, err
+}
+
+// FolderNameExists 检查同一文件夹下是否已有同名文件夹，excludeID 为需要排除的文件夹（重命名或移动的文件夹本身）
+func (r *UploadRepository) FolderNameExists(userID, parentID, name, excludeID string) bool {
+	var count int64
+	query := r.db.Model(&models.Folder{}).Where("user_id = ? AND parent_id = ? AND name = ?", userID, parentID, name)
+	if excludeID != "" {
+		query = query.Where("id <> ?", excludeID)
+	}
+	query.Count(&count)
+	return count > 0
+}
+
+// RenameFolder 修改文件夹名称
+func (r *UploadRepository) RenameFolder(folderID, name string) error {
+	return r.db.Model(&models.Folder{}).Where("id = ?", folderID).Update("name", name).Error
+}
+
+// UpdateFolderVisibility 设置文件夹是否公开
+func (r *UploadRepository) UpdateFolderVisibility(folderID string, isPublic bool) error {
+	return r.db.Model(&models.Folder{}).Where("id = ?", folderID).Update("is_public", isPublic).Error
+}
+
+// MoveFolder 将文件夹移动到 parent 下，parent 为空表示移到根目录，同时更新所有子孙文件夹的路径
+// 事务中重新读取并锁定文件夹、目标文件夹和子孙文件夹，check 基于最新的路径检查能否移动，返回错误时不做修改
+// 文件夹或目标文件夹在此期间已被删除时返回 gorm.ErrRecordNotFound
+func (r *UploadRepository) MoveFolder(folder, parent *models.Folder, check func(folder, parent *models.Folder, subtree []models.Folder) error) error {
+	var current models.Folder
+	var target *models.Folder
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", folder.ID).First(&current).Error; err != nil {
+			return err
+		}
+		if parent != nil {
+			target = &models.Folder{}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", parent.ID).First(target).Error; err != nil {
+				return err
+			}
+		}
+
+		oldPath := current.Path
+		var subtree []models.Folder
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND path LIKE ?", current.UserID, oldPath+"%").
+			Find(&subtree).Error
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(&current, target, subtree); err != nil {
+				return err
+			}
+		}
+
+		current.ParentID, current.Path = "", "/"+current.ID.String()+"/"
+		if target != nil {
+			current.ParentID, current.Path = target.ID.String(), target.Path+current.ID.String()+"/"
+		}
+		for _, child := range subtree {
+			updates := map[string]interface{}{"path": current.Path + strings.TrimPrefix(child.Path, oldPath)}
+			if child.ID == current.ID {
+				updates["parent_id"] = current.ParentID
+			}
+			if err := tx.Model(&models.Folder{}).Where("id = ?", child.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		*folder = current
+	}
+	return err
+}
+
+// DeleteFolders 彻底删除文件夹及其授权记录，文件夹中的文件需要先删除
+func (r *UploadRepository) DeleteFolders(folderIDs []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("folder_id IN ?", folderIDs).Delete(&models.FilePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", folderIDs).Delete(&models.Folder{}).Error
+	})
+}
+
+// GetFolderFiles 获取文件夹中的全部文件记录，包括上传中和上传失败的文件
+func (r *UploadRepository) GetFolderFiles(folderIDs []string) ([]models.UploadFile, error) {
+	var files []models.UploadFile
+	err := r.db.Where("folder_id IN ?", folderIDs).Find(&files).Error
+	return files, err
+}
+
+// MoveFileToFolder 将文件移到文件夹，folderID 为空表示移到根目录
+func (r *UploadRepository) MoveFileToFolder(fileID, folderID string) error {
+	return r.db.Model(&models.UploadFile{}).Where("id = ?", fileID).Update("folder_id", folderID).Error
+}
+
+// IsFolderPathPublic 检查路径上的文件夹是否都是公开的
+func (r *UploadRepository) IsFolderPathPublic(folderIDs []string) bool {
+	if len(folderIDs) == 0 {
+		return true
+	}
+	var count int64
+	r.db.Model(&models.Folder{}).Where("id IN ? AND is_public = ?", folderIDs, false).Count(&count)
+	return count == 0
+}
+
+// DeleteUserFolders 彻底删除用户的全部文件夹
+func (r *UploadRepository) DeleteUserFolders(userID string) error {
+	return deleteUserFolders(r.db, userID)
+}
+
+// deleteUserFolders 彻底删除用户的全部文件夹及其授权记录
+func deleteUserFolders(tx *gorm.DB, userID string) error {
+	folderIDs := tx.Model(&models.Folder{}).Select("id").Where("user_id = ?", userID)
+	if err := tx.Unscoped().Where("folder_id IN (?)", folderIDs).Delete(&models.FilePermission{}).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.Folder{}).Error
+}
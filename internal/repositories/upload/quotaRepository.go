@@ -0,0 +1,149 @@
+package upload
+
+import (
+	"template/internal/models"
+	"template/pkg/constants"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetUserQuota 获取用户的存储用量记录，不存在时按用户现有的文件记录统计用量后创建
+func (r *UploadRepository) GetUserQuota(userID string) (*models.UserQuota, error) {
+	var quota models.UserQuota
+	err := r.db.Where("user_id = ?", userID).First(&quota).Error
+	if err == nil {
+		return &quota, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	used, err := sumUsedBytes(r.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	quota = models.UserQuota{UserID: userID, UsedBytes: used}
+	// 并发创建时以先创建的记录为准
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&quota).Error; err != nil {
+		return nil, err
+	}
+	err = r.db.Where("user_id = ?", userID).First(&quota).Error
+	return &quota, err
+}
+
+// ReserveQuota 为用户占用 size 字节的存储空间，limit 大于0时占用后的用量不能超过 limit，返回是否占用成功
+func (r *UploadRepository) ReserveQuota(userID string, size, limit int64) (bool, error) {
+	if _, err := r.GetUserQuota(userID); err != nil {
+		return false, err
+	}
+
+	query := r.db.Model(&models.UserQuota{}).Where("user_id = ?", userID)
+	if limit > 0 {
+		query = query.Where("used_bytes + ? <= ?", size, limit)
+	}
+	result := query.UpdateColumn("used_bytes", gorm.Expr("used_bytes + ?", size))
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReleaseQuota 释放用户占用的 size 字节的存储空间
+func (r *UploadRepository) ReleaseQuota(userID string, size int64) error {
+	return releaseQuota(r.db, userID, size)
+}
+
+// SetQuotaLimit 设置用户单独的配额，quotaBytes 为空时恢复使用角色配额
+func (r *UploadRepository) SetQuotaLimit(userID string, quotaBytes *int64) (*models.UserQuota, error) {
+	quota, err := r.GetUserQuota(userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(quota).Update("quota_bytes", quotaBytes).Error; err != nil {
+		return nil, err
+	}
+	quota.QuotaBytes = quotaBytes
+	return quota, nil
+}
+
+// RecomputeQuotaUsage 按用户现有的文件记录重新统计用量
+func (r *UploadRepository) RecomputeQuotaUsage(userID string) (*models.UserQuota, error) {
+	quota, err := r.GetUserQuota(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		used, err := sumUsedBytes(tx, userID)
+		if err != nil {
+			return err
+		}
+		quota.UsedBytes = used
+		return tx.Model(quota).UpdateColumn("used_bytes", used).Error
+	})
+	return quota, err
+}
+
+// GetQuotaUserIDs 获取有文件记录或用量记录的全部用户ID
+func (r *UploadRepository) GetQuotaUserIDs() ([]string, error) {
+	var fileUsers, quotaUsers []string
+	if err := r.db.Unscoped().Model(&models.UploadFile{}).Distinct().Pluck("user_id", &fileUsers).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Model(&models.UserQuota{}).Pluck("user_id", &quotaUsers).Error; err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(fileUsers)+len(quotaUsers))
+	userIDs := make([]string, 0, len(fileUsers)+len(quotaUsers))
+	for _, userID := range append(fileUsers, quotaUsers...) {
+		if userID != "" && !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
+
+// GetReservedBytes 统计用户上传中和合并中的文件预留的空间
+func (r *UploadRepository) GetReservedBytes(userID string) (int64, error) {
+	var reserved int64
+	err := r.db.Model(&models.UploadFile{}).
+		Where("user_id = ? AND upload_status IN ?", userID, []int{constants.UploadStatusUploading, constants.UploadStatusMerging}).
+		Select("COALESCE(SUM(file_size), 0)").
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// UserExists 检查用户是否存在（包括已删除的用户）
+func (r *UploadRepository) UserExists(userID string) bool {
+	var count int64
+	r.db.Unscoped().Model(&models.User{}).Where("id = ?", userID).Count(&count)
+	return count > 0
+}
+
+// sumUsedBytes 统计用户除上传失败以外的文件记录及其历史版本的大小，回收站中的文件仍然占用空间
+func sumUsedBytes(db *gorm.DB, userID string) (int64, error) {
+	var used, versions int64
+	err := db.Unscoped().Model(&models.UploadFile{}).
+		Where("user_id = ? AND upload_status <> ?", userID, constants.UploadStatusFailed).
+		Select("COALESCE(SUM(file_size), 0)").
+		Scan(&used).Error
+	if err != nil {
+		return 0, err
+	}
+	err = db.Model(&models.FileVersion{}).
+		Joins("JOIN upload_file ON upload_file.id = file_version.file_id").
+		Where("upload_file.user_id = ?", userID).
+		Select("COALESCE(SUM(file_version.file_size), 0)").
+		Scan(&versions).Error
+	return used + versions, err
+}
+
+// releaseQuota 释放存储空间，用量最少减到0
+func releaseQuota(tx *gorm.DB, userID string, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	return tx.Model(&models.UserQuota{}).
+		Where("user_id = ?", userID).
+		UpdateColumn("used_bytes", gorm.Expr("CASE WHEN used_bytes > ? THEN used_bytes - ? ELSE 0 END", size, size)).Error
+}
@@ -0,0 +1,56 @@
+package upload
+
+import (
+	"time"
+
+	"template/internal/models"
+)
+
+// GetTrashedFiles 分页获取用户回收站中的文件，最近删除的在前
+func (r *UploadRepository) GetTrashedFiles(userID string, offset, limit int) ([]models.UploadFile, int64, error) {
+	var files []models.UploadFile
+	var total int64
+
+	query := r.db.Unscoped().Model(&models.UploadFile{}).
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("deleted_at DESC").Order("id").Offset(offset).Limit(limit).Find(&files).Error
+	return files, total, err
+}
+
+// GetTrashedFile 获取回收站中的文件记录
+func (r *UploadRepository) GetTrashedFile(fileID string) (*models.UploadFile, error) {
+	var file models.UploadFile
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", fileID).First(&file).Error
+	return &file, err
+}
+
+// GetUserTrashedFiles 获取用户回收站中的全部文件记录
+func (r *UploadRepository) GetUserTrashedFiles(userID string) ([]models.UploadFile, error) {
+	var files []models.UploadFile
+	err := r.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at").Find(&files).Error
+	return files, err
+}
+
+// GetExpiredTrashedFiles 获取删除时间早于 before 的回收站文件
+func (r *UploadRepository) GetExpiredTrashedFiles(before time.Time, limit int) ([]models.UploadFile, error) {
+	var files []models.UploadFile
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("deleted_at").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+// RestoreFile 将回收站中的文件恢复到 folderID，folderID 为空表示根目录
+// 文件已被恢复或彻底删除时返回 false
+func (r *UploadRepository) RestoreFile(fileID, folderID string) (bool, error) {
+	result := r.db.Unscoped().Model(&models.UploadFile{}).
+		Where("id = ? AND deleted_at IS NOT NULL", fileID).
+		Updates(map[string]interface{}{"deleted_at": nil, "folder_id": folderID})
+	return result.RowsAffected > 0, result.Error
+}
@@ -1,12 +1,20 @@
 package upload
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	"template/internal/models"
 	"template/pkg/constants"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errUploadNotMerging 文件已不在合并中（如已被标记失败），用于回滚事务
+var errUploadNotMerging = errors.New("upload not merging")
+
 type UploadRepository struct {
 	db *gorm.DB
 }
@@ -27,11 +35,53 @@ func (r *UploadRepository) GetUploadFileByID(fileID string) (*models.UploadFile,
 	return &file, err
 }
 
-// GetUploadFileByMD5 根据MD5获取文件信息
-func (r *UploadRepository) GetUploadFileByMD5(md5Hash string) (*models.UploadFile, error) {
-	var file models.UploadFile
-	err := r.db.Where("md5_hash = ? AND upload_status = ?", md5Hash, constants.UploadStatusCompleted).First(&file).Error
-	return &file, err
+// GetBlobByHash 根据SHA-256获取已存储的文件内容
+func (r *UploadRepository) GetBlobByHash(sha256Hash string) (*models.FileBlob, error) {
+	var blob models.FileBlob
+	if sha256Hash == "" {
+		return &blob, gorm.ErrRecordNotFound
+	}
+	err := r.db.Where("sha256_hash = ?", sha256Hash).First(&blob).Error
+	return &blob, err
+}
+
+// CreateFileWithBlob 创建已完成的文件记录并引用文件内容
+// blob 没有ID时为新存储的内容，一并创建；否则增加已有内容的引用计数
+func (r *UploadRepository) CreateFileWithBlob(file *models.UploadFile, blob *models.FileBlob) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := acquireBlob(tx, blob); err != nil {
+			return err
+		}
+		file.BlobID = blob.ID.String()
+		return tx.Create(file).Error
+	})
+}
+
+// CompleteFileWithBlob 更新合并完成的文件记录并引用文件内容，返回是否更新成功
+// blob 的处理同 CreateFileWithBlob；文件已不在合并中时不做修改并返回 false
+func (r *UploadRepository) CompleteFileWithBlob(file *models.UploadFile, blob *models.FileBlob) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := acquireBlob(tx, blob); err != nil {
+			return err
+		}
+		file.BlobID = blob.ID.String()
+
+		result := tx.Model(&models.UploadFile{}).
+			Where("id = ? AND upload_status = ?", file.ID, constants.UploadStatusMerging).
+			Select("*").Omit("id", "created_at").
+			Updates(file)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errUploadNotMerging
+		}
+		return nil
+	})
+	if errors.Is(err, errUploadNotMerging) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 // UpdateUploadFile 更新文件上传记录
@@ -46,6 +96,45 @@ func (r *UploadRepository) UpdateUploadProgress(fileID string, chunkUploaded int
 		Update("chunk_uploaded", chunkUploaded).Error
 }
 
+// MarkUploadFailed 将上传中或合并中的文件标记为失败并删除分片记录，释放上传预留的存储空间，返回是否标记成功
+// 文件已合并完成或已被标记失败时不做修改
+func (r *UploadRepository) MarkUploadFailed(fileID string) (bool, error) {
+	marked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var file models.UploadFile
+		if err := tx.Select("user_id", "file_size").Where("id = ?", fileID).First(&file).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.UploadFile{}).
+			Where("id = ? AND upload_status IN ?", fileID, []int{constants.UploadStatusUploading, constants.UploadStatusMerging}).
+			Update("upload_status", constants.UploadStatusFailed)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		if err := tx.Where("file_id = ?", fileID).Delete(&models.ChunkInfo{}).Error; err != nil {
+			return err
+		}
+		marked = true
+		return releaseQuota(tx, file.UserID, file.FileSize)
+	})
+	return marked && err == nil, err
+}
+
+// GetStaleUploads 获取在 updatedBefore 之后没有写入、或tus上传已过期的上传中文件，以及在 updatedBefore 之前开始合并而未完成的文件
+func (r *UploadRepository) GetStaleUploads(updatedBefore, now time.Time, limit int) ([]models.UploadFile, error) {
+	var files []models.UploadFile
+	expiredTus := r.db.Model(&models.TusUpload{}).Select("file_id").Where("expires_at < ?", now)
+	uploading := r.db.Where("upload_status = ?", constants.UploadStatusUploading).
+		Where(r.db.Where("updated_at < ?", updatedBefore).Or("id IN (?)", expiredTus))
+	merging := r.db.Where("upload_status = ? AND updated_at < ?", constants.UploadStatusMerging, updatedBefore)
+	err := r.db.Where(uploading).Or(merging).
+		Order("updated_at").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
 // UpdateUploadStatus 更新上传状态
 func (r *UploadRepository) UpdateUploadStatus(fileID string, status int) error {
 	return r.db.Model(&models.UploadFile{}).
@@ -53,32 +142,128 @@ func (r *UploadRepository) UpdateUploadStatus(fileID string, status int) error {
 		Update("upload_status", status).Error
 }
 
-// DeleteUploadFile 删除文件记录
+// TransitUploadStatus 将处于 fromStatus 状态的文件改为 toStatus，返回是否更新成功
+// 状态已被其他请求修改时不更新，避免重复处理
+func (r *UploadRepository) TransitUploadStatus(fileID string, fromStatus, toStatus int) (bool, error) {
+	result := r.db.Model(&models.UploadFile{}).
+		Where("id = ? AND upload_status = ?", fileID, fromStatus).
+		Update("upload_status", toStatus)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SaveScanResult 保存待扫描文件的扫描结果，返回是否更新成功
+func (r *UploadRepository) SaveScanResult(fileID string, status int, signature string, scannedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.UploadFile{}).
+		Where("id = ? AND upload_status = ?", fileID, constants.UploadStatusPendingScan).
+		Updates(map[string]interface{}{
+			"upload_status":  status,
+			"scan_signature": signature,
+			"scanned_at":     scannedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetFilesByStatus 按上传时间顺序获取指定状态的文件
+func (r *UploadRepository) GetFilesByStatus(status int, offset, limit int) ([]models.UploadFile, int64, error) {
+	var files []models.UploadFile
+	var total int64
+
+	query := r.db.Model(&models.UploadFile{}).Where("upload_status = ?", status)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("uploaded_at").Offset(offset).Limit(limit).Find(&files).Error
+	return files, total, err
+}
+
+// DeleteUploadFile 将文件记录移入回收站
 func (r *UploadRepository) DeleteUploadFile(fileID string) error {
 	return r.db.Where("id = ?", fileID).Delete(&models.UploadFile{}).Error
 }
 
-// GetUserFiles 获取用户文件列表
-func (r *UploadRepository) GetUserFiles(userID string, page, pageSize int) ([]models.UploadFile, int64, error) {
+// FileQuery 用户文件列表的查询条件
+type FileQuery struct {
+	UserID    string
+	FolderID  *string    // 所在文件夹，为空字符串表示根目录，nil 表示不限
+	MimeType  string     // 文件类型，以 /* 结尾时按前缀匹配，如 image/*
+	Keyword   string     // 文件名包含的关键词
+	StartTime *time.Time // 创建时间不早于
+	EndTime   *time.Time // 创建时间早于
+	SortBy    string     // 排序字段：created_at、filename、file_size、download_count
+	SortOrder string     // 排序方向：asc、desc
+	Offset    int
+	Limit     int
+}
+
+// fileSortColumns 文件列表允许的排序字段
+var fileSortColumns = map[string]bool{
+	"created_at":     true,
+	"filename":       true,
+	"file_size":      true,
+	"download_count": true,
+}
+
+// GetUserFiles 获取用户已保存内容的文件列表，不包括上传中、合并中和上传失败的文件
+func (r *UploadRepository) GetUserFiles(query *FileQuery) ([]models.UploadFile, int64, error) {
 	var files []models.UploadFile
 	var total int64
 
+	db := r.db.Model(&models.UploadFile{}).
+		Where("user_id = ? AND upload_status NOT IN ?", query.UserID,
+			[]int{constants.UploadStatusUploading, constants.UploadStatusMerging, constants.UploadStatusFailed})
+	if query.FolderID != nil {
+		db = db.Where("folder_id = ?", *query.FolderID)
+	}
+	if strings.HasSuffix(query.MimeType, "/*") {
+		db = db.Where("mime_type LIKE ?", strings.TrimSuffix(query.MimeType, "*")+"%")
+	} else if query.MimeType != "" {
+		db = db.Where("mime_type = ?", query.MimeType)
+	}
+	if query.Keyword != "" {
+		db = db.Where("filename LIKE ?", "%"+query.Keyword+"%")
+	}
+	if query.StartTime != nil {
+		db = db.Where("created_at >= ?", *query.StartTime)
+	}
+	if query.EndTime != nil {
+		db = db.Where("created_at < ?", *query.EndTime)
+	}
+
 	// 计算总数
-	if err := r.db.Model(&models.UploadFile{}).Where("user_id = ?", userID).Count(&total).Error; err != nil {
+	if err := db.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// 分页查询
-	offset := (page - 1) * pageSize
-	err := r.db.Where("user_id = ?", userID).
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(pageSize).
+	sortBy := "created_at"
+	if fileSortColumns[query.SortBy] {
+		sortBy = query.SortBy
+	}
+	sortOrder := "DESC"
+	if query.SortOrder == "asc" {
+		sortOrder = "ASC"
+	}
+
+	// 分页查询，排序字段相同时按ID保证顺序稳定
+	err := db.Order(sortBy + " " + sortOrder).
+		Order("id").
+		Offset(query.Offset).
+		Limit(query.Limit).
 		Find(&files).Error
 
 	return files, total, err
 }
 
+// RenameFile 修改文件名
+func (r *UploadRepository) RenameFile(fileID, filename string) error {
+	return r.db.Model(&models.UploadFile{}).Where("id = ?", fileID).Update("filename", filename).Error
+}
+
+// UpdateFileVisibility 设置文件是否公开
+func (r *UploadRepository) UpdateFileVisibility(fileID string, isPublic bool) error {
+	return r.db.Model(&models.UploadFile{}).Where("id = ?", fileID).Update("is_public", isPublic).Error
+}
+
 // CreateChunkInfo 创建分片信息
 func (r *UploadRepository) CreateChunkInfo(chunk *models.ChunkInfo) error {
 	return r.db.Create(chunk).Error
@@ -114,6 +299,70 @@ func (r *UploadRepository) GetUploadedChunksCount(fileID string) (int64, error)
 	return count, err
 }
 
+// CreateChunkInfos 批量创建分片信息
+func (r *UploadRepository) CreateChunkInfos(chunks []*models.ChunkInfo) error {
+	return r.db.Create(chunks).Error
+}
+
+// GetUploadedBytes 获取已上传分片的总字节数
+func (r *UploadRepository) GetUploadedBytes(fileID string) (int64, error) {
+	var total int64
+	err := r.db.Model(&models.ChunkInfo{}).
+		Where("file_id = ? AND is_uploaded = ?", fileID, true).
+		Select("COALESCE(SUM(chunk_size), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// UpdateChunkTotal 更新总分片数
+func (r *UploadRepository) UpdateChunkTotal(fileID string, chunkTotal int) error {
+	return r.db.Model(&models.UploadFile{}).
+		Where("id = ?", fileID).
+		Update("chunk_total", chunkTotal).Error
+}
+
+// CreateTusUpload 创建tus上传的文件记录和上传信息
+func (r *UploadRepository) CreateTusUpload(file *models.UploadFile, tus *models.TusUpload) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(file).Error; err != nil {
+			return err
+		}
+		tus.FileID = file.ID.String()
+		return tx.Create(tus).Error
+	})
+}
+
+// GetTusUpload 获取tus上传信息
+func (r *UploadRepository) GetTusUpload(fileID string) (*models.TusUpload, error) {
+	var tus models.TusUpload
+	err := r.db.Where("file_id = ?", fileID).First(&tus).Error
+	return &tus, err
+}
+
+// GetFileDerivative 获取文件指定类型的派生文件
+func (r *UploadRepository) GetFileDerivative(fileID, kind string) (*models.FileDerivative, error) {
+	var derivative models.FileDerivative
+	err := r.db.Where("file_id = ? AND kind = ?", fileID, kind).First(&derivative).Error
+	return &derivative, err
+}
+
+// GetFileDerivatives 获取文件的全部派生文件
+func (r *UploadRepository) GetFileDerivatives(fileID string) ([]models.FileDerivative, error) {
+	var derivatives []models.FileDerivative
+	err := r.db.Where("file_id = ?", fileID).Find(&derivatives).Error
+	return derivatives, err
+}
+
+// CreateFileDerivative 创建派生文件记录，同一文件同一类型已存在时忽略
+func (r *UploadRepository) CreateFileDerivative(derivative *models.FileDerivative) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(derivative).Error
+}
+
+// DeleteFileDerivatives 彻底删除文件的全部派生文件记录
+func (r *UploadRepository) DeleteFileDerivatives(fileID string) error {
+	return r.db.Unscoped().Where("file_id = ?", fileID).Delete(&models.FileDerivative{}).Error
+}
+
 // DeleteFileChunks 删除文件的所有分片记录
 func (r *UploadRepository) DeleteFileChunks(fileID string) error {
 	return r.db.Where("file_id = ?", fileID).Delete(&models.ChunkInfo{}).Error
@@ -127,3 +376,133 @@ func (r *UploadRepository) CheckChunkExists(fileID string, chunkIndex int) bool
 		Count(&count)
 	return count > 0
 }
+
+// GetAllUserFiles 获取用户的全部文件记录
+func (r *UploadRepository) GetAllUserFiles(userID string) ([]models.UploadFile, error) {
+	var files []models.UploadFile
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&files).Error
+	return files, err
+}
+
+// ReassignUserFiles 将用户的文件记录（包括回收站中的文件）转移给其他用户
+// 原用户的文件夹不转移，文件移到接收用户的根目录
+func (r *UploadRepository) ReassignUserFiles(fromUserID, toUserID string) (int64, error) {
+	var count int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.UploadFile{}).
+			Where("user_id = ?", fromUserID).
+			Updates(map[string]interface{}{"user_id": toUserID, "folder_id": ""})
+		if result.Error != nil {
+			return result.Error
+		}
+		count = result.RowsAffected
+		return deleteUserFolders(tx, fromUserID)
+	})
+	return count, err
+}
+
+// PurgeFileRecords 彻底删除文件记录及其分片、分享、权限、临时访问记录和历史版本，并释放对文件内容的引用
+// 返回不再被引用的文件内容，调用方需要删除其存储
+func (r *UploadRepository) PurgeFileRecords(file *models.UploadFile) ([]models.FileBlob, error) {
+	fileID := file.ID.String()
+
+	var orphans []models.FileBlob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{
+			&models.ChunkInfo{},
+			&models.TusUpload{},
+			&models.FileDerivative{},
+			&models.FileShare{},
+			&models.FilePermission{},
+			&models.TemporaryAccess{},
+		} {
+			if err := tx.Unscoped().Where("file_id = ?", fileID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		// 释放文件占用的存储空间，上传失败的记录已经释放过
+		var current models.UploadFile
+		err := tx.Unscoped().Select("user_id", "file_size", "upload_status").Where("id = ?", fileID).First(&current).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		result := tx.Unscoped().Where("id = ?", fileID).Delete(&models.UploadFile{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 && current.UploadStatus != constants.UploadStatusFailed {
+			if err := releaseQuota(tx, current.UserID, current.FileSize); err != nil {
+				return err
+			}
+		}
+
+		var versions []models.FileVersion
+		if err := tx.Where("file_id = ?", fileID).Find(&versions).Error; err != nil {
+			return err
+		}
+		orphans, err = deleteFileVersions(tx, current.UserID, versions)
+		if err != nil {
+			return err
+		}
+
+		if file.BlobID == "" {
+			return nil
+		}
+		orphan, err := releaseBlob(tx, file.BlobID)
+		if orphan != nil {
+			orphans = append(orphans, *orphan)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orphans, nil
+}
+
+// acquireBlob 占用文件内容的一个引用，内容记录不存在时创建
+// 已有内容在此期间被释放时返回 gorm.ErrRecordNotFound
+func acquireBlob(tx *gorm.DB, blob *models.FileBlob) error {
+	if blob.ID.IsZero() {
+		blob.RefCount = 1
+		return tx.Create(blob).Error
+	}
+
+	result := tx.Model(&models.FileBlob{}).
+		Where("id = ?", blob.ID).
+		UpdateColumn("ref_count", gorm.Expr("ref_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	blob.RefCount++
+	return nil
+}
+
+// releaseBlob 释放文件内容的一个引用，最后一个引用释放时删除内容记录并返回该记录
+func releaseBlob(tx *gorm.DB, blobID string) (*models.FileBlob, error) {
+	err := tx.Model(&models.FileBlob{}).
+		Where("id = ?", blobID).
+		UpdateColumn("ref_count", gorm.Expr("ref_count - 1")).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var blob models.FileBlob
+	if err := tx.Where("id = ?", blobID).First(&blob).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if blob.RefCount > 0 {
+		return nil, nil
+	}
+
+	if err := tx.Unscoped().Delete(&blob).Error; err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
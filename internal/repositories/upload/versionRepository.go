@@ -0,0 +1,152 @@
+package upload
+
+import (
+	"errors"
+	"time"
+
+	"template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// errFileVersionChanged 文件读取后已被其他请求替换过内容，用于回滚事务
+var errFileVersionChanged = errors.New("file version changed")
+
+// GetBlobByID 根据ID获取文件内容
+func (r *UploadRepository) GetBlobByID(blobID string) (*models.FileBlob, error) {
+	var blob models.FileBlob
+	err := r.db.Where("id = ?", blobID).First(&blob).Error
+	return &blob, err
+}
+
+// GetFileVersions 获取文件的全部历史版本，按版本号从新到旧排序
+func (r *UploadRepository) GetFileVersions(fileID string) ([]models.FileVersion, error) {
+	var versions []models.FileVersion
+	err := r.db.Where("file_id = ?", fileID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+// GetFileVersion 获取文件的指定历史版本
+func (r *UploadRepository) GetFileVersion(fileID string, version int) (*models.FileVersion, error) {
+	var fileVersion models.FileVersion
+	err := r.db.Where("file_id = ? AND version = ?", fileID, version).First(&fileVersion).Error
+	return &fileVersion, err
+}
+
+// SaveFileVersion 将文件的当前内容保存为历史版本，并替换为 blob 的内容，返回是否替换成功
+// blob 的处理同 CreateFileWithBlob；file 读取后已被其他请求替换过内容时不做修改并返回 false
+func (r *UploadRepository) SaveFileVersion(file *models.UploadFile, blob *models.FileBlob, mimeType string, status int) (bool, error) {
+	previous := &models.FileVersion{
+		FileID:     file.ID.String(),
+		Version:    file.Version,
+		BlobID:     file.BlobID,
+		FilePath:   file.FilePath,
+		FileSize:   file.FileSize,
+		MimeType:   file.MimeType,
+		MD5Hash:    file.MD5Hash,
+		SHA256Hash: file.SHA256Hash,
+		UploadedBy: file.UserID,
+		UploadedAt: file.UploadedAt,
+	}
+	now := time.Now()
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 旧记录独占存储路径，先为其创建内容记录，历史版本统一通过引用管理存储
+		if previous.BlobID == "" {
+			legacy := &models.FileBlob{
+				SHA256Hash: file.SHA256Hash,
+				MD5Hash:    file.MD5Hash,
+				StoredName: file.StoredName,
+				FilePath:   file.FilePath,
+				FileSize:   file.FileSize,
+			}
+			if err := acquireBlob(tx, legacy); err != nil {
+				return err
+			}
+			previous.BlobID = legacy.ID.String()
+		}
+		if err := acquireBlob(tx, blob); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.UploadFile{}).
+			Where("id = ? AND version = ?", file.ID, file.Version).
+			Updates(map[string]interface{}{
+				"blob_id":        blob.ID.String(),
+				"file_path":      blob.FilePath,
+				"file_size":      blob.FileSize,
+				"mime_type":      mimeType,
+				"md5_hash":       blob.MD5Hash,
+				"sha256_hash":    blob.SHA256Hash,
+				"version":        file.Version + 1,
+				"upload_status":  status,
+				"uploaded_at":    now,
+				"scanned_at":     nil,
+				"scan_signature": "",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errFileVersionChanged
+		}
+		return tx.Create(previous).Error
+	})
+	if errors.Is(err, errFileVersionChanged) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	file.BlobID = blob.ID.String()
+	file.FilePath = blob.FilePath
+	file.FileSize = blob.FileSize
+	file.MimeType = mimeType
+	file.MD5Hash = blob.MD5Hash
+	file.SHA256Hash = blob.SHA256Hash
+	file.Version++
+	file.UploadStatus = status
+	file.UploadedAt = &now
+	file.ScannedAt = nil
+	file.ScanSignature = ""
+	return true, nil
+}
+
+// DeleteFileVersions 彻底删除文件的历史版本，释放对文件内容的引用和占用的存储空间
+// 返回不再被引用的文件内容，调用方需要删除其存储
+func (r *UploadRepository) DeleteFileVersions(file *models.UploadFile, versions []models.FileVersion) ([]models.FileBlob, error) {
+	var orphans []models.FileBlob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		orphans, err = deleteFileVersions(tx, file.UserID, versions)
+		return err
+	})
+	return orphans, err
+}
+
+// deleteFileVersions 删除历史版本记录并释放内容引用和 userID 的存储用量，返回不再被引用的文件内容
+func deleteFileVersions(tx *gorm.DB, userID string, versions []models.FileVersion) ([]models.FileBlob, error) {
+	var orphans []models.FileBlob
+	var size int64
+	for _, version := range versions {
+		result := tx.Unscoped().Where("id = ?", version.ID).Delete(&models.FileVersion{})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		// 已被其他请求删除
+		if result.RowsAffected == 0 {
+			continue
+		}
+		size += version.FileSize
+
+		orphan, err := releaseBlob(tx, version.BlobID)
+		if err != nil {
+			return nil, err
+		}
+		if orphan != nil {
+			orphans = append(orphans, *orphan)
+		}
+	}
+	return orphans, releaseQuota(tx, userID, size)
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	fileController "template/internal/controllers/file"
 	"template/internal/middleware"
+	"template/pkg/common"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,8 +14,9 @@ func RegisterFileRoutes(router *gin.Engine) {
 	files := router.Group("/files")
 	{
 		// 需要权限验证的文件访问
+		// 先解析可选的登录凭证，私有文件据此判断访问权限；API密钥需要 files:read 授权范围
 		authFiles := files.Group("")
-		authFiles.Use(middleware.FileAuthMiddleware())
+		authFiles.Use(middleware.OptionalAuth(common.ScopeFilesRead), middleware.FileAuthMiddleware())
 		{
 			// 支持两种URL格式：
 			// 1. 简单格式：/files/download/uuid 和 /files/preview/uuid
@@ -24,6 +26,16 @@ func RegisterFileRoutes(router *gin.Engine) {
 			// 2. 带文件名格式：/files/download/uuid/filename.ext 和 /files/preview/uuid/filename.ext
 			authFiles.GET("/download/:fileId/:filename", fileController.DownloadFile)
 			authFiles.GET("/preview/:fileId/:filename", fileController.PreviewFile)
+
+			// HEAD 请求只返回响应头，供下载工具获取文件大小和断点续传支持
+			authFiles.HEAD("/download/:fileId", fileController.DownloadFile)
+			authFiles.HEAD("/preview/:fileId", fileController.PreviewFile)
+			authFiles.HEAD("/download/:fileId/:filename", fileController.DownloadFile)
+			authFiles.HEAD("/preview/:fileId/:filename", fileController.PreviewFile)
+
+			// 图片缩略图：/files/thumb/uuid/256
+			authFiles.GET("/thumb/:fileId/:size", fileController.ThumbnailFile)
+			authFiles.HEAD("/thumb/:fileId/:size", fileController.ThumbnailFile)
 		}
 	}
 }
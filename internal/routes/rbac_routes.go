@@ -0,0 +1,29 @@
+package routes
+
+import (
+	rbacController "template/internal/controllers/rbac"
+	"template/internal/middleware"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRBACRoutes 注册角色权限管理路由
+func RegisterRBACRoutes(r *gin.RouterGroup) {
+	rbacGroup := r.Group("")
+	rbacGroup.Use(middleware.RequirePermission(common.PermRoleManage))
+	{
+		rbacGroup.GET("/permissions", rbacController.ListPermissions)
+
+		// 角色管理
+		rbacGroup.GET("/roles", rbacController.ListRoles)
+		rbacGroup.POST("/roles", rbacController.CreateRole)
+		rbacGroup.GET("/roles/:id", rbacController.GetRole)
+		rbacGroup.PUT("/roles/:id", rbacController.UpdateRole)
+		rbacGroup.DELETE("/roles/:id", rbacController.DeleteRole)
+
+		// 用户角色分配
+		rbacGroup.GET("/users/:id/roles", rbacController.GetUserRoles)
+		rbacGroup.PUT("/users/:id/roles", rbacController.SetUserRoles)
+	}
+}
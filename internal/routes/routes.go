@@ -28,6 +28,14 @@ func RegisterRoutes(r *gin.Engine) {
 		// 上传相关路由
 		RegisterUploadRoutes(version)
 
+		// 角色权限管理路由
+		rbacRoutes := version.Group("/rbac")
+		RegisterRBACRoutes(rbacRoutes)
+
+		// Webhook管理路由
+		webhookRoutes := version.Group("/webhooks")
+		RegisterWebhookRoutes(webhookRoutes)
+
 		// 在这里添加其他模块路由
 		// 例如：
 		// productRoutes := api.Group("/product")
@@ -3,6 +3,7 @@ package routes
 import (
 	uploadController "template/internal/controllers/upload"
 	"template/internal/middleware"
+	"template/pkg/common"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,21 +15,101 @@ func RegisterUploadRoutes(router *gin.RouterGroup) {
 	{
 		// 无需认证的路由
 		upload.GET("/config", uploadController.GetUploadConfig) // 获取上传配置
+		upload.OPTIONS("/tus", uploadController.TusOptions)     // tus能力查询
 
-		// 需要认证的路由
+		// 上传文件，需要 upload:file 权限，API密钥还需要 upload:write 授权范围
 		authUpload := upload.Group("")
-		authUpload.Use(middleware.RequireAuth())
+		authUpload.Use(middleware.RequireAuth(common.ScopeUploadWrite), middleware.RequireUserPermission(common.PermUploadFile))
 		{
 			// 简单上传
 			authUpload.POST("/simple", uploadController.SimpleUpload)
 
 			// 分片上传相关
-			authUpload.POST("/chunk/init", uploadController.InitChunkUpload) // 初始化分片上传
-			authUpload.POST("/chunk", uploadController.UploadChunk)          // 上传分片
-			authUpload.POST("/chunk/merge", uploadController.MergeChunks)    // 合并分片
+			authUpload.POST("/chunk/init", uploadController.InitChunkUpload)       // 初始化分片上传
+			authUpload.POST("/chunk", uploadController.UploadChunk)                // 上传分片
+			authUpload.POST("/chunk/merge", uploadController.MergeChunks)          // 合并分片
+			authUpload.DELETE("/chunk/:fileID", uploadController.AbortChunkUpload) // 终止分片上传
 
 			// 上传进度查询
 			authUpload.GET("/progress/:fileID", uploadController.GetUploadProgress)
+
+			// tus 1.0 断点续传
+			authUpload.POST("/tus", uploadController.TusCreate)           // 创建上传
+			authUpload.HEAD("/tus/:fileID", uploadController.TusHead)     // 查询上传进度
+			authUpload.PATCH("/tus/:fileID", uploadController.TusPatch)   // 写入上传内容
+			authUpload.DELETE("/tus/:fileID", uploadController.TusDelete) // 终止上传
+
+			// 当前用户的存储用量和配额
+			authUpload.GET("/quota", uploadController.GetQuota)
+		}
+
+		// 查看文件、文件夹和回收站，API密钥需要 files:read 授权范围
+		readFiles := upload.Group("")
+		readFiles.Use(middleware.RequireAuth(common.ScopeFilesRead))
+		{
+			readFiles.GET("/files", uploadController.ListMyFiles)                               // 文件列表
+			readFiles.GET("/files/:fileID", uploadController.GetMyFile)                         // 文件详情
+			readFiles.GET("/files/:fileID/versions", uploadController.ListFileVersions)         // 版本列表
+			readFiles.GET("/files/:fileID/temp-access", uploadController.ListTemporaryAccesses) // 临时访问链接列表
+			readFiles.GET("/folders/children", uploadController.ListFolderChildren)             // 文件夹内容
+			readFiles.GET("/folders/:folderID", uploadController.GetFolder)                     // 文件夹详情和路径
+			readFiles.GET("/trash", uploadController.ListTrash)                                 // 回收站文件列表
+		}
+
+		// 修改文件和文件夹，API密钥需要 files:write 授权范围
+		writeFiles := upload.Group("")
+		writeFiles.Use(middleware.RequireAuth(common.ScopeFilesWrite))
+		{
+			writeFiles.PUT("/files/:fileID/name", uploadController.RenameMyFile)             // 修改文件名
+			writeFiles.PUT("/files/:fileID/settings", uploadController.UpdateMyFileSettings) // 设置是否公开
+			writeFiles.PUT("/files/:fileID/folder", uploadController.MoveMyFile)             // 移到文件夹
+
+			// 文件版本
+			writeFiles.POST("/files/:fileID/versions", uploadController.UploadFileVersion)                   // 上传新版本
+			writeFiles.POST("/files/:fileID/versions/:version/restore", uploadController.RestoreFileVersion) // 恢复历史版本
+
+			// 临时访问链接
+			writeFiles.POST("/files/:fileID/temp-access", uploadController.CreateTemporaryAccess)             // 创建链接
+			writeFiles.DELETE("/files/:fileID/temp-access/:accessID", uploadController.RevokeTemporaryAccess) // 停用链接
+
+			// 文件夹
+			writeFiles.POST("/folders", uploadController.CreateFolder)                           // 创建文件夹
+			writeFiles.PUT("/folders/:folderID/name", uploadController.RenameFolder)             // 修改名称
+			writeFiles.PUT("/folders/:folderID/parent", uploadController.MoveFolder)             // 移动文件夹
+			writeFiles.PUT("/folders/:folderID/settings", uploadController.UpdateFolderSettings) // 设置是否公开
+
+			// 回收站
+			writeFiles.POST("/trash/restore", uploadController.RestoreTrash) // 恢复文件
+		}
+
+		// 删除文件和文件夹，API密钥需要 files:delete 授权范围
+		deleteFiles := upload.Group("")
+		deleteFiles.Use(middleware.RequireAuth(common.ScopeFilesDelete))
+		{
+			deleteFiles.POST("/files/batch-delete", uploadController.DeleteMyFiles)               // 批量删除
+			deleteFiles.POST("/files/:fileID/versions/prune", uploadController.PruneFileVersions) // 删除旧版本
+			deleteFiles.DELETE("/folders/:folderID", uploadController.DeleteFolder)               // 删除文件夹，其中的文件移入回收站
+			deleteFiles.DELETE("/trash/:fileID", uploadController.PurgeTrashedFile)               // 彻底删除文件
+			deleteFiles.DELETE("/trash", uploadController.EmptyTrash)                             // 清空回收站
+		}
+
+		// 用户配额管理，需要文件管理权限
+		quota := upload.Group("/quota")
+		quota.Use(middleware.RequirePermission(common.PermFileManage))
+		{
+			quota.POST("/recompute", uploadController.RecomputeAllQuotas)               // 重新统计所有用户的用量
+			quota.GET("/users/:userID", uploadController.GetUserQuota)                  // 查询用户配额
+			quota.PUT("/users/:userID", uploadController.SetUserQuota)                  // 设置用户配额
+			quota.POST("/users/:userID/recompute", uploadController.RecomputeUserQuota) // 重新统计用户用量
+		}
+
+		// 隔离文件审核，需要文件管理权限，删除还需要删除任意文件的权限
+		quarantine := upload.Group("/quarantine")
+		quarantine.Use(middleware.RequirePermission(common.PermFileManage))
+		{
+			quarantine.GET("", uploadController.ListQuarantinedFiles)
+			quarantine.POST("/:fileID/release", uploadController.ReleaseQuarantinedFile)
+			quarantine.DELETE("/:fileID", middleware.RequirePermission(common.PermFileDelete), uploadController.DeleteQuarantinedFile)
 		}
 	}
 }
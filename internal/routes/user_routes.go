@@ -1,8 +1,10 @@
 package routes
 
 import (
+	rbacController "template/internal/controllers/rbac"
 	userController "template/internal/controllers/user"
 	"template/internal/middleware"
+	"template/pkg/common"
 
 	"github.com/gin-gonic/gin"
 )
@@ -12,18 +14,54 @@ func RegisterUserRoutes(r *gin.RouterGroup) {
 	// 公开路由
 	r.POST("/register", userController.Register)
 	r.POST("/login", userController.Login)
+	r.POST("/login/2fa", userController.VerifyTwoFactorLogin)
+	r.POST("/refresh", userController.RefreshToken)
 	r.POST("/send-registration-code", userController.SendRegistrationCode)
 	r.POST("/send-reset-password-code", userController.SendResetPasswordCode)
 	r.POST("/reset-password", userController.ResetPassword)
 
+	// 第三方登录
+	r.GET("/oauth/providers", userController.ListOAuthProviders)
+	r.GET("/oauth/:provider", userController.OAuthLogin)
+	r.GET("/oauth/:provider/callback", userController.OAuthCallback)
+
 	// 需要认证的路由
 	protected := r.Group("")
 	protected.Use(middleware.RequireAuth())
 	{
 		protected.GET("/info", userController.GetUserInfo)
+		protected.GET("/permissions", rbacController.GetCurrentAuthorization)
+		protected.POST("/logout", userController.Logout)
 		protected.PUT("/profile", userController.UpdateProfile)
 		protected.POST("/change-password", userController.ChangePassword)
 		protected.POST("/send-change-email-code", userController.SendChangeEmailCode)
+
+		// 登录会话管理
+		protected.GET("/sessions", userController.ListSessions)
+		protected.DELETE("/sessions/:id", userController.RevokeSession)
+		protected.DELETE("/sessions", userController.RevokeOtherSessions)
+
+		// 两步验证
+		protected.GET("/2fa/status", userController.GetTwoFactorStatus)
+		protected.POST("/2fa/setup", userController.SetupTwoFactor)
+		protected.POST("/2fa/enable", userController.EnableTwoFactor)
+		protected.POST("/2fa/disable", userController.DisableTwoFactor)
+		protected.POST("/2fa/recovery-codes", userController.RegenerateRecoveryCodes)
+
+		// 第三方账号绑定
+		protected.POST("/oauth/:provider/link", userController.OAuthLink)
+		protected.GET("/identities", userController.ListIdentities)
+		protected.DELETE("/identities/:id", userController.UnlinkIdentity)
+
+		// API密钥管理（只能使用登录令牌访问）
+		protected.GET("/api-keys", userController.ListAPIKeys)
+		protected.POST("/api-keys", userController.CreateAPIKey)
+		protected.DELETE("/api-keys/:id", userController.RevokeAPIKey)
+
+		// 个人数据导出与账号注销
+		protected.GET("/export", userController.ExportAccountData)
+		protected.POST("/send-delete-account-code", userController.SendDeleteAccountCode)
+		protected.POST("/delete-account", userController.DeleteAccount)
 	}
 
 	// 用户路由组，需要登录才能访问
@@ -38,6 +76,19 @@ func RegisterUserRoutes(r *gin.RouterGroup) {
 	adminGroup.Use(middleware.RequireAdmin())
 	{
 		// 在这里添加管理员接口
+		canRead := middleware.RequirePermission(common.PermUserRead)
+		canManage := middleware.RequirePermission(common.PermUserManage)
+
+		// 用户管理
+		adminGroup.GET("/users", canRead, userController.ListUsers)
+		adminGroup.GET("/users/:id", canRead, userController.GetUser)
+		adminGroup.POST("/users", canManage, userController.CreateUser)
+		adminGroup.POST("/users/:id/disable", canManage, userController.DisableUser)
+		adminGroup.POST("/users/:id/enable", canManage, userController.EnableUser)
+		adminGroup.DELETE("/users/:id", canManage, userController.DeleteUser)
+		adminGroup.POST("/users/:id/restore", canManage, userController.RestoreUser)
+		adminGroup.POST("/users/:id/reset-password", canManage, userController.ResetUserPassword)
+		adminGroup.POST("/users/:id/unlock", canManage, userController.UnlockUser)
 	}
 
 	// 超级管理员路由组
@@ -45,5 +96,6 @@ func RegisterUserRoutes(r *gin.RouterGroup) {
 	superGroup.Use(middleware.RequireSuperAuth())
 	{
 		// 在这里添加超级管理员接口
+		superGroup.PUT("/users/:id/role", userController.ChangeUserRole)
 	}
 }
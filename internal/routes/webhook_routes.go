@@ -0,0 +1,30 @@
+package routes
+
+import (
+	webhookController "template/internal/controllers/webhook"
+	"template/internal/middleware"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookRoutes 注册Webhook管理路由
+func RegisterWebhookRoutes(r *gin.RouterGroup) {
+	webhookGroup := r.Group("")
+	webhookGroup.Use(middleware.RequirePermission(common.PermWebhookManage))
+	{
+		webhookGroup.GET("/events", webhookController.ListEvents)
+
+		// 订阅管理
+		webhookGroup.GET("", webhookController.ListWebhooks)
+		webhookGroup.POST("", webhookController.CreateWebhook)
+		webhookGroup.GET("/:id", webhookController.GetWebhook)
+		webhookGroup.PUT("/:id", webhookController.UpdateWebhook)
+		webhookGroup.DELETE("/:id", webhookController.DeleteWebhook)
+		webhookGroup.POST("/:id/rotate-secret", webhookController.RotateWebhookSecret)
+
+		// 投递记录
+		webhookGroup.GET("/:id/deliveries", webhookController.ListDeliveries)
+		webhookGroup.POST("/:id/deliveries/:deliveryId/redeliver", webhookController.Redeliver)
+	}
+}
@@ -0,0 +1,505 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"template/internal/models"
+	"template/pkg/cache"
+	"template/pkg/common"
+	"template/pkg/constants"
+	"template/pkg/database"
+	"template/pkg/errors"
+
+	"gorm.io/gorm"
+)
+
+// roleNamePattern 角色标识格式：小写字母开头，只包含小写字母、数字和下划线
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// Authorization 用户的角色和权限
+type Authorization struct {
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+}
+
+// HasPermission 是否拥有全部指定权限
+func (a *Authorization) HasPermission(perms ...string) bool {
+	for _, perm := range perms {
+		if !contains(a.Permissions, perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasRole 是否拥有任一指定角色
+func (a *Authorization) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if contains(a.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetUserAuthorization 获取用户的角色和权限，结果会缓存一段时间，角色变更时主动清除
+func GetUserAuthorization(userID string) (*Authorization, error) {
+	key := fmt.Sprintf(constants.CacheKeyUserAuthorization, userID)
+	if value, err := cache.Get(key); err == nil && value != "" {
+		var authz Authorization
+		if json.Unmarshal([]byte(value), &authz) == nil {
+			return &authz, nil
+		}
+	}
+
+	authz, err := loadAuthorization(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err := json.Marshal(authz); err == nil {
+		if err := cache.Set(key, string(data), constants.CacheTimeShort); err != nil {
+			log.Printf("缓存用户权限失败: %v", err)
+		}
+	}
+	return authz, nil
+}
+
+// HasPermission 检查用户是否拥有全部指定权限
+func HasPermission(userID string, perms ...string) bool {
+	authz, err := GetUserAuthorization(userID)
+	if err != nil {
+		return false
+	}
+	return authz.HasPermission(perms...)
+}
+
+// HasRole 检查用户是否拥有任一指定角色
+func HasRole(userID string, roles ...string) bool {
+	authz, err := GetUserAuthorization(userID)
+	if err != nil {
+		return false
+	}
+	return authz.HasRole(roles...)
+}
+
+// InvalidateUserAuthorization 清除用户的权限缓存
+func InvalidateUserAuthorization(userIDs ...string) {
+	for _, userID := range userIDs {
+		_ = cache.Del(fmt.Sprintf(constants.CacheKeyUserAuthorization, userID))
+	}
+}
+
+// loadAuthorization 从数据库加载用户的角色和权限
+func loadAuthorization(userID string) (*Authorization, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var roles []struct {
+		ID   string
+		Name string
+	}
+	err := db.Raw(`SELECT r.id, r.name FROM role r
+		JOIN user_role ur ON ur.role_id = r.id
+		WHERE ur.user_id = ? AND r.deleted_at IS NULL`, userID).Scan(&roles).Error
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询用户角色失败")
+	}
+
+	// 尚未分配角色的用户（如新注册用户），按旧版整数角色使用对应的内置角色
+	if len(roles) == 0 {
+		var legacyRole int
+		if err := db.Raw("SELECT role FROM user WHERE id = ? LIMIT 1", userID).Scan(&legacyRole).Error; err != nil {
+			return nil, errors.New(errors.CodeQueryFailed, "查询用户角色失败")
+		}
+		err := db.Raw("SELECT id, name FROM role WHERE name = ? AND deleted_at IS NULL",
+			common.LegacyRoleName(legacyRole)).Scan(&roles).Error
+		if err != nil {
+			return nil, errors.New(errors.CodeQueryFailed, "查询用户角色失败")
+		}
+	}
+
+	authz := &Authorization{Roles: make([]string, 0, len(roles)), Permissions: []string{}}
+	roleIDs := make([]string, 0, len(roles))
+	for _, role := range roles {
+		authz.Roles = append(authz.Roles, role.Name)
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	// 超级管理员拥有全部权限
+	if authz.HasRole(common.RoleNameSuperAdmin) {
+		for _, perm := range common.Permissions {
+			authz.Permissions = append(authz.Permissions, perm.Code)
+		}
+		return authz, nil
+	}
+
+	if len(roleIDs) > 0 {
+		err := db.Raw(`SELECT DISTINCT p.code FROM permission p
+			JOIN role_permission rp ON rp.permission_id = p.id
+			WHERE rp.role_id IN ? AND p.deleted_at IS NULL ORDER BY p.code`, roleIDs).Scan(&authz.Permissions).Error
+		if err != nil {
+			return nil, errors.New(errors.CodeQueryFailed, "查询用户权限失败")
+		}
+	}
+
+	return authz, nil
+}
+
+// ListPermissions 获取全部权限
+func ListPermissions() ([]models.Permission, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var perms []models.Permission
+	if err := db.Order("code").Find(&perms).Error; err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询权限失败")
+	}
+	return perms, nil
+}
+
+// ListRoles 获取全部角色及其权限
+func ListRoles() ([]models.Role, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var roles []models.Role
+	if err := db.Preload("Permissions").Order("is_system DESC, created_at").Find(&roles).Error; err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询角色失败")
+	}
+	return roles, nil
+}
+
+// GetRole 获取角色详情
+func GetRole(roleID string) (*models.Role, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var role models.Role
+	if err := db.Preload("Permissions").Where("id = ?", roleID).First(&role).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.CodeNotFound, "角色不存在")
+		}
+		return nil, errors.New(errors.CodeQueryFailed, "查询角色失败")
+	}
+	return &role, nil
+}
+
+// CreateRole 创建自定义角色
+func CreateRole(name, displayName, description string, permCodes []string) (*models.Role, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.New(errors.CodeInvalidParameter, "角色标识只能包含小写字母、数字和下划线，且以字母开头")
+	}
+
+	perms, err := findPermissions(db, permCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	var count int64
+	db.Model(&models.Role{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		return nil, errors.New(errors.CodeConflict, "角色标识已存在")
+	}
+
+	role := &models.Role{
+		Name:        name,
+		DisplayName: displayName,
+		Description: description,
+		Permissions: perms,
+	}
+	if err := db.Create(role).Error; err != nil {
+		return nil, errors.New(errors.CodeInternal, "创建角色失败")
+	}
+	return role, nil
+}
+
+// UpdateRole 更新角色信息和权限，超级管理员角色的权限不可修改
+func UpdateRole(roleID, displayName, description string, permCodes []string) (*models.Role, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	role, err := GetRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role.Name == common.RoleNameSuperAdmin {
+		return nil, errors.New(errors.CodeForbidden, "超级管理员角色不可修改")
+	}
+
+	perms, err := findPermissions(db, permCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Updates(map[string]interface{}{
+			"display_name": displayName,
+			"description":  description,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(role).Association("Permissions").Replace(perms)
+	})
+	if err != nil {
+		return nil, errors.New(errors.CodeDBTransaction, "更新角色失败")
+	}
+
+	invalidateRoleMembers(role)
+	return GetRole(roleID)
+}
+
+// DeleteRole 删除自定义角色，同时解除与用户的关联
+func DeleteRole(roleID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	role, err := GetRole(roleID)
+	if err != nil {
+		return err
+	}
+
+	if role.IsSystem {
+		return errors.New(errors.CodeForbidden, "系统内置角色不能删除")
+	}
+
+	userIDs := roleMemberIDs(role.ID.String())
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", role.ID.String()).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		// 角色标识唯一，直接物理删除以便之后可以重新使用相同的标识
+		return tx.Unscoped().Delete(role).Error
+	})
+	if err != nil {
+		return errors.New(errors.CodeDBTransaction, "删除角色失败")
+	}
+
+	InvalidateUserAuthorization(userIDs...)
+	return nil
+}
+
+// GetUserRoles 获取用户的角色
+func GetUserRoles(userID string) ([]models.Role, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	if err := ensureUserExists(db, userID); err != nil {
+		return nil, err
+	}
+
+	var roles []models.Role
+	err := db.Preload("Permissions").
+		Where("id IN (SELECT role_id FROM user_role WHERE user_id = ?)", userID).
+		Find(&roles).Error
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询用户角色失败")
+	}
+	return roles, nil
+}
+
+// SetUserRoles 设置用户的角色
+// 只有超级管理员可以授予或移除超级管理员角色，且不能移除自己的超级管理员角色
+func SetUserRoles(operatorID, userID string, roleIDs []string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	if err := ensureUserExists(db, userID); err != nil {
+		return err
+	}
+
+	var roles []models.Role
+	if err := db.Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+		return errors.New(errors.CodeQueryFailed, "查询角色失败")
+	}
+	if len(roles) != len(uniqueStrings(roleIDs)) {
+		return errors.New(errors.CodeNotFound, "角色不存在")
+	}
+
+	current, err := GetUserAuthorization(userID)
+	if err != nil {
+		return err
+	}
+
+	grantSuper := false
+	for _, role := range roles {
+		if role.Name == common.RoleNameSuperAdmin {
+			grantSuper = true
+		}
+	}
+	hadSuper := current.HasRole(common.RoleNameSuperAdmin)
+
+	if grantSuper != hadSuper {
+		if !HasRole(operatorID, common.RoleNameSuperAdmin) {
+			return errors.New(errors.CodeForbidden, "只有超级管理员可以变更超级管理员角色")
+		}
+		if hadSuper && operatorID == userID {
+			return errors.New(errors.CodeForbidden, "不能移除自己的超级管理员角色")
+		}
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.Create(&models.UserRole{UserID: userID, RoleID: role.ID.String()}).Error; err != nil {
+				return err
+			}
+		}
+		// 同步旧版整数角色字段，保持依赖它的逻辑（如两步验证策略）行为一致
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("role", legacyRoleOf(roles)).Error
+	})
+	if err != nil {
+		return errors.New(errors.CodeDBTransaction, "设置用户角色失败")
+	}
+
+	InvalidateUserAuthorization(userID)
+	return nil
+}
+
+// SetSystemRole 设置用户的内置角色，替换原有的内置角色并保留自定义角色
+func SetSystemRole(userID, roleName string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	if !common.IsSystemRole(roleName) {
+		return errors.New(errors.CodeInvalidParameter, "无效的内置角色: "+roleName)
+	}
+
+	var role models.Role
+	if err := db.Where("name = ?", roleName).First(&role).Error; err != nil {
+		return errors.New(errors.CodeNotFound, "角色不存在")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND role_id IN (SELECT id FROM role WHERE is_system = ?)", userID, true).
+			Delete(&models.UserRole{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&models.UserRole{UserID: userID, RoleID: role.ID.String()}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Update("role", legacyRoleOf([]models.Role{role})).Error
+	})
+	if err != nil {
+		return errors.New(errors.CodeDBTransaction, "设置用户角色失败")
+	}
+
+	InvalidateUserAuthorization(userID)
+	return nil
+}
+
+// findPermissions 根据权限标识查询权限
+func findPermissions(db *gorm.DB, codes []string) ([]models.Permission, error) {
+	codes = uniqueStrings(codes)
+	for _, code := range codes {
+		if !common.IsValidPermission(code) {
+			return nil, errors.New(errors.CodeInvalidParameter, "无效的权限: "+code)
+		}
+	}
+
+	perms := []models.Permission{}
+	if len(codes) == 0 {
+		return perms, nil
+	}
+	if err := db.Where("code IN ?", codes).Find(&perms).Error; err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询权限失败")
+	}
+	return perms, nil
+}
+
+// invalidateRoleMembers 清除角色下所有用户的权限缓存
+// 内置角色还可能被尚未分配角色的用户按旧版角色使用，这部分用户的缓存会在过期后自动刷新
+func invalidateRoleMembers(role *models.Role) {
+	InvalidateUserAuthorization(roleMemberIDs(role.ID.String())...)
+}
+
+// roleMemberIDs 查询拥有指定角色的用户ID
+func roleMemberIDs(roleID string) []string {
+	var userIDs []string
+	if err := database.GetDB().Model(&models.UserRole{}).Where("role_id = ?", roleID).Pluck("user_id", &userIDs).Error; err != nil {
+		log.Printf("查询角色成员失败: %v", err)
+	}
+	return userIDs
+}
+
+// ensureUserExists 检查用户是否存在
+func ensureUserExists(db *gorm.DB, userID string) error {
+	var count int64
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return errors.New(errors.CodeQueryFailed, "数据库查询失败")
+	}
+	if count == 0 {
+		return errors.New(errors.CodeUserNotFound, "用户不存在")
+	}
+	return nil
+}
+
+// legacyRoleOf 根据分配的角色计算旧版整数角色
+func legacyRoleOf(roles []models.Role) int {
+	legacy := common.UserRoleUser
+	for _, role := range roles {
+		switch role.Name {
+		case common.RoleNameSuperAdmin:
+			return common.UserRoleSuperAdmin
+		case common.RoleNameAdmin:
+			legacy = common.UserRoleAdmin
+		}
+	}
+	return legacy
+}
+
+// uniqueStrings 去除重复值
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// contains 切片中是否包含指定值
+func contains(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
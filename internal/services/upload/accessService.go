@@ -0,0 +1,294 @@
+package upload
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"template/internal/models"
+	appConfig "template/pkg/config"
+	"template/pkg/constants"
+	"template/pkg/logger"
+)
+
+// accessTokenRandomBytes 临时访问令牌的随机字节数
+const accessTokenRandomBytes = 24
+
+// 临时访问错误，控制器和文件权限中间件据此返回对应的状态码
+var (
+	ErrTemporaryAccessNotFound = errors.New("临时访问链接不存在")
+	ErrInvalidAccessExpiry     = fmt.Errorf("过期时间必须晚于当前时间，且有效期不超过 %d 小时", int(constants.TemporaryAccessMaxTTL.Hours()))
+	ErrInvalidAllowedIP        = errors.New("允许的IP地址格式不正确")
+	ErrTooManyTemporaryAccess  = fmt.Errorf("每个文件最多有 %d 个有效的临时访问链接", constants.TemporaryAccessMaxPerFile)
+
+	ErrAccessSignatureInvalid = errors.New("访问链接无效")
+	ErrAccessExpired          = errors.New("访问链接已过期或已停用")
+	ErrAccessUsedUp           = errors.New("访问链接的使用次数已用完")
+	ErrAccessIPNotAllowed     = errors.New("当前IP不允许使用此访问链接")
+)
+
+// TemporaryAccessOptions 创建临时访问链接的参数
+type TemporaryAccessOptions struct {
+	Purpose    string    // 用途说明
+	ExpiresAt  time.Time // 过期时间，为零值时使用默认有效期
+	UsageLimit *int      // 使用次数限制，为空时使用默认值，0表示不限制
+	AllowedIPs []string  // 允许的IP地址或CIDR网段，为空时不限制
+}
+
+// CreateTemporaryAccess 为文件创建临时访问链接
+func CreateTemporaryAccess(fileID, userID string, opts *TemporaryAccessOptions) (*models.TemporaryAccess, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.CreateTemporaryAccess(fileID, userID, opts)
+}
+
+// ListTemporaryAccesses 获取文件的临时访问链接
+func ListTemporaryAccesses(fileID, userID string) ([]models.TemporaryAccess, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.ListTemporaryAccesses(fileID, userID)
+}
+
+// RevokeTemporaryAccess 停用文件的临时访问链接
+func RevokeTemporaryAccess(fileID, userID, accessID string) error {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.RevokeTemporaryAccess(fileID, userID, accessID)
+}
+
+// UseTemporaryAccess 校验临时访问链接，consume 为 true 时占用一次使用次数
+func UseTemporaryAccess(fileID string, query url.Values, clientIP string, consume bool) error {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.UseTemporaryAccess(fileID, query, clientIP, consume)
+}
+
+// CreateTemporaryAccess 为用户自己的文件创建临时访问链接
+func (s *UploadService) CreateTemporaryAccess(fileID, userID string, opts *TemporaryAccessOptions) (*models.TemporaryAccess, error) {
+	file, err := s.GetUserFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := opts.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = now.Add(constants.TemporaryAccessDefaultTTL)
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > constants.TemporaryAccessMaxTTL {
+		return nil, ErrInvalidAccessExpiry
+	}
+	allowedIPs, err := normalizeAllowedIPs(opts.AllowedIPs)
+	if err != nil {
+		return nil, err
+	}
+	usageLimit := constants.TemporaryAccessDefaultUsage
+	if opts.UsageLimit != nil {
+		usageLimit = *opts.UsageLimit
+	}
+
+	count, err := s.uploadRepo.CountValidTemporaryAccesses(fileID, now)
+	if err != nil {
+		return nil, fmt.Errorf("查询临时访问链接失败: %v", err)
+	}
+	if count >= constants.TemporaryAccessMaxPerFile {
+		return nil, ErrTooManyTemporaryAccess
+	}
+
+	token, err := generateAccessToken()
+	if err != nil {
+		return nil, err
+	}
+	// 过期时间转为本地时区保存，与数据库中的其他时间一致，按过期时间查询时才能正确比较
+	access := &models.TemporaryAccess{
+		FileID:      file.ID.String(),
+		AccessToken: token,
+		Purpose:     opts.Purpose,
+		ExpiresAt:   expiresAt.Local(),
+		UsageLimit:  usageLimit,
+		AllowedIPs:  allowedIPs,
+		CreatedBy:   userID,
+		IsActive:    true,
+	}
+	if err := s.uploadRepo.CreateTemporaryAccess(access); err != nil {
+		return nil, fmt.Errorf("创建临时访问链接失败: %v", err)
+	}
+
+	logger.Info("已创建临时访问链接", "fileID", fileID, "accessID", access.ID.String(), "expiresAt", access.ExpiresAt)
+	return access, nil
+}
+
+// ListTemporaryAccesses 获取用户自己的文件的全部临时访问链接
+func (s *UploadService) ListTemporaryAccesses(fileID, userID string) ([]models.TemporaryAccess, error) {
+	if _, err := s.GetUserFile(fileID, userID); err != nil {
+		return nil, err
+	}
+	accesses, err := s.uploadRepo.GetFileTemporaryAccesses(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("查询临时访问链接失败: %v", err)
+	}
+	return accesses, nil
+}
+
+// RevokeTemporaryAccess 停用用户自己的文件的临时访问链接，停用后链接立即失效
+func (s *UploadService) RevokeTemporaryAccess(fileID, userID, accessID string) error {
+	if _, err := s.GetUserFile(fileID, userID); err != nil {
+		return err
+	}
+	revoked, err := s.uploadRepo.DeactivateTemporaryAccess(accessID, fileID)
+	if err != nil {
+		return fmt.Errorf("停用临时访问链接失败: %v", err)
+	}
+	if !revoked {
+		return ErrTemporaryAccessNotFound
+	}
+	return nil
+}
+
+// UseTemporaryAccess 校验请求中的签名、过期时间和IP地址，consume 为 true 时通过后占用一次使用次数
+// 使用次数按从头开始的下载计算，不占用次数的请求（HEAD、断点续传的后续分段）只校验链接本身，次数用完后在有效期内仍可以续传
+// 先校验签名再查询数据库，伪造的链接不会产生数据库查询
+func (s *UploadService) UseTemporaryAccess(fileID string, query url.Values, clientIP string, consume bool) error {
+	token := query.Get("access")
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if token == "" || err != nil {
+		return ErrAccessSignatureInvalid
+	}
+	expected := signFileAccess(fileID, token, expires)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("signature"))) {
+		return ErrAccessSignatureInvalid
+	}
+
+	now := time.Now()
+	if now.Unix() >= expires {
+		return ErrAccessExpired
+	}
+	access, err := s.uploadRepo.GetTemporaryAccessByToken(token)
+	if err != nil || access.FileID != fileID {
+		return ErrAccessSignatureInvalid
+	}
+	if !access.IsActive || !access.ExpiresAt.After(now) {
+		return ErrAccessExpired
+	}
+	if !ipAllowed(access.AllowedIPs, clientIP) {
+		return ErrAccessIPNotAllowed
+	}
+
+	if !consume {
+		return nil
+	}
+	consumed, err := s.uploadRepo.ConsumeTemporaryAccess(access.ID.String(), now)
+	if err != nil {
+		return fmt.Errorf("更新临时访问链接失败: %v", err)
+	}
+	if !consumed {
+		return ErrAccessUsedUp
+	}
+	return nil
+}
+
+// IsTemporaryAccessDenied 判断是否因链接无效、过期、用完或IP不允许而拒绝访问
+func IsTemporaryAccessDenied(err error) bool {
+	return errors.Is(err, ErrAccessSignatureInvalid) ||
+		errors.Is(err, ErrAccessExpired) ||
+		errors.Is(err, ErrAccessUsedUp) ||
+		errors.Is(err, ErrAccessIPNotAllowed)
+}
+
+// TemporaryAccessURL 生成临时访问链接的下载地址
+func TemporaryAccessURL(file *models.UploadFile, access *models.TemporaryAccess) string {
+	fileID := file.ID.String()
+	expires := access.ExpiresAt.Unix()
+	query := url.Values{}
+	query.Set("access", access.AccessToken)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signFileAccess(fileID, access.AccessToken, expires))
+	return fmt.Sprintf("/files/download/%s/%s?%s", fileID, url.PathEscape(file.Filename), query.Encode())
+}
+
+// signFileAccess 计算临时访问链接的签名：HMAC-SHA256(key, "<fileID>.<token>.<expires>")
+func signFileAccess(fileID, token string, expires int64) string {
+	key := appConfig.GetConfig().Upload.URLSigningKey
+	if key == "" {
+		key = appConfig.GetConfig().JWT.SecretKey
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(fileID + "." + token + "." + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// generateAccessToken 生成临时访问令牌
+func generateAccessToken() (string, error) {
+	buf := make([]byte, accessTokenRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成访问令牌失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// normalizeAllowedIPs 校验允许的IP地址或CIDR网段，返回JSON数组，为空时返回空字符串
+func normalizeAllowedIPs(ips []string) (string, error) {
+	allowed := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return "", ErrInvalidAllowedIP
+		}
+		allowed = append(allowed, ip)
+	}
+	if len(allowed) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(allowed)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ipAllowed 检查客户端IP是否在允许的IP地址或网段中，没有限制时都允许
+func ipAllowed(allowedIPs, clientIP string) bool {
+	if allowedIPs == "" {
+		return true
+	}
+	allowed := ParseAllowedIPs(allowedIPs)
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, entry := range allowed {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if allowedIP := net.ParseIP(entry); allowedIP != nil && allowedIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAllowedIPs 解析临时访问记录中保存的允许IP列表
+func ParseAllowedIPs(allowedIPs string) []string {
+	allowed := make([]string, 0)
+	if allowedIPs != "" {
+		_ = json.Unmarshal([]byte(allowedIPs), &allowed)
+	}
+	return allowed
+}
@@ -0,0 +1,123 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"template/internal/models"
+	"template/pkg/constants"
+	"template/pkg/logger"
+)
+
+// staleUploadBatchSize 定时任务每轮最多清理的上传数
+const staleUploadBatchSize = 100
+
+// ErrUploadNotFound 上传不存在、不属于当前用户或已经结束
+var ErrUploadNotFound = errors.New("上传不存在或已结束")
+
+// CleanupResult 过期上传的清理结果
+type CleanupResult struct {
+	Count      int   // 标记为失败的上传数
+	FreedBytes int64 // 删除的分片文件的大小
+}
+
+// CleanupStaleUploads 清理过期未完成的上传，由定时任务调用
+func CleanupStaleUploads() (*CleanupResult, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.CleanupStaleUploads()
+}
+
+// AbortChunkUpload 终止分片上传
+func AbortChunkUpload(fileID, userID string) error {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.AbortChunkUpload(fileID, userID)
+}
+
+// CleanupStaleUploads 将超过保留时间没有写入的上传和已过期的tus上传标记为失败，删除已上传的分片
+func (s *UploadService) CleanupStaleUploads() (*CleanupResult, error) {
+	now := time.Now()
+	files, err := s.uploadRepo.GetStaleUploads(now.Add(-s.config.UploadExpiration), now, staleUploadBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("查询过期上传失败: %v", err)
+	}
+
+	result := &CleanupResult{}
+	for i := range files {
+		freed, ok := s.expireUpload(&files[i])
+		if ok {
+			result.Count++
+			result.FreedBytes += freed
+		}
+	}
+	return result, nil
+}
+
+// AbortChunkUpload 终止当前用户未完成的分片上传，删除已上传的分片和文件记录
+func (s *UploadService) AbortChunkUpload(fileID, userID string) error {
+	file, err := s.uploadRepo.GetUploadFileByID(fileID)
+	if err != nil || file.UserID != userID || file.UploadStatus != constants.UploadStatusUploading {
+		return ErrUploadNotFound
+	}
+
+	if err := s.removeFile(file); err != nil {
+		return err
+	}
+
+	logger.Info("分片上传已终止", "fileID", fileID, "userID", userID)
+	return nil
+}
+
+// expireUpload 将过期的上传标记为失败并删除已上传的分片，返回删除的分片大小和是否已处理
+// 正在写入的tus上传跳过，文件在此期间已完成或已失败时不做处理
+func (s *UploadService) expireUpload(file *models.UploadFile) (int64, bool) {
+	fileID := file.ID.String()
+	if !acquireTusUpload(fileID) {
+		return 0, false
+	}
+	defer releaseTusUpload(fileID)
+
+	chunks, err := s.uploadRepo.GetFileChunks(fileID)
+	if err != nil {
+		logger.Error("查询分片信息失败", "fileID", fileID, "error", err)
+		return 0, false
+	}
+
+	marked, err := s.uploadRepo.MarkUploadFailed(fileID)
+	if err != nil {
+		logger.Error("标记上传失败出错", "fileID", fileID, "error", err)
+		return 0, false
+	}
+	if !marked {
+		return 0, false
+	}
+
+	var freed int64
+	for _, chunk := range chunks {
+		// 以存储中的实际大小计算释放的空间，未上传的分片没有文件
+		size, err := s.storage.GetFileSize(chunk.ChunkPath)
+		if err != nil {
+			continue
+		}
+		if err := s.storage.DeleteFile(chunk.ChunkPath); err != nil {
+			logger.Error("删除分片失败", "fileID", fileID, "chunkPath", chunk.ChunkPath, "error", err)
+			continue
+		}
+		freed += size
+	}
+	s.removeChunkDir(fileID)
+
+	logger.Info("过期的上传已清理", "fileID", fileID, "userID", file.UserID, "freedBytes", freed)
+	return freed, true
+}
+
+// removeChunkDir 删除本地存储中已清空的分片目录，目录不为空或不存在时不做处理
+func (s *UploadService) removeChunkDir(fileID string) {
+	_ = os.Remove(filepath.Join(s.config.TempDir, fileID))
+}
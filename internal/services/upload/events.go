@@ -0,0 +1,42 @@
+package upload
+
+import (
+	"template/internal/models"
+	"template/internal/services/webhook"
+	"template/pkg/common"
+)
+
+// emitFileUploaded 发布文件上传完成事件
+func emitFileUploaded(file *models.UploadFile) {
+	webhook.Emit(common.WebhookEventFileUploaded, map[string]interface{}{
+		"file_id":     file.ID.String(),
+		"filename":    file.Filename,
+		"file_size":   file.FileSize,
+		"mime_type":   file.MimeType,
+		"md5_hash":    file.MD5Hash,
+		"sha256_hash": file.SHA256Hash,
+		"user_id":     file.UserID,
+	})
+}
+
+// emitFileQuarantined 发布文件被隔离事件
+func emitFileQuarantined(file *models.UploadFile) {
+	webhook.Emit(common.WebhookEventFileQuarantined, map[string]interface{}{
+		"file_id":     file.ID.String(),
+		"filename":    file.Filename,
+		"sha256_hash": file.SHA256Hash,
+		"user_id":     file.UserID,
+		"signature":   file.ScanSignature,
+	})
+}
+
+// NotifyFileDownloaded 发布文件下载事件，userID为空表示匿名下载
+func NotifyFileDownloaded(file *models.UploadFile, userID, ip string) {
+	webhook.Emit(common.WebhookEventFileDownloaded, map[string]interface{}{
+		"file_id":  file.ID.String(),
+		"filename": file.Filename,
+		"owner_id": file.UserID,
+		"user_id":  userID,
+		"ip":       ip,
+	})
+}
@@ -0,0 +1,163 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"template/internal/dto/request"
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	"template/pkg/constants"
+	"template/pkg/logger"
+	"template/pkg/upload"
+)
+
+// 文件管理错误，控制器据此返回对应的状态码
+var (
+	ErrFileNotFound         = errors.New("文件不存在")
+	ErrInvalidFilename      = errors.New("文件名无效")
+	ErrFileExtensionChanged = errors.New("不能修改文件扩展名")
+)
+
+// fileSortColumns 文件列表排序字段对应的数据库字段
+var fileSortColumns = map[string]string{
+	"createdAt":     "created_at",
+	"filename":      "filename",
+	"fileSize":      "file_size",
+	"downloadCount": "download_count",
+}
+
+// SearchUserFiles 分页查询用户的文件
+func SearchUserFiles(userID string, req *request.ListFilesRequest) ([]models.UploadFile, int64, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.SearchUserFiles(userID, req)
+}
+
+// GetUserFile 获取用户的文件
+func GetUserFile(fileID, userID string) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetUserFile(fileID, userID)
+}
+
+// RenameUserFile 修改用户文件的文件名
+func RenameUserFile(fileID, userID, filename string) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.RenameUserFile(fileID, userID, filename)
+}
+
+// SetUserFileVisibility 设置用户文件是否公开
+func SetUserFileVisibility(fileID, userID string, isPublic bool) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.SetUserFileVisibility(fileID, userID, isPublic)
+}
+
+// DeleteUserFiles 批量删除用户的文件
+func DeleteUserFiles(userID string, fileIDs []string) (deleted, failed []string) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.DeleteUserFiles(userID, fileIDs)
+}
+
+// SearchUserFiles 分页查询用户已保存内容的文件，支持按类型、文件名和上传日期筛选
+func (s *UploadService) SearchUserFiles(userID string, req *request.ListFilesRequest) ([]models.UploadFile, int64, error) {
+	query := &uploadRepo.FileQuery{
+		UserID:    userID,
+		MimeType:  req.MimeType,
+		Keyword:   req.Keyword,
+		StartTime: req.StartDate,
+		SortBy:    fileSortColumns[req.SortBy],
+		SortOrder: req.SortOrder,
+		Offset:    req.GetOffset(),
+		Limit:     req.GetPageSize(),
+	}
+	// 截止日期当天上传的文件也包含在内
+	if req.EndDate != nil {
+		endTime := req.EndDate.Add(24 * time.Hour)
+		query.EndTime = &endTime
+	}
+
+	files, total, err := s.uploadRepo.GetUserFiles(query)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询文件列表失败: %v", err)
+	}
+	return files, total, nil
+}
+
+// GetUserFile 获取用户已保存内容的文件，文件不存在、不属于该用户或尚未上传完成时返回 ErrFileNotFound
+func (s *UploadService) GetUserFile(fileID, userID string) (*models.UploadFile, error) {
+	file, err := s.uploadRepo.GetUploadFileByID(fileID)
+	if err != nil || file.UserID != userID {
+		return nil, ErrFileNotFound
+	}
+	switch file.UploadStatus {
+	case constants.UploadStatusUploading, constants.UploadStatusMerging, constants.UploadStatusFailed:
+		return nil, ErrFileNotFound
+	}
+	return file, nil
+}
+
+// RenameUserFile 修改文件名，扩展名需要保持不变，避免与已识别的文件类型不符
+func (s *UploadService) RenameUserFile(fileID, userID, filename string) (*models.UploadFile, error) {
+	file, err := s.GetUserFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := upload.ValidateFilename(filename); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilename, err)
+	}
+	if upload.GetFileExtension(filename) != upload.GetFileExtension(file.Filename) {
+		return nil, ErrFileExtensionChanged
+	}
+
+	if err := s.uploadRepo.RenameFile(fileID, filename); err != nil {
+		return nil, fmt.Errorf("修改文件名失败: %v", err)
+	}
+	file.Filename = filename
+	return file, nil
+}
+
+// SetUserFileVisibility 设置文件是否公开，私有文件只有所有者和被授权的用户可以访问
+func (s *UploadService) SetUserFileVisibility(fileID, userID string, isPublic bool) (*models.UploadFile, error) {
+	file, err := s.GetUserFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uploadRepo.UpdateFileVisibility(fileID, isPublic); err != nil {
+		return nil, fmt.Errorf("设置文件可见性失败: %v", err)
+	}
+	file.IsPublic = isPublic
+	return file, nil
+}
+
+// DeleteUserFiles 批量将用户的文件移入回收站，返回已删除和删除失败的文件ID
+func (s *UploadService) DeleteUserFiles(userID string, fileIDs []string) (deleted, failed []string) {
+	deleted = make([]string, 0, len(fileIDs))
+	failed = make([]string, 0)
+
+	for _, fileID := range fileIDs {
+		file, err := s.GetUserFile(fileID, userID)
+		if err != nil {
+			failed = append(failed, fileID)
+			continue
+		}
+		if err := s.moveToTrash(file); err != nil {
+			logger.Error("删除文件失败", "fileID", fileID, "error", err)
+			failed = append(failed, fileID)
+			continue
+		}
+		deleted = append(deleted, fileID)
+	}
+	return deleted, failed
+}
@@ -0,0 +1,344 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	"template/pkg/common"
+	"template/pkg/logger"
+	"template/pkg/upload"
+
+	"gorm.io/gorm"
+)
+
+// maxFolderDepth 文件夹最大层级，受物化路径的长度限制
+const maxFolderDepth = 20
+
+// 文件夹错误，控制器据此返回对应的状态码
+var (
+	ErrFolderNotFound    = errors.New("文件夹不存在")
+	ErrInvalidFolderName = errors.New("文件夹名称无效")
+	ErrFolderNameExists  = errors.New("同一文件夹下已有同名文件夹")
+	ErrFolderCycle       = errors.New("不能将文件夹移动到自身或其子文件夹中")
+	ErrFolderTooDeep     = errors.New("文件夹层级过深")
+)
+
+// FolderChildren 文件夹下的子文件夹和文件
+type FolderChildren struct {
+	Folders []models.Folder     // 全部子文件夹
+	Files   []models.UploadFile // 当前页的文件
+	Total   int64               // 文件总数
+}
+
+// CreateFolder 创建文件夹
+func CreateFolder(userID, name, parentID string) (*models.Folder, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.CreateFolder(userID, name, parentID)
+}
+
+// GetUserFolder 获取用户的文件夹
+func GetUserFolder(folderID, userID string) (*models.Folder, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetUserFolder(folderID, userID)
+}
+
+// GetFolderBreadcrumbs 获取从根目录到文件夹的路径
+func GetFolderBreadcrumbs(folder *models.Folder) ([]models.Folder, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetFolderBreadcrumbs(folder)
+}
+
+// ListFolderChildren 获取文件夹下的子文件夹和文件
+func ListFolderChildren(userID, folderID string, page *common.PaginationRequest) (*FolderChildren, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.ListFolderChildren(userID, folderID, page)
+}
+
+// RenameFolder 修改文件夹名称
+func RenameFolder(folderID, userID, name string) (*models.Folder, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.RenameFolder(folderID, userID, name)
+}
+
+// MoveFolder 移动文件夹
+func MoveFolder(folderID, userID, parentID string) (*models.Folder, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.MoveFolder(folderID, userID, parentID)
+}
+
+// SetFolderVisibility 设置文件夹是否公开
+func SetFolderVisibility(folderID, userID string, isPublic bool) (*models.Folder, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.SetFolderVisibility(folderID, userID, isPublic)
+}
+
+// DeleteFolder 删除文件夹及其中的全部内容
+func DeleteFolder(folderID, userID string) (int, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.DeleteFolder(folderID, userID)
+}
+
+// MoveUserFile 将文件移到文件夹
+func MoveUserFile(fileID, userID, folderID string) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.MoveUserFile(fileID, userID, folderID)
+}
+
+// CreateFolder 在 parentID 下创建文件夹，parentID 为空表示根目录
+func (s *UploadService) CreateFolder(userID, name, parentID string) (*models.Folder, error) {
+	name, err := validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.resolveFolder(parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if parent != nil && len(parent.PathIDs()) >= maxFolderDepth {
+		return nil, ErrFolderTooDeep
+	}
+	if s.uploadRepo.FolderNameExists(userID, parentID, name, "") {
+		return nil, ErrFolderNameExists
+	}
+
+	folder := &models.Folder{UserID: userID, ParentID: parentID, Name: name, IsPublic: true}
+	folder.ID = common.NewUUID()
+	folder.Path = "/" + folder.ID.String() + "/"
+	if parent != nil {
+		folder.Path = parent.Path + folder.ID.String() + "/"
+	}
+	if err := s.uploadRepo.CreateFolder(folder); err != nil {
+		return nil, fmt.Errorf("创建文件夹失败: %v", err)
+	}
+	return folder, nil
+}
+
+// GetUserFolder 获取用户的文件夹，不存在或不属于该用户时返回 ErrFolderNotFound
+func (s *UploadService) GetUserFolder(folderID, userID string) (*models.Folder, error) {
+	folder, err := s.uploadRepo.GetFolderByID(folderID)
+	if err != nil || folder.UserID != userID {
+		return nil, ErrFolderNotFound
+	}
+	return folder, nil
+}
+
+// GetFolderBreadcrumbs 按物化路径获取从根目录到文件夹自身的各级文件夹
+func (s *UploadService) GetFolderBreadcrumbs(folder *models.Folder) ([]models.Folder, error) {
+	ids := folder.PathIDs()
+	folders, err := s.uploadRepo.GetFoldersByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("查询文件夹失败: %v", err)
+	}
+
+	byID := make(map[string]models.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID.String()] = f
+	}
+	breadcrumbs := make([]models.Folder, 0, len(ids))
+	for _, id := range ids {
+		if f, ok := byID[id]; ok {
+			breadcrumbs = append(breadcrumbs, f)
+		}
+	}
+	return breadcrumbs, nil
+}
+
+// ListFolderChildren 获取文件夹下的全部子文件夹和分页的文件，folderID 为空表示根目录
+func (s *UploadService) ListFolderChildren(userID, folderID string, page *common.PaginationRequest) (*FolderChildren, error) {
+	if _, err := s.resolveFolder(folderID, userID); err != nil {
+		return nil, err
+	}
+
+	folders, err := s.uploadRepo.GetChildFolders(userID, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询子文件夹失败: %v", err)
+	}
+	files, total, err := s.uploadRepo.GetUserFiles(&uploadRepo.FileQuery{
+		UserID:    userID,
+		FolderID:  &folderID,
+		SortBy:    "filename",
+		SortOrder: "asc",
+		Offset:    page.GetOffset(),
+		Limit:     page.GetPageSize(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询文件列表失败: %v", err)
+	}
+	return &FolderChildren{Folders: folders, Files: files, Total: total}, nil
+}
+
+// RenameFolder 修改文件夹名称
+func (s *UploadService) RenameFolder(folderID, userID, name string) (*models.Folder, error) {
+	folder, err := s.GetUserFolder(folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	name, err = validateFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.uploadRepo.FolderNameExists(userID, folder.ParentID, name, folderID) {
+		return nil, ErrFolderNameExists
+	}
+
+	if err := s.uploadRepo.RenameFolder(folderID, name); err != nil {
+		return nil, fmt.Errorf("修改文件夹名称失败: %v", err)
+	}
+	folder.Name = name
+	return folder, nil
+}
+
+// MoveFolder 将文件夹移到 parentID 下，parentID 为空表示移到根目录；不能移到自身或子孙文件夹中
+func (s *UploadService) MoveFolder(folderID, userID, parentID string) (*models.Folder, error) {
+	folder, err := s.GetUserFolder(folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.resolveFolder(parentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if s.uploadRepo.FolderNameExists(userID, parentID, folder.Name, folderID) {
+		return nil, ErrFolderNameExists
+	}
+
+	// 文件夹和目标文件夹可能同时被其他请求移动，在移动的事务中按最新的路径检查
+	err = s.uploadRepo.MoveFolder(folder, parent, checkFolderMove)
+	switch {
+	case errors.Is(err, ErrFolderCycle), errors.Is(err, ErrFolderTooDeep):
+		return nil, err
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrFolderNotFound
+	case err != nil:
+		return nil, fmt.Errorf("移动文件夹失败: %v", err)
+	}
+	return folder, nil
+}
+
+// checkFolderMove 检查文件夹能否移到 parent 下：不能移到自身或子孙文件夹中，移动后子树中最深的文件夹也不能超过层级限制
+func checkFolderMove(folder, parent *models.Folder, subtree []models.Folder) error {
+	if parent != nil && strings.HasPrefix(parent.Path, folder.Path) {
+		return ErrFolderCycle
+	}
+
+	subtreeDepth := 0
+	for i := range subtree {
+		subtreeDepth = max(subtreeDepth, len(subtree[i].PathIDs())-len(folder.PathIDs())+1)
+	}
+	parentDepth := 0
+	if parent != nil {
+		parentDepth = len(parent.PathIDs())
+	}
+	if parentDepth+subtreeDepth > maxFolderDepth {
+		return ErrFolderTooDeep
+	}
+	return nil
+}
+
+// SetFolderVisibility 设置文件夹是否公开，私有文件夹及其子文件夹中的文件都按私有文件处理
+func (s *UploadService) SetFolderVisibility(folderID, userID string, isPublic bool) (*models.Folder, error) {
+	folder, err := s.GetUserFolder(folderID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uploadRepo.UpdateFolderVisibility(folderID, isPublic); err != nil {
+		return nil, fmt.Errorf("设置文件夹可见性失败: %v", err)
+	}
+	folder.IsPublic = isPublic
+	return folder, nil
+}
+
+// DeleteFolder 删除文件夹和全部子孙文件夹，其中的文件移入回收站，返回删除的文件数
+// 有文件删除失败时保留文件夹，可以重试
+func (s *UploadService) DeleteFolder(folderID, userID string) (int, error) {
+	folder, err := s.GetUserFolder(folderID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	subtree, err := s.uploadRepo.GetFolderSubtree(folder)
+	if err != nil {
+		return 0, fmt.Errorf("查询子文件夹失败: %v", err)
+	}
+	folderIDs := make([]string, 0, len(subtree))
+	for i := range subtree {
+		folderIDs = append(folderIDs, subtree[i].ID.String())
+	}
+
+	files, err := s.uploadRepo.GetFolderFiles(folderIDs)
+	if err != nil {
+		return 0, fmt.Errorf("查询文件夹中的文件失败: %v", err)
+	}
+	deleted := 0
+	for i := range files {
+		if err := s.moveToTrash(&files[i]); err != nil {
+			logger.Error("删除文件失败", "fileID", files[i].ID.String(), "error", err)
+			continue
+		}
+		deleted++
+	}
+	if deleted < len(files) {
+		return deleted, fmt.Errorf("有 %d 个文件删除失败", len(files)-deleted)
+	}
+
+	if err := s.uploadRepo.DeleteFolders(folderIDs); err != nil {
+		return deleted, fmt.Errorf("删除文件夹失败: %v", err)
+	}
+	return deleted, nil
+}
+
+// MoveUserFile 将文件移到 folderID，folderID 为空表示移到根目录
+func (s *UploadService) MoveUserFile(fileID, userID, folderID string) (*models.UploadFile, error) {
+	file, err := s.GetUserFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.resolveFolder(folderID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.uploadRepo.MoveFileToFolder(fileID, folderID); err != nil {
+		return nil, fmt.Errorf("移动文件失败: %v", err)
+	}
+	file.FolderID = folderID
+	return file, nil
+}
+
+// resolveFolder 获取用户的文件夹，folderID 为空表示根目录，返回 nil
+func (s *UploadService) resolveFolder(folderID, userID string) (*models.Folder, error) {
+	if folderID == "" {
+		return nil, nil
+	}
+	return s.GetUserFolder(folderID, userID)
+}
+
+// validateFolderName 校验文件夹名称，返回去掉首尾空白后的名称
+func validateFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if err := upload.ValidateFilename(name); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFolderName, err)
+	}
+	return name, nil
+}
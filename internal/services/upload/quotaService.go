@@ -0,0 +1,208 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+
+	"template/internal/models"
+	"template/internal/services/rbac"
+	appConfig "template/pkg/config"
+	"template/pkg/logger"
+)
+
+// bytesPerMB 配置中的配额单位
+const bytesPerMB = 1024 * 1024
+
+// 配额错误，控制器据此返回对应的状态码
+var (
+	ErrQuotaExceeded     = errors.New("存储空间不足")
+	ErrQuotaUserNotFound = errors.New("用户不存在")
+)
+
+// QuotaInfo 用户的存储用量和配额
+type QuotaInfo struct {
+	UserID        string
+	UsedBytes     int64 // 已上传完成的文件占用的空间
+	ReservedBytes int64 // 上传中的文件预留的空间
+	LimitBytes    int64 // 配额，0表示不限制
+	IsCustom      bool  // 是否是为用户单独设置的配额
+}
+
+// RemainingBytes 剩余可用空间，不限制时返回 -1
+func (q *QuotaInfo) RemainingBytes() int64 {
+	if q.LimitBytes == 0 {
+		return -1
+	}
+	return max(q.LimitBytes-q.UsedBytes-q.ReservedBytes, 0)
+}
+
+// GetQuota 获取用户的存储用量和配额
+func GetQuota(userID string) (*QuotaInfo, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetQuota(userID)
+}
+
+// GetUserQuota 管理员查询用户的存储用量和配额
+func GetUserQuota(userID string) (*QuotaInfo, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetUserQuota(userID)
+}
+
+// SetUserQuota 为用户单独设置配额，quotaBytes 为空时恢复使用角色配额
+func SetUserQuota(userID string, quotaBytes *int64) (*QuotaInfo, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.SetUserQuota(userID, quotaBytes)
+}
+
+// RecomputeQuota 按文件记录重新统计用户的用量
+func RecomputeQuota(userID string) (*QuotaInfo, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.RecomputeQuota(userID)
+}
+
+// RecomputeAllQuotas 按文件记录重新统计所有用户的用量，返回统计的用户数
+func RecomputeAllQuotas() (int, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.RecomputeAllQuotas()
+}
+
+// GetQuota 获取用户的存储用量和配额
+func (s *UploadService) GetQuota(userID string) (*QuotaInfo, error) {
+	quota, err := s.uploadRepo.GetUserQuota(userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询存储用量失败: %v", err)
+	}
+	return s.quotaInfo(quota)
+}
+
+// GetUserQuota 查询指定用户的存储用量和配额，用户不存在时返回 ErrQuotaUserNotFound
+func (s *UploadService) GetUserQuota(userID string) (*QuotaInfo, error) {
+	if !s.uploadRepo.UserExists(userID) {
+		return nil, ErrQuotaUserNotFound
+	}
+	return s.GetQuota(userID)
+}
+
+// SetUserQuota 为用户单独设置配额
+func (s *UploadService) SetUserQuota(userID string, quotaBytes *int64) (*QuotaInfo, error) {
+	if !s.uploadRepo.UserExists(userID) {
+		return nil, ErrQuotaUserNotFound
+	}
+
+	quota, err := s.uploadRepo.SetQuotaLimit(userID, quotaBytes)
+	if err != nil {
+		return nil, fmt.Errorf("设置配额失败: %v", err)
+	}
+	return s.quotaInfo(quota)
+}
+
+// RecomputeQuota 按用户现有的文件记录重新统计用量，用于修正异常中断等原因造成的偏差
+func (s *UploadService) RecomputeQuota(userID string) (*QuotaInfo, error) {
+	if !s.uploadRepo.UserExists(userID) {
+		return nil, ErrQuotaUserNotFound
+	}
+
+	quota, err := s.uploadRepo.RecomputeQuotaUsage(userID)
+	if err != nil {
+		return nil, fmt.Errorf("统计存储用量失败: %v", err)
+	}
+	return s.quotaInfo(quota)
+}
+
+// RecomputeAllQuotas 重新统计所有用户的用量
+func (s *UploadService) RecomputeAllQuotas() (int, error) {
+	userIDs, err := s.uploadRepo.GetQuotaUserIDs()
+	if err != nil {
+		return 0, fmt.Errorf("查询用户失败: %v", err)
+	}
+
+	count := 0
+	for _, userID := range userIDs {
+		if _, err := s.uploadRepo.RecomputeQuotaUsage(userID); err != nil {
+			logger.Error("统计存储用量失败", "userID", userID, "error", err)
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// reserveQuota 为上传占用存储空间，超出配额时返回 ErrQuotaExceeded
+func (s *UploadService) reserveQuota(userID string, size int64) error {
+	quota, err := s.GetQuota(userID)
+	if err != nil {
+		return err
+	}
+
+	reserved, err := s.uploadRepo.ReserveQuota(userID, size, quota.LimitBytes)
+	if err != nil {
+		return fmt.Errorf("占用存储空间失败: %v", err)
+	}
+	if !reserved {
+		return fmt.Errorf("%w：配额 %d 字节，剩余 %d 字节", ErrQuotaExceeded, quota.LimitBytes, quota.RemainingBytes())
+	}
+	return nil
+}
+
+// releaseQuota 释放上传失败前占用的存储空间
+func (s *UploadService) releaseQuota(userID string, size int64) {
+	if err := s.uploadRepo.ReleaseQuota(userID, size); err != nil {
+		logger.Error("释放存储空间失败", "userID", userID, "error", err)
+	}
+}
+
+// quotaInfo 构建用户的存储用量和配额
+func (s *UploadService) quotaInfo(quota *models.UserQuota) (*QuotaInfo, error) {
+	reserved, err := s.uploadRepo.GetReservedBytes(quota.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("查询存储用量失败: %v", err)
+	}
+
+	info := &QuotaInfo{
+		UserID:        quota.UserID,
+		UsedBytes:     max(quota.UsedBytes-reserved, 0),
+		ReservedBytes: min(reserved, quota.UsedBytes),
+		LimitBytes:    roleQuota(quota.UserID),
+	}
+	if quota.QuotaBytes != nil {
+		info.LimitBytes = *quota.QuotaBytes
+		info.IsCustom = true
+	}
+	return info, nil
+}
+
+// roleQuota 获取用户角色的配额（字节），用户有多个角色时取最大值，0表示不限制
+func roleQuota(userID string) int64 {
+	cfg := appConfig.GetConfig().Quota
+
+	var roles []string
+	if authz, err := rbac.GetUserAuthorization(userID); err == nil {
+		roles = authz.Roles
+	}
+	if len(roles) == 0 {
+		return max(cfg.DefaultMB, 0) * bytesPerMB
+	}
+
+	var limit int64
+	for _, role := range roles {
+		mb, ok := cfg.Roles[role]
+		if !ok {
+			mb = cfg.DefaultMB
+		}
+		if mb <= 0 {
+			return 0
+		}
+		limit = max(limit, mb*bytesPerMB)
+	}
+	return limit
+}
@@ -0,0 +1,214 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"template/internal/models"
+	"template/pkg/common"
+	appConfig "template/pkg/config"
+	"template/pkg/constants"
+	"template/pkg/logger"
+	"template/pkg/upload"
+)
+
+// 安全扫描默认参数
+const (
+	defaultScanConcurrency = 2  // 默认同时扫描的文件数
+	pendingScanBatchSize   = 50 // 定时任务每轮最多重新扫描的文件数
+)
+
+// ErrNotQuarantined 文件不存在或未被隔离
+var ErrNotQuarantined = errors.New("文件不存在或未被隔离")
+
+// 扫描任务状态
+var (
+	scanJobsMu sync.Mutex
+	scanJobs   = make(map[string]bool) // 正在扫描的文件，上传触发和定时任务不会重复扫描同一文件
+	scanSlots  chan struct{}           // 限制同时扫描的文件数
+	scanOnce   sync.Once
+)
+
+// ScanPendingFiles 扫描所有待扫描的文件，由定时任务调用，处理扫描器暂时不可用或服务重启时遗留的文件
+func ScanPendingFiles() (int, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.ScanPendingFiles()
+}
+
+// ListQuarantinedFiles 分页获取被隔离的文件
+func ListQuarantinedFiles(page *common.PaginationRequest) ([]models.UploadFile, int64, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.uploadRepo.GetFilesByStatus(constants.UploadStatusQuarantined, page.GetOffset(), page.GetPageSize())
+}
+
+// ReleaseQuarantinedFile 解除隔离，文件恢复为可访问
+func ReleaseQuarantinedFile(fileID, operatorID string) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.ReleaseQuarantinedFile(fileID, operatorID)
+}
+
+// DeleteQuarantinedFile 删除被隔离的文件
+func DeleteQuarantinedFile(fileID, operatorID string) error {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.DeleteQuarantinedFile(fileID, operatorID)
+}
+
+// ScanPendingFiles 扫描待扫描的文件，返回本轮完成扫描的文件数
+func (s *UploadService) ScanPendingFiles() (int, error) {
+	if !s.scanEnabled() {
+		return 0, nil
+	}
+
+	files, _, err := s.uploadRepo.GetFilesByStatus(constants.UploadStatusPendingScan, 0, pendingScanBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("查询待扫描文件失败: %v", err)
+	}
+
+	scanned := 0
+	for i := range files {
+		if s.scanFile(&files[i]) {
+			scanned++
+		}
+	}
+	return scanned, nil
+}
+
+// ReleaseQuarantinedFile 管理员确认文件安全后解除隔离，保留扫描记录
+func (s *UploadService) ReleaseQuarantinedFile(fileID, operatorID string) (*models.UploadFile, error) {
+	released, err := s.uploadRepo.TransitUploadStatus(fileID, constants.UploadStatusQuarantined, constants.UploadStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("解除隔离失败: %v", err)
+	}
+	if !released {
+		return nil, ErrNotQuarantined
+	}
+
+	file, err := s.uploadRepo.GetUploadFileByID(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("文件记录不存在: %v", err)
+	}
+
+	logger.Warn("隔离文件已解除隔离", "fileID", fileID, "signature", file.ScanSignature, "operatorID", operatorID)
+	s.publishFile(file)
+	return file, nil
+}
+
+// DeleteQuarantinedFile 彻底删除被隔离的文件
+func (s *UploadService) DeleteQuarantinedFile(fileID, operatorID string) error {
+	file, err := s.uploadRepo.GetUploadFileByID(fileID)
+	if err != nil || file.UploadStatus != constants.UploadStatusQuarantined {
+		return ErrNotQuarantined
+	}
+
+	if err := s.removeFile(file); err != nil {
+		return err
+	}
+
+	logger.Info("隔离文件已删除", "fileID", fileID, "signature", file.ScanSignature, "operatorID", operatorID)
+	return nil
+}
+
+// scheduleScan 在后台扫描上传完成的文件
+func (s *UploadService) scheduleScan(file *models.UploadFile) {
+	go s.scanFile(file)
+}
+
+// scanFile 扫描文件并保存结果，返回是否完成扫描
+// 扫描通过的文件改为已完成并发布上传事件，发现威胁的文件被隔离；扫描出错时保持待扫描状态，由定时任务重试
+func (s *UploadService) scanFile(file *models.UploadFile) bool {
+	fileID := file.ID.String()
+
+	scanJobsMu.Lock()
+	if scanJobs[fileID] {
+		scanJobsMu.Unlock()
+		return false
+	}
+	scanJobs[fileID] = true
+	scanJobsMu.Unlock()
+
+	defer func() {
+		scanJobsMu.Lock()
+		delete(scanJobs, fileID)
+		scanJobsMu.Unlock()
+	}()
+
+	slots := scanConcurrencySlots()
+	slots <- struct{}{}
+	defer func() { <-slots }()
+
+	obj, err := s.storage.Open(file.FilePath)
+	if err != nil {
+		logger.Error("读取待扫描文件失败", "fileID", fileID, "error", err)
+		return false
+	}
+	result, err := s.scanner.Scan(obj)
+	obj.Close()
+	if err != nil {
+		logger.Warn("文件扫描失败，稍后重试", "fileID", fileID, "error", err)
+		return false
+	}
+
+	status := constants.UploadStatusCompleted
+	if result.Infected {
+		status = constants.UploadStatusQuarantined
+	}
+
+	now := time.Now()
+	saved, err := s.uploadRepo.SaveScanResult(fileID, status, result.Signature, now)
+	if err != nil {
+		logger.Error("保存扫描结果失败", "fileID", fileID, "error", err)
+		return false
+	}
+	if !saved {
+		// 扫描期间文件已被删除
+		return false
+	}
+
+	file.UploadStatus = status
+	file.ScanSignature = result.Signature
+	file.ScannedAt = &now
+
+	if result.Infected {
+		logger.Warn("文件扫描发现威胁，已隔离", "fileID", fileID, "signature", result.Signature, "userID", file.UserID)
+		emitFileQuarantined(file)
+		return true
+	}
+
+	s.publishFile(file)
+	return true
+}
+
+// scanEnabled 是否启用了安全扫描
+func (s *UploadService) scanEnabled() bool {
+	return s.scanner != nil && !upload.IsNoopScanner(s.scanner)
+}
+
+// uploadedStatus 文件内容保存完成后的状态，启用安全扫描时需要先等待扫描
+func (s *UploadService) uploadedStatus() int {
+	if s.scanEnabled() {
+		return constants.UploadStatusPendingScan
+	}
+	return constants.UploadStatusCompleted
+}
+
+// scanConcurrencySlots 获取限制同时扫描数量的信号量
+func scanConcurrencySlots() chan struct{} {
+	scanOnce.Do(func() {
+		concurrency := appConfig.GetConfig().Scanner.Concurrency
+		if concurrency <= 0 {
+			concurrency = defaultScanConcurrency
+		}
+		scanSlots = make(chan struct{}, concurrency)
+	})
+	return scanSlots
+}
@@ -0,0 +1,228 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+
+	"template/internal/models"
+	appConfig "template/pkg/config"
+	"template/pkg/constants"
+	"template/pkg/logger"
+	"template/pkg/upload"
+
+	"gorm.io/gorm"
+)
+
+// 缩略图默认参数
+const (
+	defaultThumbnailQuality   = 85         // 默认JPEG质量
+	defaultThumbnailMaxPixels = 50_000_000 // 默认原图像素数上限
+	thumbnailKindPrefix       = "thumb_"   // 缩略图派生类型前缀
+)
+
+// defaultThumbnailSizes 默认生成的缩略图尺寸
+var defaultThumbnailSizes = []int{128, 256, 512}
+
+// 缩略图错误，控制器据此返回对应的状态码
+var (
+	ErrThumbnailSize        = errors.New("不支持的缩略图尺寸")
+	ErrThumbnailUnsupported = errors.New("该文件不支持生成缩略图")
+	ErrThumbnailUnavailable = errors.New("缩略图生成失败")
+)
+
+// thumbnailJobs 正在生成缩略图的文件，同一文件同时只生成一次，其他请求等待生成结束
+var (
+	thumbnailJobsMu sync.Mutex
+	thumbnailJobs   = make(map[string]chan struct{})
+)
+
+// GetThumbnail 获取文件指定尺寸的缩略图，尚未生成时立即生成
+func GetThumbnail(file *models.UploadFile, size int) (*models.FileDerivative, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetThumbnail(file, size)
+}
+
+// OpenDerivative 打开派生文件内容
+func OpenDerivative(derivative *models.FileDerivative) (*upload.Object, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.storage.Open(derivative.FilePath)
+}
+
+// GetThumbnail 获取文件指定尺寸的缩略图
+// 上传完成后缩略图在后台生成，生成结束前的请求以及启用缩略图之前上传的文件在请求时同步生成
+func (s *UploadService) GetThumbnail(file *models.UploadFile, size int) (*models.FileDerivative, error) {
+	if !slices.Contains(thumbnailConfig().Sizes, size) {
+		return nil, ErrThumbnailSize
+	}
+	if !supportsThumbnail(file) {
+		return nil, ErrThumbnailUnsupported
+	}
+
+	fileID := file.ID.String()
+	kind := thumbnailKind(size)
+	derivative, err := s.uploadRepo.GetFileDerivative(fileID, kind)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.generateThumbnails(file)
+		derivative, err = s.uploadRepo.GetFileDerivative(fileID, kind)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrThumbnailUnavailable
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询缩略图失败: %v", err)
+	}
+
+	if derivative.Status != constants.DerivativeStatusReady {
+		return nil, fmt.Errorf("%w: %s", ErrThumbnailUnavailable, derivative.Error)
+	}
+	return derivative, nil
+}
+
+// scheduleThumbnails 在后台为上传完成的图片生成缩略图
+func (s *UploadService) scheduleThumbnails(file *models.UploadFile) {
+	if !supportsThumbnail(file) {
+		return
+	}
+	go s.generateThumbnails(file)
+}
+
+// generateThumbnails 生成文件缺少的所有尺寸的缩略图
+// 图片无法解码或超出像素数上限时记录为生成失败，不再重试；存储失败时不记录，下次请求时重新生成
+func (s *UploadService) generateThumbnails(file *models.UploadFile) {
+	fileID := file.ID.String()
+
+	thumbnailJobsMu.Lock()
+	if done, running := thumbnailJobs[fileID]; running {
+		thumbnailJobsMu.Unlock()
+		<-done
+		return
+	}
+	done := make(chan struct{})
+	thumbnailJobs[fileID] = done
+	thumbnailJobsMu.Unlock()
+
+	defer func() {
+		thumbnailJobsMu.Lock()
+		delete(thumbnailJobs, fileID)
+		thumbnailJobsMu.Unlock()
+		close(done)
+	}()
+
+	cfg := thumbnailConfig()
+	var sizes []int
+	for _, size := range cfg.Sizes {
+		if _, err := s.uploadRepo.GetFileDerivative(fileID, thumbnailKind(size)); errors.Is(err, gorm.ErrRecordNotFound) {
+			sizes = append(sizes, size)
+		}
+	}
+	if len(sizes) == 0 {
+		return
+	}
+
+	img, err := s.decodeImage(file, cfg.MaxPixels)
+	for _, size := range sizes {
+		derivative := &models.FileDerivative{FileID: fileID, Kind: thumbnailKind(size)}
+		if err != nil {
+			derivative.Status = constants.DerivativeStatusFailed
+			derivative.Error = err.Error()
+		} else if storeErr := s.storeThumbnail(derivative, img, size, cfg.Quality); storeErr != nil {
+			logger.Error("保存缩略图失败", "fileID", fileID, "size", size, "error", storeErr)
+			continue
+		}
+
+		if err := s.uploadRepo.CreateFileDerivative(derivative); err != nil {
+			logger.Error("保存缩略图记录失败", "fileID", fileID, "size", size, "error", err)
+		}
+	}
+
+	if err != nil {
+		logger.Warn("生成缩略图失败", "fileID", fileID, "error", err)
+	}
+}
+
+// decodeImage 从存储中读取并解码图片，先检查像素数再完整解码
+func (s *UploadService) decodeImage(file *models.UploadFile, maxPixels int64) (img image.Image, err error) {
+	// 解码器遇到异常数据时可能panic，转换为生成失败，避免后台任务导致进程退出
+	defer func() {
+		if r := recover(); r != nil {
+			img, err = nil, fmt.Errorf("图片解码失败: %v", r)
+		}
+	}()
+
+	obj, err := s.storage.Open(file.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	err = upload.CheckImageSize(obj, maxPixels)
+	obj.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if obj, err = s.storage.Open(file.FilePath); err != nil {
+		return nil, fmt.Errorf("读取文件失败: %v", err)
+	}
+	defer obj.Close()
+	return upload.DecodeImage(obj)
+}
+
+// storeThumbnail 生成缩略图并保存到存储，填充派生文件记录
+func (s *UploadService) storeThumbnail(derivative *models.FileDerivative, img image.Image, size, quality int) error {
+	thumb, err := upload.GenerateThumbnail(img, size, quality)
+	if err != nil {
+		return err
+	}
+
+	ext := ".jpg"
+	if thumb.MimeType == "image/png" {
+		ext = ".png"
+	}
+	path := filepath.Join(s.config.UploadDir, constants.DerivativeDir, derivative.FileID, derivative.Kind+ext)
+	if err := s.storage.SaveChunk(path, bytes.NewReader(thumb.Data)); err != nil {
+		return err
+	}
+
+	derivative.Status = constants.DerivativeStatusReady
+	derivative.FilePath = path
+	derivative.FileSize = int64(len(thumb.Data))
+	derivative.MimeType = thumb.MimeType
+	derivative.Width = thumb.Width
+	derivative.Height = thumb.Height
+	return nil
+}
+
+// supportsThumbnail 已上传完成的JPEG、PNG、GIF和WebP图片可以生成缩略图
+func supportsThumbnail(file *models.UploadFile) bool {
+	return file.UploadStatus == constants.UploadStatusCompleted && upload.ThumbnailMimeTypes[file.MimeType]
+}
+
+// thumbnailKind 缩略图的派生类型
+func thumbnailKind(size int) string {
+	return thumbnailKindPrefix + strconv.Itoa(size)
+}
+
+// thumbnailConfig 获取缩略图配置，未配置的项使用默认值
+func thumbnailConfig() appConfig.ThumbnailConfig {
+	cfg := appConfig.GetConfig().Thumbnail
+	cfg.Sizes = slices.DeleteFunc(slices.Clone(cfg.Sizes), func(size int) bool { return size <= 0 })
+	if len(cfg.Sizes) == 0 {
+		cfg.Sizes = defaultThumbnailSizes
+	}
+	if cfg.Quality <= 0 || cfg.Quality > 100 {
+		cfg.Quality = defaultThumbnailQuality
+	}
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = defaultThumbnailMaxPixels
+	}
+	return cfg
+}
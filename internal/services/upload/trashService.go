@@ -0,0 +1,176 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"template/internal/models"
+	"template/pkg/common"
+	"template/pkg/constants"
+	"template/pkg/logger"
+)
+
+// expiredTrashBatchSize 定时任务每轮最多彻底删除的回收站文件数
+const expiredTrashBatchSize = 100
+
+// ErrTrashedFileNotFound 回收站中没有该文件或文件不属于当前用户
+var ErrTrashedFileNotFound = errors.New("回收站中没有该文件")
+
+// ListTrash 分页获取用户回收站中的文件
+func ListTrash(userID string, page *common.PaginationRequest) ([]models.UploadFile, int64, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.ListTrash(userID, page)
+}
+
+// RestoreTrashedFiles 批量恢复回收站中的文件
+func RestoreTrashedFiles(userID string, fileIDs []string) (restored, failed []string) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.RestoreTrashedFiles(userID, fileIDs)
+}
+
+// PurgeTrashedFile 彻底删除回收站中的文件
+func PurgeTrashedFile(fileID, userID string) error {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.PurgeTrashedFile(fileID, userID)
+}
+
+// EmptyTrash 清空用户的回收站
+func EmptyTrash(userID string) (int, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.EmptyTrash(userID)
+}
+
+// PurgeExpiredTrash 彻底删除超过保留时间的回收站文件，由定时任务调用
+func PurgeExpiredTrash() (int, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.PurgeExpiredTrash()
+}
+
+// TrashRetention 回收站中的文件的保留时间
+func TrashRetention() time.Duration {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.config.TrashRetention
+}
+
+// ListTrash 分页获取用户回收站中的文件，最近删除的在前
+func (s *UploadService) ListTrash(userID string, page *common.PaginationRequest) ([]models.UploadFile, int64, error) {
+	files, total, err := s.uploadRepo.GetTrashedFiles(userID, page.GetOffset(), page.GetPageSize())
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询回收站失败: %v", err)
+	}
+	return files, total, nil
+}
+
+// RestoreTrashedFiles 批量恢复回收站中的文件，返回已恢复和恢复失败的文件ID
+// 文件原来所在的文件夹已被删除时恢复到根目录
+func (s *UploadService) RestoreTrashedFiles(userID string, fileIDs []string) (restored, failed []string) {
+	restored = make([]string, 0, len(fileIDs))
+	failed = make([]string, 0)
+
+	for _, fileID := range fileIDs {
+		file, err := s.getTrashedFile(fileID, userID)
+		if err != nil {
+			failed = append(failed, fileID)
+			continue
+		}
+
+		folderID := file.FolderID
+		if folderID != "" {
+			if folder, err := s.uploadRepo.GetFolderByID(folderID); err != nil || folder.UserID != userID {
+				folderID = ""
+			}
+		}
+		ok, err := s.uploadRepo.RestoreFile(fileID, folderID)
+		if err != nil {
+			logger.Error("恢复文件失败", "fileID", fileID, "error", err)
+		}
+		if !ok {
+			failed = append(failed, fileID)
+			continue
+		}
+		restored = append(restored, fileID)
+	}
+	return restored, failed
+}
+
+// PurgeTrashedFile 彻底删除回收站中的文件，包括存储的文件内容和相关记录
+func (s *UploadService) PurgeTrashedFile(fileID, userID string) error {
+	file, err := s.getTrashedFile(fileID, userID)
+	if err != nil {
+		return err
+	}
+	return s.removeFile(file)
+}
+
+// EmptyTrash 彻底删除用户回收站中的全部文件，返回删除的文件数
+func (s *UploadService) EmptyTrash(userID string) (int, error) {
+	files, err := s.uploadRepo.GetUserTrashedFiles(userID)
+	if err != nil {
+		return 0, fmt.Errorf("查询回收站失败: %v", err)
+	}
+
+	purged := 0
+	for i := range files {
+		if err := s.removeFile(&files[i]); err != nil {
+			logger.Error("删除文件失败", "fileID", files[i].ID.String(), "error", err)
+			continue
+		}
+		purged++
+	}
+	if purged < len(files) {
+		return purged, fmt.Errorf("有 %d 个文件删除失败", len(files)-purged)
+	}
+	return purged, nil
+}
+
+// PurgeExpiredTrash 彻底删除在回收站中超过保留时间的文件，返回删除的文件数
+func (s *UploadService) PurgeExpiredTrash() (int, error) {
+	files, err := s.uploadRepo.GetExpiredTrashedFiles(time.Now().Add(-s.config.TrashRetention), expiredTrashBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("查询过期的回收站文件失败: %v", err)
+	}
+
+	purged := 0
+	for i := range files {
+		if err := s.removeFile(&files[i]); err != nil {
+			logger.Error("删除文件失败", "fileID", files[i].ID.String(), "error", err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// moveToTrash 将文件移入回收站，未完成和上传失败的文件没有可恢复的内容，直接彻底删除
+func (s *UploadService) moveToTrash(file *models.UploadFile) error {
+	switch file.UploadStatus {
+	case constants.UploadStatusUploading, constants.UploadStatusMerging, constants.UploadStatusFailed:
+		return s.removeFile(file)
+	}
+	if err := s.uploadRepo.DeleteUploadFile(file.ID.String()); err != nil {
+		return fmt.Errorf("删除文件记录失败: %v", err)
+	}
+	return nil
+}
+
+// getTrashedFile 获取用户回收站中的文件，不存在或不属于该用户时返回 ErrTrashedFileNotFound
+func (s *UploadService) getTrashedFile(fileID, userID string) (*models.UploadFile, error) {
+	file, err := s.uploadRepo.GetTrashedFile(fileID)
+	if err != nil || file.UserID != userID {
+		return nil, ErrTrashedFileNotFound
+	}
+	return file, nil
+}
@@ -0,0 +1,347 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"template/internal/models"
+	"template/pkg/constants"
+	"template/pkg/logger"
+	"template/pkg/upload"
+)
+
+// tus上传的错误，控制器据此返回协议规定的状态码
+var (
+	ErrTusNotFound         = errors.New("上传不存在")
+	ErrTusGone             = errors.New("上传已过期或已失败")
+	ErrTusOffsetMismatch   = errors.New("Upload-Offset 与已上传的长度不一致")
+	ErrTusChecksumMismatch = errors.New("上传内容与 Upload-Checksum 不一致")
+	ErrTusTooLarge         = errors.New("上传内容超过声明的文件大小")
+	ErrTusLocked           = errors.New("上传正在被其他请求写入")
+)
+
+// TusUploadInfo tus上传的状态
+type TusUploadInfo struct {
+	File      *models.UploadFile
+	Offset    int64      // 已上传的字节数
+	Metadata  string     // 创建上传时的 Upload-Metadata 原文
+	ExpiresAt *time.Time // 过期时间，已完成的上传为nil
+}
+
+// tusWriting 正在写入的上传，同一个上传同时只允许一个请求写入
+var (
+	tusWritingMu sync.Mutex
+	tusWriting   = make(map[string]bool)
+)
+
+// CreateTusUpload 创建tus上传
+func CreateTusUpload(userID string, length int64, metadata string) (*TusUploadInfo, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.CreateTusUpload(userID, length, metadata)
+}
+
+// GetTusUpload 获取tus上传的状态
+func GetTusUpload(fileID, userID string) (*TusUploadInfo, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetTusUpload(fileID, userID)
+}
+
+// WriteTusUpload 从 offset 开始写入上传内容
+func WriteTusUpload(fileID, userID string, offset int64, body io.Reader, checksum *upload.TusChecksum) (*TusUploadInfo, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.WriteTusUpload(fileID, userID, offset, body, checksum)
+}
+
+// TerminateTusUpload 终止tus上传并删除已上传的内容
+func TerminateTusUpload(fileID, userID string) error {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.TerminateTusUpload(fileID, userID)
+}
+
+// CreateTusUpload 创建tus上传，文件名从 Upload-Metadata 的 filename 或 name 中获取，
+// 目标文件夹从 folderID 中获取，为空时上传到根目录
+func (s *UploadService) CreateTusUpload(userID string, length int64, metadata string) (*TusUploadInfo, error) {
+	values, err := upload.ParseTusMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	filename := values["filename"]
+	if filename == "" {
+		filename = values["name"]
+	}
+	mimeType, err := s.validateUploadInfo(filename, length)
+	if err != nil {
+		return nil, err
+	}
+	folderID := values["folderID"]
+	if _, err := s.resolveFolder(folderID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.reserveQuota(userID, length); err != nil {
+		return nil, err
+	}
+
+	file := newUploadingFile(filename, mimeType, length, upload.CalculateChunkTotal(length, s.config.ChunkSize), userID)
+	file.FolderID = folderID
+	tus := &models.TusUpload{
+		Metadata:  metadata,
+		ExpiresAt: time.Now().Add(s.config.UploadExpiration),
+	}
+	if err := s.uploadRepo.CreateTusUpload(file, tus); err != nil {
+		s.releaseQuota(userID, length)
+		return nil, fmt.Errorf("创建文件记录失败: %v", err)
+	}
+
+	logger.Info("tus上传创建成功", "fileID", file.ID.String(), "length", length)
+	return &TusUploadInfo{File: file, Metadata: metadata, ExpiresAt: &tus.ExpiresAt}, nil
+}
+
+// GetTusUpload 获取tus上传的状态
+func (s *UploadService) GetTusUpload(fileID, userID string) (*TusUploadInfo, error) {
+	file, tus, err := s.findTusUpload(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTusActive(file, tus); err != nil {
+		return nil, err
+	}
+	return s.tusUploadInfo(file, tus)
+}
+
+// WriteTusUpload 从 offset 开始写入上传内容，请求体按分片大小切分保存为分片
+// 没有校验和时，连接中断前已完整保存的分片会保留，客户端可以查询进度后继续上传；
+// 有校验和时，请求体必须完整且校验通过才会保存。写满声明的长度后自动合并为完整文件
+func (s *UploadService) WriteTusUpload(fileID, userID string, offset int64, body io.Reader, checksum *upload.TusChecksum) (*TusUploadInfo, error) {
+	if !acquireTusUpload(fileID) {
+		return nil, ErrTusLocked
+	}
+	defer releaseTusUpload(fileID)
+
+	file, tus, err := s.findTusUpload(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTusActive(file, tus); err != nil {
+		return nil, err
+	}
+
+	current, err := s.tusOffset(file)
+	if err != nil {
+		return nil, err
+	}
+	if offset != current {
+		return nil, ErrTusOffsetMismatch
+	}
+	if file.UploadStatus != constants.UploadStatusUploading {
+		return s.tusUploadInfo(file, tus)
+	}
+
+	chunkCount, err := s.uploadRepo.GetUploadedChunksCount(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取上传进度失败: %v", err)
+	}
+
+	// 1. 读取请求体并保存为分片
+	reader := io.LimitReader(body, file.FileSize-current)
+	if checksum != nil {
+		reader = io.TeeReader(reader, checksum.Writer())
+	}
+
+	// 第一次写入时根据文件头识别实际类型，与扩展名不符时拒绝，合并后还会再次检查
+	if current == 0 {
+		buffered := bufio.NewReaderSize(reader, upload.SniffLen)
+		if head, _ := buffered.Peek(upload.SniffLen); len(head) > 0 {
+			if _, err := upload.ValidateContentType(file.Filename, head); err != nil {
+				return nil, err
+			}
+		}
+		reader = buffered
+	}
+	chunks, readErr := s.saveTusChunks(fileID, int(chunkCount), reader)
+
+	// 2. 请求体超出声明的长度或校验失败时丢弃本次写入的全部内容
+	discardErr := readErr
+	if discardErr == nil && readsMore(body) {
+		discardErr = ErrTusTooLarge
+	}
+	if discardErr == nil && checksum != nil && !checksum.Verify() {
+		discardErr = ErrTusChecksumMismatch
+	}
+	if discardErr != nil && (checksum != nil || discardErr == ErrTusTooLarge) {
+		for _, chunk := range chunks {
+			s.storage.DeleteFile(chunk.ChunkPath)
+		}
+		return nil, discardErr
+	}
+
+	// 3. 记录已保存的分片
+	if len(chunks) > 0 {
+		if err := s.uploadRepo.CreateChunkInfos(chunks); err != nil {
+			for _, chunk := range chunks {
+				s.storage.DeleteFile(chunk.ChunkPath)
+			}
+			return nil, fmt.Errorf("保存分片记录失败: %v", err)
+		}
+		chunkCount += int64(len(chunks))
+		if err := s.uploadRepo.UpdateUploadProgress(fileID, int(chunkCount)); err != nil {
+			logger.Error("更新上传进度失败", "fileID", fileID, "error", err)
+		}
+	}
+	if readErr != nil {
+		return nil, fmt.Errorf("读取上传内容失败: %v", readErr)
+	}
+
+	// 4. 写满声明的长度后合并分片
+	info, err := s.tusUploadInfo(file, tus)
+	if err != nil || info.Offset < file.FileSize {
+		return info, err
+	}
+
+	if err := s.uploadRepo.UpdateChunkTotal(fileID, int(chunkCount)); err != nil {
+		return nil, fmt.Errorf("更新分片数量失败: %v", err)
+	}
+	if _, err := s.MergeChunks(fileID); err != nil {
+		return nil, err
+	}
+
+	completed, err := s.uploadRepo.GetUploadFileByID(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("文件记录不存在: %v", err)
+	}
+	return s.tusUploadInfo(completed, tus)
+}
+
+// TerminateTusUpload 终止tus上传，已上传的分片和文件记录一并删除；已完成的上传同样可以删除
+func (s *UploadService) TerminateTusUpload(fileID, userID string) error {
+	if !acquireTusUpload(fileID) {
+		return ErrTusLocked
+	}
+	defer releaseTusUpload(fileID)
+
+	file, _, err := s.findTusUpload(fileID, userID)
+	if err != nil {
+		return err
+	}
+	return s.removeFile(file)
+}
+
+// saveTusChunks 将请求体按分片大小切分保存，返回已完整保存的分片
+func (s *UploadService) saveTusChunks(fileID string, startIndex int, reader io.Reader) ([]*models.ChunkInfo, error) {
+	var chunks []*models.ChunkInfo
+	buf := make([]byte, s.config.ChunkSize)
+
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			index := startIndex + len(chunks)
+			chunk := &models.ChunkInfo{
+				FileID:     fileID,
+				ChunkIndex: index,
+				ChunkSize:  int64(n),
+				ChunkPath:  upload.GenerateChunkPath(s.config.TempDir, fileID, index),
+				IsUploaded: true,
+			}
+			if saveErr := s.storage.SaveChunk(chunk.ChunkPath, bytes.NewReader(buf[:n])); saveErr != nil {
+				return chunks, fmt.Errorf("保存分片失败: %v", saveErr)
+			}
+			chunks = append(chunks, chunk)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return chunks, err
+		}
+	}
+}
+
+// findTusUpload 查询当前用户的tus上传，其他用户的上传视为不存在
+func (s *UploadService) findTusUpload(fileID, userID string) (*models.UploadFile, *models.TusUpload, error) {
+	file, err := s.uploadRepo.GetUploadFileByID(fileID)
+	if err != nil || file.UserID != userID {
+		return nil, nil, ErrTusNotFound
+	}
+
+	tus, err := s.uploadRepo.GetTusUpload(fileID)
+	if err != nil {
+		return nil, nil, ErrTusNotFound
+	}
+	return file, tus, nil
+}
+
+// tusOffset 获取已上传的字节数，内容已保存完成（包括待扫描和已隔离）的上传为文件大小
+func (s *UploadService) tusOffset(file *models.UploadFile) (int64, error) {
+	if file.UploadStatus != constants.UploadStatusUploading {
+		return file.FileSize, nil
+	}
+
+	offset, err := s.uploadRepo.GetUploadedBytes(file.ID.String())
+	if err != nil {
+		return 0, fmt.Errorf("获取上传进度失败: %v", err)
+	}
+	return offset, nil
+}
+
+// tusUploadInfo 构建tus上传的状态
+func (s *UploadService) tusUploadInfo(file *models.UploadFile, tus *models.TusUpload) (*TusUploadInfo, error) {
+	offset, err := s.tusOffset(file)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &TusUploadInfo{File: file, Offset: offset, Metadata: tus.Metadata}
+	if file.UploadStatus == constants.UploadStatusUploading {
+		info.ExpiresAt = &tus.ExpiresAt
+	}
+	return info, nil
+}
+
+// checkTusActive 检查上传是否可以继续：失败或已过期的上传不能再访问
+func checkTusActive(file *models.UploadFile, tus *models.TusUpload) error {
+	if file.UploadStatus == constants.UploadStatusFailed {
+		return ErrTusGone
+	}
+	if file.UploadStatus == constants.UploadStatusUploading && time.Now().After(tus.ExpiresAt) {
+		return ErrTusGone
+	}
+	return nil
+}
+
+// readsMore 检查读取器是否还有未读取的内容
+func readsMore(r io.Reader) bool {
+	n, _ := r.Read(make([]byte, 1))
+	return n > 0
+}
+
+func acquireTusUpload(fileID string) bool {
+	tusWritingMu.Lock()
+	defer tusWritingMu.Unlock()
+
+	if tusWriting[fileID] {
+		return false
+	}
+	tusWriting[fileID] = true
+	return true
+}
+
+func releaseTusUpload(fileID string) {
+	tusWritingMu.Lock()
+	defer tusWritingMu.Unlock()
+	delete(tusWriting, fileID)
+}
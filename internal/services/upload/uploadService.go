@@ -1,15 +1,18 @@
 package upload
 
 import (
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"template/internal/dto/response"
 	"template/internal/models"
 	uploadRepo "template/internal/repositories/upload"
 	"template/pkg/common"
+	appConfig "template/pkg/config"
 	"template/pkg/constants"
 	"template/pkg/database"
 	"template/pkg/logger"
@@ -19,20 +22,47 @@ import (
 var uploadService *UploadService
 var config *upload.Config
 
+// 上传请求错误，控制器据此返回对应的状态码
+var (
+	ErrFileTooLarge        = errors.New("文件大小超出限制")
+	ErrUnsupportedFileType = errors.New("不支持的文件类型")
+	ErrChunkDigestMismatch = errors.New("分片MD5校验失败")
+	ErrChunksIncomplete    = errors.New("分片尚未完全上传")
+	ErrDigestMismatch      = errors.New("文件校验失败")
+	ErrUploadNotMerging    = errors.New("文件正在合并或已不在上传中")
+)
+
 // UploadService 上传服务
 type UploadService struct {
 	uploadRepo *uploadRepo.UploadRepository
 	storage    upload.Storage
+	scanner    upload.Scanner
 	config     *upload.Config
 }
 
 // InitUploadService 初始化上传服务
 func InitUploadService() {
 	config = upload.NewDefaultConfig()
+	if hours := appConfig.GetConfig().Upload.ExpirationHours; hours > 0 {
+		config.UploadExpiration = time.Duration(hours) * time.Hour
+	}
+	if days := appConfig.GetConfig().Upload.TrashRetentionDays; days > 0 {
+		config.TrashRetention = time.Duration(days) * 24 * time.Hour
+	}
 	db := database.GetDB()
+
+	// 根据配置选择本地存储或S3兼容对象存储
+	storage, err := upload.NewStorage(appConfig.GetConfig().Storage, config.UploadDir)
+	logger.ErrorExit(err, "初始化文件存储失败")
+
+	// 根据配置选择恶意文件扫描器，默认不扫描
+	scanner, err := upload.NewScanner(appConfig.GetConfig().Scanner)
+	logger.ErrorExit(err, "初始化文件扫描器失败")
+
 	uploadService = &UploadService{
 		uploadRepo: uploadRepo.NewUploadRepository(db),
-		storage:    upload.NewLocalStorage(config.UploadDir),
+		storage:    storage,
+		scanner:    scanner,
 		config:     config,
 	}
 }
@@ -43,19 +73,19 @@ func GetUploadService() *UploadService {
 }
 
 // SimpleUpload 简单文件上传
-func SimpleUpload(file *multipart.FileHeader, userID string) (*response.SimpleUploadResponse, error) {
+func SimpleUpload(file *multipart.FileHeader, userID, folderID string) (*response.SimpleUploadResponse, error) {
 	if uploadService == nil {
 		InitUploadService()
 	}
-	return uploadService.SimpleUpload(file, userID)
+	return uploadService.SimpleUpload(file, userID, folderID)
 }
 
 // InitChunkUpload 初始化分片上传
-func InitChunkUpload(filename string, fileSize int64, md5Hash string, chunkSize int64, userID string) (*response.ChunkUploadInitResponse, error) {
+func InitChunkUpload(filename string, fileSize int64, md5Hash, sha256Hash string, chunkSize int64, userID, folderID string) (*response.ChunkUploadInitResponse, error) {
 	if uploadService == nil {
 		InitUploadService()
 	}
-	return uploadService.InitChunkUpload(filename, fileSize, md5Hash, chunkSize, userID)
+	return uploadService.InitChunkUpload(filename, fileSize, md5Hash, sha256Hash, chunkSize, userID, folderID)
 }
 
 // UploadChunk 上传分片
@@ -82,136 +112,122 @@ func GetUploadProgress(fileID string) (*response.UploadProgressResponse, error)
 	return uploadService.GetUploadProgress(fileID)
 }
 
-// SimpleUpload 简单文件上传
-func (s *UploadService) SimpleUpload(file *multipart.FileHeader, userID string) (*response.SimpleUploadResponse, error) {
-	// 1. 验证文件
+// SimpleUpload 简单文件上传，folderID 为空时上传到根目录
+func (s *UploadService) SimpleUpload(file *multipart.FileHeader, userID, folderID string) (*response.SimpleUploadResponse, error) {
+	// 1. 验证文件和目标文件夹
 	if err := s.validateFile(file); err != nil {
 		return nil, err
 	}
+	if _, err := s.resolveFolder(folderID, userID); err != nil {
+		return nil, err
+	}
 
-	// 2. 打开文件
+	// 2. 占用存储配额，上传失败时释放
+	if err := s.reserveQuota(userID, file.Size); err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			s.releaseQuota(userID, file.Size)
+		}
+	}()
+
+	// 3. 打开文件
 	src, err := file.Open()
 	if err != nil {
 		return nil, fmt.Errorf("无法打开文件: %v", err)
 	}
 	defer src.Close()
 
-	// 3. 计算文件MD5
-	md5Hash, err := upload.CalculateFileMD5(src)
+	// 4. 根据文件头识别实际类型，与扩展名不符时拒绝
+	mimeType, err := upload.SniffContentType(file.Filename, src)
 	if err != nil {
-		return nil, fmt.Errorf("计算文件MD5失败: %v", err)
-	}
-
-	// 4. 检查文件是否已存在（秒传功能）
-	if existingFile, err := s.uploadRepo.GetUploadFileByMD5(md5Hash); err == nil {
-		logger.Info("文件已存在，执行秒传", "md5", md5Hash, "fileID", existingFile.ID.String())
-		return &response.SimpleUploadResponse{
-			FileID:     existingFile.ID.String(),
-			Filename:   existingFile.Filename,
-			StoredName: existingFile.StoredName,
-			FileSize:   existingFile.FileSize,
-			MimeType:   existingFile.MimeType,
-			Extension:  existingFile.Extension,
-			MD5Hash:    existingFile.MD5Hash,
-			FilePath:   fmt.Sprintf("/files/preview/%s/%s", existingFile.ID.String(), existingFile.Filename),
-			UploadedAt: *existingFile.UploadedAt,
-		}, nil
+		return nil, err
 	}
 
-	// 5. 生成存储文件名
+	// 5. 计算文件哈希
+	digest, err := upload.CalculateFileDigest(src)
+	if err != nil {
+		return nil, fmt.Errorf("计算文件哈希失败: %v", err)
+	}
+
+	// 6. 已存储过相同内容时直接引用（秒传），为当前用户创建独立的文件记录
+	if blob, err := s.uploadRepo.GetBlobByHash(digest.SHA256); err == nil {
+		uploadFile := s.newCompletedFile(file.Filename, upload.GenerateStoredFilename(file.Filename), mimeType, userID, blob)
+		uploadFile.FolderID = folderID
+		if err := s.uploadRepo.CreateFileWithBlob(uploadFile, blob); err == nil {
+			committed = true
+			logger.Info("文件已存在，执行秒传", "sha256", digest.SHA256, "fileID", uploadFile.ID.String())
+			s.afterUpload(uploadFile)
+			return newSimpleUploadResponse(uploadFile), nil
+		}
+		// 已有内容在此期间被删除时按新文件保存
+	}
+
+	// 7. 生成存储文件名
 	storedName := upload.GenerateStoredFilename(file.Filename)
 
-	// 6. 保存文件
-	filePath, err := s.storage.SaveFile(storedName, src)
+	// 8. 保存文件，以实际写入存储的内容的哈希为准
+	filePath, saved, err := s.storage.SaveFile(storedName, src)
 	if err != nil {
 		return nil, fmt.Errorf("保存文件失败: %v", err)
 	}
 
-	// 7. 创建数据库记录
-	now := time.Now()
-	uploadFile := &models.UploadFile{
-		BaseModel:     models.BaseModel{ID: common.NewUUID()},
-		Filename:      file.Filename,
-		StoredName:    storedName,
-		FilePath:      filePath,
-		FileSize:      file.Size,
-		MimeType:      upload.GetMimeTypeFromExtension(file.Filename),
-		Extension:     upload.GetFileExtension(file.Filename),
-		MD5Hash:       md5Hash,
-		UploadStatus:  constants.UploadStatusCompleted,
-		ChunkTotal:    1,
-		ChunkUploaded: 1,
-		UserID:        userID,
-		UploadedAt:    &now,
-		IsPublic:      true,
+	// 9. 创建文件内容和文件记录
+	blob := &models.FileBlob{
+		SHA256Hash: saved.SHA256,
+		MD5Hash:    saved.MD5,
+		StoredName: storedName,
+		FilePath:   filePath,
+		FileSize:   saved.Size,
 	}
+	uploadFile := s.newCompletedFile(file.Filename, storedName, mimeType, userID, blob)
+	uploadFile.FolderID = folderID
 
-	if err := s.uploadRepo.CreateUploadFile(uploadFile); err != nil {
+	if err := s.uploadRepo.CreateFileWithBlob(uploadFile, blob); err != nil {
 		// 如果数据库保存失败，删除已上传的文件
 		s.storage.DeleteFile(filePath)
 		return nil, fmt.Errorf("保存文件记录失败: %v", err)
 	}
+	committed = true
 
-	return &response.SimpleUploadResponse{
-		FileID:     uploadFile.ID.String(),
-		Filename:   uploadFile.Filename,
-		StoredName: uploadFile.StoredName,
-		FileSize:   uploadFile.FileSize,
-		MimeType:   uploadFile.MimeType,
-		Extension:  uploadFile.Extension,
-		MD5Hash:    uploadFile.MD5Hash,
-		FilePath:   fmt.Sprintf("/files/preview/%s/%s", uploadFile.ID.String(), uploadFile.Filename),
-		UploadedAt: *uploadFile.UploadedAt,
-	}, nil
+	s.afterUpload(uploadFile)
+
+	return newSimpleUploadResponse(uploadFile), nil
 }
 
 // InitChunkUpload 初始化分片上传
-func (s *UploadService) InitChunkUpload(filename string, fileSize int64, md5Hash string, chunkSize int64, userID string) (*response.ChunkUploadInitResponse, error) {
-	// 1. 验证参数
-	if err := upload.ValidateFilename(filename); err != nil {
+// md5Hash 和 sha256Hash 是客户端声明的文件哈希，合并时会重新计算校验
+// 声明的哈希不能证明客户端持有文件内容，因此不在初始化时秒传，合并校验通过后再按实际内容去重
+// folderID 为空时上传到根目录
+func (s *UploadService) InitChunkUpload(filename string, fileSize int64, md5Hash, sha256Hash string, chunkSize int64, userID, folderID string) (*response.ChunkUploadInitResponse, error) {
+	// 1. 验证参数和目标文件夹
+	mimeType, err := s.validateUploadInfo(filename, fileSize)
+	if err != nil {
 		return nil, err
 	}
-
-	if !s.config.ValidateFileSize(fileSize) {
-		return nil, fmt.Errorf("文件大小超出限制，最大允许 %d 字节", s.config.MaxFileSize)
-	}
-
-	mimeType := upload.GetMimeTypeFromExtension(filename)
-	if !s.config.ValidateMimeType(mimeType) {
-		return nil, fmt.Errorf("不支持的文件类型: %s", mimeType)
+	if _, err := s.resolveFolder(folderID, userID); err != nil {
+		return nil, err
 	}
 
-	// 2. 检查文件是否已存在（秒传功能）
-	if existingFile, err := s.uploadRepo.GetUploadFileByMD5(md5Hash); err == nil {
-		logger.Info("文件已存在，执行秒传", "md5", md5Hash, "fileID", existingFile.ID.String())
-		return &response.ChunkUploadInitResponse{
-			FileID:     existingFile.ID.String(),
-			ChunkSize:  chunkSize,
-			ChunkTotal: 1,
-		}, nil
+	// 2. 按声明的文件大小占用存储配额，创建文件记录失败时释放
+	if err := s.reserveQuota(userID, fileSize); err != nil {
+		return nil, err
 	}
 
 	// 3. 计算分片数量
 	chunkTotal := upload.CalculateChunkTotal(fileSize, chunkSize)
 
 	// 4. 创建文件记录
-	fileID := common.NewUUID()
-	uploadFile := &models.UploadFile{
-		BaseModel:     models.BaseModel{ID: fileID},
-		Filename:      filename,
-		StoredName:    upload.GenerateStoredFilename(filename),
-		FileSize:      fileSize,
-		MimeType:      mimeType,
-		Extension:     upload.GetFileExtension(filename),
-		MD5Hash:       md5Hash,
-		UploadStatus:  constants.UploadStatusUploading,
-		ChunkTotal:    chunkTotal,
-		ChunkUploaded: 0,
-		UserID:        userID,
-		IsPublic:      true,
-	}
+	uploadFile := newUploadingFile(filename, mimeType, fileSize, chunkTotal, userID)
+	uploadFile.MD5Hash = md5Hash
+	uploadFile.SHA256Hash = sha256Hash
+	uploadFile.FolderID = folderID
+	fileID := uploadFile.ID
 
 	if err := s.uploadRepo.CreateUploadFile(uploadFile); err != nil {
+		s.releaseQuota(userID, fileSize)
 		return nil, fmt.Errorf("创建文件记录失败: %v", err)
 	}
 
@@ -281,17 +297,25 @@ func (s *UploadService) UploadChunk(fileID string, chunkIndex int, md5Hash strin
 			return nil, fmt.Errorf("计算分片MD5失败: %v", err)
 		}
 		if calculatedMD5 != md5Hash {
-			return nil, fmt.Errorf("分片MD5校验失败")
+			return nil, ErrChunkDigestMismatch
 		}
 		src.Seek(0, 0) // 重置文件指针
 	}
 
-	// 6. 保存分片
+	// 6. 第一个分片包含文件头，识别实际类型，与扩展名不符时拒绝
+	if chunkIndex == 0 {
+		if _, err := upload.SniffContentType(uploadFile.Filename, src); err != nil {
+			return nil, err
+		}
+		src.Seek(0, 0)
+	}
+
+	// 7. 保存分片
 	if err := s.storage.SaveChunk(chunkInfo.ChunkPath, src); err != nil {
 		return nil, fmt.Errorf("保存分片失败: %v", err)
 	}
 
-	// 7. 更新分片状态
+	// 8. 更新分片状态
 	chunkInfo.ChunkSize = chunk.Size
 	chunkInfo.MD5Hash = md5Hash
 	chunkInfo.IsUploaded = true
@@ -300,7 +324,7 @@ func (s *UploadService) UploadChunk(fileID string, chunkIndex int, md5Hash strin
 		return nil, fmt.Errorf("更新分片状态失败: %v", err)
 	}
 
-	// 8. 更新上传进度
+	// 9. 更新上传进度
 	count, err := s.uploadRepo.GetUploadedChunksCount(fileID)
 	if err != nil {
 		return nil, fmt.Errorf("获取上传进度失败: %v", err)
@@ -329,19 +353,30 @@ func (s *UploadService) MergeChunks(fileID string) (*response.ChunkMergeResponse
 		return nil, fmt.Errorf("文件记录不存在: %v", err)
 	}
 
-	// 2. 检查所有分片是否已上传
+	// 2. 将文件标记为合并中，同一文件只有一个请求能进行合并
+	merging, err := s.uploadRepo.TransitUploadStatus(fileID, constants.UploadStatusUploading, constants.UploadStatusMerging)
+	if err != nil {
+		return nil, fmt.Errorf("更新上传状态失败: %v", err)
+	}
+	if !merging {
+		return nil, ErrUploadNotMerging
+	}
+
+	// 3. 检查所有分片是否已上传并获取分片信息
 	count, err := s.uploadRepo.GetUploadedChunksCount(fileID)
 	if err != nil {
+		s.resumeUploading(fileID)
 		return nil, fmt.Errorf("获取上传进度失败: %v", err)
 	}
 
 	if int(count) != uploadFile.ChunkTotal {
-		return nil, fmt.Errorf("分片尚未完全上传，进度: %d/%d", count, uploadFile.ChunkTotal)
+		s.resumeUploading(fileID)
+		return nil, fmt.Errorf("%w，进度: %d/%d", ErrChunksIncomplete, count, uploadFile.ChunkTotal)
 	}
 
-	// 3. 获取所有分片信息
 	chunks, err := s.uploadRepo.GetFileChunks(fileID)
 	if err != nil {
+		s.resumeUploading(fileID)
 		return nil, fmt.Errorf("获取分片信息失败: %v", err)
 	}
 
@@ -353,26 +388,77 @@ func (s *UploadService) MergeChunks(fileID string) (*response.ChunkMergeResponse
 
 	// 5. 合并分片
 	targetPath := filepath.Join(s.config.UploadDir, uploadFile.StoredName)
-	if err := s.storage.MergeChunks(chunkPaths, targetPath); err != nil {
+	digest, err := s.storage.MergeChunks(chunkPaths, targetPath)
+	if err != nil {
+		s.resumeUploading(fileID)
 		return nil, fmt.Errorf("合并分片失败: %v", err)
 	}
 
-	// 6. 更新文件记录
+	// 6. 校验合并后的内容与声明的大小和哈希一致、实际类型与扩展名相符，否则上传失败，需要重新上传
+	err = verifyDigest(uploadFile, digest)
+	if err == nil {
+		uploadFile.MimeType, err = s.sniffStoredFile(uploadFile.Filename, targetPath, digest.Size)
+	}
+	if err != nil {
+		s.storage.DeleteFile(targetPath)
+		if _, err := s.uploadRepo.MarkUploadFailed(fileID); err != nil {
+			logger.Error("标记上传失败出错", "fileID", fileID, "error", err)
+		}
+		logger.Warn("分片合并校验失败", "fileID", fileID, "error", err)
+		return nil, err
+	}
+
+	// 7. 更新文件记录，已存储过相同内容时引用已有内容并删除合并出的副本
+	// 文件在合并期间已被标记失败或删除时不再更新，合并出的内容也一并删除
 	now := time.Now()
-	uploadFile.FilePath = targetPath
-	uploadFile.UploadStatus = constants.UploadStatusCompleted
+	uploadFile.MD5Hash = digest.MD5
+	uploadFile.SHA256Hash = digest.SHA256
+	uploadFile.UploadStatus = s.uploadedStatus()
 	uploadFile.UploadedAt = &now
 
-	if err := s.uploadRepo.UpdateUploadFile(uploadFile); err != nil {
-		return nil, fmt.Errorf("更新文件记录失败: %v", err)
+	var blob *models.FileBlob
+	completed, reused := false, false
+	if existing, err := s.uploadRepo.GetBlobByHash(digest.SHA256); err == nil {
+		uploadFile.FilePath = existing.FilePath
+		completed, err = s.uploadRepo.CompleteFileWithBlob(uploadFile, existing)
+		if err == nil {
+			blob, reused = existing, true
+		}
+	}
+
+	if !reused {
+		uploadFile.FilePath = targetPath
+		blob = &models.FileBlob{
+			SHA256Hash: digest.SHA256,
+			MD5Hash:    digest.MD5,
+			StoredName: uploadFile.StoredName,
+			FilePath:   targetPath,
+			FileSize:   uploadFile.FileSize,
+		}
+		completed, err = s.uploadRepo.CompleteFileWithBlob(uploadFile, blob)
+		if err != nil {
+			s.storage.DeleteFile(targetPath)
+			s.resumeUploading(fileID)
+			return nil, fmt.Errorf("更新文件记录失败: %v", err)
+		}
 	}
 
-	// 7. 清理分片记录
+	// 合并出的文件只在作为新内容保存时保留，已有内容恰好位于同一路径时不能删除
+	if blob.FilePath != targetPath || (!reused && !completed) {
+		s.storage.DeleteFile(targetPath)
+	}
+	if !completed {
+		logger.Warn("文件在合并期间已被标记失败或删除", "fileID", fileID)
+		return nil, ErrUploadNotMerging
+	}
+
+	// 8. 清理分片记录
 	if err := s.uploadRepo.DeleteFileChunks(fileID); err != nil {
 		logger.Error("清理分片记录失败", "fileID", fileID, "error", err)
 	}
 
 	logger.Info("分片合并成功", "fileID", fileID, "filename", uploadFile.Filename)
+	s.afterUpload(uploadFile)
 
 	return &response.ChunkMergeResponse{
 		FileID:     uploadFile.ID.String(),
@@ -382,6 +468,7 @@ func (s *UploadService) MergeChunks(fileID string) (*response.ChunkMergeResponse
 		MimeType:   uploadFile.MimeType,
 		Extension:  uploadFile.Extension,
 		MD5Hash:    uploadFile.MD5Hash,
+		SHA256Hash: uploadFile.SHA256Hash,
 		FilePath:   fmt.Sprintf("/files/preview/%s/%s", uploadFile.ID.String(), uploadFile.Filename),
 		UploadedAt: *uploadFile.UploadedAt,
 	}, nil
@@ -407,27 +494,145 @@ func (s *UploadService) GetUploadProgress(fileID string) (*response.UploadProgre
 	}, nil
 }
 
+// afterUpload 文件上传完成后的处理，启用安全扫描时先在后台扫描，扫描通过后再发布
+func (s *UploadService) afterUpload(file *models.UploadFile) {
+	if file.UploadStatus == constants.UploadStatusPendingScan {
+		s.scheduleScan(file)
+		return
+	}
+	s.publishFile(file)
+}
+
+// publishFile 文件可以访问后发布上传完成事件，图片在后台生成缩略图
+func (s *UploadService) publishFile(file *models.UploadFile) {
+	emitFileUploaded(file)
+	s.scheduleThumbnails(file)
+}
+
+// resumeUploading 合并未能完成时将文件恢复为上传中，客户端可以重新合并
+func (s *UploadService) resumeUploading(fileID string) {
+	if _, err := s.uploadRepo.TransitUploadStatus(fileID, constants.UploadStatusMerging, constants.UploadStatusUploading); err != nil {
+		logger.Error("恢复上传状态失败", "fileID", fileID, "error", err)
+	}
+}
+
+// newUploadingFile 创建上传中的文件记录
+func newUploadingFile(filename, mimeType string, fileSize int64, chunkTotal int, userID string) *models.UploadFile {
+	return &models.UploadFile{
+		BaseModel:     models.BaseModel{ID: common.NewUUID()},
+		Filename:      filename,
+		StoredName:    upload.GenerateStoredFilename(filename),
+		FileSize:      fileSize,
+		MimeType:      mimeType,
+		Extension:     upload.GetFileExtension(filename),
+		UploadStatus:  constants.UploadStatusUploading,
+		ChunkTotal:    chunkTotal,
+		ChunkUploaded: 0,
+		UserID:        userID,
+		IsPublic:      true,
+	}
+}
+
+// newCompletedFile 创建引用指定文件内容的已完成文件记录
+func (s *UploadService) newCompletedFile(filename, storedName, mimeType, userID string, blob *models.FileBlob) *models.UploadFile {
+	now := time.Now()
+	return &models.UploadFile{
+		BaseModel:     models.BaseModel{ID: common.NewUUID()},
+		Filename:      filename,
+		StoredName:    storedName,
+		FilePath:      blob.FilePath,
+		FileSize:      blob.FileSize,
+		MimeType:      mimeType,
+		Extension:     upload.GetFileExtension(filename),
+		MD5Hash:       blob.MD5Hash,
+		SHA256Hash:    blob.SHA256Hash,
+		UploadStatus:  s.uploadedStatus(),
+		ChunkTotal:    1,
+		ChunkUploaded: 1,
+		UserID:        userID,
+		UploadedAt:    &now,
+		IsPublic:      true,
+	}
+}
+
+// sniffStoredFile 读取已存储文件的文件头，检查实际类型与扩展名是否相符
+func (s *UploadService) sniffStoredFile(filename, filePath string, fileSize int64) (string, error) {
+	obj, err := s.storage.OpenRange(filePath, 0, min(fileSize, upload.SniffLen))
+	if err != nil {
+		return "", fmt.Errorf("读取文件失败: %v", err)
+	}
+	defer obj.Close()
+	return upload.SniffContentType(filename, obj)
+}
+
+// verifyDigest 校验合并后的内容与上传初始化时声明的大小和哈希是否一致
+func verifyDigest(file *models.UploadFile, digest *upload.Digest) error {
+	if digest.Size != file.FileSize {
+		return fmt.Errorf("%w：大小不一致，声明 %d 字节，实际 %d 字节", ErrDigestMismatch, file.FileSize, digest.Size)
+	}
+	if file.SHA256Hash != "" && !strings.EqualFold(file.SHA256Hash, digest.SHA256) {
+		return fmt.Errorf("%w：SHA-256不一致", ErrDigestMismatch)
+	}
+	if file.MD5Hash != "" && !strings.EqualFold(file.MD5Hash, digest.MD5) {
+		return fmt.Errorf("%w：MD5不一致", ErrDigestMismatch)
+	}
+	return nil
+}
+
+// newSimpleUploadResponse 构建简单上传响应
+func newSimpleUploadResponse(file *models.UploadFile) *response.SimpleUploadResponse {
+	return &response.SimpleUploadResponse{
+		FileID:     file.ID.String(),
+		Filename:   file.Filename,
+		StoredName: file.StoredName,
+		FileSize:   file.FileSize,
+		MimeType:   file.MimeType,
+		Extension:  file.Extension,
+		MD5Hash:    file.MD5Hash,
+		SHA256Hash: file.SHA256Hash,
+		FilePath:   fmt.Sprintf("/files/preview/%s/%s", file.ID.String(), file.Filename),
+		UploadedAt: *file.UploadedAt,
+	}
+}
+
+// validateUploadInfo 验证分片上传声明的文件名和大小，返回文件类型
+func (s *UploadService) validateUploadInfo(filename string, fileSize int64) (string, error) {
+	if err := upload.ValidateFilename(filename); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFilename, err)
+	}
+
+	if !s.config.ValidateFileSize(fileSize) {
+		return "", fmt.Errorf("%w，最大允许 %d 字节", ErrFileTooLarge, s.config.MaxFileSize)
+	}
+
+	mimeType := upload.GetMimeTypeFromExtension(filename)
+	if !s.config.ValidateMimeType(mimeType) {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFileType, mimeType)
+	}
+	return mimeType, nil
+}
+
 // validateFile 验证文件
 func (s *UploadService) validateFile(file *multipart.FileHeader) error {
 	// 验证文件名
 	if err := upload.ValidateFilename(file.Filename); err != nil {
-		return err
+		return fmt.Errorf("%w: %v", ErrInvalidFilename, err)
 	}
 
 	// 验证文件大小
 	if !s.config.ValidateFileSize(file.Size) {
-		return fmt.Errorf("文件大小超出限制，最大允许 %d 字节", s.config.MaxFileSize)
+		return fmt.Errorf("%w，最大允许 %d 字节", ErrFileTooLarge, s.config.MaxFileSize)
 	}
 
 	// 验证文件类型
 	mimeType := upload.GetMimeTypeFromExtension(file.Filename)
 	if !s.config.ValidateMimeType(mimeType) {
-		return fmt.Errorf("不支持的文件类型: %s", mimeType)
+		return fmt.Errorf("%w: %s", ErrUnsupportedFileType, mimeType)
 	}
 
 	// 验证文件扩展名
 	if !upload.ValidateFileExtension(file.Filename) {
-		return fmt.Errorf("不支持的文件扩展名: %s", upload.GetFileExtension(file.Filename))
+		return fmt.Errorf("%w，扩展名: %s", ErrUnsupportedFileType, upload.GetFileExtension(file.Filename))
 	}
 
 	return nil
@@ -0,0 +1,163 @@
+package upload
+
+import (
+	"fmt"
+
+	"template/internal/models"
+	"template/pkg/constants"
+	"template/pkg/logger"
+	"template/pkg/upload"
+)
+
+// ListUserFiles 获取用户上传的全部文件
+func ListUserFiles(userID string) ([]models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.uploadRepo.GetAllUserFiles(userID)
+}
+
+// OpenStoredFile 打开已上传完成的文件内容
+func OpenStoredFile(file *models.UploadFile) (*upload.Object, error) {
+	if err := checkAccessible(file); err != nil {
+		return nil, err
+	}
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.storage.Open(file.FilePath)
+}
+
+// OpenStoredFileRange 读取已上传完成的文件的一部分
+func OpenStoredFileRange(file *models.UploadFile, offset, length int64) (*upload.Object, error) {
+	if err := checkAccessible(file); err != nil {
+		return nil, err
+	}
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.storage.OpenRange(file.FilePath, offset, length)
+}
+
+// PurgeUserFiles 删除用户上传的全部文件，包括存储的文件内容和相关记录
+func PurgeUserFiles(userID string) (int, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.PurgeUserFiles(userID)
+}
+
+// ReassignUserFiles 将用户上传的文件转移给其他用户
+func ReassignUserFiles(fromUserID, toUserID string) (int64, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	count, err := uploadService.uploadRepo.ReassignUserFiles(fromUserID, toUserID)
+	if err != nil {
+		return count, err
+	}
+
+	// 文件转移后两个用户的用量都需要重新统计
+	for _, userID := range []string{fromUserID, toUserID} {
+		if _, err := uploadService.uploadRepo.RecomputeQuotaUsage(userID); err != nil {
+			logger.Error("统计存储用量失败", "userID", userID, "error", err)
+		}
+	}
+	return count, nil
+}
+
+// PurgeUserFiles 删除用户上传的全部文件，包括回收站中的文件
+func (s *UploadService) PurgeUserFiles(userID string) (int, error) {
+	files, err := s.uploadRepo.GetAllUserFiles(userID)
+	if err != nil {
+		return 0, fmt.Errorf("查询用户文件失败: %v", err)
+	}
+	trashed, err := s.uploadRepo.GetUserTrashedFiles(userID)
+	if err != nil {
+		return 0, fmt.Errorf("查询回收站失败: %v", err)
+	}
+	files = append(files, trashed...)
+
+	purged := 0
+	for i := range files {
+		if err := s.removeFile(&files[i]); err != nil {
+			logger.Error("删除文件失败", "fileID", files[i].ID.String(), "error", err)
+			continue
+		}
+		purged++
+	}
+
+	if err := s.uploadRepo.DeleteUserFolders(userID); err != nil {
+		logger.Error("删除文件夹失败", "userID", userID, "error", err)
+	}
+
+	return purged, nil
+}
+
+// removeFile 彻底删除文件记录，文件内容不再被其他记录引用时一并删除存储
+func (s *UploadService) removeFile(file *models.UploadFile) error {
+	fileID := file.ID.String()
+
+	// 未完成的分片上传还有临时分片文件
+	if chunks, err := s.uploadRepo.GetFileChunks(fileID); err == nil && len(chunks) > 0 {
+		for _, chunk := range chunks {
+			if s.storage.FileExists(chunk.ChunkPath) {
+				_ = s.storage.DeleteFile(chunk.ChunkPath)
+			}
+		}
+		s.removeChunkDir(fileID)
+	}
+
+	derivatives, _ := s.uploadRepo.GetFileDerivatives(fileID)
+
+	orphans, err := s.uploadRepo.PurgeFileRecords(file)
+	if err != nil {
+		return fmt.Errorf("删除文件记录失败: %v", err)
+	}
+
+	s.deleteDerivativeFiles(derivatives)
+
+	// 没有引用文件内容的旧记录独占存储路径
+	storedPaths := make([]string, 0, len(orphans)+1)
+	if file.BlobID == "" {
+		storedPaths = append(storedPaths, file.FilePath)
+	}
+	for _, orphan := range orphans {
+		storedPaths = append(storedPaths, orphan.FilePath)
+	}
+	return s.deleteStoredFiles(storedPaths)
+}
+
+// deleteDerivativeFiles 删除派生文件的存储
+func (s *UploadService) deleteDerivativeFiles(derivatives []models.FileDerivative) {
+	for _, derivative := range derivatives {
+		if derivative.FilePath != "" && s.storage.FileExists(derivative.FilePath) {
+			_ = s.storage.DeleteFile(derivative.FilePath)
+		}
+	}
+}
+
+// deleteStoredFiles 删除不再被引用的文件内容的存储
+func (s *UploadService) deleteStoredFiles(paths []string) error {
+	for _, path := range paths {
+		if path != "" && s.storage.FileExists(path) {
+			if err := s.storage.DeleteFile(path); err != nil {
+				return fmt.Errorf("删除文件失败: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAccessible 检查文件是否可以读取：未上传完成、待扫描和已隔离的文件都不能读取
+func checkAccessible(file *models.UploadFile) error {
+	switch {
+	case file.FilePath == "" || file.UploadStatus == constants.UploadStatusUploading || file.UploadStatus == constants.UploadStatusMerging:
+		return fmt.Errorf("文件尚未上传完成")
+	case file.UploadStatus == constants.UploadStatusPendingScan:
+		return fmt.Errorf("文件正在进行安全扫描")
+	case file.UploadStatus == constants.UploadStatusQuarantined:
+		return fmt.Errorf("文件未通过安全扫描，已被隔离")
+	}
+	return nil
+}
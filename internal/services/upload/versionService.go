@@ -0,0 +1,294 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+
+	"template/internal/models"
+	appConfig "template/pkg/config"
+	"template/pkg/constants"
+	"template/pkg/logger"
+	"template/pkg/upload"
+
+	"gorm.io/gorm"
+)
+
+// 文件版本错误，控制器据此返回对应的状态码
+var (
+	ErrVersionNotFound    = errors.New("版本不存在")
+	ErrFileNotCompleted   = errors.New("文件正在进行安全扫描或已被隔离，暂时不能修改版本")
+	ErrFileVersionChanged = errors.New("文件已被其他请求修改，请刷新后重试")
+
+	// errBlobReleased 引用的已有内容在此期间被释放
+	errBlobReleased = errors.New("文件内容已被删除")
+)
+
+// UploadFileVersion 上传文件的新版本
+func UploadFileVersion(fileID, userID string, header *multipart.FileHeader) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.UploadFileVersion(fileID, userID, header)
+}
+
+// ListFileVersions 获取用户的文件及其历史版本
+func ListFileVersions(fileID, userID string) (*models.UploadFile, []models.FileVersion, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.ListFileVersions(fileID, userID)
+}
+
+// RestoreFileVersion 将文件恢复为指定的历史版本
+func RestoreFileVersion(fileID, userID string, version int) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.RestoreFileVersion(fileID, userID, version)
+}
+
+// PruneFileVersions 删除超出保留数的历史版本
+func PruneFileVersions(fileID, userID string, keep *int) (int, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.PruneFileVersions(fileID, userID, keep)
+}
+
+// GetFileAtVersion 获取文件指定版本的内容信息
+func GetFileAtVersion(file *models.UploadFile, version int) (*models.UploadFile, error) {
+	if uploadService == nil {
+		InitUploadService()
+	}
+	return uploadService.GetFileAtVersion(file, version)
+}
+
+// UploadFileVersion 上传文件的新版本，当前内容保存为历史版本，文件ID、文件名和分享链接都不变
+// 新版本的扩展名和实际类型必须与文件名相符，按大小占用文件所有者的存储配额
+func (s *UploadService) UploadFileVersion(fileID, userID string, header *multipart.FileHeader) (*models.UploadFile, error) {
+	// 1. 验证文件
+	file, err := s.versionedFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.GetFileExtension(header.Filename) != file.Extension {
+		return nil, ErrFileExtensionChanged
+	}
+	if err := s.validateFile(header); err != nil {
+		return nil, err
+	}
+
+	// 2. 占用存储配额，历史版本仍然占用空间，失败时释放
+	if err := s.reserveQuota(file.UserID, header.Size); err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			s.releaseQuota(file.UserID, header.Size)
+		}
+	}()
+
+	// 3. 打开文件，根据文件头识别实际类型
+	src, err := header.Open()
+	if err != nil {
+		return nil, fmt.Errorf("无法打开文件: %v", err)
+	}
+	defer src.Close()
+
+	mimeType, err := upload.SniffContentType(file.Filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 计算文件哈希，已存储过相同内容时直接引用
+	digest, err := upload.CalculateFileDigest(src)
+	if err != nil {
+		return nil, fmt.Errorf("计算文件哈希失败: %v", err)
+	}
+	if blob, err := s.uploadRepo.GetBlobByHash(digest.SHA256); err == nil {
+		if err := s.replaceContent(file, blob, mimeType); !errors.Is(err, errBlobReleased) {
+			if err != nil {
+				return nil, err
+			}
+			committed = true
+			return file, nil
+		}
+		// 已有内容在此期间被删除时按新内容保存
+	}
+
+	// 5. 保存新内容
+	storedName := upload.GenerateStoredFilename(file.Filename)
+	filePath, saved, err := s.storage.SaveFile(storedName, src)
+	if err != nil {
+		return nil, fmt.Errorf("保存文件失败: %v", err)
+	}
+	blob := &models.FileBlob{
+		SHA256Hash: saved.SHA256,
+		MD5Hash:    saved.MD5,
+		StoredName: storedName,
+		FilePath:   filePath,
+		FileSize:   saved.Size,
+	}
+	if err := s.replaceContent(file, blob, mimeType); err != nil {
+		s.storage.DeleteFile(filePath)
+		return nil, err
+	}
+	committed = true
+	return file, nil
+}
+
+// ListFileVersions 获取用户的文件及其历史版本，历史版本按版本号从新到旧排序
+func (s *UploadService) ListFileVersions(fileID, userID string) (*models.UploadFile, []models.FileVersion, error) {
+	file, err := s.GetUserFile(fileID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	versions, err := s.uploadRepo.GetFileVersions(fileID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询历史版本失败: %v", err)
+	}
+	return file, versions, nil
+}
+
+// RestoreFileVersion 将历史版本的内容恢复为新的当前版本，当前内容保存为历史版本，被恢复的版本保留不变
+func (s *UploadService) RestoreFileVersion(fileID, userID string, version int) (*models.UploadFile, error) {
+	file, err := s.versionedFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.uploadRepo.GetFileVersion(fileID, version)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+	blob, err := s.uploadRepo.GetBlobByID(target.BlobID)
+	if err != nil {
+		return nil, fmt.Errorf("查询版本内容失败: %v", err)
+	}
+
+	if err := s.reserveQuota(file.UserID, blob.FileSize); err != nil {
+		return nil, err
+	}
+	if err := s.replaceContent(file, blob, target.MimeType); err != nil {
+		s.releaseQuota(file.UserID, blob.FileSize)
+		return nil, err
+	}
+	return file, nil
+}
+
+// PruneFileVersions 只保留最新的 keep 个历史版本，keep 为空时使用配置的保留数，返回删除的版本数
+func (s *UploadService) PruneFileVersions(fileID, userID string, keep *int) (int, error) {
+	file, err := s.GetUserFile(fileID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	retention := versionRetention()
+	if keep != nil {
+		retention = *keep
+	} else if retention <= 0 {
+		return 0, nil
+	}
+	return s.pruneVersions(file, retention)
+}
+
+// GetFileAtVersion 返回文件指定版本的内容信息，版本为当前版本时返回文件本身
+// 返回的记录只用于读取内容，除内容相关的字段外与文件相同
+func (s *UploadService) GetFileAtVersion(file *models.UploadFile, version int) (*models.UploadFile, error) {
+	if version == file.Version {
+		return file, nil
+	}
+	target, err := s.uploadRepo.GetFileVersion(file.ID.String(), version)
+	if err != nil {
+		return nil, ErrVersionNotFound
+	}
+
+	versioned := *file
+	versioned.BlobID = target.BlobID
+	versioned.FilePath = target.FilePath
+	versioned.FileSize = target.FileSize
+	versioned.MimeType = target.MimeType
+	versioned.MD5Hash = target.MD5Hash
+	versioned.SHA256Hash = target.SHA256Hash
+	versioned.UploadedAt = target.UploadedAt
+	versioned.Version = target.Version
+	return &versioned, nil
+}
+
+// replaceContent 将文件的当前内容保存为历史版本并替换为 blob 的内容
+// 替换后删除按旧内容生成的缩略图，按保留数删除旧版本，再重新扫描或发布文件
+func (s *UploadService) replaceContent(file *models.UploadFile, blob *models.FileBlob, mimeType string) error {
+	fileID := file.ID.String()
+
+	replaced, err := s.uploadRepo.SaveFileVersion(file, blob, mimeType, s.uploadedStatus())
+	if err != nil {
+		if !blob.ID.IsZero() && errors.Is(err, gorm.ErrRecordNotFound) {
+			return errBlobReleased
+		}
+		return fmt.Errorf("保存文件版本失败: %v", err)
+	}
+	if !replaced {
+		return ErrFileVersionChanged
+	}
+
+	derivatives, _ := s.uploadRepo.GetFileDerivatives(fileID)
+	if err := s.uploadRepo.DeleteFileDerivatives(fileID); err != nil {
+		logger.Error("删除缩略图记录失败", "fileID", fileID, "error", err)
+	} else {
+		s.deleteDerivativeFiles(derivatives)
+	}
+
+	if retention := versionRetention(); retention > 0 {
+		if _, err := s.pruneVersions(file, retention); err != nil {
+			logger.Error("删除旧版本失败", "fileID", fileID, "error", err)
+		}
+	}
+
+	logger.Info("文件已更新版本", "fileID", fileID, "version", file.Version)
+	s.afterUpload(file)
+	return nil
+}
+
+// pruneVersions 删除最新的 keep 个以外的历史版本及不再被引用的内容，返回删除的版本数
+func (s *UploadService) pruneVersions(file *models.UploadFile, keep int) (int, error) {
+	versions, err := s.uploadRepo.GetFileVersions(file.ID.String())
+	if err != nil {
+		return 0, fmt.Errorf("查询历史版本失败: %v", err)
+	}
+	if len(versions) <= keep {
+		return 0, nil
+	}
+
+	pruned := versions[keep:]
+	orphans, err := s.uploadRepo.DeleteFileVersions(file, pruned)
+	if err != nil {
+		return 0, fmt.Errorf("删除历史版本失败: %v", err)
+	}
+
+	paths := make([]string, 0, len(orphans))
+	for _, orphan := range orphans {
+		paths = append(paths, orphan.FilePath)
+	}
+	if err := s.deleteStoredFiles(paths); err != nil {
+		logger.Error("删除历史版本内容失败", "fileID", file.ID.String(), "error", err)
+	}
+	return len(pruned), nil
+}
+
+// versionedFile 获取可以修改版本的文件，待扫描和已隔离的文件不能修改
+func (s *UploadService) versionedFile(fileID, userID string) (*models.UploadFile, error) {
+	file, err := s.GetUserFile(fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if file.UploadStatus != constants.UploadStatusCompleted {
+		return nil, ErrFileNotCompleted
+	}
+	return file, nil
+}
+
+// versionRetention 每个文件保留的历史版本数，0表示不限制
+func versionRetention() int {
+	return max(appConfig.GetConfig().Upload.VersionRetention, 0)
+}
@@ -0,0 +1,318 @@
+package user
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"template/internal/models"
+	"template/internal/services/rbac"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+	"template/pkg/config"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 账号注销相关参数
+const (
+	defaultDeletionGraceDays = 7                  // 默认保留天数
+	anonymizedEmailSuffix    = "@deleted.invalid" // 匿名化后使用的邮箱后缀
+
+	deletedFilePolicyPurge    = "purge"    // 删除注销用户的文件
+	deletedFilePolicyReassign = "reassign" // 将注销用户的文件转移给指定用户
+)
+
+// UserExport 用户数据导出内容
+type UserExport struct {
+	Username string
+	Profile  map[string]interface{}
+	Files    []models.UploadFile
+}
+
+// BuildUserExport 收集用户的个人数据，写入压缩包之前完成所有查询，便于在输出响应前返回错误
+func BuildUserExport(userID string) (*UserExport, error) {
+	info, err := GetUserInfo(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := ListIdentities(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	apiKeys, err := ListAPIKeys(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := uploadService.ListUserFiles(userID)
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询上传文件失败")
+	}
+
+	username, _ := info["username"].(string)
+	return &UserExport{
+		Username: username,
+		Profile: map[string]interface{}{
+			"user":        info,
+			"identities":  identities,
+			"sessions":    sessions,
+			"api_keys":    apiKeys,
+			"exported_at": time.Now(),
+		},
+		Files: files,
+	}, nil
+}
+
+// WriteZip 将导出内容写为zip压缩包：profile.json、uploads.json 以及 files/ 目录下的文件内容
+func (e *UserExport) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	if err := writeZipJSON(zw, "profile.json", e.Profile); err != nil {
+		return err
+	}
+	if err := writeZipJSON(zw, "uploads.json", e.Files); err != nil {
+		return err
+	}
+
+	for i := range e.Files {
+		file := &e.Files[i]
+		src, err := uploadService.OpenStoredFile(file)
+		if err != nil {
+			// 未上传完成或已丢失的文件只保留元数据
+			log.Printf("导出文件跳过 %s: %v", file.ID.String(), err)
+			continue
+		}
+
+		name := fmt.Sprintf("files/%s/%s", file.ID.String(), path.Base(strings.ReplaceAll(file.Filename, "\\", "/")))
+		dst, err := zw.Create(name)
+		if err == nil {
+			_, err = io.Copy(dst, src)
+		}
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeZipJSON 向压缩包写入一个JSON文件
+func writeZipJSON(zw *zip.Writer, name string, value interface{}) error {
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(dst)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// SendDeleteAccountCode 向当前邮箱发送注销账号验证码
+func SendDeleteAccountCode(userID string) error {
+	userRow, err := findDeletableUser(userID)
+	if err != nil {
+		return err
+	}
+
+	code := generateVerificationCode(userRow.Email, common.CodeTypeDeleteAccount)
+	if code == "" {
+		return errors.New(errors.CodeInternal, "生成验证码失败")
+	}
+
+	if err := sendVerificationEmail(userRow.Email, code, common.CodeTypeDeleteAccount); err != nil {
+		return fmt.Errorf("发送验证码失败: %v", err)
+	}
+
+	return nil
+}
+
+// DeleteAccount 注销账号
+// 账号立即不可登录，保留期结束后由定时任务匿名化个人信息，保留期内可联系管理员恢复
+func DeleteAccount(userID, code string) (time.Time, error) {
+	db := database.GetDB()
+	if db == nil {
+		return time.Time{}, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	userRow, err := findDeletableUser(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !ValidateCode(userRow.Email, code, common.CodeTypeDeleteAccount) {
+		return time.Time{}, errors.New(errors.CodeInvalidVerifyCode, "验证码无效或已过期")
+	}
+
+	now := time.Now()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"status":                common.UserStatusDeleted,
+			"deletion_requested_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", userID).Delete(&models.User{}).Error
+	})
+	if err != nil {
+		return time.Time{}, errors.New(errors.CodeDBTransaction, "注销账号失败")
+	}
+
+	revokeUserAccess(userID)
+	return now.Add(deletionGracePeriod()), nil
+}
+
+// PurgeDeletedAccounts 匿名化保留期已过的注销账号，返回处理的账号数量
+func PurgeDeletedAccounts() (int, error) {
+	db := database.GetDB()
+	if db == nil {
+		return 0, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var userIDs []string
+	err := db.Unscoped().Model(&models.User{}).
+		Where("deletion_requested_at IS NOT NULL AND deletion_requested_at < ? AND anonymized_at IS NULL",
+			time.Now().Add(-deletionGracePeriod())).
+		Pluck("id", &userIDs).Error
+	if err != nil {
+		return 0, errors.New(errors.CodeQueryFailed, "查询待匿名化账号失败")
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		if err := anonymizeUser(userID); err != nil {
+			log.Printf("匿名化账号 %s 失败: %v", userID, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// anonymizeUser 处理注销用户的文件，清除个人信息和关联数据
+func anonymizeUser(userID string) error {
+	db := database.GetDB()
+
+	if err := handleDeletedUserFiles(userID); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 删除关联数据，第三方身份需要物理删除以释放唯一索引
+		for _, model := range []interface{}{
+			&models.UserIdentity{},
+			&models.APIKey{},
+			&models.UserSession{},
+			&models.UserRecoveryCode{},
+			&models.UserRole{},
+			&models.FilePermission{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		// 保留记录本身以维持外键引用，清空所有可识别个人身份的字段；空密码无法通过校验
+		return tx.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"username":           "deleted_" + strings.ReplaceAll(userID, "-", ""),
+			"email":              userID + anonymizedEmailSuffix,
+			"password":           "",
+			"avatar":             "",
+			"bio":                "",
+			"two_factor_enabled": false,
+			"two_factor_secret":  "",
+			"anonymized_at":      time.Now(),
+		}).Error
+	})
+}
+
+// handleDeletedUserFiles 按配置删除或转移注销用户的文件
+func handleDeletedUserFiles(userID string) error {
+	cfg := config.GetConfig().Account
+
+	if cfg.DeletedFilePolicy != deletedFilePolicyReassign {
+		_, err := uploadService.PurgeUserFiles(userID)
+		return err
+	}
+
+	targetID, err := findReassignTarget(cfg.ReassignFilesTo)
+	if err != nil {
+		return err
+	}
+
+	_, err = uploadService.ReassignUserFiles(userID, targetID)
+	return err
+}
+
+// ValidateAccountConfig 检查注销用户文件的处理方式，转移文件时接收文件的用户必须存在
+// 启动时调用，避免保留期结束后才发现配置错误
+func ValidateAccountConfig() error {
+	cfg := config.GetConfig().Account
+	switch cfg.DeletedFilePolicy {
+	case "", deletedFilePolicyPurge:
+		return nil
+	case deletedFilePolicyReassign:
+		_, err := findReassignTarget(cfg.ReassignFilesTo)
+		return err
+	default:
+		return fmt.Errorf("不支持的注销用户文件处理方式: %s", cfg.DeletedFilePolicy)
+	}
+}
+
+// findReassignTarget 根据用户名查询接收文件的用户ID，已删除的用户不能接收文件
+func findReassignTarget(username string) (string, error) {
+	if username == "" {
+		return "", fmt.Errorf("未配置接收文件的用户")
+	}
+
+	var ids []string
+	err := database.GetDB().Model(&models.User{}).Where("username = ?", username).Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return "", fmt.Errorf("查询接收文件的用户失败: %v", err)
+	}
+	if len(ids) == 0 {
+		return "", fmt.Errorf("接收文件的用户 %s 不存在", username)
+	}
+	return ids[0], nil
+}
+
+// findDeletableUser 查询可以注销的用户
+func findDeletableUser(userID string) (*loginUserRow, error) {
+	userRow, err := findTwoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(userRow.Email, oauthPlaceholderEmail) {
+		return nil, errors.New(errors.CodeInvalidParameter, "账号未绑定可用邮箱，请先修改邮箱后再注销")
+	}
+
+	// 超级管理员需要先由其他超级管理员取消角色，避免系统失去管理员
+	if rbac.HasRole(userID, common.RoleNameSuperAdmin) {
+		return nil, errors.New(errors.CodeForbidden, "超级管理员账号不能注销")
+	}
+
+	return userRow, nil
+}
+
+// deletionGracePeriod 获取注销保留期
+func deletionGracePeriod() time.Duration {
+	days := config.GetConfig().Account.DeletionGraceDays
+	if days <= 0 {
+		days = defaultDeletionGraceDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
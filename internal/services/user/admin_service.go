@@ -0,0 +1,341 @@
+package user
+
+import (
+	"log"
+	"strings"
+	"template/internal/models"
+	"template/internal/services/rbac"
+	"template/pkg/common"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"template/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+// 管理级别，用于判断操作者能否管理目标用户
+const (
+	manageLevelUser = iota + 1
+	manageLevelAdmin
+	manageLevelSuperAdmin
+)
+
+// tempPasswordLength 管理员重置密码时自动生成的临时密码长度
+const tempPasswordLength = 12
+
+// UserFilter 管理员查询用户的筛选条件
+type UserFilter struct {
+	Keyword string // 按用户名或邮箱模糊搜索
+	Status  int    // 用户状态，0表示不限
+	Role    int    // 用户角色，0表示不限
+	Deleted bool   // 只查询已删除的用户
+}
+
+// ManagedUser 管理员查看的用户信息
+type ManagedUser struct {
+	ID                     string  `db:"id"`
+	Username               string  `db:"username"`
+	Email                  string  `db:"email"`
+	Avatar                 string  `db:"avatar"`
+	Bio                    string  `db:"bio"`
+	Status                 int     `db:"status"`
+	Role                   int     `db:"role"`
+	TwoFactorEnabled       bool    `db:"two_factor_enabled"`
+	PasswordChangeRequired bool    `db:"password_change_required"`
+	CreatedAt              string  `db:"created_at"`
+	UpdatedAt              string  `db:"updated_at"`
+	DeletedAt              *string `db:"deleted_at"`
+}
+
+// managedUserColumns 管理员查询用户的字段列表
+const managedUserColumns = "id, username, email, avatar, bio, status, role, two_factor_enabled, password_change_required, created_at, updated_at, deleted_at"
+
+// ListUsers 分页查询用户
+func ListUsers(filter UserFilter, page *common.PaginationRequest) ([]ManagedUser, int64, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, 0, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	// 使用 Table 查询并扫描到普通结构体，避免UUID扫描问题
+	query := db.Table("user")
+	if filter.Deleted {
+		query = query.Where("deleted_at IS NOT NULL")
+	} else {
+		query = query.Where("deleted_at IS NULL")
+	}
+	if keyword := strings.TrimSpace(filter.Keyword); keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("username LIKE ? OR email LIKE ?", like, like)
+	}
+	if filter.Status > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Role > 0 {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New(errors.CodeQueryFailed, "查询用户失败")
+	}
+
+	users := []ManagedUser{}
+	err := query.Select(managedUserColumns).
+		Order("created_at DESC").
+		Offset(page.GetOffset()).
+		Limit(page.GetPageSize()).
+		Scan(&users).Error
+	if err != nil {
+		return nil, 0, errors.New(errors.CodeQueryFailed, "查询用户失败")
+	}
+
+	return users, total, nil
+}
+
+// GetManagedUser 获取用户详情，包括已删除的用户
+func GetManagedUser(userID string) (*ManagedUser, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var user ManagedUser
+	err := db.Raw("SELECT "+managedUserColumns+" FROM user WHERE id = ? LIMIT 1", userID).Scan(&user).Error
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "数据库查询失败")
+	}
+	if user.ID == "" {
+		return nil, errors.New(errors.CodeUserNotFound, "用户不存在")
+	}
+	return &user, nil
+}
+
+// CreateUserByAdmin 管理员创建普通用户，角色需要由超级管理员另行调整
+func CreateUserByAdmin(username, email, password string) (*ManagedUser, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	// 已删除的用户仍占用用户名和邮箱，恢复时不会冲突
+	var count int64
+	db.Unscoped().Model(&models.User{}).Where("username = ?", username).Count(&count)
+	if count > 0 {
+		return nil, errors.New(errors.CodeUserExists, "用户名已被使用")
+	}
+	db.Unscoped().Model(&models.User{}).Where("email = ?", email).Count(&count)
+	if count > 0 {
+		return nil, errors.New(errors.CodeEmailExists, "邮箱已被注册")
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "密码加密失败")
+	}
+
+	user := models.User{
+		Username: username,
+		Email:    email,
+		Password: hashedPassword,
+		Status:   common.UserStatusNormal,
+		Role:     common.UserRoleUser,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return nil, errors.New(errors.CodeInternal, "创建用户失败")
+	}
+
+	return GetManagedUser(user.ID.String())
+}
+
+// SetUserStatus 启用或禁用用户，禁用后立即吊销该用户的所有令牌和会话
+func SetUserStatus(operatorID, userID string, status int) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	target, err := findManageableUser(operatorID, userID)
+	if err != nil {
+		return err
+	}
+	if target.DeletedAt != nil {
+		return errors.New(errors.CodeConflict, "用户已被删除，请先恢复")
+	}
+
+	if err := db.Model(&models.User{}).Where("id = ?", userID).Update("status", status).Error; err != nil {
+		return errors.New(errors.CodeInternal, "更新用户状态失败")
+	}
+
+	if status != common.UserStatusNormal {
+		revokeUserAccess(userID)
+	}
+	return nil
+}
+
+// DeleteUserByAdmin 软删除用户，可通过 RestoreUser 恢复
+func DeleteUserByAdmin(operatorID, userID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	target, err := findManageableUser(operatorID, userID)
+	if err != nil {
+		return err
+	}
+	if target.DeletedAt != nil {
+		return errors.New(errors.CodeConflict, "用户已被删除")
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("status", common.UserStatusDeleted).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", userID).Delete(&models.User{}).Error
+	})
+	if err != nil {
+		return errors.New(errors.CodeDBTransaction, "删除用户失败")
+	}
+
+	revokeUserAccess(userID)
+	return nil
+}
+
+// RestoreUser 恢复已删除的用户
+func RestoreUser(operatorID, userID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	target, err := findManageableUser(operatorID, userID)
+	if err != nil {
+		return err
+	}
+	if target.DeletedAt == nil {
+		return errors.New(errors.CodeConflict, "用户未被删除")
+	}
+
+	var anonymized int64
+	db.Unscoped().Model(&models.User{}).Where("id = ? AND anonymized_at IS NOT NULL", userID).Count(&anonymized)
+	if anonymized > 0 {
+		return errors.New(errors.CodeConflict, "用户信息已匿名化，无法恢复")
+	}
+
+	// 恢复同时撤销用户的注销申请
+	err = db.Unscoped().Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"deleted_at":            nil,
+		"deletion_requested_at": nil,
+		"status":                common.UserStatusNormal,
+	}).Error
+	if err != nil {
+		return errors.New(errors.CodeInternal, "恢复用户失败")
+	}
+	return nil
+}
+
+// ChangeUserRole 修改用户的内置角色，仅超级管理员可调用
+func ChangeUserRole(operatorID, userID string, role int) error {
+	if operatorID == userID {
+		return errors.New(errors.CodeForbidden, "不能修改自己的角色")
+	}
+
+	target, err := GetManagedUser(userID)
+	if err != nil {
+		return err
+	}
+	if target.DeletedAt != nil {
+		return errors.New(errors.CodeConflict, "用户已被删除，请先恢复")
+	}
+
+	return rbac.SetSystemRole(userID, common.LegacyRoleName(role))
+}
+
+// ForcePasswordReset 管理员重置用户密码，用户登录后需要先修改密码
+// 未指定新密码时自动生成临时密码并返回
+func ForcePasswordReset(operatorID, userID, password string) (string, error) {
+	db := database.GetDB()
+	if db == nil {
+		return "", errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	target, err := findManageableUser(operatorID, userID)
+	if err != nil {
+		return "", err
+	}
+	if target.DeletedAt != nil {
+		return "", errors.New(errors.CodeConflict, "用户已被删除，请先恢复")
+	}
+
+	generated := ""
+	if password == "" {
+		if generated, err = utils.GenerateTempPassword(tempPasswordLength); err != nil {
+			return "", errors.New(errors.CodeInternal, "生成临时密码失败")
+		}
+		password = generated
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return "", errors.New(errors.CodeInternal, "密码加密失败")
+	}
+
+	err = db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password":                 hashedPassword,
+		"password_change_required": true,
+	}).Error
+	if err != nil {
+		return "", errors.New(errors.CodeInternal, "重置密码失败")
+	}
+
+	revokeUserAccess(userID)
+	return generated, nil
+}
+
+// findManageableUser 查询目标用户并检查操作者是否有权管理
+// 不能管理自己；管理员只能管理普通用户，超级管理员可以管理其他所有用户
+func findManageableUser(operatorID, userID string) (*ManagedUser, error) {
+	if operatorID == userID {
+		return nil, errors.New(errors.CodeForbidden, "不能对自己执行此操作")
+	}
+
+	target, err := GetManagedUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	operatorLevel := manageLevel(operatorID)
+	if operatorLevel != manageLevelSuperAdmin && manageLevel(userID) >= operatorLevel {
+		return nil, errors.New(errors.CodeForbidden, "不能管理同级或更高级别的管理员")
+	}
+
+	return target, nil
+}
+
+// manageLevel 获取用户的管理级别
+func manageLevel(userID string) int {
+	authz, err := rbac.GetUserAuthorization(userID)
+	if err != nil {
+		return manageLevelUser
+	}
+
+	switch {
+	case authz.HasRole(common.RoleNameSuperAdmin):
+		return manageLevelSuperAdmin
+	case authz.HasRole(common.RoleNameAdmin):
+		return manageLevelAdmin
+	default:
+		return manageLevelUser
+	}
+}
+
+// revokeUserAccess 吊销用户的所有令牌和登录会话
+func revokeUserAccess(userID string) {
+	if err := common.RevokeUserTokens(userID); err != nil {
+		log.Printf("吊销用户令牌失败: %v", err)
+	}
+	if _, err := RevokeOtherSessions(userID, ""); err != nil {
+		log.Printf("吊销用户会话失败: %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"template/internal/models"
+	"template/pkg/common"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"time"
+)
+
+// API密钥相关参数
+const (
+	apiKeyMaxPerUser    = 20          // 每个用户最多可创建的密钥数量
+	apiKeyRandomBytes   = 24          // 密钥随机部分的字节数
+	apiKeyDisplayLength = 12          // 列表中展示的密钥前缀长度
+	apiKeyTouchInterval = time.Minute // 最后使用时间的更新间隔，避免每次请求都写库
+)
+
+// CreateAPIKey 创建API密钥，明文密钥只在创建时返回一次
+func CreateAPIKey(userID, name string, scopes []string, expiresInDays int) (*models.APIKey, string, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, "", errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var count int64
+	db.Model(&models.APIKey{}).Where("user_id = ?", userID).Count(&count)
+	if count >= apiKeyMaxPerUser {
+		return nil, "", errors.New(errors.CodeInvalidParameter, "API密钥数量已达上限")
+	}
+
+	buf := make([]byte, apiKeyRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", errors.New(errors.CodeInternal, "生成密钥失败")
+	}
+	plaintext := common.APIKeyPrefix + hex.EncodeToString(buf)
+
+	key := &models.APIKey{
+		UserID:  userID,
+		Name:    name,
+		Prefix:  plaintext[:apiKeyDisplayLength],
+		KeyHash: hashAPIKey(plaintext),
+		Scopes:  strings.Join(scopes, ","),
+	}
+	if expiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, expiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := db.Create(key).Error; err != nil {
+		return nil, "", errors.New(errors.CodeInternal, "创建API密钥失败")
+	}
+
+	return key, plaintext, nil
+}
+
+// ListAPIKeys 获取用户的API密钥列表
+func ListAPIKeys(userID string) ([]models.APIKey, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var keys []models.APIKey
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询API密钥失败")
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey 删除API密钥，删除后立即失效
+func RevokeAPIKey(userID, keyID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	result := db.Where("id = ? AND user_id = ?", keyID, userID).Delete(&models.APIKey{})
+	if result.Error != nil {
+		return errors.New(errors.CodeInternal, "删除API密钥失败")
+	}
+	if result.RowsAffected == 0 {
+		return errors.New(errors.CodeNotFound, "API密钥不存在")
+	}
+	return nil
+}
+
+// AuthenticateAPIKey 校验API密钥，返回与JWT认证相同结构的用户信息，供认证中间件调用
+func AuthenticateAPIKey(plaintext, ip string) (*common.JWTClaims, error) {
+	if !strings.HasPrefix(plaintext, common.APIKeyPrefix) {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "API密钥格式错误")
+	}
+
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var key models.APIKey
+	if err := db.Where("key_hash = ?", hashAPIKey(plaintext)).First(&key).Error; err != nil {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "API密钥无效")
+	}
+
+	if key.IsExpired() {
+		return nil, errors.New(errors.CodeExpiredAuthToken, "API密钥已过期")
+	}
+
+	// 使用原始SQL查询来避免UUID扫描问题
+	var userRow struct {
+		ID       string `db:"id"`
+		Username string `db:"username"`
+		Status   int    `db:"status"`
+		Role     int    `db:"role"`
+
+		PasswordChangeRequired bool `db:"password_change_required"`
+	}
+	err := db.Raw("SELECT id, username, status, role, password_change_required FROM user WHERE id = ? LIMIT 1", key.UserID).Scan(&userRow).Error
+	if err != nil || userRow.ID == "" {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "API密钥所属用户不存在")
+	}
+
+	if userRow.Status != common.UserStatusNormal {
+		return nil, errors.New(errors.CodeUserDisabled, "账号已被禁用")
+	}
+
+	touchAPIKey(key.ID.String(), ip)
+
+	claims := &common.JWTClaims{
+		UserID:   userRow.ID,
+		Username: userRow.Username,
+		Role:     userRow.Role,
+		AuthType: common.AuthTypeAPIKey,
+		Scopes:   key.ScopeList(),
+
+		PasswordChangeRequired: userRow.PasswordChangeRequired,
+	}
+	claims.ID = key.ID.String()
+	return claims, nil
+}
+
+// touchAPIKey 更新最后使用时间，同一密钥一分钟内最多更新一次
+func touchAPIKey(keyID, ip string) {
+	now := time.Now()
+	database.GetDB().Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", keyID, now.Add(-apiKeyTouchInterval)).
+		Updates(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		})
+}
+
+// normalizeScopes 校验并去重授权范围
+func normalizeScopes(scopes []string) ([]string, error) {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !common.IsValidScope(scope) {
+			return nil, errors.New(errors.CodeInvalidParameter, "无效的授权范围: "+scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New(errors.CodeInvalidParameter, "至少需要一个授权范围")
+	}
+	return result, nil
+}
+
+// hashAPIKey 计算API密钥哈希，数据库中不保存明文
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+package user
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"template/pkg/cache"
+	"template/pkg/config"
+	"template/pkg/constants"
+	"template/pkg/errors"
+	"template/pkg/utils"
+	"time"
+)
+
+// 登录防护默认参数，配置为0时使用
+const (
+	defaultLoginMaxAttempts   = 5
+	defaultLoginIPMaxAttempts = 20
+	defaultLoginLockMinutes   = 15
+)
+
+// 渐进延迟参数：连续失败达到阈值后，每次失败的响应延迟翻倍，直到上限
+const (
+	loginDelayThreshold = 3
+	loginDelayBase      = 500 * time.Millisecond
+	loginDelayMax       = 4 * time.Second
+)
+
+var (
+	dummyPasswordHash string
+	dummyPasswordOnce sync.Once
+)
+
+// loginLimits 获取登录防护参数
+func loginLimits() (accountMax, ipMax int64, window time.Duration) {
+	cfg := config.GetConfig().Security
+
+	accountMax, ipMax = defaultLoginMaxAttempts, defaultLoginIPMaxAttempts
+	if cfg.LoginMaxAttempts > 0 {
+		accountMax = int64(cfg.LoginMaxAttempts)
+	}
+	if cfg.LoginIPMaxAttempts > 0 {
+		ipMax = int64(cfg.LoginIPMaxAttempts)
+	}
+
+	window = defaultLoginLockMinutes * time.Minute
+	if cfg.LoginLockMinutes > 0 {
+		window = time.Duration(cfg.LoginLockMinutes) * time.Minute
+	}
+	return accountMax, ipMax, window
+}
+
+// loginGuardKey 计算账号的防护标识，已存在的账号按用户ID统计，用户名和邮箱共享计数
+func loginGuardKey(userID, account string) string {
+	if userID != "" {
+		return userID
+	}
+	return "unknown:" + strings.ToLower(strings.TrimSpace(account))
+}
+
+// checkIPLoginAllowed 检查IP是否因失败次数过多被临时封禁
+func checkIPLoginAllowed(ip string) error {
+	if ip == "" {
+		return nil
+	}
+
+	_, ipMax, _ := loginLimits()
+	key := fmt.Sprintf(constants.CacheKeyLoginIP, ip)
+	if count := getCounter(key); count >= ipMax {
+		return lockedError(key, "IP登录失败次数过多")
+	}
+	return nil
+}
+
+// checkAccountLoginAllowed 检查账号是否被临时锁定
+func checkAccountLoginAllowed(guardKey string) error {
+	key := fmt.Sprintf(constants.CacheKeyLoginLock, guardKey)
+	if cache.Exists(key) {
+		return lockedError(key, "账号已被临时锁定")
+	}
+	return nil
+}
+
+// recordLoginFailure 记录一次登录失败，达到上限时锁定账号，并按失败次数延迟响应
+// 始终返回统一的"账号或密码错误"，锁定在下一次登录时才会提示
+func recordLoginFailure(guardKey, ip string) error {
+	accountMax, _, window := loginLimits()
+
+	if ip != "" {
+		if _, err := cache.Incr(fmt.Sprintf(constants.CacheKeyLoginIP, ip), window); err != nil {
+			log.Printf("记录IP登录失败次数失败: %v", err)
+		}
+	}
+
+	failures, err := cache.Incr(fmt.Sprintf(constants.CacheKeyLoginAttempt, guardKey), window)
+	if err != nil {
+		log.Printf("记录账号登录失败次数失败: %v", err)
+	}
+
+	if failures >= accountMax {
+		lockKey := fmt.Sprintf(constants.CacheKeyLoginLock, guardKey)
+		if err := cache.Set(lockKey, time.Now().Format(time.RFC3339), window); err != nil {
+			log.Printf("锁定账号失败: %v", err)
+		}
+		_ = cache.Del(fmt.Sprintf(constants.CacheKeyLoginAttempt, guardKey))
+	}
+
+	if delay := loginFailureDelay(failures); delay > 0 {
+		time.Sleep(delay)
+	}
+
+	return errors.New(errors.CodeInvalidCredential, "账号或密码错误")
+}
+
+// clearLoginFailures 登录成功后清除账号的失败计数
+func clearLoginFailures(guardKey string) {
+	_ = cache.Del(fmt.Sprintf(constants.CacheKeyLoginAttempt, guardKey))
+}
+
+// UnlockAccount 管理员解除账号的登录锁定，与其他管理操作一样只能解锁自己有权管理的用户
+func UnlockAccount(operatorID, userID string) error {
+	if _, err := findManageableUser(operatorID, userID); err != nil {
+		return err
+	}
+
+	if err := cache.Del(fmt.Sprintf(constants.CacheKeyLoginLock, userID)); err != nil {
+		return errors.New(errors.CodeRedisError, "解除锁定失败")
+	}
+	_ = cache.Del(fmt.Sprintf(constants.CacheKeyLoginAttempt, userID))
+
+	return nil
+}
+
+// loginFailureDelay 计算失败后的响应延迟
+func loginFailureDelay(failures int64) time.Duration {
+	if failures < loginDelayThreshold {
+		return 0
+	}
+
+	delay := loginDelayBase << uint(failures-loginDelayThreshold)
+	if delay <= 0 || delay > loginDelayMax {
+		return loginDelayMax
+	}
+	return delay
+}
+
+// lockedError 构造锁定错误，附带剩余锁定时间
+func lockedError(key, reason string) error {
+	retryAfter := 60
+	if ttl, err := cache.TTL(key); err == nil && ttl > 0 {
+		retryAfter = int(ttl.Seconds()) + 1
+	}
+
+	return errors.New(errors.CodeRateLimited, fmt.Sprintf("%s，%d秒后重试", reason, retryAfter)).
+		WithMetadata("retry_after", retryAfter)
+}
+
+// getCounter 读取计数器的值
+func getCounter(key string) int64 {
+	value, err := cache.Get(key)
+	if err != nil {
+		return 0
+	}
+
+	count, _ := strconv.ParseInt(value, 10, 64)
+	return count
+}
+
+// compareDummyPassword 账号不存在时也执行一次密码比对，使响应时间与密码错误时相近
+func compareDummyPassword(password string) {
+	dummyPasswordOnce.Do(func() {
+		dummyPasswordHash, _ = utils.HashPassword("dummy-password-for-timing")
+	})
+	utils.ComparePasswords(dummyPasswordHash, password)
+}
@@ -0,0 +1,454 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"template/internal/models"
+	"template/pkg/cache"
+	"template/pkg/common"
+	"template/pkg/config"
+	"template/pkg/constants"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"template/pkg/oauth"
+	"template/pkg/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 第三方登录相关参数
+const (
+	oauthExchangeTimeout  = 15 * time.Second // 请求第三方接口的超时时间
+	oauthPlaceholderEmail = "@oauth.invalid" // 第三方未提供可用邮箱时使用的占位邮箱后缀
+	oauthUsernameMaxLen   = 20               // 自动生成的用户名最大长度，与注册规则一致
+)
+
+// OAuthStateTTL 授权请求有效期，浏览器绑定Cookie的有效期与之相同
+const OAuthStateTTL = 10 * time.Minute
+
+// usernameInvalidChars 用户名中不允许的字符
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_\-]`)
+
+// oauthState 授权请求状态，保存在缓存中，回调时取出并删除
+type oauthState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	RedirectURI  string `json:"redirect_uri"`
+	LinkUserID   string `json:"link_user_id,omitempty"` // 不为空表示为已登录用户绑定身份
+	BindingHash  string `json:"binding_hash"`           // 浏览器绑定值的哈希，回调时校验，防止授权状态被转交给其他浏览器
+}
+
+// OAuthAuthorization 发起授权的结果
+type OAuthAuthorization struct {
+	AuthURL string // 第三方授权地址
+	Binding string // 浏览器绑定值，需要写入发起授权的浏览器的Cookie，回调时原样提交
+}
+
+// OAuthCallbackResult 第三方登录回调结果
+type OAuthCallbackResult struct {
+	Linked bool         // 绑定流程，只完成身份绑定，不签发令牌
+	Login  *LoginResult // 登录流程的登录结果
+}
+
+// BeginOAuth 生成第三方授权地址和浏览器绑定值，linkUserID不为空时表示为该用户绑定身份
+func BeginOAuth(providerName, redirectURI, linkUserID string) (*OAuthAuthorization, error) {
+	provider, err := oauth.GetProvider(providerName)
+	if err != nil {
+		return nil, errors.New(errors.CodeNotFound, err.Error())
+	}
+
+	req, err := oauth.NewAuthRequest(redirectURI)
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "生成授权参数失败")
+	}
+	binding, err := oauth.GenerateRandomString(32)
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "生成授权参数失败")
+	}
+
+	state := oauthState{
+		Provider:     providerName,
+		Nonce:        req.Nonce,
+		CodeVerifier: req.CodeVerifier,
+		RedirectURI:  redirectURI,
+		LinkUserID:   linkUserID,
+		BindingHash:  hashOAuthBinding(binding),
+	}
+	data, _ := json.Marshal(state)
+	if err := cache.Set(fmt.Sprintf(constants.CacheKeyOAuthState, req.State), string(data), OAuthStateTTL); err != nil {
+		return nil, errors.New(errors.CodeRedisError, "保存授权状态失败")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oauthExchangeTimeout)
+	defer cancel()
+
+	authURL, err := provider.AuthCodeURL(ctx, *req)
+	if err != nil {
+		return nil, errors.New(errors.CodeThirdPartyService, err.Error())
+	}
+
+	return &OAuthAuthorization{AuthURL: authURL, Binding: binding}, nil
+}
+
+// CompleteOAuth 处理第三方回调，校验状态和浏览器绑定值并换取身份，然后完成登录或绑定
+// binding 为发起授权时写入浏览器Cookie的绑定值，与授权状态不匹配时拒绝，防止登录CSRF和绑定流程被劫持
+func CompleteOAuth(providerName, stateValue, binding, code string, client ClientInfo) (*OAuthCallbackResult, error) {
+	state, err := consumeOAuthState(stateValue)
+	if err != nil {
+		return nil, err
+	}
+
+	if binding == "" || subtle.ConstantTimeCompare([]byte(hashOAuthBinding(binding)), []byte(state.BindingHash)) != 1 {
+		return nil, errors.New(errors.CodeInvalidParameter, "授权请求不是由当前浏览器发起的，请重新登录")
+	}
+
+	if state.Provider != providerName {
+		return nil, errors.New(errors.CodeInvalidParameter, "授权状态与登录方式不匹配")
+	}
+
+	provider, err := oauth.GetProvider(providerName)
+	if err != nil {
+		return nil, errors.New(errors.CodeNotFound, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oauthExchangeTimeout)
+	defer cancel()
+
+	identity, err := provider.Exchange(ctx, code, oauth.AuthRequest{
+		State:        stateValue,
+		Nonce:        state.Nonce,
+		CodeVerifier: state.CodeVerifier,
+		RedirectURI:  state.RedirectURI,
+	})
+	if err != nil {
+		log.Printf("第三方登录换取身份失败 [%s]: %v", providerName, err)
+		return nil, errors.New(errors.CodeThirdPartyService, "第三方登录失败，请重试")
+	}
+
+	if state.LinkUserID != "" {
+		if err := linkIdentity(state.LinkUserID, identity); err != nil {
+			return nil, err
+		}
+		return &OAuthCallbackResult{Linked: true}, nil
+	}
+
+	result, err := loginWithIdentity(identity, client)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthCallbackResult{Login: result}, nil
+}
+
+// ListIdentities 获取用户绑定的第三方身份
+func ListIdentities(userID string) ([]models.UserIdentity, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var identities []models.UserIdentity
+	if err := db.Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询绑定信息失败")
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity 解绑第三方身份
+func UnlinkIdentity(userID, identityID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var identity models.UserIdentity
+	if err := db.Where("id = ? AND user_id = ?", identityID, userID).First(&identity).Error; err != nil {
+		return errors.New(errors.CodeNotFound, "绑定信息不存在")
+	}
+
+	// 自动创建且没有真实邮箱的账号，解绑最后一个身份后将无法再登录
+	var userRow struct {
+		Email string `db:"email"`
+	}
+	db.Raw("SELECT email FROM user WHERE id = ? LIMIT 1", userID).Scan(&userRow)
+	if strings.HasSuffix(userRow.Email, oauthPlaceholderEmail) {
+		var count int64
+		db.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&count)
+		if count <= 1 {
+			return errors.New(errors.CodeForbidden, "请先绑定邮箱后再解绑")
+		}
+	}
+
+	// 物理删除，便于之后重新绑定
+	if err := db.Unscoped().Delete(&identity).Error; err != nil {
+		return errors.New(errors.CodeInternal, "解绑失败")
+	}
+	return nil
+}
+
+// consumeOAuthState 取出并删除授权状态，每个状态只能使用一次
+func consumeOAuthState(stateValue string) (*oauthState, error) {
+	if stateValue == "" {
+		return nil, errors.New(errors.CodeInvalidParameter, "缺少授权状态")
+	}
+
+	key := fmt.Sprintf(constants.CacheKeyOAuthState, stateValue)
+	data, err := cache.Get(key)
+	if err != nil || data == "" {
+		return nil, errors.New(errors.CodeInvalidParameter, "授权状态无效或已过期，请重新登录")
+	}
+	_ = cache.Del(key)
+
+	var state oauthState
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, errors.New(errors.CodeInvalidParameter, "授权状态无效")
+	}
+	return &state, nil
+}
+
+// hashOAuthBinding 计算浏览器绑定值的哈希，缓存中只保存哈希
+func hashOAuthBinding(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(sum[:])
+}
+
+// loginWithIdentity 使用第三方身份登录，依次尝试已绑定身份、已验证邮箱匹配和自动注册
+func loginWithIdentity(identity *oauth.Identity, client ClientInfo) (*LoginResult, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var userID string
+	var existing models.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&existing).Error
+	switch {
+	case err == nil:
+		userID = existing.UserID
+	case err == gorm.ErrRecordNotFound:
+		userID, err = findOrProvisionUser(identity)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New(errors.CodeQueryFailed, "查询绑定信息失败")
+	}
+
+	userRow, err := findTwoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userRow.Status != common.UserStatusNormal {
+		return nil, errors.New(errors.CodeUserDisabled, "账号已被禁用")
+	}
+
+	touchIdentity(identity)
+
+	subject := common.TokenSubject{
+		UserID:   userRow.ID,
+		Username: userRow.Username,
+		Role:     userRow.Role,
+
+		PasswordChangeRequired: userRow.PasswordChangeRequired,
+	}
+
+	// 第三方登录同样需要通过本站的两步验证
+	if userRow.TwoFactorEnabled {
+		return beginTwoFactorChallenge(subject, userRow.toUserInfo())
+	}
+
+	tokens, err := issueLoginTokens(subject, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		UserInfo:               userRow.toUserInfo(),
+		Tokens:                 tokens,
+		TwoFactorSetupRequired: config.GetConfig().Security.IsTwoFactorRequired(userRow.Role),
+	}, nil
+}
+
+// findOrProvisionUser 未绑定的身份：已验证邮箱匹配到本地账号时自动绑定，否则自动注册
+func findOrProvisionUser(identity *oauth.Identity) (string, error) {
+	db := database.GetDB()
+
+	if identity.Email != "" && identity.EmailVerified {
+		var userRow struct {
+			ID string `db:"id"`
+		}
+		db.Raw("SELECT id FROM user WHERE email = ? LIMIT 1", identity.Email).Scan(&userRow)
+		if userRow.ID != "" {
+			if err := createIdentity(db, userRow.ID, identity); err != nil {
+				return "", errors.New(errors.CodeInternal, "绑定第三方账号失败")
+			}
+			return userRow.ID, nil
+		}
+	}
+
+	if !config.GetConfig().OAuth.AutoRegister {
+		return "", errors.New(errors.CodeUserNotFound, "该第三方账号未绑定本站账号")
+	}
+
+	return provisionUser(identity)
+}
+
+// provisionUser 根据第三方身份自动创建账号
+func provisionUser(identity *oauth.Identity) (string, error) {
+	db := database.GetDB()
+
+	username, err := generateUsername(identity)
+	if err != nil {
+		return "", err
+	}
+
+	// 只使用已验证且未被占用的邮箱，否则使用占位邮箱
+	email := ""
+	if identity.Email != "" && identity.EmailVerified && len(identity.Email) <= 100 {
+		var count int64
+		db.Model(&models.User{}).Where("email = ?", identity.Email).Count(&count)
+		if count == 0 {
+			email = identity.Email
+		}
+	}
+	if email == "" {
+		sum := sha256.Sum256([]byte(identity.Subject))
+		email = identity.Provider + "_" + hex.EncodeToString(sum[:])[:12] + oauthPlaceholderEmail
+	}
+
+	// 随机密码，用户可通过重置密码设置自己的密码
+	randomPassword, err := oauth.GenerateRandomString(32)
+	if err != nil {
+		return "", errors.New(errors.CodeInternal, "生成密码失败")
+	}
+	hashedPassword, err := utils.HashPassword(randomPassword)
+	if err != nil {
+		return "", errors.New(errors.CodeInternal, "密码加密失败")
+	}
+
+	user := models.User{
+		Username: username,
+		Email:    email,
+		Password: hashedPassword,
+		Avatar:   truncate(identity.AvatarURL, 255),
+		Status:   common.UserStatusNormal,
+		Role:     common.UserRoleUser,
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		return createIdentity(tx, user.ID.String(), identity)
+	})
+	if err != nil {
+		return "", errors.New(errors.CodeInternal, "创建用户失败")
+	}
+
+	emitUserRegistered(&user, identity.Provider)
+	return user.ID.String(), nil
+}
+
+// linkIdentity 为已登录用户绑定第三方身份
+func linkIdentity(userID string, identity *oauth.Identity) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var existing models.UserIdentity
+	err := db.Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).First(&existing).Error
+	if err == nil {
+		if existing.UserID != userID {
+			return errors.New(errors.CodeConflict, "该第三方账号已绑定其他用户")
+		}
+		touchIdentity(identity)
+		return nil
+	}
+
+	var count int64
+	db.Model(&models.UserIdentity{}).Where("user_id = ? AND provider = ?", userID, identity.Provider).Count(&count)
+	if count > 0 {
+		return errors.New(errors.CodeConflict, "已绑定该登录方式的其他账号，请先解绑")
+	}
+
+	if err := createIdentity(db, userID, identity); err != nil {
+		return errors.New(errors.CodeInternal, "绑定第三方账号失败")
+	}
+	return nil
+}
+
+// createIdentity 保存第三方身份
+func createIdentity(db *gorm.DB, userID string, identity *oauth.Identity) error {
+	now := time.Now()
+	return db.Create(&models.UserIdentity{
+		UserID:      userID,
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       truncate(identity.Email, 100),
+		Name:        truncate(identity.Name, 100),
+		AvatarURL:   truncate(identity.AvatarURL, 500),
+		LastLoginAt: &now,
+	}).Error
+}
+
+// touchIdentity 更新身份资料和最后登录时间
+func touchIdentity(identity *oauth.Identity) {
+	err := database.GetDB().Model(&models.UserIdentity{}).
+		Where("provider = ? AND subject = ?", identity.Provider, identity.Subject).
+		Updates(map[string]interface{}{
+			"email":         truncate(identity.Email, 100),
+			"name":          truncate(identity.Name, 100),
+			"avatar_url":    truncate(identity.AvatarURL, 500),
+			"last_login_at": time.Now(),
+		}).Error
+	if err != nil {
+		log.Printf("更新第三方身份失败: %v", err)
+	}
+}
+
+// generateUsername 根据第三方身份生成不重复的用户名
+func generateUsername(identity *oauth.Identity) (string, error) {
+	base := identity.Username
+	if base == "" && identity.Email != "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	if base == "" {
+		base = identity.Name
+	}
+
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if len(base) < 2 {
+		base = "user"
+	}
+	// 预留 "_xxxx" 随机后缀的长度
+	base = truncate(base, oauthUsernameMaxLen-5)
+
+	db := database.GetDB()
+	candidate := base
+	for i := 0; i < 5; i++ {
+		var count int64
+		db.Model(&models.User{}).Where("username = ?", candidate).Count(&count)
+		if count == 0 {
+			return candidate, nil
+		}
+
+		suffix := make([]byte, 2)
+		if _, err := rand.Read(suffix); err != nil {
+			return "", errors.New(errors.CodeInternal, "生成用户名失败")
+		}
+		candidate = base + "_" + hex.EncodeToString(suffix)
+	}
+
+	return "", errors.New(errors.CodeUserExists, "生成用户名失败，请稍后重试")
+}
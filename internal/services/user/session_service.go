@@ -0,0 +1,225 @@
+package user
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"template/internal/models"
+	"template/pkg/cache"
+	"template/pkg/common"
+	"template/pkg/constants"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"time"
+)
+
+// 会话状态缓存值
+const (
+	sessionStateActive  = "active"
+	sessionStateRevoked = "revoked"
+)
+
+// sessionStateTTL 会话有效状态的缓存时间，同时也是最后活跃时间的更新间隔
+const sessionStateTTL = time.Minute
+
+// ClientInfo 登录客户端信息
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Device    string // 客户端自定义的设备名称，为空时根据UA推断
+}
+
+// createSession 创建登录会话
+func createSession(userID string, client ClientInfo) (*models.UserSession, error) {
+	db := database.GetDB()
+
+	device := client.Device
+	if device == "" {
+		device = parseDeviceName(client.UserAgent)
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		UserID:     userID,
+		Device:     device,
+		IP:         client.IP,
+		UserAgent:  truncate(client.UserAgent, 500),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(common.RefreshTokenTTL()),
+	}
+
+	if err := db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// extendSession 刷新令牌时延长会话有效期
+func extendSession(sessionID string) error {
+	db := database.GetDB()
+
+	var session models.UserSession
+	if err := db.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return errors.New(errors.CodeInvalidAuthToken, "登录会话不存在")
+	}
+
+	if !session.IsActive() {
+		return errors.New(errors.CodeInvalidAuthToken, "登录会话已失效")
+	}
+
+	now := time.Now()
+	return db.Model(&models.UserSession{}).Where("id = ?", sessionID).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"expires_at":   now.Add(common.RefreshTokenTTL()),
+	}).Error
+}
+
+// ValidateSession 校验会话是否有效，供认证中间件调用
+// 有效状态会缓存一分钟，缓存失效时顺便更新会话的最后活跃时间
+func ValidateSession(sessionID string) bool {
+	key := fmt.Sprintf(constants.CacheKeySessionState, sessionID)
+	if state, err := cache.Get(key); err == nil {
+		return state == sessionStateActive
+	}
+
+	db := database.GetDB()
+	var session models.UserSession
+	if err := db.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return false
+	}
+
+	if !session.IsActive() {
+		_ = cache.Set(key, sessionStateRevoked, common.AccessTokenTTL())
+		return false
+	}
+
+	db.Model(&models.UserSession{}).Where("id = ?", sessionID).UpdateColumn("last_seen_at", time.Now())
+	_ = cache.Set(key, sessionStateActive, sessionStateTTL)
+	return true
+}
+
+// ListSessions 获取用户的有效会话列表
+func ListSessions(userID string) ([]models.UserSession, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var sessions []models.UserSession
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询会话失败")
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession 吊销用户的某个会话
+func RevokeSession(userID, sessionID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	result := db.Model(&models.UserSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return errors.New(errors.CodeInternal, "吊销会话失败")
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New(errors.CodeNotFound, "会话不存在或已失效")
+	}
+
+	markSessionRevoked(sessionID)
+	return nil
+}
+
+// RevokeOtherSessions 吊销用户除当前会话以外的所有会话，exceptID为空时吊销全部
+func RevokeOtherSessions(userID, exceptID string) (int64, error) {
+	db := database.GetDB()
+	if db == nil {
+		return 0, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var sessionIDs []string
+	query := db.Model(&models.UserSession{}).Where("user_id = ? AND revoked_at IS NULL", userID)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+	if err := query.Pluck("id", &sessionIDs).Error; err != nil {
+		return 0, errors.New(errors.CodeQueryFailed, "查询会话失败")
+	}
+
+	if len(sessionIDs) == 0 {
+		return 0, nil
+	}
+
+	if err := db.Model(&models.UserSession{}).Where("id IN ?", sessionIDs).Update("revoked_at", time.Now()).Error; err != nil {
+		return 0, errors.New(errors.CodeInternal, "吊销会话失败")
+	}
+
+	for _, id := range sessionIDs {
+		markSessionRevoked(id)
+	}
+
+	return int64(len(sessionIDs)), nil
+}
+
+// markSessionRevoked 在缓存中标记会话已吊销，使认证中间件立即拒绝
+func markSessionRevoked(sessionID string) {
+	key := fmt.Sprintf(constants.CacheKeySessionState, sessionID)
+	if err := cache.Set(key, sessionStateRevoked, common.AccessTokenTTL()); err != nil {
+		log.Printf("缓存会话吊销状态失败: %v", err)
+	}
+}
+
+// parseDeviceName 根据UA粗略推断设备名称，如 "Chrome on Windows"
+func parseDeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "未知设备"
+	}
+
+	browser := "未知浏览器"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/") || strings.Contains(userAgent, "Opera"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.Contains(userAgent, "curl/"):
+		browser = "curl"
+	}
+
+	platform := "未知系统"
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		platform = "Android"
+	case strings.Contains(userAgent, "iPhone") || strings.Contains(userAgent, "iPad"):
+		platform = "iOS"
+	case strings.Contains(userAgent, "Windows"):
+		platform = "Windows"
+	case strings.Contains(userAgent, "Mac OS X") || strings.Contains(userAgent, "Macintosh"):
+		platform = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		platform = "Linux"
+	}
+
+	return browser + " on " + platform
+}
+
+// truncate 截断过长的字符串
+func truncate(s string, maxLen int) string {
+	if len(s) > maxLen {
+		return s[:maxLen]
+	}
+	return s
+}
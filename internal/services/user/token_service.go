@@ -0,0 +1,103 @@
+package user
+
+import (
+	"log"
+	"template/pkg/common"
+	"template/pkg/database"
+	"template/pkg/errors"
+)
+
+// RefreshToken 使用刷新令牌换取新的令牌对，旧的刷新令牌随即失效
+func RefreshToken(refreshToken string) (*common.TokenPair, error) {
+	claims, err := common.ParseToken(refreshToken)
+	if err != nil || !claims.IsRefreshToken() {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "刷新令牌无效或已过期")
+	}
+
+	if common.IsTokenRevoked(claims) {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "刷新令牌已被吊销")
+	}
+
+	if err := common.ConsumeRefreshToken(claims); err != nil {
+		return nil, errors.New(errors.CodeInvalidAuthToken, err.Error())
+	}
+
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	// 重新读取用户信息，确保角色和状态是最新的
+	var userRow struct {
+		ID       string `db:"id"`
+		Username string `db:"username"`
+		Status   int    `db:"status"`
+		Role     int    `db:"role"`
+
+		PasswordChangeRequired bool `db:"password_change_required"`
+	}
+
+	err = db.Raw("SELECT id, username, status, role, password_change_required FROM user WHERE id = ? LIMIT 1", claims.UserID).Scan(&userRow).Error
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "数据库查询失败")
+	}
+
+	if userRow.ID == "" {
+		return nil, errors.New(errors.CodeUserNotFound, "用户不存在")
+	}
+
+	if userRow.Status != common.UserStatusNormal {
+		return nil, errors.New(errors.CodeUserDisabled, "账号已被禁用")
+	}
+
+	// 延长所属会话的有效期，已吊销的会话不允许刷新
+	if claims.SessionID != "" {
+		if err := extendSession(claims.SessionID); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := common.GenerateTokenPair(common.TokenSubject{
+		UserID:    userRow.ID,
+		Username:  userRow.Username,
+		Role:      userRow.Role,
+		SessionID: claims.SessionID,
+		TwoFactor: claims.TwoFactor,
+
+		PasswordChangeRequired: userRow.PasswordChangeRequired,
+	})
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "生成token失败")
+	}
+
+	return tokens, nil
+}
+
+// Logout 退出登录，吊销当前会话、访问令牌以及客户端提交的刷新令牌
+func Logout(accessClaims *common.JWTClaims, refreshToken string) error {
+	if err := common.RevokeToken(accessClaims); err != nil {
+		return errors.New(errors.CodeRedisError, "吊销令牌失败")
+	}
+
+	if accessClaims.SessionID != "" {
+		if err := RevokeSession(accessClaims.UserID, accessClaims.SessionID); err != nil && !errors.Is(err, errors.CodeNotFound) {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	refreshClaims, err := common.ParseToken(refreshToken)
+	if err != nil || !refreshClaims.IsRefreshToken() || refreshClaims.UserID != accessClaims.UserID {
+		// 刷新令牌无效时不影响退出结果
+		return nil
+	}
+
+	if err := common.RevokeToken(refreshClaims); err != nil {
+		log.Printf("吊销刷新令牌失败: %v", err)
+	}
+
+	return nil
+}
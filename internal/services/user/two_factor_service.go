@@ -0,0 +1,412 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"template/internal/models"
+	"template/pkg/cache"
+	"template/pkg/common"
+	"template/pkg/config"
+	"template/pkg/constants"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"template/pkg/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 两步验证相关参数
+const (
+	twoFactorChallengeTTL   = 5 * time.Minute  // 登录挑战令牌有效期
+	twoFactorSetupTTL       = 10 * time.Minute // 待确认密钥的保留时间
+	twoFactorMaxAttempts    = 5                // 每个挑战令牌允许的验证失败次数
+	twoFactorRecoveryCodes  = 10               // 每次生成的恢复码数量
+	twoFactorDefaultIssuer  = "Template"       // 未配置应用名称时使用的签发方名称
+	twoFactorUsedStepExpire = time.Duration(utils.TOTPPeriod*(2*utils.TOTPSkew+1)) * time.Second
+)
+
+// TwoFactorSetup 两步验证绑定信息
+type TwoFactorSetup struct {
+	Secret    string
+	URI       string
+	ExpiresAt time.Time
+}
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled                bool
+	Required               bool
+	RemainingRecoveryCodes int64
+}
+
+// beginTwoFactorChallenge 密码校验通过后签发挑战令牌
+func beginTwoFactorChallenge(subject common.TokenSubject, userInfo map[string]interface{}) (*LoginResult, error) {
+	token, claims, err := common.GenerateChallengeToken(subject, twoFactorChallengeTTL)
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "生成挑战令牌失败")
+	}
+
+	key := fmt.Sprintf(constants.CacheKeyTwoFactorChallenge, claims.ID)
+	if err := cache.Set(key, subject.UserID, twoFactorChallengeTTL); err != nil {
+		return nil, errors.New(errors.CodeRedisError, "保存挑战令牌失败")
+	}
+
+	return &LoginResult{
+		UserInfo:           userInfo,
+		TwoFactorRequired:  true,
+		ChallengeToken:     token,
+		ChallengeExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// VerifyTwoFactorLogin 校验挑战令牌和验证码（TOTP或恢复码），通过后完成登录
+func VerifyTwoFactorLogin(challengeToken, code string, client ClientInfo) (*LoginResult, error) {
+	claims, err := common.ParseToken(challengeToken)
+	if err != nil || claims.TokenType != common.TokenTypeTwoFactorChallenge {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "挑战令牌无效或已过期")
+	}
+
+	challengeKey := fmt.Sprintf(constants.CacheKeyTwoFactorChallenge, claims.ID)
+	if userID, err := cache.Get(challengeKey); err != nil || userID != claims.UserID {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "挑战令牌无效或已使用")
+	}
+
+	userRow, err := findTwoFactorUser(claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userRow.Status != common.UserStatusNormal {
+		return nil, errors.New(errors.CodeUserDisabled, "账号已被禁用")
+	}
+
+	if !userRow.TwoFactorEnabled {
+		return nil, errors.New(errors.CodeInvalidAuthToken, "账号未启用两步验证，请重新登录")
+	}
+
+	if !verifySecondFactor(userRow.ID, userRow.TwoFactorSecret, code, true) {
+		if recordChallengeFailure(claims) {
+			_ = cache.Del(challengeKey)
+			return nil, errors.New(errors.CodeRateLimited, "验证失败次数过多，请重新登录")
+		}
+		return nil, errors.New(errors.CodeInvalidVerifyCode, "两步验证码错误")
+	}
+
+	// 挑战令牌只能使用一次
+	_ = cache.Del(challengeKey)
+	_ = cache.Del(fmt.Sprintf(constants.CacheKeyTwoFactorAttempts, claims.ID))
+
+	tokens, err := issueLoginTokens(common.TokenSubject{
+		UserID:    userRow.ID,
+		Username:  userRow.Username,
+		Role:      userRow.Role,
+		TwoFactor: true,
+
+		PasswordChangeRequired: userRow.PasswordChangeRequired,
+	}, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResult{
+		UserInfo: userRow.toUserInfo(),
+		Tokens:   tokens,
+	}, nil
+}
+
+// SetupTwoFactor 生成新的TOTP密钥，需调用 EnableTwoFactor 确认后才会生效
+func SetupTwoFactor(userID string) (*TwoFactorSetup, error) {
+	userRow, err := findTwoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userRow.TwoFactorEnabled {
+		return nil, errors.New(errors.CodeConflict, "已启用两步验证，请先关闭后再重新绑定")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "生成密钥失败")
+	}
+
+	key := fmt.Sprintf(constants.CacheKeyTwoFactorSetup, userID)
+	if err := cache.Set(key, secret, twoFactorSetupTTL); err != nil {
+		return nil, errors.New(errors.CodeRedisError, "保存密钥失败")
+	}
+
+	account := userRow.Email
+	if account == "" {
+		account = userRow.Username
+	}
+
+	return &TwoFactorSetup{
+		Secret:    secret,
+		URI:       utils.BuildTOTPURI(twoFactorIssuer(), account, secret),
+		ExpiresAt: time.Now().Add(twoFactorSetupTTL),
+	}, nil
+}
+
+// EnableTwoFactor 校验验证码并启用两步验证，返回一次性恢复码
+func EnableTwoFactor(userID, code string) ([]string, error) {
+	key := fmt.Sprintf(constants.CacheKeyTwoFactorSetup, userID)
+	secret, err := cache.Get(key)
+	if err != nil || secret == "" {
+		return nil, errors.New(errors.CodeInvalidParameter, "请先获取两步验证密钥")
+	}
+
+	step, ok := utils.ValidateTOTPCode(secret, code, time.Now())
+	if !ok {
+		return nil, errors.New(errors.CodeInvalidVerifyCode, "两步验证码错误")
+	}
+	markTOTPStepUsed(userID, step)
+
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var codes []string
+	err = db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND two_factor_enabled = ?", userID, false).
+			Updates(map[string]interface{}{
+				"two_factor_enabled": true,
+				"two_factor_secret":  secret,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New(errors.CodeConflict, "已启用两步验证")
+		}
+
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		if _, ok := err.(*errors.Error); ok {
+			return nil, err
+		}
+		return nil, errors.New(errors.CodeInternal, "启用两步验证失败")
+	}
+
+	_ = cache.Del(key)
+	return codes, nil
+}
+
+// DisableTwoFactor 校验密码和验证码后关闭两步验证
+func DisableTwoFactor(userID, password, code string) error {
+	userRow, err := findTwoFactorUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !userRow.TwoFactorEnabled {
+		return errors.New(errors.CodeInvalidParameter, "未启用两步验证")
+	}
+
+	if !utils.ComparePasswords(userRow.Password, password) {
+		return errors.New(errors.CodeWrongPassword, "密码错误")
+	}
+
+	if !verifySecondFactor(userRow.ID, userRow.TwoFactorSecret, code, true) {
+		return errors.New(errors.CodeInvalidVerifyCode, "两步验证码错误")
+	}
+
+	db := database.GetDB()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"two_factor_enabled": false,
+			"two_factor_secret":  "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error
+	})
+	if err != nil {
+		return errors.New(errors.CodeInternal, "关闭两步验证失败")
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes 校验TOTP验证码后重新生成恢复码，旧的恢复码全部失效
+func RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	userRow, err := findTwoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !userRow.TwoFactorEnabled {
+		return nil, errors.New(errors.CodeInvalidParameter, "未启用两步验证")
+	}
+
+	// 只接受TOTP验证码，避免用恢复码换取新的恢复码
+	if !verifySecondFactor(userRow.ID, userRow.TwoFactorSecret, code, false) {
+		return nil, errors.New(errors.CodeInvalidVerifyCode, "两步验证码错误")
+	}
+
+	var codes []string
+	err = database.GetDB().Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "生成恢复码失败")
+	}
+
+	return codes, nil
+}
+
+// GetTwoFactorStatus 获取用户的两步验证状态
+func GetTwoFactorStatus(userID string) (*TwoFactorStatus, error) {
+	userRow, err := findTwoFactorUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{
+		Enabled:  userRow.TwoFactorEnabled,
+		Required: config.GetConfig().Security.IsTwoFactorRequired(userRow.Role),
+	}
+
+	if userRow.TwoFactorEnabled {
+		database.GetDB().Model(&models.UserRecoveryCode{}).
+			Where("user_id = ? AND used_at IS NULL", userID).
+			Count(&status.RemainingRecoveryCodes)
+	}
+
+	return status, nil
+}
+
+// findTwoFactorUser 查询两步验证所需的用户信息
+func findTwoFactorUser(userID string) (*loginUserRow, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	// 使用原始SQL查询来避免UUID扫描问题
+	var userRow loginUserRow
+	err := db.Raw("SELECT "+loginUserColumns+" FROM user WHERE id = ? LIMIT 1", userID).Scan(&userRow).Error
+	if err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "数据库查询失败")
+	}
+
+	if userRow.ID == "" {
+		return nil, errors.New(errors.CodeUserNotFound, "用户不存在")
+	}
+
+	return &userRow, nil
+}
+
+// verifySecondFactor 校验TOTP验证码，allowRecovery为true时也接受恢复码
+func verifySecondFactor(userID, secret, code string, allowRecovery bool) bool {
+	if len(code) == utils.TOTPDigits {
+		step, ok := utils.ValidateTOTPCode(secret, code, time.Now())
+		if !ok {
+			return false
+		}
+		// 同一时间步的验证码只能使用一次，防止被截获后重放
+		if isTOTPStepUsed(userID, step) {
+			return false
+		}
+		markTOTPStepUsed(userID, step)
+		return true
+	}
+
+	if !allowRecovery {
+		return false
+	}
+	return consumeRecoveryCode(userID, code)
+}
+
+// consumeRecoveryCode 使用恢复码，每个恢复码只能成功使用一次
+func consumeRecoveryCode(userID, code string) bool {
+	normalized := utils.NormalizeRecoveryCode(code)
+	if normalized == "" {
+		return false
+	}
+
+	result := database.GetDB().Model(&models.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashRecoveryCode(normalized)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		log.Printf("更新恢复码状态失败: %v", result.Error)
+		return false
+	}
+
+	return result.RowsAffected > 0
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成新的恢复码
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(twoFactorRecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ?", userID).Delete(&models.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	records := make([]models.UserRecoveryCode, 0, len(codes))
+	for _, code := range codes {
+		records = append(records, models.UserRecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(utils.NormalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// recordChallengeFailure 记录挑战令牌的验证失败次数，超过上限时返回true
+func recordChallengeFailure(claims *common.JWTClaims) bool {
+	key := fmt.Sprintf(constants.CacheKeyTwoFactorAttempts, claims.ID)
+
+	attempts := 0
+	if value, err := cache.Get(key); err == nil {
+		attempts, _ = strconv.Atoi(value)
+	}
+	attempts++
+
+	_ = cache.Set(key, strconv.Itoa(attempts), twoFactorChallengeTTL)
+	return attempts >= twoFactorMaxAttempts
+}
+
+// isTOTPStepUsed 检查时间步是否已使用过
+func isTOTPStepUsed(userID string, step int64) bool {
+	return cache.Exists(fmt.Sprintf(constants.CacheKeyTwoFactorUsedStep, userID, step))
+}
+
+// markTOTPStepUsed 标记时间步已使用，记录保留到该验证码不再有效
+func markTOTPStepUsed(userID string, step int64) {
+	key := fmt.Sprintf(constants.CacheKeyTwoFactorUsedStep, userID, step)
+	if err := cache.Set(key, "1", twoFactorUsedStepExpire); err != nil {
+		log.Printf("记录TOTP时间步失败: %v", err)
+	}
+}
+
+// hashRecoveryCode 计算恢复码哈希，数据库中不保存恢复码明文
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// twoFactorIssuer 获取验证器App中显示的签发方名称
+func twoFactorIssuer() string {
+	if name := config.GetConfig().App.Name; name != "" {
+		return name
+	}
+	return twoFactorDefaultIssuer
+}
@@ -5,11 +5,14 @@ import (
 	"log"
 	"math/rand"
 	"template/internal/models"
+	"template/internal/services/webhook"
 	"template/pkg/cache"
 	"template/pkg/common"
+	"template/pkg/config"
 	"template/pkg/database"
 	"template/pkg/email"
 	"template/pkg/errors"
+	"template/pkg/logger"
 	"template/pkg/utils"
 	"time"
 )
@@ -24,6 +27,8 @@ type UserService struct {
 // InitUserService 初始化用户服务
 func InitUserService() {
 	userService = &UserService{}
+
+	logger.ErrorExit(ValidateAccountConfig(), "账号注销配置错误")
 }
 
 // GetUserService 获取用户服务实例
@@ -31,73 +36,141 @@ func GetUserService() *UserService {
 	return userService
 }
 
-// Login 用户登录
-func Login(account, password string) (map[string]interface{}, string, time.Time, error) {
+// LoginResult 登录结果
+type LoginResult struct {
+	UserInfo               map[string]interface{}
+	Tokens                 *common.TokenPair
+	TwoFactorRequired      bool      // 需要提交两步验证码才能完成登录
+	ChallengeToken         string    // 两步验证挑战令牌
+	ChallengeExpiresAt     time.Time // 挑战令牌过期时间
+	TwoFactorSetupRequired bool      // 账号角色要求启用两步验证，但尚未启用
+}
+
+// loginUserRow 登录相关查询使用的用户字段
+type loginUserRow struct {
+	ID               string `db:"id"`
+	Username         string `db:"username"`
+	Password         string `db:"password"`
+	Email            string `db:"email"`
+	Avatar           string `db:"avatar"`
+	Bio              string `db:"bio"`
+	Status           int    `db:"status"`
+	Role             int    `db:"role"`
+	TwoFactorEnabled bool   `db:"two_factor_enabled"`
+	TwoFactorSecret  string `db:"two_factor_secret"`
+	CreatedAt        string `db:"created_at"`
+	UpdatedAt        string `db:"updated_at"`
+
+	PasswordChangeRequired bool `db:"password_change_required"`
+}
+
+// loginUserColumns 登录相关查询的字段列表
+const loginUserColumns = "id, username, password, email, avatar, bio, status, role, two_factor_enabled, two_factor_secret, password_change_required, created_at, updated_at"
+
+// toUserInfo 构造返回给客户端的用户信息
+func (r *loginUserRow) toUserInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 r.ID,
+		"username":           r.Username,
+		"email":              r.Email,
+		"avatar":             r.Avatar,
+		"bio":                r.Bio,
+		"role":               r.Role,
+		"status":             r.Status,
+		"two_factor_enabled": r.TwoFactorEnabled,
+
+		"password_change_required": r.PasswordChangeRequired,
+	}
+}
+
+// Login 用户登录，每次登录都会创建一个新的设备会话
+// 启用了两步验证的账号只返回挑战令牌，需要调用 VerifyTwoFactorLogin 完成登录
+func Login(account, password string, client ClientInfo) (*LoginResult, error) {
 	db := database.GetDB()
 	if db == nil {
-		return nil, "", time.Time{}, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
 	}
 
-	// 使用原始SQL查询来避免UUID扫描问题
-	var userRow struct {
-		ID        string `db:"id"`
-		Username  string `db:"username"`
-		Password  string `db:"password"`
-		Email     string `db:"email"`
-		Avatar    string `db:"avatar"`
-		Bio       string `db:"bio"`
-		Status    int    `db:"status"`
-		Role      int    `db:"role"`
-		CreatedAt string `db:"created_at"`
-		UpdatedAt string `db:"updated_at"`
+	// 失败次数过多的IP直接拒绝
+	if err := checkIPLoginAllowed(client.IP); err != nil {
+		return nil, err
 	}
 
-	err := db.Raw("SELECT id, username, password, email, avatar, bio, status, role, created_at, updated_at FROM user WHERE username = ? OR email = ? LIMIT 1", account, account).Scan(&userRow).Error
+	// 使用原始SQL查询来避免UUID扫描问题
+	var userRow loginUserRow
+	err := db.Raw("SELECT "+loginUserColumns+" FROM user WHERE username = ? OR email = ? LIMIT 1", account, account).Scan(&userRow).Error
 	if err != nil {
-		return nil, "", time.Time{}, errors.New(errors.CodeQueryFailed, "数据库查询失败")
+		return nil, errors.New(errors.CodeQueryFailed, "数据库查询失败")
+	}
+
+	// 账号不存在时同样计数和锁定，避免通过锁定行为区分账号是否存在
+	guardKey := loginGuardKey(userRow.ID, account)
+	if err := checkAccountLoginAllowed(guardKey); err != nil {
+		return nil, err
 	}
 
+	// 账号不存在和密码错误返回相同的错误，并保持相近的响应时间，防止枚举账号
 	if userRow.ID == "" {
-		return nil, "", time.Time{}, errors.New(errors.CodeUserNotFound, "用户不存在")
+		compareDummyPassword(password)
+		return nil, recordLoginFailure(guardKey, client.IP)
 	}
 
-	// 验证密码
 	if !utils.ComparePasswords(userRow.Password, password) {
-		return nil, "", time.Time{}, errors.New(errors.CodeWrongPassword, "密码错误")
+		return nil, recordLoginFailure(guardKey, client.IP)
 	}
 
+	clearLoginFailures(guardKey)
+
 	// 检查用户状态
 	if userRow.Status != common.UserStatusNormal {
-		return nil, "", time.Time{}, errors.New(errors.CodeUserDisabled, "账号已被禁用")
+		return nil, errors.New(errors.CodeUserDisabled, "账号已被禁用")
 	}
 
 	// 解析UUID
 	userID, err := common.ParseUUID(userRow.ID)
 	if err != nil {
-		return nil, "", time.Time{}, errors.New(errors.CodeInternal, "用户ID格式错误")
+		return nil, errors.New(errors.CodeInternal, "用户ID格式错误")
+	}
+
+	subject := common.TokenSubject{
+		UserID:   userID.String(),
+		Username: userRow.Username,
+		Role:     userRow.Role,
+
+		PasswordChangeRequired: userRow.PasswordChangeRequired,
+	}
+
+	// 已启用两步验证，先返回挑战令牌
+	if userRow.TwoFactorEnabled {
+		return beginTwoFactorChallenge(subject, userRow.toUserInfo())
 	}
 
-	// 生成 JWT token
-	token, err := common.GenerateToken(userID, userRow.Username, userRow.Role)
+	tokens, err := issueLoginTokens(subject, client)
 	if err != nil {
-		return nil, "", time.Time{}, errors.New(errors.CodeInternal, "生成token失败")
+		return nil, err
 	}
 
-	// 计算过期时间
-	expiresAt := time.Now().Add(time.Duration(24) * time.Hour) // 默认24小时
+	return &LoginResult{
+		UserInfo:               userRow.toUserInfo(),
+		Tokens:                 tokens,
+		TwoFactorSetupRequired: config.GetConfig().Security.IsTwoFactorRequired(userRow.Role),
+	}, nil
+}
 
-	// 构造用户信息
-	userInfo := map[string]interface{}{
-		"id":       userRow.ID,
-		"username": userRow.Username,
-		"email":    userRow.Email,
-		"avatar":   userRow.Avatar,
-		"bio":      userRow.Bio,
-		"role":     userRow.Role,
-		"status":   userRow.Status,
+// issueLoginTokens 创建登录会话并签发令牌
+func issueLoginTokens(subject common.TokenSubject, client ClientInfo) (*common.TokenPair, error) {
+	session, err := createSession(subject.UserID, client)
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "创建登录会话失败")
 	}
 
-	return userInfo, token, expiresAt, nil
+	subject.SessionID = session.ID.String()
+	tokens, err := common.GenerateTokenPair(subject)
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "生成token失败")
+	}
+
+	return tokens, nil
 }
 
 // FindUsers 获取用户列表
@@ -274,9 +347,20 @@ func RegisterUser(username, email, password, code string) error {
 		return errors.New(errors.CodeInternal, "创建用户失败")
 	}
 
+	emitUserRegistered(&user, "email")
 	return nil
 }
 
+// emitUserRegistered 发布用户注册事件
+func emitUserRegistered(user *models.User, source string) {
+	webhook.Emit(common.WebhookEventUserRegistered, map[string]interface{}{
+		"user_id":  user.ID.String(),
+		"username": user.Username,
+		"email":    user.Email,
+		"source":   source, // 注册方式：email 或第三方登录提供方名称
+	})
+}
+
 // sendVerificationEmail 发送验证码邮件
 func sendVerificationEmail(emailAddr string, code string, codeType string) error {
 	// 检查邮件服务是否可用
@@ -289,6 +373,8 @@ func sendVerificationEmail(emailAddr string, code string, codeType string) error
 		subject = "注册验证码"
 	} else if codeType == common.CodeTypeChangeEmail {
 		subject = "修改邮箱验证码"
+	} else if codeType == common.CodeTypeDeleteAccount {
+		subject = "注销账号验证码"
 	} else {
 		subject = "重置密码验证码"
 	}
@@ -317,7 +403,10 @@ func ResetPassword(email, code, newPassword string) error {
 		return errors.New(errors.CodeInternal, "密码加密失败")
 	}
 
-	result := db.Model(&models.User{}).Where("email = ?", email).Update("password", hashedPassword)
+	result := db.Model(&models.User{}).Where("email = ?", email).Updates(map[string]interface{}{
+		"password":                 hashedPassword,
+		"password_change_required": false,
+	})
 	if result.Error != nil {
 		return errors.New(errors.CodeInternal, "更新密码失败")
 	}
@@ -326,6 +415,19 @@ func ResetPassword(email, code, newPassword string) error {
 		return errors.New(errors.CodeUserNotFound, "未找到用户")
 	}
 
+	// 密码重置后吊销该用户所有已签发的令牌
+	var userRow struct {
+		ID string `db:"id"`
+	}
+	if err := db.Raw("SELECT id FROM user WHERE email = ? LIMIT 1", email).Scan(&userRow).Error; err == nil && userRow.ID != "" {
+		if err := common.RevokeUserTokens(userRow.ID); err != nil {
+			log.Printf("吊销用户令牌失败: %v", err)
+		}
+		if _, err := RevokeOtherSessions(userRow.ID, ""); err != nil {
+			log.Printf("吊销用户会话失败: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -464,7 +566,10 @@ func ChangePassword(userID, oldPassword, newPassword string) error {
 	}
 
 	// 更新密码
-	result := db.Model(&models.User{}).Where("id = ?", userID).Update("password", hashedPassword)
+	result := db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password":                 hashedPassword,
+		"password_change_required": false,
+	})
 	if result.Error != nil {
 		return errors.New(errors.CodeInternal, "更新密码失败")
 	}
@@ -473,5 +578,13 @@ func ChangePassword(userID, oldPassword, newPassword string) error {
 		return errors.New(errors.CodeUserNotFound, "用户不存在")
 	}
 
+	// 修改密码后吊销该用户所有已签发的令牌
+	if err := common.RevokeUserTokens(userID); err != nil {
+		log.Printf("吊销用户令牌失败: %v", err)
+	}
+	if _, err := RevokeOtherSessions(userID, ""); err != nil {
+		log.Printf("吊销用户会话失败: %v", err)
+	}
+
 	return nil
 }
@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"template/internal/models"
+	"template/pkg/config"
+	"template/pkg/database"
+	"time"
+)
+
+// 投递相关参数
+const (
+	SignatureHeader = "X-Webhook-Signature" // 签名，格式为 sha256=<hex>
+	TimestampHeader = "X-Webhook-Timestamp" // 签名时间戳（Unix秒）
+	EventHeader     = "X-Webhook-Event"     // 事件类型
+	DeliveryHeader  = "X-Webhook-Delivery"  // 投递ID
+
+	defaultTimeout     = 10 // 默认投递超时时间（秒）
+	defaultMaxAttempts = 8  // 默认最大投递次数
+	defaultBatchSize   = 50 // 默认每轮处理数量
+
+	retryBaseDelay  = time.Minute   // 首次重试间隔
+	retryMaxDelay   = 6 * time.Hour // 最大重试间隔
+	maxResponseBody = 1024          // 保存的响应内容最大长度
+	maxErrorLength  = 500           // 保存的错误信息最大长度
+)
+
+// processMutex 同一时间只运行一轮投递，定时任务和事件触发的投递不会重复处理
+var processMutex sync.Mutex
+
+// DeliveryResult 单次投递结果
+type DeliveryResult struct {
+	StatusCode   int
+	ResponseBody string
+	Duration     time.Duration
+	Err          error
+}
+
+// Succeeded 响应状态码为2xx时视为投递成功
+func (r *DeliveryResult) Succeeded() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, "<timestamp>.<body>")
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature 校验签名，供接收方参考实现
+func VerifySignature(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// RetryDelay 计算第attempt次投递失败后的重试间隔，按指数退避
+func RetryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+// Send 发送一次投递请求
+func Send(client *http.Client, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery) *DeliveryResult {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return &DeliveryResult{Err: err}
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "template-webhook/1.0")
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, timestamp, body))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return &DeliveryResult{Duration: time.Since(start), Err: err}
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return &DeliveryResult{
+		StatusCode:   resp.StatusCode,
+		ResponseBody: string(respBody),
+		Duration:     time.Since(start),
+	}
+}
+
+// ProcessDueDeliveries 投递所有到期的记录，由定时任务和事件发布时触发
+func ProcessDueDeliveries() {
+	if !processMutex.TryLock() {
+		return
+	}
+	defer processMutex.Unlock()
+
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+
+	cfg := deliveryConfig()
+	var deliveries []models.WebhookDelivery
+	err := db.Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at").Limit(cfg.BatchSize).Find(&deliveries).Error
+	if err != nil {
+		log.Printf("查询待投递的Webhook失败: %v", err)
+		return
+	}
+
+	client := &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second}
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		if !claimDelivery(&deliveries[i], cfg) {
+			continue
+		}
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			deliver(client, delivery, cfg.MaxAttempts)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+}
+
+// claimDelivery 占用一条投递记录：增加投递次数并把下次投递时间推迟到超时之后
+// 多个实例同时处理时只有一个能更新成功；进程中途退出时记录会在超时后重新投递
+func claimDelivery(delivery *models.WebhookDelivery, cfg config.WebhookConfig) bool {
+	lease := time.Now().Add(2 * time.Duration(cfg.Timeout) * time.Second)
+	result := database.GetDB().Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.WebhookDeliveryPending, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts + 1,
+			"next_attempt_at": lease,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	delivery.Attempts++
+	return true
+}
+
+// deliver 投递并记录结果，失败时安排重试
+func deliver(client *http.Client, delivery *models.WebhookDelivery, maxAttempts int) {
+	db := database.GetDB()
+
+	var endpoint models.WebhookEndpoint
+	if err := db.Where("id = ?", delivery.EndpointID).First(&endpoint).Error; err != nil || !endpoint.IsActive {
+		db.Model(delivery).Updates(map[string]interface{}{
+			"status":          models.WebhookDeliveryFailed,
+			"next_attempt_at": nil,
+			"error":           "Webhook不存在或已停用",
+		})
+		return
+	}
+
+	result := Send(client, &endpoint, delivery)
+	now := time.Now()
+	updates := map[string]interface{}{
+		"last_attempt_at": now,
+		"response_status": result.StatusCode,
+		"response_body":   result.ResponseBody,
+		"duration_ms":     result.Duration.Milliseconds(),
+		"error":           "",
+	}
+
+	switch {
+	case result.Succeeded():
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["next_attempt_at"] = nil
+	case delivery.Attempts >= maxAttempts:
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["next_attempt_at"] = nil
+	default:
+		updates["next_attempt_at"] = now.Add(RetryDelay(delivery.Attempts))
+	}
+
+	if result.Err != nil {
+		updates["error"] = truncate(result.Err.Error(), maxErrorLength)
+	} else if !result.Succeeded() {
+		updates["error"] = fmt.Sprintf("接收方返回状态码 %d", result.StatusCode)
+	}
+
+	if err := db.Model(delivery).Updates(updates).Error; err != nil {
+		log.Printf("更新Webhook投递记录失败 %s: %v", delivery.ID.String(), err)
+	}
+}
+
+// deliveryConfig 获取投递配置，未配置的项使用默认值
+func deliveryConfig() config.WebhookConfig {
+	cfg := config.GetConfig().Webhook
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = defaultBatchSize
+	}
+	return cfg
+}
+
+// truncate 按字节截断字符串
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
@@ -0,0 +1,341 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+	"template/internal/models"
+	"template/pkg/common"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook相关参数
+const (
+	secretPrefix      = "whsec_" // 签名密钥前缀
+	secretRandomBytes = 24       // 签名密钥随机部分的字节数
+)
+
+// Event Webhook事件，序列化后作为请求体发送
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// Emit 发布事件，为所有订阅了该事件的启用中的Webhook创建投递记录并异步投递
+// 发布失败只记录日志，不影响业务流程
+func Emit(eventType string, data interface{}) {
+	db := database.GetDB()
+	if db == nil {
+		return
+	}
+
+	var endpoints []models.WebhookEndpoint
+	if err := db.Where("is_active = ? AND events LIKE ?", true, "%"+eventType+"%").Find(&endpoints).Error; err != nil {
+		log.Printf("查询Webhook订阅失败: %v", err)
+		return
+	}
+
+	event := Event{
+		ID:        common.NewUUID().String(),
+		Type:      eventType,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("序列化Webhook事件失败: %v", err)
+		return
+	}
+
+	created := 0
+	now := time.Now()
+	for i := range endpoints {
+		if !endpoints[i].Subscribes(eventType) {
+			continue
+		}
+		delivery := &models.WebhookDelivery{
+			EndpointID:    endpoints[i].ID.String(),
+			EventID:       event.ID,
+			EventType:     eventType,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := db.Create(delivery).Error; err != nil {
+			log.Printf("创建Webhook投递记录失败: %v", err)
+			continue
+		}
+		created++
+	}
+
+	if created > 0 {
+		go ProcessDueDeliveries()
+	}
+}
+
+// ListEndpoints 获取全部Webhook订阅
+func ListEndpoints() ([]models.WebhookEndpoint, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var endpoints []models.WebhookEndpoint
+	if err := db.Order("created_at DESC").Find(&endpoints).Error; err != nil {
+		return nil, errors.New(errors.CodeQueryFailed, "查询Webhook失败")
+	}
+	return endpoints, nil
+}
+
+// GetEndpoint 获取Webhook订阅详情
+func GetEndpoint(endpointID string) (*models.WebhookEndpoint, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	var endpoint models.WebhookEndpoint
+	if err := db.Where("id = ?", endpointID).First(&endpoint).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.CodeNotFound, "Webhook不存在")
+		}
+		return nil, errors.New(errors.CodeQueryFailed, "查询Webhook失败")
+	}
+	return &endpoint, nil
+}
+
+// CreateEndpoint 创建Webhook订阅，签名密钥只在创建时返回一次
+func CreateEndpoint(operatorID, rawURL, description string, events []string) (*models.WebhookEndpoint, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeEvents(events)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		URL:         rawURL,
+		Secret:      secret,
+		Events:      strings.Join(events, ","),
+		Description: description,
+		IsActive:    true,
+		CreatedBy:   operatorID,
+	}
+	if err := db.Create(endpoint).Error; err != nil {
+		return nil, errors.New(errors.CodeInternal, "创建Webhook失败")
+	}
+	return endpoint, nil
+}
+
+// UpdateEndpoint 更新Webhook订阅
+func UpdateEndpoint(endpointID, rawURL, description string, events []string, isActive bool) (*models.WebhookEndpoint, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	endpoint, err := GetEndpoint(endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	events, err = normalizeEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Model(endpoint).Updates(map[string]interface{}{
+		"url":         rawURL,
+		"events":      strings.Join(events, ","),
+		"description": description,
+		"is_active":   isActive,
+	}).Error
+	if err != nil {
+		return nil, errors.New(errors.CodeInternal, "更新Webhook失败")
+	}
+	return GetEndpoint(endpointID)
+}
+
+// RotateSecret 重新生成签名密钥，旧密钥立即失效
+func RotateSecret(endpointID string) (*models.WebhookEndpoint, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	endpoint, err := GetEndpoint(endpointID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Model(endpoint).Update("secret", secret).Error; err != nil {
+		return nil, errors.New(errors.CodeInternal, "更新签名密钥失败")
+	}
+	endpoint.Secret = secret
+	return endpoint, nil
+}
+
+// DeleteEndpoint 删除Webhook订阅，未完成的投递不再发送
+func DeleteEndpoint(endpointID string) error {
+	db := database.GetDB()
+	if db == nil {
+		return errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", endpointID).Delete(&models.WebhookEndpoint{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("endpoint_id = ? AND status = ?", endpointID, models.WebhookDeliveryPending).
+			Updates(map[string]interface{}{
+				"status":          models.WebhookDeliveryFailed,
+				"next_attempt_at": nil,
+				"error":           "Webhook已删除",
+			}).Error
+	})
+	if err == gorm.ErrRecordNotFound {
+		return errors.New(errors.CodeNotFound, "Webhook不存在")
+	}
+	if err != nil {
+		return errors.New(errors.CodeInternal, "删除Webhook失败")
+	}
+	return nil
+}
+
+// ListDeliveries 分页获取Webhook的投递记录，status为空时返回全部
+func ListDeliveries(endpointID, status string, page *common.PaginationRequest) ([]models.WebhookDelivery, int64, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, 0, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	if _, err := GetEndpoint(endpointID); err != nil {
+		return nil, 0, err
+	}
+
+	query := db.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpointID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, errors.New(errors.CodeQueryFailed, "查询投递记录失败")
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at DESC").
+		Offset(page.GetOffset()).
+		Limit(page.GetPageSize()).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, errors.New(errors.CodeQueryFailed, "查询投递记录失败")
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver 重新投递，复制原投递的事件内容创建新的投递记录，原记录保留在日志中
+func Redeliver(endpointID, deliveryID string) (*models.WebhookDelivery, error) {
+	db := database.GetDB()
+	if db == nil {
+		return nil, errors.New(errors.CodeDBConnectionFailed, "数据库连接失败")
+	}
+
+	endpoint, err := GetEndpoint(endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if !endpoint.IsActive {
+		return nil, errors.New(errors.CodeInvalidParameter, "Webhook已停用")
+	}
+
+	var original models.WebhookDelivery
+	if err := db.Where("id = ? AND endpoint_id = ?", deliveryID, endpointID).First(&original).Error; err != nil {
+		return nil, errors.New(errors.CodeNotFound, "投递记录不存在")
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		EndpointID:    endpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+	}
+	if err := db.Create(delivery).Error; err != nil {
+		return nil, errors.New(errors.CodeInternal, "创建投递记录失败")
+	}
+
+	go ProcessDueDeliveries()
+	return delivery, nil
+}
+
+// validateURL 校验接收地址，只允许http和https
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New(errors.CodeInvalidParameter, "接收地址必须是有效的http或https地址")
+	}
+	return nil
+}
+
+// normalizeEvents 校验并去重事件类型
+func normalizeEvents(events []string) ([]string, error) {
+	seen := make(map[string]bool, len(events))
+	result := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.TrimSpace(event)
+		if !common.IsValidWebhookEvent(event) {
+			return nil, errors.New(errors.CodeInvalidParameter, "无效的事件类型: "+event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, event)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New(errors.CodeInvalidParameter, "至少需要订阅一个事件")
+	}
+	return result, nil
+}
+
+// generateSecret 生成签名密钥
+func generateSecret() (string, error) {
+	buf := make([]byte, secretRandomBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.New(errors.CodeInternal, "生成签名密钥失败")
+	}
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
@@ -13,6 +13,7 @@ type Cache interface {
 	Exists(key string) bool
 	TTL(key string) (time.Duration, error)
 	Expire(key string, expiration time.Duration) error
+	Incr(key string, expiration time.Duration) (int64, error)
 	Close() error
 }
 
@@ -72,6 +73,11 @@ func Expire(key string, expiration time.Duration) error {
 	return GetCache().Expire(key, expiration)
 }
 
+// Incr 计数器加一，键不存在时创建并设置过期时间，返回加一后的值
+func Incr(key string, expiration time.Duration) (int64, error) {
+	return GetCache().Incr(key, expiration)
+}
+
 // Close 关闭缓存连接
 func Close() error {
 	if defaultCache != nil {
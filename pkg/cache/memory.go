@@ -2,6 +2,7 @@ package cache
 
 import (
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -158,6 +159,30 @@ func (c *MemCache) Expire(key string, expiration time.Duration) error {
 	return nil
 }
 
+// Incr 计数器加一，键不存在或已过期时从1开始计数并设置过期时间
+func (c *MemCache) Incr(key string, expiration time.Duration) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	item, exists := c.data[key]
+	if !exists || (!item.expiration.IsZero() && item.expiration.Before(time.Now())) {
+		item = memCacheItem{value: "0"}
+		if expiration > 0 {
+			item.expiration = time.Now().Add(expiration)
+		}
+	}
+
+	count, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, errors.New("缓存值不是整数")
+	}
+	count++
+
+	item.value = strconv.FormatInt(count, 10)
+	c.data[key] = item
+	return count, nil
+}
+
 // Close 关闭内存缓存
 func (c *MemCache) Close() error {
 	c.mutex.Lock()
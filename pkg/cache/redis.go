@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"errors"
+	"strconv"
 	"template/pkg/config"
 	"time"
 
@@ -28,7 +29,7 @@ func InitRedis() error {
 
 	// 创建Redis客户端
 	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Host + ":" + string(cfg.Port),
+		Addr:     cfg.Host + ":" + strconv.Itoa(cfg.Port),
 		Password: cfg.Password,
 		DB:       cfg.DB,
 	})
@@ -82,6 +83,21 @@ func (c *RedisCache) Expire(key string, expiration time.Duration) error {
 	return c.client.Expire(c.ctx, key, expiration).Err()
 }
 
+// Incr 计数器加一，键首次创建时设置过期时间
+func (c *RedisCache) Incr(key string, expiration time.Duration) (int64, error) {
+	count, err := c.client.Incr(c.ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 && expiration > 0 {
+		if err := c.client.Expire(c.ctx, key, expiration).Err(); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
 // Close 关闭Redis连接
 func (c *RedisCache) Close() error {
 	if c.client != nil {
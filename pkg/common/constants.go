@@ -10,6 +10,8 @@ const (
 	CodeTypeResetPassword = "reset_password"
 	// CodeTypeChangeEmail 修改邮箱验证码
 	CodeTypeChangeEmail = "change_email"
+	// CodeTypeDeleteAccount 注销账号验证码
+	CodeTypeDeleteAccount = "delete_account"
 )
 
 // 系统常量
@@ -38,6 +40,65 @@ const (
 	UserRoleUser       = 3 // 普通用户
 )
 
+// 认证方式常量
+const (
+	AuthTypeJWT    = "jwt"     // 登录令牌
+	AuthTypeAPIKey = "api_key" // API密钥（个人访问令牌）
+
+	// APIKeyPrefix API密钥前缀，便于识别和密钥泄露扫描
+	APIKeyPrefix = "pat_"
+)
+
+// API密钥授权范围
+const (
+	ScopeUploadWrite = "upload:write" // 上传文件
+	ScopeFilesRead   = "files:read"   // 下载和预览私有文件，查看文件、文件夹和回收站
+	ScopeFilesWrite  = "files:write"  // 修改文件和文件夹，管理历史版本和临时访问链接
+	ScopeFilesDelete = "files:delete" // 删除文件和文件夹，清理回收站和历史版本
+)
+
+// APIKeyScopes 所有可分配给API密钥的授权范围
+var APIKeyScopes = []string{ScopeUploadWrite, ScopeFilesRead, ScopeFilesWrite, ScopeFilesDelete}
+
+// IsValidScope 检查授权范围是否有效
+func IsValidScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook事件类型
+const (
+	WebhookEventUserRegistered = "user.registered" // 用户注册（包括第三方登录自动创建账号）
+	WebhookEventFileUploaded   = "file.uploaded"   // 文件上传完成
+	WebhookEventFileDownloaded = "file.downloaded" // 文件被下载
+	WebhookEventShareCreated   = "share.created"   // 创建文件分享
+
+	WebhookEventFileQuarantined = "file.quarantined" // 文件安全扫描发现威胁，已被隔离
+)
+
+// WebhookEvents 所有可订阅的Webhook事件
+var WebhookEvents = []string{
+	WebhookEventUserRegistered,
+	WebhookEventFileUploaded,
+	WebhookEventFileDownloaded,
+	WebhookEventShareCreated,
+	WebhookEventFileQuarantined,
+}
+
+// IsValidWebhookEvent 检查Webhook事件类型是否有效
+func IsValidWebhookEvent(event string) bool {
+	for _, e := range WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
 // 日志等级常量
 const (
 	LogLevelSuccess = 1 // 成功（绿色）
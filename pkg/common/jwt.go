@@ -5,14 +5,179 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// 令牌类型常量
+const (
+	TokenTypeAccess             = "access"               // 访问令牌
+	TokenTypeRefresh            = "refresh"              // 刷新令牌
+	TokenTypeTwoFactorChallenge = "two_factor_challenge" // 两步验证挑战令牌，仅可用于完成登录
+)
+
+// 令牌有效期默认值（配置缺省时使用）
+const (
+	defaultAccessExpiresIn  = 30  // 访问令牌默认有效期，单位分钟
+	defaultRefreshExpiresIn = 168 // 刷新令牌默认有效期，单位小时
+)
+
+func init() {
+	// 签发时间精确到毫秒，与用户令牌整体吊销时间比较时才能区分同一秒内签发的令牌
+	jwt.TimePrecision = time.Millisecond
+}
+
 // JWTClaims 自定义JWT声明结构
 type JWTClaims struct {
-	UserID   string `json:"user_id"` // 改为string类型以支持UUID
-	Role     int    `json:"role"`
-	Username string `json:"username"`
+	UserID    string `json:"user_id"` // 改为string类型以支持UUID
+	Role      int    `json:"role"`
+	Username  string `json:"username"`
+	TokenType string `json:"token_type,omitempty"` // 令牌类型，旧版令牌为空，按访问令牌处理
+	SessionID string `json:"sid,omitempty"`        // 登录会话ID
+	TwoFactor bool   `json:"mfa,omitempty"`        // 本次登录是否通过了两步验证
+
+	PasswordChangeRequired bool `json:"pcr,omitempty"` // 管理员重置了密码，修改密码前只能访问少数接口
 	jwt.RegisteredClaims
+
+	// 以下字段只在请求上下文中使用，不写入令牌
+	AuthType string   `json:"-"` // 认证方式，见 AuthTypeJWT / AuthTypeAPIKey
+	Scopes   []string `json:"-"` // API密钥的授权范围
+}
+
+// IsRefreshToken 是否为刷新令牌
+func (c *JWTClaims) IsRefreshToken() bool {
+	return c.TokenType == TokenTypeRefresh
+}
+
+// IsAccessToken 是否为访问令牌（旧版令牌没有类型，按访问令牌处理）
+func (c *JWTClaims) IsAccessToken() bool {
+	return c.TokenType == "" || c.TokenType == TokenTypeAccess
+}
+
+// IsAPIKey 是否通过API密钥认证
+func (c *JWTClaims) IsAPIKey() bool {
+	return c.AuthType == AuthTypeAPIKey
+}
+
+// HasScope 是否拥有指定授权范围，JWT认证代表用户本人，不受授权范围限制
+func (c *JWTClaims) HasScope(scope string) bool {
+	if !c.IsAPIKey() {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenSubject 签发令牌所需的用户信息
+type TokenSubject struct {
+	UserID    string
+	Username  string
+	Role      int
+	SessionID string
+	TwoFactor bool
+
+	PasswordChangeRequired bool
+}
+
+// TokenPair 访问令牌与刷新令牌
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	minutes := config.GetConfig().JWT.AccessExpiresIn
+	if minutes <= 0 {
+		minutes = defaultAccessExpiresIn
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// RefreshTokenTTL 刷新令牌有效期
+func RefreshTokenTTL() time.Duration {
+	hours := config.GetConfig().JWT.RefreshExpiresIn
+	if hours <= 0 {
+		hours = defaultRefreshExpiresIn
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// GenerateTokenPair 签发一对访问令牌和刷新令牌，刷新令牌会登记到缓存中用于轮换校验
+func GenerateTokenPair(subject TokenSubject) (*TokenPair, error) {
+	now := time.Now()
+	accessExpiresAt := now.Add(AccessTokenTTL())
+	refreshExpiresAt := now.Add(RefreshTokenTTL())
+
+	accessToken, err := signToken(subject, TokenTypeAccess, uuid.New().String(), now, accessExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshID := uuid.New().String()
+	refreshToken, err := signToken(subject, TokenTypeRefresh, refreshID, now, refreshExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeRefreshToken(refreshID, subject.UserID, RefreshTokenTTL()); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// GenerateChallengeToken 签发两步验证挑战令牌，密码校验通过后用于提交验证码
+func GenerateChallengeToken(subject TokenSubject, ttl time.Duration) (string, *JWTClaims, error) {
+	now := time.Now()
+	tokenID := uuid.New().String()
+	token, err := signToken(subject, TokenTypeTwoFactorChallenge, tokenID, now, now.Add(ttl))
+	if err != nil {
+		return "", nil, err
+	}
+
+	claims := &JWTClaims{
+		UserID:    subject.UserID,
+		TokenType: TokenTypeTwoFactorChallenge,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return token, claims, nil
+}
+
+// signToken 按指定类型签发令牌
+func signToken(subject TokenSubject, tokenType, tokenID string, issuedAt, expiresAt time.Time) (string, error) {
+	claims := JWTClaims{
+		UserID:    subject.UserID,
+		Username:  subject.Username,
+		Role:      subject.Role,
+		TokenType: tokenType,
+		SessionID: subject.SessionID,
+		TwoFactor: subject.TwoFactor,
+
+		PasswordChangeRequired: subject.PasswordChangeRequired,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(config.GetConfig().JWT.SecretKey))
 }
 
 // GenerateToken 生成JWT令牌 - 支持UUID
@@ -0,0 +1,86 @@
+package common
+
+// 系统内置角色标识
+const (
+	RoleNameSuperAdmin = "super_admin" // 超级管理员，始终拥有全部权限
+	RoleNameAdmin      = "admin"       // 管理员
+	RoleNameUser       = "user"        // 普通用户
+)
+
+// 权限标识，格式为 资源:操作
+const (
+	PermUserRead   = "user:read"   // 查看用户
+	PermUserManage = "user:manage" // 管理用户（禁用、解锁、重置密码等）
+	PermRoleManage = "role:manage" // 管理角色并为用户分配角色
+	PermFileRead   = "file:read"   // 查看所有用户的文件
+	PermFileDelete = "file:delete" // 删除任意文件
+	PermFileManage = "file:manage" // 管理文件分享和访问权限
+	PermUploadFile = "upload:file" // 上传文件
+
+	PermWebhookManage = "webhook:manage" // 管理Webhook订阅
+)
+
+// PermissionDefinition 权限定义
+type PermissionDefinition struct {
+	Code string
+	Name string
+}
+
+// Permissions 系统内置的全部权限，启动时同步到数据库
+var Permissions = []PermissionDefinition{
+	{PermUserRead, "查看用户"},
+	{PermUserManage, "管理用户"},
+	{PermRoleManage, "管理角色"},
+	{PermFileRead, "查看所有文件"},
+	{PermFileDelete, "删除任意文件"},
+	{PermFileManage, "管理文件权限"},
+	{PermUploadFile, "上传文件"},
+	{PermWebhookManage, "管理Webhook"},
+}
+
+// RoleDefinition 内置角色定义
+type RoleDefinition struct {
+	Name        string
+	DisplayName string
+	LegacyRole  int      // 对应的旧版整数角色
+	Permissions []string // 创建角色时授予的默认权限
+}
+
+// SystemRoles 系统内置角色，超级管理员的权限在每次启动时都会与全部权限同步
+var SystemRoles = []RoleDefinition{
+	{RoleNameSuperAdmin, "超级管理员", UserRoleSuperAdmin, nil},
+	{RoleNameAdmin, "管理员", UserRoleAdmin, []string{
+		PermUserRead, PermUserManage, PermFileRead, PermFileDelete, PermFileManage, PermUploadFile,
+	}},
+	{RoleNameUser, "普通用户", UserRoleUser, []string{PermUploadFile}},
+}
+
+// LegacyRoleName 获取旧版整数角色对应的内置角色标识
+func LegacyRoleName(role int) string {
+	for _, r := range SystemRoles {
+		if r.LegacyRole == role {
+			return r.Name
+		}
+	}
+	return RoleNameUser
+}
+
+// IsSystemRole 是否是系统内置角色
+func IsSystemRole(name string) bool {
+	for _, r := range SystemRoles {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidPermission 检查权限标识是否有效
+func IsValidPermission(code string) bool {
+	for _, p := range Permissions {
+		if p.Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"template/pkg/cache"
+	"template/pkg/config"
+	"template/pkg/constants"
+	"time"
+)
+
+// ErrRefreshTokenReused 刷新令牌已被使用过（可能被盗用）
+var ErrRefreshTokenReused = errors.New("刷新令牌已失效")
+
+// storeRefreshToken 登记新签发的刷新令牌
+func storeRefreshToken(tokenID, userID string, ttl time.Duration) error {
+	return cache.Set(fmt.Sprintf(constants.CacheKeyRefreshToken, tokenID), userID, ttl)
+}
+
+// ConsumeRefreshToken 消费刷新令牌，每个刷新令牌只能使用一次
+// 如果令牌已被使用过，视为令牌泄露，吊销该用户的全部令牌
+func ConsumeRefreshToken(claims *JWTClaims) error {
+	key := fmt.Sprintf(constants.CacheKeyRefreshToken, claims.ID)
+	userID, err := cache.Get(key)
+	if err != nil || userID != claims.UserID {
+		_ = RevokeUserTokens(claims.UserID)
+		return ErrRefreshTokenReused
+	}
+	return cache.Del(key)
+}
+
+// RevokeToken 吊销单个令牌，黑名单记录保留到令牌自然过期
+func RevokeToken(claims *JWTClaims) error {
+	if claims == nil || claims.ID == "" || claims.ExpiresAt == nil {
+		return nil
+	}
+
+	if claims.IsRefreshToken() {
+		_ = cache.Del(fmt.Sprintf(constants.CacheKeyRefreshToken, claims.ID))
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return cache.Set(fmt.Sprintf(constants.CacheKeyRevokedToken, claims.ID), "1", ttl)
+}
+
+// RevokeUserTokens 吊销用户在此之前签发的所有令牌
+func RevokeUserTokens(userID string) error {
+	// 记录需要覆盖所有可能仍然有效的令牌，取刷新令牌与旧版令牌有效期的较大值
+	ttl := RefreshTokenTTL()
+	if legacy := time.Duration(config.GetConfig().JWT.ExpiresIn) * time.Hour; legacy > ttl {
+		ttl = legacy
+	}
+
+	key := fmt.Sprintf(constants.CacheKeyUserRevokedAt, userID)
+	return cache.Set(key, strconv.FormatInt(time.Now().UnixMilli(), 10), ttl)
+}
+
+// IsTokenRevoked 检查令牌是否已被吊销
+func IsTokenRevoked(claims *JWTClaims) bool {
+	if claims.ID != "" && cache.Exists(fmt.Sprintf(constants.CacheKeyRevokedToken, claims.ID)) {
+		return true
+	}
+
+	value, err := cache.Get(fmt.Sprintf(constants.CacheKeyUserRevokedAt, claims.UserID))
+	if err != nil {
+		return false
+	}
+	revokedAt, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false
+	}
+	// 吊销时间和签发时间都精确到毫秒，吊销后同一秒内重新登录签发的令牌仍然有效
+	return claims.IssuedAt == nil || claims.IssuedAt.UnixMilli() < revokedAt
+}
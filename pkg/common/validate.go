@@ -16,6 +16,16 @@ type ValidationError struct {
 
 // ValidateRequest 通用的请求验证函数，使用错误处理系统
 func ValidateRequest[T any](c *gin.Context) (*T, error) {
+	return validateWith[T](c, binding.JSON)
+}
+
+// ValidateQuery 验证查询参数，用于列表筛选等GET请求
+func ValidateQuery[T any](c *gin.Context) (*T, error) {
+	return validateWith[T](c, binding.Query)
+}
+
+// validateWith 使用指定的绑定方式绑定并验证请求
+func validateWith[T any](c *gin.Context, b binding.Binding) (*T, error) {
 	var req T
 
 	// 如果请求结构体实现了 GetValidationMessages 接口，则获取验证消息
@@ -25,7 +35,7 @@ func ValidateRequest[T any](c *gin.Context) (*T, error) {
 	}
 
 	// 绑定并验证请求
-	if err := c.ShouldBindWith(&req, binding.JSON); err != nil {
+	if err := c.ShouldBindWith(&req, b); err != nil {
 		if validationErrors, ok := err.(validator.ValidationErrors); ok {
 			// 构建错误信息
 			for _, validationErr := range validationErrors {
@@ -18,14 +18,23 @@ const (
 
 // Config 应用配置结构
 type Config struct {
-	App      AppConfig      `yaml:"app" env:"APP"`
-	Database DatabaseConfig `yaml:"database" env:"DB"`
-	Redis    RedisConfig    `yaml:"redis" env:"REDIS"`
-	JWT      JWTConfig      `yaml:"jwt" env:"JWT"`
-	Log      LogConfig      `yaml:"log" env:"LOG"`
-	Mail     MailConfig     `yaml:"mail" env:"MAIL"`
-	CORS     CORSConfig     `yaml:"cors" env:"CORS"`
-	Frontend FrontendConfig `yaml:"frontend" env:"FRONTEND"`
+	App       AppConfig       `yaml:"app" env:"APP"`
+	Database  DatabaseConfig  `yaml:"database" env:"DB"`
+	Redis     RedisConfig     `yaml:"redis" env:"REDIS"`
+	JWT       JWTConfig       `yaml:"jwt" env:"JWT"`
+	Log       LogConfig       `yaml:"log" env:"LOG"`
+	Mail      MailConfig      `yaml:"mail" env:"MAIL"`
+	CORS      CORSConfig      `yaml:"cors" env:"CORS"`
+	Frontend  FrontendConfig  `yaml:"frontend" env:"FRONTEND"`
+	Security  SecurityConfig  `yaml:"security" env:"SECURITY"`
+	OAuth     OAuthConfig     `yaml:"oauth" env:"OAUTH"`
+	Account   AccountConfig   `yaml:"account" env:"ACCOUNT"`
+	Webhook   WebhookConfig   `yaml:"webhook" env:"WEBHOOK"`
+	Storage   StorageConfig   `yaml:"storage" env:"STORAGE"`
+	Thumbnail ThumbnailConfig `yaml:"thumbnail" env:"THUMBNAIL"`
+	Scanner   ScannerConfig   `yaml:"scanner" env:"SCANNER"`
+	Quota     QuotaConfig     `yaml:"quota" env:"QUOTA"`
+	Upload    UploadConfig    `yaml:"upload" env:"UPLOAD"`
 }
 
 // AppConfig 应用基础配置
@@ -57,8 +66,10 @@ type RedisConfig struct {
 
 // JWTConfig JWT配置
 type JWTConfig struct {
-	SecretKey string `yaml:"secret_key" env:"SECRET_KEY"`
-	ExpiresIn int    `yaml:"expires_in" env:"EXPIRES_IN"`
+	SecretKey        string `yaml:"secret_key" env:"SECRET_KEY"`
+	ExpiresIn        int    `yaml:"expires_in" env:"EXPIRES_IN"`                 // 旧版单令牌有效期，单位小时
+	AccessExpiresIn  int    `yaml:"access_expires_in" env:"ACCESS_EXPIRES_IN"`   // 访问令牌有效期，单位分钟
+	RefreshExpiresIn int    `yaml:"refresh_expires_in" env:"REFRESH_EXPIRES_IN"` // 刷新令牌有效期，单位小时
 }
 
 // LogConfig 日志配置
@@ -116,6 +127,109 @@ type FrontendFallbackConfig struct {
 	Message string `yaml:"message" env:"MESSAGE"`
 }
 
+// SecurityConfig 安全策略配置
+type SecurityConfig struct {
+	TwoFactorRequiredRoles []int `yaml:"two_factor_required_roles" env:"TWO_FACTOR_REQUIRED_ROLES"` // 必须启用两步验证才能访问管理接口的角色
+	LoginMaxAttempts       int   `yaml:"login_max_attempts" env:"LOGIN_MAX_ATTEMPTS"`               // 单个账号连续登录失败的次数上限，达到后临时锁定
+	LoginIPMaxAttempts     int   `yaml:"login_ip_max_attempts" env:"LOGIN_IP_MAX_ATTEMPTS"`         // 单个IP登录失败的次数上限，达到后临时封禁
+	LoginLockMinutes       int   `yaml:"login_lock_minutes" env:"LOGIN_LOCK_MINUTES"`               // 锁定时长，同时也是失败次数的统计窗口，单位分钟
+}
+
+// IsTwoFactorRequired 指定角色是否被要求启用两步验证
+func (c *SecurityConfig) IsTwoFactorRequired(role int) bool {
+	for _, r := range c.TwoFactorRequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// OAuthConfig 第三方登录配置
+type OAuthConfig struct {
+	Enabled          bool                           `yaml:"enabled" env:"ENABLED"`
+	RedirectBaseURL  string                         `yaml:"redirect_base_url" env:"REDIRECT_BASE_URL"` // 回调地址前缀，为空时根据请求推断
+	FrontendRedirect string                         `yaml:"frontend_redirect" env:"FRONTEND_REDIRECT"` // 登录完成后跳转的前端地址，为空时直接返回JSON
+	AutoRegister     bool                           `yaml:"auto_register" env:"AUTO_REGISTER"`         // 首次登录时是否自动创建账号
+	Providers        map[string]OAuthProviderConfig `yaml:"providers" env:"PROVIDERS"`
+}
+
+// OAuthProviderConfig 第三方登录提供方配置
+type OAuthProviderConfig struct {
+	Type         string   `yaml:"type"`          // oidc 或 oauth2
+	DisplayName  string   `yaml:"display_name"`  // 展示名称
+	ClientID     string   `yaml:"client_id"`     // 客户端ID
+	ClientSecret string   `yaml:"client_secret"` // 客户端密钥
+	Issuer       string   `yaml:"issuer"`        // OIDC签发方，用于自动发现端点
+	AuthURL      string   `yaml:"auth_url"`      // 授权端点（oauth2类型必填，oidc类型可覆盖发现结果）
+	TokenURL     string   `yaml:"token_url"`     // 令牌端点
+	UserInfoURL  string   `yaml:"userinfo_url"`  // 用户信息端点
+	EmailsURL    string   `yaml:"emails_url"`    // 邮箱列表端点（GitHub风格，用户信息中没有公开邮箱时使用）
+	Scopes       []string `yaml:"scopes"`        // 授权范围
+	RedirectURL  string   `yaml:"redirect_url"`  // 回调地址，为空时使用 redirect_base_url 拼接
+}
+
+// AccountConfig 账号注销配置
+type AccountConfig struct {
+	DeletionGraceDays int    `yaml:"deletion_grace_days" env:"DELETION_GRACE_DAYS"` // 申请注销后的保留天数，到期后匿名化个人信息
+	DeletedFilePolicy string `yaml:"deleted_file_policy" env:"DELETED_FILE_POLICY"` // 注销用户上传文件的处理方式：purge 或 reassign
+	ReassignFilesTo   string `yaml:"reassign_files_to" env:"REASSIGN_FILES_TO"`     // 转移文件时接收文件的用户名
+}
+
+// WebhookConfig Webhook投递配置
+type WebhookConfig struct {
+	Timeout     int `yaml:"timeout" env:"TIMEOUT"`           // 单次投递超时时间（秒）
+	MaxAttempts int `yaml:"max_attempts" env:"MAX_ATTEMPTS"` // 最大投递次数，超过后标记为失败
+	BatchSize   int `yaml:"batch_size" env:"BATCH_SIZE"`     // 每轮最多处理的投递数量
+}
+
+// StorageConfig 文件存储配置
+type StorageConfig struct {
+	Driver string   `yaml:"driver" env:"DRIVER"` // 存储类型：local（默认）或 s3
+	S3     S3Config `yaml:"s3" env:"S3"`
+}
+
+// S3Config S3兼容对象存储配置（AWS S3、MinIO等）
+type S3Config struct {
+	Endpoint   string `yaml:"endpoint" env:"ENDPOINT"`         // 服务地址，如 https://s3.us-east-1.amazonaws.com 或 http://127.0.0.1:9000
+	Region     string `yaml:"region" env:"REGION"`             // 区域，默认 us-east-1
+	Bucket     string `yaml:"bucket" env:"BUCKET"`             // 存储桶
+	AccessKey  string `yaml:"access_key" env:"ACCESS_KEY"`     // 访问密钥ID
+	SecretKey  string `yaml:"secret_key" env:"SECRET_KEY"`     // 访问密钥
+	PathStyle  bool   `yaml:"path_style" env:"PATH_STYLE"`     // 使用路径风格访问（MinIO需要开启）
+	Prefix     string `yaml:"prefix" env:"PREFIX"`             // 对象键前缀
+	PartSizeMB int    `yaml:"part_size_mb" env:"PART_SIZE_MB"` // 合并分片时的分段大小（MB），最小5
+}
+
+// ThumbnailConfig 图片缩略图配置
+type ThumbnailConfig struct {
+	Sizes     []int `yaml:"sizes"`                       // 生成的缩略图尺寸（长边像素），只能通过配置文件设置
+	Quality   int   `yaml:"quality" env:"QUALITY"`       // JPEG缩略图质量（1-100）
+	MaxPixels int64 `yaml:"max_pixels" env:"MAX_PIXELS"` // 原图像素数上限，超过时不生成缩略图
+}
+
+// ScannerConfig 恶意文件扫描配置
+type ScannerConfig struct {
+	Driver      string `yaml:"driver" env:"DRIVER"`           // 扫描器类型：none（默认，不扫描）或 clamd
+	Address     string `yaml:"address" env:"ADDRESS"`         // clamd的TCP地址，如 127.0.0.1:3310
+	Timeout     int    `yaml:"timeout" env:"TIMEOUT"`         // 单个文件的扫描超时时间（秒）
+	Concurrency int    `yaml:"concurrency" env:"CONCURRENCY"` // 同时扫描的文件数
+}
+
+// QuotaConfig 存储配额配置，配额单位为MB，0表示不限制
+type QuotaConfig struct {
+	DefaultMB int64            `yaml:"default_mb" env:"DEFAULT_MB"` // 未在 roles 中配置的角色的配额
+	Roles     map[string]int64 `yaml:"roles"`                       // 各角色的配额，用户有多个角色时取最大值，只能通过配置文件设置
+}
+
+// UploadConfig 上传配置
+type UploadConfig struct {
+	ExpirationHours    int    `yaml:"expiration_hours" env:"EXPIRATION_HOURS"`         // 未完成的上传超过该时间没有写入时由定时任务清理（小时）
+	VersionRetention   int    `yaml:"version_retention" env:"VERSION_RETENTION"`       // 每个文件保留的历史版本数，超出时删除最旧的版本，0表示不限制
+	TrashRetentionDays int    `yaml:"trash_retention_days" env:"TRASH_RETENTION_DAYS"` // 回收站中的文件超过该时间由定时任务彻底删除（天）
+	URLSigningKey      string `yaml:"url_signing_key" env:"URL_SIGNING_KEY"`           // 临时访问链接的签名密钥，为空时使用JWT签名密钥
+}
+
 var (
 	config Config
 	once   sync.Once
@@ -175,6 +289,34 @@ func loadConfigFromEnv(cfg *Config) {
 
 	// 处理Frontend配置的环境变量
 	loadEnvToStruct(envPrefix+"FRONTEND_", &cfg.Frontend)
+
+	// 处理Security配置的环境变量
+	loadEnvToStruct(envPrefix+"SECURITY_", &cfg.Security)
+
+	// 处理OAuth配置的环境变量（提供方列表只能通过配置文件设置）
+	loadEnvToStruct(envPrefix+"OAUTH_", &cfg.OAuth)
+
+	// 处理Account配置的环境变量
+	loadEnvToStruct(envPrefix+"ACCOUNT_", &cfg.Account)
+
+	// 处理Webhook配置的环境变量
+	loadEnvToStruct(envPrefix+"WEBHOOK_", &cfg.Webhook)
+
+	// 处理Storage配置的环境变量，S3配置使用 STORAGE_S3_ 前缀
+	loadEnvToStruct(envPrefix+"STORAGE_", &cfg.Storage)
+	loadEnvToStruct(envPrefix+"STORAGE_S3_", &cfg.Storage.S3)
+
+	// 处理Thumbnail配置的环境变量
+	loadEnvToStruct(envPrefix+"THUMBNAIL_", &cfg.Thumbnail)
+
+	// 处理Scanner配置的环境变量
+	loadEnvToStruct(envPrefix+"SCANNER_", &cfg.Scanner)
+
+	// 处理Quota配置的环境变量
+	loadEnvToStruct(envPrefix+"QUOTA_", &cfg.Quota)
+
+	// 处理Upload配置的环境变量
+	loadEnvToStruct(envPrefix+"UPLOAD_", &cfg.Upload)
 }
 
 // loadEnvToStruct 加载环境变量到结构体
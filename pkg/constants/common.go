@@ -19,6 +19,22 @@ const (
 	CacheKeyUserToken    = "template:user:token:%s"    // 用户令牌
 	CacheKeyEmailCode    = "template:email:code:%s"    // 邮箱验证码
 	CacheKeyLoginAttempt = "template:login:attempt:%s" // 登录尝试次数
+	CacheKeyLoginLock    = "template:login:lock:%s"    // 账号登录锁定
+	CacheKeyLoginIP      = "template:login:ip:%s"      // 单个IP登录失败次数
+
+	CacheKeyRefreshToken  = "template:token:refresh:%s"   // 有效的刷新令牌（按令牌ID）
+	CacheKeyRevokedToken  = "template:token:revoked:%s"   // 已吊销的令牌（按令牌ID）
+	CacheKeyUserRevokedAt = "template:user:revoked_at:%s" // 用户令牌整体吊销时间点
+	CacheKeySessionState  = "template:session:state:%s"   // 会话状态（active/revoked）
+
+	CacheKeyTwoFactorSetup     = "template:2fa:setup:%s"     // 待确认的TOTP密钥（按用户ID）
+	CacheKeyTwoFactorChallenge = "template:2fa:challenge:%s" // 登录挑战令牌（按令牌ID）
+	CacheKeyTwoFactorAttempts  = "template:2fa:attempts:%s"  // 挑战令牌验证失败次数
+	CacheKeyTwoFactorUsedStep  = "template:2fa:used:%s:%d"   // 已使用的TOTP时间步，防止重放
+
+	CacheKeyOAuthState = "template:oauth:state:%s" // 第三方登录授权请求状态
+
+	CacheKeyUserAuthorization = "template:rbac:user:%s" // 用户的角色和权限
 )
 
 // 缓存时间常量
@@ -1,17 +1,39 @@
 package constants
 
+import "time"
+
 // 上传状态常量
 const (
-	UploadStatusUploading = 1 // 上传中
-	UploadStatusCompleted = 2 // 已完成
-	UploadStatusFailed    = 3 // 上传失败
+	UploadStatusUploading   = 1 // 上传中
+	UploadStatusCompleted   = 2 // 已完成
+	UploadStatusFailed      = 3 // 上传失败
+	UploadStatusPendingScan = 4 // 已上传，等待安全扫描
+	UploadStatusQuarantined = 5 // 安全扫描发现威胁，已隔离
+	UploadStatusMerging     = 6 // 分片上传完毕，正在合并
+)
+
+// 派生文件状态常量
+const (
+	DerivativeStatusReady  = 1 // 可用
+	DerivativeStatusFailed = 2 // 生成失败，不再重试
 )
 
 // 文件存储相关常量
 const (
-	DefaultUploadDir = "./uploads"     // 默认上传目录
-	TempChunkDir     = "./uploads/tmp" // 临时分片目录
-	ChunkSize        = 1024 * 1024 * 2 // 默认分片大小 2MB
+	DefaultUploadDir = "./uploads"         // 默认上传目录
+	TempChunkDir     = "./uploads/tmp"     // 临时分片目录
+	DerivativeDir    = "derivatives"       // 派生文件目录，位于上传目录下
+	ChunkSize        = 1024 * 1024 * 2     // 默认分片大小 2MB
+	UploadExpiration = 24 * time.Hour      // 未完成的上传的保留时间
+	TrashRetention   = 30 * 24 * time.Hour // 回收站中的文件的保留时间
+)
+
+// 临时访问链接相关常量
+const (
+	TemporaryAccessDefaultTTL   = time.Hour          // 临时访问链接的默认有效期
+	TemporaryAccessMaxTTL       = 7 * 24 * time.Hour // 临时访问链接的最长有效期
+	TemporaryAccessMaxPerFile   = 100                // 每个文件最多的有效临时访问链接数
+	TemporaryAccessDefaultUsage = 1                  // 临时访问链接默认的使用次数
 )
 
 // 文件限制常量
@@ -26,6 +26,11 @@ func GetDB() *gorm.DB {
 	return db
 }
 
+// SetDB 替换数据库连接，供测试使用
+func SetDB(conn *gorm.DB) {
+	db = conn
+}
+
 // InitDB 初始化数据库连接
 func InitDB() {
 	cfg := config.GetConfig().Database
@@ -93,6 +98,16 @@ func InitDB() {
 		log.Fatal("创建 root 用户失败: %v", err)
 	}
 
+	// 同步内置角色和权限，并为已有用户分配角色
+	if err := SeedRBAC(); err != nil {
+		log.Fatal("初始化角色权限失败: %v", err)
+	}
+
+	// 为旧版本上传的文件创建内容记录
+	if err := migrateFileBlobs(); err != nil {
+		log.Fatal("迁移文件内容记录失败: %v", err)
+	}
+
 	log.Info("数据库连接成功")
 }
 
@@ -100,12 +115,29 @@ func InitDB() {
 func autoMigrate() error {
 	return db.AutoMigrate(
 		&models.User{},
+		&models.UserSession{},
+		&models.UserRecoveryCode{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		// 角色权限模型
+		&models.Role{},
+		&models.Permission{},
+		&models.UserRole{},
 		&models.UploadFile{},
+		&models.FileBlob{},
 		&models.ChunkInfo{},
+		&models.TusUpload{},
+		&models.FileDerivative{},
+		&models.UserQuota{},
+		&models.Folder{},
+		&models.FileVersion{},
 		// 文件权限管理模型
 		&models.FileShare{},
 		&models.FilePermission{},
 		&models.TemporaryAccess{},
+		// Webhook模型
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
 		// 在这里添加其他模型
 	)
 }
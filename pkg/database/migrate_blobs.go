@@ -0,0 +1,66 @@
+package database
+
+import (
+	"template/internal/models"
+	"template/pkg/constants"
+	log "template/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// migrateFileBlobs 为引入文件内容引用计数之前上传完成的文件创建内容记录
+// 内容相同的旧文件各自存储了一份，只有第一个会登记为共享内容，其余保持独占存储
+// 旧文件没有SHA-256，不参与秒传
+func migrateFileBlobs() error {
+	// 内容改为按SHA-256识别，去掉旧版本在MD5上创建的唯一索引
+	if db.Migrator().HasIndex(&models.FileBlob{}, "idx_file_blob_md5_hash") {
+		if err := db.Migrator().DropIndex(&models.FileBlob{}, "idx_file_blob_md5_hash"); err != nil {
+			return err
+		}
+	}
+
+	var files []models.UploadFile
+	err := db.Where("blob_id = ? AND upload_status = ? AND file_path <> ?", "", constants.UploadStatusCompleted, "").
+		Find(&files).Error
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for i := range files {
+		file := &files[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var count int64
+			if err := tx.Model(&models.FileBlob{}).Where("md5_hash = ?", file.MD5Hash).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil
+			}
+
+			blob := &models.FileBlob{
+				MD5Hash:    file.MD5Hash,
+				StoredName: file.StoredName,
+				FilePath:   file.FilePath,
+				FileSize:   file.FileSize,
+				RefCount:   1,
+			}
+			if err := tx.Create(blob).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(file).Update("blob_id", blob.ID.String()).Error; err != nil {
+				return err
+			}
+			migrated++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if migrated > 0 {
+		log.Info("已为 %d 个旧文件创建内容记录", migrated)
+	}
+	return nil
+}
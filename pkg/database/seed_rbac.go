@@ -0,0 +1,81 @@
+package database
+
+import (
+	"template/internal/models"
+	"template/pkg/common"
+	log "template/pkg/logger"
+
+	"gorm.io/gorm"
+)
+
+// SeedRBAC 同步内置权限和角色，并把旧版整数角色映射为角色关联
+// 启动时由 InitDB 调用，重复执行不会产生重复数据
+func SeedRBAC() error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 同步权限：新增缺失的权限，更新已有权限的名称
+		allPermissions := make([]models.Permission, 0, len(common.Permissions))
+		for _, def := range common.Permissions {
+			var perm models.Permission
+			err := tx.Where(models.Permission{Code: def.Code}).Attrs(models.Permission{Name: def.Name}).FirstOrCreate(&perm).Error
+			if err != nil {
+				return err
+			}
+			if perm.Name != def.Name {
+				if err := tx.Model(&perm).Update("name", def.Name).Error; err != nil {
+					return err
+				}
+			}
+			allPermissions = append(allPermissions, perm)
+		}
+
+		permByCode := make(map[string]models.Permission, len(allPermissions))
+		for _, perm := range allPermissions {
+			permByCode[perm.Code] = perm
+		}
+
+		for _, def := range common.SystemRoles {
+			var role models.Role
+			err := tx.Where("name = ?", def.Name).First(&role).Error
+			if err != nil && err != gorm.ErrRecordNotFound {
+				return err
+			}
+
+			created := err == gorm.ErrRecordNotFound
+			if created {
+				role = models.Role{Name: def.Name, DisplayName: def.DisplayName, IsSystem: true}
+				if err := tx.Create(&role).Error; err != nil {
+					return err
+				}
+				log.Info("已创建内置角色: %s", def.Name)
+			}
+
+			// 超级管理员始终拥有全部权限；其他内置角色只在首次创建时授予默认权限，之后由管理员维护
+			switch {
+			case def.Name == common.RoleNameSuperAdmin:
+				if err := tx.Model(&role).Association("Permissions").Replace(allPermissions); err != nil {
+					return err
+				}
+			case created:
+				perms := make([]models.Permission, 0, len(def.Permissions))
+				for _, code := range def.Permissions {
+					perms = append(perms, permByCode[code])
+				}
+				if err := tx.Model(&role).Association("Permissions").Replace(perms); err != nil {
+					return err
+				}
+			}
+
+			// 为还没有任何角色的用户，按旧版整数角色分配对应的内置角色
+			err = tx.Exec(`INSERT INTO user_role (user_id, role_id)
+				SELECT u.id, ? FROM user u
+				WHERE u.role = ? AND u.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM user_role ur WHERE ur.user_id = u.id)`,
+				role.ID.String(), def.LegacyRole).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
@@ -37,6 +37,7 @@ const (
 	CodeInvalidVerifyCode ErrorCode = 1006 // 无效的验证码
 	CodeEmailExists       ErrorCode = 1007 // 邮箱已存在
 	CodeEmailSendFailed   ErrorCode = 1008 // 邮件发送失败
+	CodeInvalidCredential ErrorCode = 1009 // 账号或密码错误（不区分账号不存在和密码错误）
 
 	// 数据库相关错误码（2000-2999）
 	CodeDBConnectionFailed ErrorCode = 2000 // 数据库连接失败
@@ -51,6 +52,9 @@ const (
 	CodeEmailServiceError ErrorCode = 3002 // 邮件服务错误
 	CodeSMSServiceError   ErrorCode = 3003 // 短信服务错误
 	CodePaymentError      ErrorCode = 3004 // 支付服务错误
+
+	// 文件相关错误码（4000-4999）
+	CodeQuotaExceeded ErrorCode = 4000 // 存储空间不足
 )
 
 // 错误码对应的HTTP状态码
@@ -77,6 +81,7 @@ var errorCodeToHTTPStatus = map[ErrorCode]int{
 	CodeInvalidVerifyCode: 400,
 	CodeEmailExists:       409,
 	CodeEmailSendFailed:   500,
+	CodeInvalidCredential: 401,
 
 	CodeDBConnectionFailed: 500,
 	CodeQueryFailed:        500,
@@ -89,6 +94,8 @@ var errorCodeToHTTPStatus = map[ErrorCode]int{
 	CodeEmailServiceError: 500,
 	CodeSMSServiceError:   500,
 	CodePaymentError:      500,
+
+	CodeQuotaExceeded: 413,
 }
 
 // 错误码对应的错误消息（面向用户友好的消息）
@@ -115,6 +122,7 @@ var errorCodeToMessage = map[ErrorCode]string{
 	CodeInvalidVerifyCode: "验证码无效或已过期",
 	CodeEmailExists:       "邮箱已被注册",
 	CodeEmailSendFailed:   "邮件发送失败，请稍后再试",
+	CodeInvalidCredential: "账号或密码错误",
 
 	CodeDBConnectionFailed: "数据库连接失败",
 	CodeQueryFailed:        "数据查询失败",
@@ -127,6 +135,8 @@ var errorCodeToMessage = map[ErrorCode]string{
 	CodeEmailServiceError: "邮件服务异常",
 	CodeSMSServiceError:   "短信服务异常",
 	CodePaymentError:      "支付服务异常",
+
+	CodeQuotaExceeded: "存储空间不足",
 }
 
 // Error 自定义错误类型
@@ -0,0 +1,238 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"template/pkg/config"
+	"time"
+)
+
+// httpClient 访问第三方接口使用的HTTP客户端
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// tokenResponse 令牌端点响应
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// OAuth2Provider GitHub风格的OAuth2提供方，通过用户信息接口获取身份
+type OAuth2Provider struct {
+	name string
+	cfg  config.OAuthProviderConfig
+}
+
+// NewOAuth2Provider 创建OAuth2提供方
+func NewOAuth2Provider(name string, cfg config.OAuthProviderConfig) *OAuth2Provider {
+	return &OAuth2Provider{name: name, cfg: cfg}
+}
+
+// Name 提供方名称
+func (p *OAuth2Provider) Name() string {
+	return p.name
+}
+
+// DisplayName 展示名称
+func (p *OAuth2Provider) DisplayName() string {
+	if p.cfg.DisplayName != "" {
+		return p.cfg.DisplayName
+	}
+	return p.name
+}
+
+// AuthCodeURL 生成授权地址
+func (p *OAuth2Provider) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	return buildAuthCodeURL(p.cfg.AuthURL, p.cfg.ClientID, p.cfg.Scopes, req, false)
+}
+
+// Exchange 使用授权码换取访问令牌并获取用户信息
+func (p *OAuth2Provider) Exchange(ctx context.Context, code string, req AuthRequest) (*Identity, error) {
+	token, err := exchangeCode(ctx, p.cfg.TokenURL, p.cfg.ClientID, p.cfg.ClientSecret, code, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile struct {
+		ID        json.RawMessage `json:"id"`
+		Sub       string          `json:"sub"`
+		Login     string          `json:"login"`
+		Name      string          `json:"name"`
+		Email     string          `json:"email"`
+		AvatarURL string          `json:"avatar_url"`
+	}
+	if err := getJSON(ctx, p.cfg.UserInfoURL, token.AccessToken, &profile); err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+
+	subject := profile.Sub
+	if subject == "" {
+		subject = rawIDString(profile.ID)
+	}
+	if subject == "" {
+		return nil, fmt.Errorf("用户信息中缺少用户标识")
+	}
+
+	identity := &Identity{
+		Provider:  p.name,
+		Subject:   subject,
+		Name:      profile.Name,
+		Username:  profile.Login,
+		AvatarURL: profile.AvatarURL,
+	}
+
+	// 用户信息中的公开邮箱无法确认是否已验证，优先使用邮箱列表接口中的已验证主邮箱
+	if p.cfg.EmailsURL != "" {
+		if email, err := p.fetchPrimaryEmail(ctx, token.AccessToken); err == nil && email != "" {
+			identity.Email = email
+			identity.EmailVerified = true
+		}
+	}
+	if identity.Email == "" {
+		identity.Email = profile.Email
+	}
+
+	return identity, nil
+}
+
+// fetchPrimaryEmail 从邮箱列表接口获取已验证的主邮箱
+func (p *OAuth2Provider) fetchPrimaryEmail(ctx context.Context, accessToken string) (string, error) {
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, p.cfg.EmailsURL, accessToken, &emails); err != nil {
+		return "", err
+	}
+
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			return e.Email, nil
+		}
+	}
+	return "", nil
+}
+
+// buildAuthCodeURL 拼接授权地址，统一使用PKCE（S256）
+func buildAuthCodeURL(authURL, clientID string, scopes []string, req AuthRequest, withNonce bool) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", fmt.Errorf("授权地址格式错误: %w", err)
+	}
+
+	query := u.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", clientID)
+	query.Set("redirect_uri", req.RedirectURI)
+	query.Set("state", req.State)
+	query.Set("code_challenge", CodeChallengeS256(req.CodeVerifier))
+	query.Set("code_challenge_method", "S256")
+	if len(scopes) > 0 {
+		query.Set("scope", strings.Join(scopes, " "))
+	}
+	if withNonce && req.Nonce != "" {
+		query.Set("nonce", req.Nonce)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// exchangeCode 调用令牌端点，使用授权码和PKCE校验值换取令牌
+func exchangeCode(ctx context.Context, tokenURL, clientID, clientSecret, code string, req AuthRequest) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", req.RedirectURI)
+	form.Set("client_id", clientID)
+	form.Set("code_verifier", req.CodeVerifier)
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌端点失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌响应失败: %w", err)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("解析令牌响应失败: %w", err)
+	}
+
+	if token.Error != "" {
+		return nil, fmt.Errorf("换取令牌失败: %s %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return nil, fmt.Errorf("换取令牌失败，状态码: %d", resp.StatusCode)
+	}
+
+	return &token, nil
+}
+
+// getJSON 携带访问令牌请求JSON接口
+func getJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("状态码: %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// rawIDString 将数字或字符串形式的ID统一转换为字符串
+func rawIDString(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		if i, err := n.Int64(); err == nil {
+			return strconv.FormatInt(i, 10)
+		}
+		return n.String()
+	}
+	return ""
+}
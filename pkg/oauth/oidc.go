@@ -0,0 +1,286 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"template/pkg/config"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksRefreshInterval 遇到未知kid时重新拉取JWKS的最小间隔
+const jwksRefreshInterval = time.Minute
+
+// discoveryDocument OIDC发现文档（只保留需要的字段）
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims ID令牌中的声明
+type idTokenClaims struct {
+	Nonce             string      `json:"nonce"`
+	Email             string      `json:"email"`
+	EmailVerified     interface{} `json:"email_verified"` // 部分提供方返回字符串 "true"
+	Name              string      `json:"name"`
+	PreferredUsername string      `json:"preferred_username"`
+	Picture           string      `json:"picture"`
+	jwt.RegisteredClaims
+}
+
+// OIDCProvider 标准OpenID Connect提供方，通过发现文档获取端点并校验ID令牌签名
+type OIDCProvider struct {
+	name string
+	cfg  config.OAuthProviderConfig
+
+	mu         sync.Mutex
+	discovery  *discoveryDocument
+	keys       map[string]*rsa.PublicKey
+	keysLoaded time.Time
+}
+
+// NewOIDCProvider 创建OIDC提供方，发现文档在首次使用时加载
+func NewOIDCProvider(name string, cfg config.OAuthProviderConfig) *OIDCProvider {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OIDCProvider{name: name, cfg: cfg}
+}
+
+// Name 提供方名称
+func (p *OIDCProvider) Name() string {
+	return p.name
+}
+
+// DisplayName 展示名称
+func (p *OIDCProvider) DisplayName() string {
+	if p.cfg.DisplayName != "" {
+		return p.cfg.DisplayName
+	}
+	return p.name
+}
+
+// AuthCodeURL 生成授权地址
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, req AuthRequest) (string, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+	return buildAuthCodeURL(doc.AuthorizationEndpoint, p.cfg.ClientID, p.cfg.Scopes, req, true)
+}
+
+// Exchange 使用授权码换取ID令牌，校验签名、签发方、受众和nonce后返回身份信息
+func (p *OIDCProvider) Exchange(ctx context.Context, code string, req AuthRequest) (*Identity, error) {
+	doc, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := exchangeCode(ctx, doc.TokenEndpoint, p.cfg.ClientID, p.cfg.ClientSecret, code, req)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("令牌响应中缺少 id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, doc, token.IDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Nonce != req.Nonce {
+		return nil, errors.New("ID令牌 nonce 不匹配")
+	}
+
+	identity := &Identity{
+		Provider:      p.name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: parseBoolClaim(claims.EmailVerified),
+		Name:          claims.Name,
+		Username:      claims.PreferredUsername,
+		AvatarURL:     claims.Picture,
+	}
+
+	// ID令牌中没有邮箱时，从用户信息端点补充
+	if identity.Email == "" && doc.UserInfoEndpoint != "" {
+		var info idTokenClaims
+		if err := getJSON(ctx, doc.UserInfoEndpoint, token.AccessToken, &info); err == nil && info.Subject == claims.Subject {
+			identity.Email = info.Email
+			identity.EmailVerified = parseBoolClaim(info.EmailVerified)
+			if identity.Name == "" {
+				identity.Name = info.Name
+			}
+			if identity.Username == "" {
+				identity.Username = info.PreferredUsername
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+// verifyIDToken 校验ID令牌
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, doc *discoveryDocument, rawToken string) (*idTokenClaims, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, doc, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(doc.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ID令牌校验失败: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("ID令牌中缺少 sub")
+	}
+	return claims, nil
+}
+
+// getDiscovery 获取发现文档，成功后缓存
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	endpoint := strings.TrimRight(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	var doc discoveryDocument
+	if err := getJSON(ctx, endpoint, "", &doc); err != nil {
+		return nil, fmt.Errorf("获取OIDC发现文档失败: %w", err)
+	}
+
+	if strings.TrimRight(doc.Issuer, "/") != strings.TrimRight(p.cfg.Issuer, "/") {
+		return nil, fmt.Errorf("OIDC发现文档的 issuer 不匹配: %s", doc.Issuer)
+	}
+
+	// 允许通过配置覆盖端点
+	if p.cfg.AuthURL != "" {
+		doc.AuthorizationEndpoint = p.cfg.AuthURL
+	}
+	if p.cfg.TokenURL != "" {
+		doc.TokenEndpoint = p.cfg.TokenURL
+	}
+	if p.cfg.UserInfoURL != "" {
+		doc.UserInfoEndpoint = p.cfg.UserInfoURL
+	}
+
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("OIDC发现文档缺少必要的端点")
+	}
+
+	p.discovery = &doc
+	return p.discovery, nil
+}
+
+// getKey 根据kid获取签名公钥，遇到未知kid时重新拉取JWKS以支持密钥轮换
+func (p *OIDCProvider) getKey(ctx context.Context, doc *discoveryDocument, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+
+	if time.Since(p.keysLoaded) < jwksRefreshInterval && p.keys != nil {
+		return nil, fmt.Errorf("未找到签名公钥: %s", kid)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := getJSON(ctx, doc.JWKSURI, "", &jwks); err != nil {
+		return nil, fmt.Errorf("获取JWKS失败: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAPublicKey(k.N, k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	p.keys = keys
+	p.keysLoaded = time.Now()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("未找到签名公钥: %s", kid)
+}
+
+// lookupKey 查找公钥，kid为空且只有一个公钥时直接使用该公钥
+func (p *OIDCProvider) lookupKey(kid string) *rsa.PublicKey {
+	if key, ok := p.keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key
+		}
+	}
+	return nil
+}
+
+// parseRSAPublicKey 从JWK的n、e参数构造RSA公钥
+func parseRSAPublicKey(n, e string) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(n)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(e)
+	if err != nil {
+		return nil, err
+	}
+
+	exponent := new(big.Int).SetBytes(eBytes)
+	if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("无效的RSA公钥指数")
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(exponent.Int64()),
+	}, nil
+}
+
+// parseBoolClaim 解析布尔或字符串形式的声明
+func parseBoolClaim(v interface{}) bool {
+	switch value := v.(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
@@ -0,0 +1,173 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"template/pkg/config"
+	"template/pkg/logger"
+)
+
+// 提供方类型
+const (
+	ProviderTypeOIDC   = "oidc"   // 标准OpenID Connect，支持自动发现
+	ProviderTypeOAuth2 = "oauth2" // GitHub风格的OAuth2，通过用户信息接口获取身份
+)
+
+// ErrProviderNotFound 提供方不存在或未启用
+var ErrProviderNotFound = errors.New("不支持的登录方式")
+
+// AuthRequest 一次授权请求的参数，回调时需要使用相同的参数完成换取
+type AuthRequest struct {
+	State        string // 防CSRF的随机状态值
+	Nonce        string // OIDC nonce，防止ID令牌重放
+	CodeVerifier string // PKCE校验值
+	RedirectURI  string // 回调地址
+}
+
+// Identity 第三方身份信息
+type Identity struct {
+	Provider      string // 提供方名称
+	Subject       string // 提供方内的唯一用户标识
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string // 提供方内的用户名（如GitHub login），用于生成本地用户名
+	AvatarURL     string
+}
+
+// Provider 第三方登录提供方
+type Provider interface {
+	// Name 提供方名称，对应路由中的 :provider
+	Name() string
+	// DisplayName 展示名称
+	DisplayName() string
+	// AuthCodeURL 生成授权地址
+	AuthCodeURL(ctx context.Context, req AuthRequest) (string, error)
+	// Exchange 使用授权码换取令牌并获取用户身份
+	Exchange(ctx context.Context, code string, req AuthRequest) (*Identity, error)
+}
+
+var (
+	providers   = make(map[string]Provider)
+	providersMu sync.RWMutex
+	loadOnce    sync.Once
+)
+
+// NewProvider 根据配置创建提供方
+func NewProvider(name string, cfg config.OAuthProviderConfig) (Provider, error) {
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("登录方式 %s 未配置 client_id", name)
+	}
+
+	switch cfg.Type {
+	case ProviderTypeOIDC, "":
+		if cfg.Issuer == "" {
+			return nil, fmt.Errorf("登录方式 %s 未配置 issuer", name)
+		}
+		return NewOIDCProvider(name, cfg), nil
+	case ProviderTypeOAuth2:
+		if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "" {
+			return nil, fmt.Errorf("登录方式 %s 缺少 auth_url、token_url 或 userinfo_url", name)
+		}
+		return NewOAuth2Provider(name, cfg), nil
+	default:
+		return nil, fmt.Errorf("登录方式 %s 的类型 %s 不受支持", name, cfg.Type)
+	}
+}
+
+// RegisterProvider 注册提供方，同名提供方会被覆盖
+func RegisterProvider(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+// GetProvider 获取已启用的提供方，首次调用时从配置加载
+func GetProvider(name string) (Provider, error) {
+	loadOnce.Do(loadProvidersFromConfig)
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	p, ok := providers[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+	return p, nil
+}
+
+// ListProviders 获取所有已启用的提供方，按名称排序
+func ListProviders() []Provider {
+	loadOnce.Do(loadProvidersFromConfig)
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	list := make([]Provider, 0, len(providers))
+	for _, p := range providers {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name() < list[j].Name() })
+	return list
+}
+
+// loadProvidersFromConfig 从配置文件加载提供方，配置错误的提供方会被跳过
+func loadProvidersFromConfig() {
+	cfg := config.GetConfig().OAuth
+	if !cfg.Enabled {
+		return
+	}
+
+	for name, providerCfg := range cfg.Providers {
+		p, err := NewProvider(name, providerCfg)
+		if err != nil {
+			logger.Warn("加载第三方登录配置失败: %v", err)
+			continue
+		}
+		RegisterProvider(p)
+	}
+}
+
+// GenerateRandomString 生成URL安全的随机字符串，用于state、nonce和PKCE校验值
+func GenerateRandomString(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewAuthRequest 生成新的授权请求参数
+func NewAuthRequest(redirectURI string) (*AuthRequest, error) {
+	state, err := GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthRequest{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		RedirectURI:  redirectURI,
+	}, nil
+}
+
+// CodeChallengeS256 按RFC 7636计算PKCE的S256校验码
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
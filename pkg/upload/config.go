@@ -1,6 +1,8 @@
 package upload
 
 import (
+	"time"
+
 	"template/pkg/constants"
 )
 
@@ -11,6 +13,8 @@ type Config struct {
 	ChunkSize        int64           `yaml:"chunk_size"`         // 分片大小
 	UploadDir        string          `yaml:"upload_dir"`         // 上传目录
 	TempDir          string          `yaml:"temp_dir"`           // 临时目录
+	UploadExpiration time.Duration   `yaml:"upload_expiration"`  // 未完成的上传的保留时间
+	TrashRetention   time.Duration   `yaml:"trash_retention"`    // 回收站中的文件的保留时间
 }
 
 // NewDefaultConfig 创建默认配置
@@ -21,6 +25,8 @@ func NewDefaultConfig() *Config {
 		ChunkSize:        constants.ChunkSize,
 		UploadDir:        constants.DefaultUploadDir,
 		TempDir:          constants.TempChunkDir,
+		UploadExpiration: constants.UploadExpiration,
+		TrashRetention:   constants.TrashRetention,
 	}
 }
 
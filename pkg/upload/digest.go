@@ -0,0 +1,58 @@
+package upload
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"mime/multipart"
+)
+
+// Digest 文件内容摘要，SHA-256用于识别内容，MD5保留用于兼容旧客户端
+type Digest struct {
+	SHA256 string // SHA-256哈希（十六进制）
+	MD5    string // MD5哈希（十六进制）
+	Size   int64  // 内容长度
+}
+
+// digestWriter 写入时同时计算SHA-256和MD5，用于在保存文件时边写边算
+type digestWriter struct {
+	sha256 hash.Hash
+	md5    hash.Hash
+	size   int64
+}
+
+func newDigestWriter() *digestWriter {
+	return &digestWriter{sha256: sha256.New(), md5: md5.New()}
+}
+
+// Write 实现 io.Writer
+func (w *digestWriter) Write(p []byte) (int, error) {
+	w.sha256.Write(p)
+	w.md5.Write(p)
+	w.size += int64(len(p))
+	return len(p), nil
+}
+
+// Digest 获取已写入内容的摘要
+func (w *digestWriter) Digest() *Digest {
+	return &Digest{
+		SHA256: hex.EncodeToString(w.sha256.Sum(nil)),
+		MD5:    hex.EncodeToString(w.md5.Sum(nil)),
+		Size:   w.size,
+	}
+}
+
+// CalculateFileDigest 计算文件的摘要，计算前后都会把文件指针重置到开头
+func CalculateFileDigest(file multipart.File) (*Digest, error) {
+	file.Seek(0, 0)
+
+	w := newDigestWriter()
+	if _, err := io.Copy(w, file); err != nil {
+		return nil, err
+	}
+
+	file.Seek(0, 0)
+	return w.Digest(), nil
+}
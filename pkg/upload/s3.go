@@ -0,0 +1,545 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"template/pkg/config"
+)
+
+// S3相关参数
+const (
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD" // 请求体不参与签名，避免为计算哈希读取两遍数据
+	s3DefaultRegion   = "us-east-1"        // 默认区域
+	s3MinPartSize     = 5 * 1024 * 1024    // S3要求除最后一个分段外，分段不能小于5MB
+	s3DefaultPartSize = 8 * 1024 * 1024    // 默认分段大小
+	s3TimeFormat      = "20060102T150405Z" // x-amz-date 格式
+	s3DateFormat      = "20060102"         // 签名范围中的日期格式
+	s3Algorithm       = "AWS4-HMAC-SHA256" // 签名算法
+	s3ErrorBodyLimit  = 4096               // 读取错误响应的最大长度
+
+	s3DialTimeout           = 10 * time.Second // 建立连接的超时时间
+	s3TLSHandshakeTimeout   = 10 * time.Second // TLS握手的超时时间
+	s3ResponseHeaderTimeout = 60 * time.Second // 请求发送完毕后等待响应头的超时时间
+	s3IdleConnTimeout       = 90 * time.Second // 空闲连接的保留时间
+)
+
+// S3Storage S3兼容对象存储实现（AWS S3、MinIO等），使用 AWS Signature V4 签名
+// 文件路径与本地存储保持一致（如 uploads/xxx.jpg），映射为对象键时会加上配置的前缀
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	pathStyle bool
+	prefix    string
+	partSize  int64
+	baseDir   string
+	client    *http.Client
+}
+
+// s3Error S3错误响应
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// s3CompletedPart 已上传的分段
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// NewS3Storage 创建S3存储实例，baseDir 与本地存储的上传目录含义相同，用于生成文件路径
+func NewS3Storage(cfg config.S3Config, baseDir string) (*S3Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3存储需要配置 endpoint 和 bucket")
+	}
+
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("无效的S3地址: %s", cfg.Endpoint)
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region = s3DefaultRegion
+	}
+
+	partSize := int64(cfg.PartSizeMB) * 1024 * 1024
+	if partSize == 0 {
+		partSize = s3DefaultPartSize
+	}
+	if partSize < s3MinPartSize {
+		partSize = s3MinPartSize
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    region,
+		bucket:    cfg.Bucket,
+		accessKey: cfg.AccessKey,
+		secretKey: cfg.SecretKey,
+		pathStyle: cfg.PathStyle,
+		prefix:    strings.Trim(cfg.Prefix, "/"),
+		partSize:  partSize,
+		baseDir:   baseDir,
+		client:    newS3HTTPClient(),
+	}, nil
+}
+
+// newS3HTTPClient 创建访问对象存储的HTTP客户端
+// 大文件的上传和下载耗时与文件大小有关，不设置整体超时，只限制建立连接、TLS握手和等待响应头的时间，
+// 避免对象存储无响应时请求一直挂起
+func newS3HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   s3DialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = s3TLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = s3ResponseHeaderTimeout
+	transport.IdleConnTimeout = s3IdleConnTimeout
+	return &http.Client{Transport: transport}
+}
+
+// SetHTTPClient 设置发送请求使用的HTTP客户端
+func (s *S3Storage) SetHTTPClient(client *http.Client) {
+	s.client = client
+}
+
+// SaveFile 上传文件对象，上传的同时计算摘要
+func (s *S3Storage) SaveFile(filename string, file multipart.File) (string, *Digest, error) {
+	filePath := filepath.Join(s.baseDir, filename)
+	size, err := remainingSize(file)
+	if err != nil {
+		return "", nil, err
+	}
+
+	digest := newDigestWriter()
+	if err := s.putObject(filePath, io.TeeReader(file, digest), size); err != nil {
+		return "", nil, err
+	}
+	return filePath, digest.Digest(), nil
+}
+
+// SaveChunk 上传分片对象，分片需要放在共享存储中，多个实例才能合并同一个文件的分片
+func (s *S3Storage) SaveChunk(chunkPath string, chunk io.Reader) error {
+	if seeker, ok := chunk.(io.ReadSeeker); ok {
+		size, err := remainingSize(seeker)
+		if err != nil {
+			return err
+		}
+		return s.putObject(chunkPath, seeker, size)
+	}
+
+	// 无法获取长度的数据先读入内存，分片大小有限
+	data, err := io.ReadAll(chunk)
+	if err != nil {
+		return err
+	}
+	return s.putObject(chunkPath, bytes.NewReader(data), int64(len(data)))
+}
+
+// MergeChunks 使用分段上传合并分片：依次读取分片对象，按分段大小重新切分后上传
+// 客户端的分片可能小于S3的最小分段大小，因此不能直接把分片作为分段
+func (s *S3Storage) MergeChunks(chunkPaths []string, targetPath string) (*Digest, error) {
+	uploadID, err := s.createMultipartUpload(targetPath)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := newDigestWriter()
+	parts, err := s.uploadParts(uploadID, chunkPaths, targetPath, digest)
+	if err == nil {
+		err = s.completeMultipartUpload(uploadID, targetPath, parts)
+	}
+	if err != nil {
+		s.abortMultipartUpload(uploadID, targetPath)
+		return nil, err
+	}
+
+	// 删除已合并的分片
+	for _, chunkPath := range chunkPaths {
+		s.DeleteFile(chunkPath)
+	}
+	return digest.Digest(), nil
+}
+
+// DeleteFile 删除对象
+func (s *S3Storage) DeleteFile(filePath string) error {
+	resp, err := s.do(http.MethodDelete, filePath, nil, nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// FileExists 检查对象是否存在
+func (s *S3Storage) FileExists(filePath string) bool {
+	_, err := s.GetFileSize(filePath)
+	return err == nil
+}
+
+// GetFileSize 获取对象大小
+func (s *S3Storage) GetFileSize(filePath string) (int64, error) {
+	resp, err := s.do(http.MethodHead, filePath, nil, nil, nil, 0)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.ContentLength, nil
+}
+
+// Open 以流的方式读取对象内容，对象不存在时返回 os.ErrNotExist
+func (s *S3Storage) Open(filePath string) (*Object, error) {
+	resp, err := s.do(http.MethodGet, filePath, nil, nil, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s3Object(resp), nil
+}
+
+// OpenRange 使用 Range 请求读取对象的一部分
+func (s *S3Storage) OpenRange(filePath string, offset, length int64) (*Object, error) {
+	headers := http.Header{"Range": {fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)}}
+	resp, err := s.do(http.MethodGet, filePath, nil, headers, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s3Object(resp), nil
+}
+
+// s3Object 从GET响应中读取对象元数据
+func s3Object(resp *http.Response) *Object {
+	obj := &Object{ReadCloser: resp.Body, Size: resp.ContentLength}
+
+	// 部分内容响应的总大小在 Content-Range: bytes 0-9/100 中
+	if contentRange := resp.Header.Get("Content-Range"); contentRange != "" {
+		if i := strings.LastIndex(contentRange, "/"); i >= 0 {
+			if total, err := strconv.ParseInt(contentRange[i+1:], 10, 64); err == nil {
+				obj.Size = total
+			}
+		}
+	}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		obj.ModTime = modTime
+	}
+	return obj
+}
+
+// putObject 上传单个对象
+func (s *S3Storage) putObject(filePath string, body io.Reader, size int64) error {
+	headers := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err := s.do(http.MethodPut, filePath, nil, headers, body, size)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// createMultipartUpload 创建分段上传
+func (s *S3Storage) createMultipartUpload(filePath string) (string, error) {
+	resp, err := s.do(http.MethodPost, filePath, url.Values{"uploads": {""}}, nil, nil, 0)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("解析分段上传响应失败: %v", err)
+	}
+	return result.UploadID, nil
+}
+
+// uploadParts 读取全部分片并上传分段，上传的内容同时写入 digest 计算摘要
+func (s *S3Storage) uploadParts(uploadID string, chunkPaths []string, targetPath string, digest io.Writer) ([]s3CompletedPart, error) {
+	var parts []s3CompletedPart
+	buf := bytes.NewBuffer(make([]byte, 0, s.partSize))
+
+	flush := func() error {
+		digest.Write(buf.Bytes())
+		etag, err := s.uploadPart(uploadID, targetPath, len(parts)+1, buf.Bytes())
+		if err != nil {
+			return err
+		}
+		parts = append(parts, s3CompletedPart{PartNumber: len(parts) + 1, ETag: etag})
+		buf.Reset()
+		return nil
+	}
+
+	for _, chunkPath := range chunkPaths {
+		chunk, err := s.Open(chunkPath)
+		if err != nil {
+			return nil, err
+		}
+
+		for {
+			// 每次最多读取到分段大小，满了就上传
+			_, err := io.CopyN(buf, chunk, s.partSize-int64(buf.Len()))
+			if int64(buf.Len()) >= s.partSize {
+				if err := flush(); err != nil {
+					chunk.Close()
+					return nil, err
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				chunk.Close()
+				return nil, err
+			}
+		}
+		chunk.Close()
+	}
+
+	// 最后一个分段可以小于最小分段大小；空文件也需要一个分段
+	if buf.Len() > 0 || len(parts) == 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// uploadPart 上传一个分段，返回ETag
+func (s *S3Storage) uploadPart(uploadID, filePath string, partNumber int, data []byte) (string, error) {
+	query := url.Values{
+		"partNumber": {strconv.Itoa(partNumber)},
+		"uploadId":   {uploadID},
+	}
+	resp, err := s.do(http.MethodPut, filePath, query, nil, bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("分段上传响应缺少ETag")
+	}
+	return etag, nil
+}
+
+// completeMultipartUpload 完成分段上传
+func (s *S3Storage) completeMultipartUpload(uploadID, filePath string, parts []s3CompletedPart) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	headers := http.Header{"Content-Type": {"application/xml"}}
+	resp, err := s.do(http.MethodPost, filePath, url.Values{"uploadId": {uploadID}}, headers, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 完成分段上传时，即使状态码为200，响应体中也可能是错误信息
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorBodyLimit))
+	var s3Err s3Error
+	if xml.Unmarshal(data, &s3Err) == nil && s3Err.Code != "" {
+		return fmt.Errorf("完成分段上传失败: %s %s", s3Err.Code, s3Err.Message)
+	}
+	return nil
+}
+
+// abortMultipartUpload 取消分段上传，释放已上传的分段
+func (s *S3Storage) abortMultipartUpload(uploadID, filePath string) {
+	resp, err := s.do(http.MethodDelete, filePath, url.Values{"uploadId": {uploadID}}, nil, nil, 0)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// do 发送签名请求，非2xx响应转换为错误
+func (s *S3Storage) do(method, filePath string, query url.Values, headers http.Header, body io.Reader, size int64) (*http.Response, error) {
+	key := s.objectKey(filePath)
+	reqURL := *s.endpoint
+	escapedKey := s3Escape(key, false)
+	if s.pathStyle {
+		reqURL.Path = "/" + s.bucket + "/" + key
+		reqURL.RawPath = "/" + s3Escape(s.bucket, false) + "/" + escapedKey
+	} else {
+		reqURL.Host = s.bucket + "." + s.endpoint.Host
+		reqURL.Path = "/" + key
+		reqURL.RawPath = "/" + escapedKey
+	}
+	reqURL.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, reqURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+	SignS3Request(req, s.accessKey, s.secretKey, s.region, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("S3对象不存在 %s: %w", key, os.ErrNotExist)
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, s3ErrorBodyLimit))
+	var s3Err s3Error
+	xml.Unmarshal(data, &s3Err)
+	return nil, fmt.Errorf("S3请求失败 %s %s: %d %s %s", method, key, resp.StatusCode, s3Err.Code, s3Err.Message)
+}
+
+// objectKey 将文件路径转换为对象键
+func (s *S3Storage) objectKey(filePath string) string {
+	key := strings.TrimLeft(path.Clean("/"+filepath.ToSlash(filePath)), "/")
+	if s.prefix != "" {
+		key = s.prefix + "/" + key
+	}
+	return key
+}
+
+// SignS3Request 使用 AWS Signature V4 为请求签名
+// 请求需要提前设置 X-Amz-Content-Sha256，签名包含 host、range、content-type、content-md5 和全部 x-amz-* 请求头
+func SignS3Request(req *http.Request, accessKey, secretKey, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format(s3TimeFormat)
+	date := now.Format(s3DateFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	// 规范请求头
+	signed := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "content-type" || lower == "content-md5" {
+			signed[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(signed))
+	for name := range signed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + signed[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		req.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{s3Algorithm, amzDate, scope, hex.EncodeToString(hash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, accessKey, scope, signedHeaders, signature))
+}
+
+// canonicalQuery 按参数名排序并编码查询参数
+func canonicalQuery(query url.Values) string {
+	if len(query) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape 按S3规则进行URI编码：只保留非保留字符，路径中的 / 可选择不编码
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// remainingSize 获取可定位读取器从当前位置到结尾的长度
+func remainingSize(r io.Seeker) (int64, error) {
+	current, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := r.Seek(current, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end - current, nil
+}
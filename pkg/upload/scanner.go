@@ -0,0 +1,163 @@
+package upload
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"template/pkg/config"
+)
+
+// 扫描器类型
+const (
+	ScannerDriverNone  = "none"  // 不扫描，上传完成即可访问
+	ScannerDriverClamd = "clamd" // ClamAV 的 clamd 服务
+)
+
+// clamd相关参数
+const (
+	DefaultClamdAddress = "127.0.0.1:3310" // 默认clamd地址
+	DefaultScanTimeout  = 60               // 默认单个文件的扫描超时时间（秒）
+	clamdChunkSize      = 64 * 1024        // INSTREAM 每次发送的数据块大小
+	clamdMaxReplyLength = 1024             // 扫描结果的最大长度
+	clamdInstream       = "zINSTREAM\x00"  // 以\0结尾的INSTREAM命令
+	clamdReplyPrefix    = "stream: "       // 扫描结果前缀
+)
+
+// ScanResult 扫描结果
+type ScanResult struct {
+	Infected  bool   // 是否发现威胁
+	Signature string // 发现的威胁名称
+}
+
+// Scanner 恶意文件扫描接口
+type Scanner interface {
+	// Scan 扫描文件内容；扫描器不可用或扫描出错时返回错误，由调用方稍后重试
+	Scan(r io.Reader) (*ScanResult, error)
+}
+
+// NewScanner 根据配置创建扫描器，未配置时不扫描
+func NewScanner(cfg config.ScannerConfig) (Scanner, error) {
+	switch cfg.Driver {
+	case "", ScannerDriverNone:
+		return NoopScanner{}, nil
+	case ScannerDriverClamd:
+		address := cfg.Address
+		if address == "" {
+			address = DefaultClamdAddress
+		}
+		timeout := cfg.Timeout
+		if timeout <= 0 {
+			timeout = DefaultScanTimeout
+		}
+		return NewClamdScanner(address, time.Duration(timeout)*time.Second), nil
+	default:
+		return nil, fmt.Errorf("不支持的扫描器类型: %s", cfg.Driver)
+	}
+}
+
+// IsNoopScanner 是否是不执行扫描的扫描器
+func IsNoopScanner(scanner Scanner) bool {
+	_, ok := scanner.(NoopScanner)
+	return ok
+}
+
+// NoopScanner 不执行扫描，所有文件都视为安全
+type NoopScanner struct{}
+
+// Scan 不读取内容，直接返回安全
+func (NoopScanner) Scan(io.Reader) (*ScanResult, error) {
+	return &ScanResult{}, nil
+}
+
+// ClamdScanner 通过TCP连接clamd，使用 INSTREAM 命令发送文件内容扫描
+type ClamdScanner struct {
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner 创建clamd扫描器，timeout 为单个文件从连接到返回结果的总时间
+func NewClamdScanner(address string, timeout time.Duration) *ClamdScanner {
+	return &ClamdScanner{address: address, timeout: timeout}
+}
+
+// Scan 扫描文件内容
+// 协议：发送 zINSTREAM\0，之后每个数据块前加4字节大端长度，以长度为0的块结束，clamd返回以\0结尾的结果
+func (cs *ClamdScanner) Scan(r io.Reader) (*ScanResult, error) {
+	conn, err := net.DialTimeout("tcp", cs.address, cs.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接clamd失败: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(cs.timeout))
+
+	if err := cs.stream(conn, r); err != nil {
+		// 超出 StreamMaxLength 等情况下clamd会先返回错误再关闭连接，优先使用它返回的结果
+		if reply, replyErr := readClamdReply(conn); replyErr == nil && reply != "" {
+			return parseClamdReply(reply)
+		}
+		return nil, err
+	}
+
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return nil, fmt.Errorf("读取clamd扫描结果失败: %v", err)
+	}
+	return parseClamdReply(reply)
+}
+
+// stream 发送INSTREAM命令和文件内容
+func (cs *ClamdScanner) stream(conn net.Conn, r io.Reader) error {
+	if _, err := io.WriteString(conn, clamdInstream); err != nil {
+		return fmt.Errorf("发送clamd命令失败: %v", err)
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, err := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, werr := conn.Write(buf[:4+n]); werr != nil {
+				return fmt.Errorf("发送文件内容失败: %v", werr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取文件失败: %v", err)
+		}
+	}
+
+	binary.BigEndian.PutUint32(buf[:4], 0)
+	if _, err := conn.Write(buf[:4]); err != nil {
+		return fmt.Errorf("发送文件内容失败: %v", err)
+	}
+	return nil
+}
+
+// readClamdReply 读取以\0结尾的扫描结果，连接关闭时返回已读取的内容
+func readClamdReply(conn net.Conn) (string, error) {
+	reply, err := bufio.NewReader(io.LimitReader(conn, clamdMaxReplyLength)).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimRight(reply, "\x00")), nil
+}
+
+// parseClamdReply 解析扫描结果：stream: OK、stream: <威胁名称> FOUND 或 <错误信息> ERROR
+func parseClamdReply(reply string) (*ScanResult, error) {
+	result := strings.TrimPrefix(reply, clamdReplyPrefix)
+	switch {
+	case result == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd扫描失败: %s", reply)
+	}
+}
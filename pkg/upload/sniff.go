@@ -0,0 +1,85 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"template/pkg/constants"
+)
+
+// SniffLen 识别文件类型时读取的文件头长度
+const SniffLen = 512
+
+// ErrContentTypeMismatch 文件内容的实际类型与扩展名不符
+var ErrContentTypeMismatch = errors.New("文件内容与扩展名不符")
+
+// DetectContentType 根据文件头的特征字节识别文件的实际类型，返回不带参数的MIME类型
+// 在标准库识别的基础上补充了 MP4/MOV 和空ZIP包，无法识别的二进制内容返回 application/octet-stream
+func DetectContentType(head []byte) string {
+	if mimeType := detectISOMedia(head); mimeType != "" {
+		return mimeType
+	}
+	if bytes.HasPrefix(head, []byte("PK\x05\x06")) {
+		return "application/zip"
+	}
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// ValidateContentType 检查文件内容的实际类型与扩展名对应的类型是否一致，返回实际类型
+// 扩展名为 .txt 时接受任意文本内容（如HTML），统一按 text/plain 记录，避免以其他文本类型输出
+func ValidateContentType(filename string, head []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	expected, exists := constants.ExtensionMimeMap[ext]
+	if !exists {
+		return "", fmt.Errorf("不支持的文件扩展名: %s", ext)
+	}
+
+	detected := DetectContentType(head)
+	if expected == "text/plain" && strings.HasPrefix(detected, "text/") {
+		return expected, nil
+	}
+	if detected != expected {
+		return "", fmt.Errorf("%w：%s 文件的实际类型为 %s", ErrContentTypeMismatch, ext, detected)
+	}
+	return detected, nil
+}
+
+// SniffContentType 读取文件头并检查文件内容的实际类型与扩展名是否一致
+func SniffContentType(filename string, r io.Reader) (string, error) {
+	head := make([]byte, SniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("读取文件失败: %v", err)
+	}
+	return ValidateContentType(filename, head[:n])
+}
+
+// detectISOMedia 识别以 ftyp 开头的ISO媒体文件，品牌为 "qt  " 的是QuickTime（MOV），其余按MP4处理
+// 没有 ftyp 的旧版QuickTime文件以 moov、mdat 等原子开头；原子大小的首字节不是可打印字符，以此与文本区分
+func detectISOMedia(head []byte) string {
+	if len(head) < 12 || head[0] >= 0x20 {
+		return ""
+	}
+
+	switch string(head[4:8]) {
+	case "ftyp":
+		if string(head[8:12]) == "qt  " {
+			return "video/mov"
+		}
+		return "video/mp4"
+	case "moov", "mdat", "wide":
+		return "video/mov"
+	}
+	return ""
+}
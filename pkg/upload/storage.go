@@ -1,28 +1,61 @@
 package upload
 
 import (
+	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"time"
+
+	"template/pkg/config"
 )
 
 // Storage 文件存储接口
 type Storage interface {
-	// SaveFile 保存文件
-	SaveFile(filename string, file multipart.File) (string, error)
+	// SaveFile 保存文件，返回存储路径和保存内容的摘要
+	SaveFile(filename string, file multipart.File) (string, *Digest, error)
 	// SaveChunk 保存分片
 	SaveChunk(chunkPath string, chunk io.Reader) error
-	// MergeChunks 合并分片
-	MergeChunks(chunkPaths []string, targetPath string) error
+	// MergeChunks 合并分片，返回合并后内容的摘要
+	MergeChunks(chunkPaths []string, targetPath string) (*Digest, error)
 	// DeleteFile 删除文件
 	DeleteFile(filePath string) error
 	// FileExists 检查文件是否存在
 	FileExists(filePath string) bool
 	// GetFileSize 获取文件大小
 	GetFileSize(filePath string) (int64, error)
+	// Open 以流的方式读取文件，文件不存在时返回的错误满足 errors.Is(err, os.ErrNotExist)
+	Open(filePath string) (*Object, error)
+	// OpenRange 读取从 offset 开始的 length 个字节，调用方需要保证范围有效
+	OpenRange(filePath string, offset, length int64) (*Object, error)
+}
+
+// Object 打开的文件内容和元数据
+type Object struct {
+	io.ReadCloser
+	Size    int64     // 文件总大小（读取部分内容时也是整个文件的大小）
+	ModTime time.Time // 最后修改时间
 }
 
+// NewStorage 根据配置创建文件存储，baseDir 为上传目录
+func NewStorage(cfg config.StorageConfig, baseDir string) (Storage, error) {
+	switch cfg.Driver {
+	case "", StorageDriverLocal:
+		return NewLocalStorage(baseDir), nil
+	case StorageDriverS3:
+		return NewS3Storage(cfg.S3, baseDir)
+	default:
+		return nil, fmt.Errorf("不支持的存储类型: %s", cfg.Driver)
+	}
+}
+
+// 存储类型
+const (
+	StorageDriverLocal = "local" // 本地磁盘
+	StorageDriverS3    = "s3"    // S3兼容对象存储
+)
+
 // LocalStorage 本地存储实现
 type LocalStorage struct {
 	baseDir string
@@ -36,28 +69,29 @@ func NewLocalStorage(baseDir string) *LocalStorage {
 }
 
 // SaveFile 保存文件到本地
-func (ls *LocalStorage) SaveFile(filename string, file multipart.File) (string, error) {
+func (ls *LocalStorage) SaveFile(filename string, file multipart.File) (string, *Digest, error) {
 	// 确保目录存在
 	if err := os.MkdirAll(ls.baseDir, 0755); err != nil {
-		return "", err
+		return "", nil, err
 	}
 
 	// 创建目标文件
 	filePath := filepath.Join(ls.baseDir, filename)
 	dst, err := os.Create(filePath)
 	if err != nil {
-		return "", err
+		return "", nil, err
 	}
 	defer dst.Close()
 
-	// 复制文件内容
-	_, err = io.Copy(dst, file)
+	// 复制文件内容，同时计算摘要
+	digest := newDigestWriter()
+	_, err = io.Copy(io.MultiWriter(dst, digest), file)
 	if err != nil {
 		os.Remove(filePath) // 删除失败的文件
-		return "", err
+		return "", nil, err
 	}
 
-	return filePath, nil
+	return filePath, digest.Digest(), nil
 }
 
 // SaveChunk 保存分片到本地
@@ -81,20 +115,24 @@ func (ls *LocalStorage) SaveChunk(chunkPath string, chunk io.Reader) error {
 }
 
 // MergeChunks 合并分片
-func (ls *LocalStorage) MergeChunks(chunkPaths []string, targetPath string) error {
+func (ls *LocalStorage) MergeChunks(chunkPaths []string, targetPath string) (*Digest, error) {
 	// 确保目标目录存在
 	dir := filepath.Dir(targetPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
+		return nil, err
 	}
 
 	// 创建目标文件
 	target, err := os.Create(targetPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer target.Close()
 
+	// 写入目标文件的同时计算摘要
+	digest := newDigestWriter()
+	writer := io.MultiWriter(target, digest)
+
 	// 收集需要清理的目录
 	dirsToClean := make(map[string]bool)
 
@@ -102,14 +140,14 @@ func (ls *LocalStorage) MergeChunks(chunkPaths []string, targetPath string) erro
 	for _, chunkPath := range chunkPaths {
 		chunk, err := os.Open(chunkPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		_, err = io.Copy(target, chunk)
+		_, err = io.Copy(writer, chunk)
 		chunk.Close()
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// 删除已合并的分片
@@ -128,7 +166,7 @@ func (ls *LocalStorage) MergeChunks(chunkPaths []string, targetPath string) erro
 		}
 	}
 
-	return nil
+	return digest.Digest(), nil
 }
 
 // DeleteFile 删除本地文件
@@ -150,3 +188,39 @@ func (ls *LocalStorage) GetFileSize(filePath string) (int64, error) {
 	}
 	return info.Size(), nil
 }
+
+// Open 打开本地文件
+func (ls *LocalStorage) Open(filePath string) (*Object, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Object{ReadCloser: file, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// OpenRange 打开本地文件并定位到指定范围
+func (ls *LocalStorage) OpenRange(filePath string, offset, length int64) (*Object, error) {
+	obj, err := ls.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file := obj.ReadCloser.(*os.File)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	obj.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}
+	return obj, nil
+}
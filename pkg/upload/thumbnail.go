@@ -0,0 +1,94 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+
+	// 注册GIF和WebP解码器
+	_ "golang.org/x/image/webp"
+	_ "image/gif"
+)
+
+// ThumbnailMimeTypes 支持生成缩略图的图片类型
+var ThumbnailMimeTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// ErrImageTooLarge 图片像素数超出限制
+var ErrImageTooLarge = errors.New("图片像素数超出限制")
+
+// Thumbnail 生成的缩略图
+type Thumbnail struct {
+	Data     []byte
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// CheckImageSize 只读取图片头部的尺寸信息，像素数超过 maxPixels 时返回 ErrImageTooLarge，
+// 避免解码超大图片耗尽内存
+func CheckImageSize(r io.Reader, maxPixels int64) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return fmt.Errorf("无法识别的图片: %v", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return ErrImageTooLarge
+	}
+	return nil
+}
+
+// DecodeImage 解码JPEG、PNG、GIF（第一帧）和WebP图片
+func DecodeImage(r io.Reader) (image.Image, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("图片解码失败: %v", err)
+	}
+	return img, nil
+}
+
+// GenerateThumbnail 等比缩放图片使长边不超过 size，小图不放大
+// 不透明的图片编码为JPEG，带透明区域的编码为PNG
+func GenerateThumbnail(img image.Image, size, quality int) (*Thumbnail, error) {
+	width, height := thumbnailDimensions(img.Bounds().Dx(), img.Bounds().Dy(), size)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	thumb := &Thumbnail{Width: width, Height: height}
+	if dst.Opaque() {
+		thumb.MimeType = "image/jpeg"
+		if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, fmt.Errorf("缩略图编码失败: %v", err)
+		}
+	} else {
+		thumb.MimeType = "image/png"
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, fmt.Errorf("缩略图编码失败: %v", err)
+		}
+	}
+	thumb.Data = buf.Bytes()
+	return thumb, nil
+}
+
+// thumbnailDimensions 计算缩放后的尺寸，宽高至少为1像素
+func thumbnailDimensions(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
@@ -0,0 +1,96 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// tus 1.0 协议相关常量
+const (
+	TusVersion             = "1.0.0"                                    // 支持的协议版本
+	TusExtensions          = "creation,termination,checksum,expiration" // 支持的扩展
+	TusChecksumAlgorithms  = "sha1,md5,sha256"                          // checksum 扩展支持的算法
+	TusOffsetOctetStream   = "application/offset+octet-stream"          // PATCH 请求的内容类型
+	StatusChecksumMismatch = 460                                        // checksum 扩展定义的校验失败状态码
+)
+
+// ParseTusMetadata 解析 Upload-Metadata 请求头：逗号分隔的键值对，值使用Base64编码，可以省略
+func ParseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return metadata, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("Upload-Metadata 格式错误")
+		}
+
+		key := fields[0]
+		if _, exists := metadata[key]; exists {
+			return nil, fmt.Errorf("Upload-Metadata 包含重复的键: %s", key)
+		}
+
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("Upload-Metadata 的值 %s 不是有效的Base64", key)
+			}
+			value = string(decoded)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+// TusChecksum Upload-Checksum 请求头声明的校验和，写入请求体后调用 Verify 校验
+type TusChecksum struct {
+	Algorithm string
+	expected  []byte
+	hash      hash.Hash
+}
+
+// ParseTusChecksum 解析 Upload-Checksum 请求头，格式为 "<算法> <Base64编码的校验和>"
+func ParseTusChecksum(header string) (*TusChecksum, error) {
+	fields := strings.Fields(header)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Upload-Checksum 格式错误")
+	}
+
+	var h hash.Hash
+	switch fields[0] {
+	case "sha1":
+		h = sha1.New()
+	case "md5":
+		h = md5.New()
+	case "sha256":
+		h = sha256.New()
+	default:
+		return nil, fmt.Errorf("不支持的校验算法: %s", fields[0])
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil || len(expected) != h.Size() {
+		return nil, fmt.Errorf("Upload-Checksum 的校验和无效")
+	}
+	return &TusChecksum{Algorithm: fields[0], expected: expected, hash: h}, nil
+}
+
+// Writer 返回计算校验和的写入器
+func (c *TusChecksum) Writer() io.Writer {
+	return c.hash
+}
+
+// Verify 检查写入的内容与声明的校验和是否一致
+func (c *TusChecksum) Verify() bool {
+	return bytes.Equal(c.hash.Sum(nil), c.expected)
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRangeNotSatisfiable 请求的范围超出文件大小
+var ErrRangeNotSatisfiable = errors.New("请求的范围无效")
+
+// ByteRange HTTP请求的字节范围
+type ByteRange struct {
+	Start  int64 // 起始位置
+	Length int64 // 长度
+}
+
+// End 范围的最后一个字节位置
+func (r *ByteRange) End() int64 {
+	return r.Start + r.Length - 1
+}
+
+// ParseRange 解析 Range 请求头，只支持单个字节范围
+// 请求头为空、格式错误或包含多个范围时返回 nil，表示返回完整内容；范围超出文件大小时返回 ErrRangeNotSatisfiable
+func ParseRange(header string, size int64) (*ByteRange, error) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(header), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return nil, nil
+	}
+
+	startStr, endStr, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return nil, nil
+	}
+
+	// 后缀范围：bytes=-500 表示最后500个字节
+	if startStr == "" {
+		suffix, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || suffix < 0 {
+			return nil, nil
+		}
+		if suffix == 0 || size == 0 {
+			return nil, ErrRangeNotSatisfiable
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return &ByteRange{Start: size - suffix, Length: suffix}, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return nil, nil
+	}
+
+	end := size - 1
+	if endStr != "" {
+		end, err = strconv.ParseInt(endStr, 10, 64)
+		if err != nil || end < start {
+			return nil, nil
+		}
+	}
+
+	if start >= size {
+		return nil, ErrRangeNotSatisfiable
+	}
+	if end >= size {
+		end = size - 1
+	}
+	return &ByteRange{Start: start, Length: end - start + 1}, nil
+}
+
+// NotModified 根据 If-None-Match 或 If-Modified-Since 判断客户端缓存是否仍然有效
+// 同时存在时以 If-None-Match 为准
+func NotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etag != "" && etagListMatches(ifNoneMatch, etag)
+	}
+
+	if ifModifiedSince := r.Header.Get("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// IfRangeMatches 判断 If-Range 条件是否满足，不满足时应忽略 Range 返回完整内容
+// If-Range 中的ETag使用强比较，日期需要与最后修改时间完全一致
+func IfRangeMatches(r *http.Request, etag string, lastModified time.Time) bool {
+	ifRange := strings.TrimSpace(r.Header.Get("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return etag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+
+	date, err := http.ParseTime(ifRange)
+	return err == nil && !lastModified.IsZero() && lastModified.Truncate(time.Second).Equal(date)
+}
+
+// etagListMatches 检查 If-None-Match 中的ETag列表是否包含指定ETag（弱比较）
+func etagListMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
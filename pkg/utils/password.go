@@ -1,9 +1,15 @@
 package utils
 
 import (
+	"crypto/rand"
+	"math/big"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+// tempPasswordChars 临时密码使用的字符，去掉了容易混淆的 0/O、1/l/I
+const tempPasswordChars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
 // HashPassword 使用bcrypt算法对密码进行加密
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -15,3 +21,17 @@ func ComparePasswords(hashedPassword, password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
 }
+
+// GenerateTempPassword 生成指定长度的随机临时密码
+func GenerateTempPassword(length int) (string, error) {
+	max := big.NewInt(int64(len(tempPasswordChars)))
+	buf := make([]byte, length)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		buf[i] = tempPasswordChars[n.Int64()]
+	}
+	return string(buf), nil
+}
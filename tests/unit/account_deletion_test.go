@@ -0,0 +1,201 @@
+package unit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"template/internal/middleware"
+	"template/internal/models"
+	uploadService "template/internal/services/upload"
+	"template/internal/services/user"
+	"template/pkg/cache"
+	"template/pkg/common"
+	"template/pkg/config"
+	"template/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setAccountConfig 临时修改账号注销配置，测试结束后恢复
+func setAccountConfig(t *testing.T, graceDays int, filePolicy, reassignTo string) {
+	account := &config.GetConfig().Account
+	previous := *account
+	account.DeletionGraceDays = graceDays
+	account.DeletedFilePolicy = filePolicy
+	account.ReassignFilesTo = reassignTo
+	t.Cleanup(func() { *account = previous })
+}
+
+// setDeleteAccountCode 写入注销账号验证码
+func setDeleteAccountCode(t *testing.T, email, code string) {
+	key := fmt.Sprintf("%s:%s:code", email, common.CodeTypeDeleteAccount)
+	require.NoError(t, cache.GetCache().Set(key, code, time.Minute))
+}
+
+// findUnscopedUser 查询用户记录，包括已软删除的记录
+func findUnscopedUser(t *testing.T, db *gorm.DB, userID string) *models.User {
+	var u models.User
+	require.NoError(t, db.Unscoped().Where("id = ?", userID).First(&u).Error)
+	return &u
+}
+
+// TestDeleteAccount 测试注销账号需要正确的验证码，超级管理员不能注销，注销后账号立即不可用
+func TestDeleteAccount(t *testing.T) {
+	db := newUserTestDB(t)
+	setAccountConfig(t, 7, "", "")
+	superAdmin := newTestUser(t, db, "delete-super", common.UserRoleSuperAdmin)
+	member := newTestUser(t, db, "delete-member", common.UserRoleUser)
+	router := newAuthTestRouter(middleware.RequireAuth())
+	tokens := loginTestUser(t, member, user.ClientInfo{IP: "203.0.113.1"})
+
+	setDeleteAccountCode(t, superAdmin.Email, "123456")
+	_, err := user.DeleteAccount(superAdmin.ID.String(), "123456")
+	assert.True(t, errors.Is(err, errors.CodeForbidden), "got %v", err)
+
+	setDeleteAccountCode(t, member.Email, "123456")
+	_, err = user.DeleteAccount(member.ID.String(), "654321")
+	assert.True(t, errors.Is(err, errors.CodeInvalidVerifyCode), "got %v", err)
+	require.Equal(t, http.StatusOK, doAuthRequest(router, tokens.AccessToken), "验证码错误时账号不受影响")
+
+	setDeleteAccountCode(t, member.Email, "123456")
+	purgeAt, err := user.DeleteAccount(member.ID.String(), "123456")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), purgeAt, time.Minute)
+
+	deleted := findUnscopedUser(t, db, member.ID.String())
+	assert.Equal(t, common.UserStatusDeleted, deleted.Status)
+	assert.True(t, deleted.DeletedAt.Valid)
+	require.NotNil(t, deleted.DeletionRequestedAt)
+	assert.Nil(t, deleted.AnonymizedAt, "保留期内不清除个人信息")
+
+	// 已签发的令牌立即失效，也不能再登录
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(router, tokens.AccessToken))
+	_, err = user.RefreshToken(tokens.RefreshToken)
+	assert.Error(t, err)
+	_, err = user.Login(member.Username, testUserPassword, user.ClientInfo{IP: "203.0.113.1"})
+	assert.Error(t, err)
+}
+
+// requestTestDeletion 注销账号并把申请时间提前指定时长
+func requestTestDeletion(t *testing.T, db *gorm.DB, u *models.User, ago time.Duration) {
+	setDeleteAccountCode(t, u.Email, "123456")
+	_, err := user.DeleteAccount(u.ID.String(), "123456")
+	require.NoError(t, err)
+	require.NoError(t, db.Unscoped().Model(&models.User{}).Where("id = ?", u.ID.String()).
+		Update("deletion_requested_at", time.Now().Add(-ago)).Error)
+}
+
+// TestPurgeDeletedAccounts 测试保留期结束后删除注销用户的文件并清除个人信息，保留期内的账号不受影响
+func TestPurgeDeletedAccounts(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	setAccountConfig(t, 7, "purge", "")
+
+	expired := newTestUser(t, db, "purge-expired", common.UserRoleUser)
+	pending := newTestUser(t, db, "purge-pending", common.UserRoleUser)
+	for _, u := range []*models.User{expired, pending} {
+		_, err := uploadService.SimpleUpload(newTestFileHeader(t, "notes.txt", []byte("notes of "+u.Username)), u.ID.String(), "")
+		require.NoError(t, err)
+		loginTestUser(t, u, user.ClientInfo{IP: "203.0.113.2"})
+		_, _, err = user.CreateAPIKey(u.ID.String(), "ci", []string{common.ScopeFilesRead}, 0)
+		require.NoError(t, err)
+	}
+	requestTestDeletion(t, db, expired, 8*24*time.Hour)
+	requestTestDeletion(t, db, pending, 6*24*time.Hour)
+
+	count, err := user.PurgeDeletedAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// 个人信息被清除，关联数据被删除
+	anonymized := findUnscopedUser(t, db, expired.ID.String())
+	assert.Equal(t, "deleted_"+strings.ReplaceAll(expired.ID.String(), "-", ""), anonymized.Username)
+	assert.Equal(t, expired.ID.String()+"@deleted.invalid", anonymized.Email)
+	assert.Empty(t, anonymized.Password)
+	assert.NotNil(t, anonymized.AnonymizedAt)
+	for _, model := range []interface{}{&models.UserSession{}, &models.APIKey{}, &models.UserRole{}} {
+		var n int64
+		require.NoError(t, db.Unscoped().Model(model).Where("user_id = ?", expired.ID.String()).Count(&n).Error)
+		assert.Zero(t, n, "%T", model)
+	}
+	files, err := uploadService.ListUserFiles(expired.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, files)
+
+	// 保留期内的账号保持原样
+	kept := findUnscopedUser(t, db, pending.ID.String())
+	assert.Equal(t, pending.Username, kept.Username)
+	assert.Equal(t, pending.Email, kept.Email)
+	assert.Nil(t, kept.AnonymizedAt)
+	files, err = uploadService.ListUserFiles(pending.ID.String())
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// 已匿名化的账号不会重复处理
+	count, err = user.PurgeDeletedAccounts()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+// TestPurgeDeletedAccountsReassignFiles 测试配置为转移文件时，注销用户的文件转给指定用户
+func TestPurgeDeletedAccountsReassignFiles(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	receiver := newTestUser(t, db, "reassign-receiver", common.UserRoleAdmin)
+	setAccountConfig(t, 7, "reassign", receiver.Username)
+
+	leaver := newTestUser(t, db, "reassign-leaver", common.UserRoleUser)
+	uploaded, err := uploadService.SimpleUpload(newTestFileHeader(t, "handover.txt", []byte("handover notes")), leaver.ID.String(), "")
+	require.NoError(t, err)
+	requestTestDeletion(t, db, leaver, 8*24*time.Hour)
+
+	count, err := user.PurgeDeletedAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	files, err := uploadService.ListUserFiles(receiver.ID.String())
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, uploaded.FileID, files[0].ID.String())
+	files, err = uploadService.ListUserFiles(leaver.ID.String())
+	require.NoError(t, err)
+	assert.Empty(t, files)
+	assert.NotNil(t, findUnscopedUser(t, db, leaver.ID.String()).AnonymizedAt)
+}
+
+// TestValidateAccountConfig 测试启动时检查注销用户文件的处理方式
+func TestValidateAccountConfig(t *testing.T) {
+	db := newUserTestDB(t)
+	receiver := newTestUser(t, db, "config-receiver", common.UserRoleAdmin)
+
+	tests := []struct {
+		name       string
+		policy     string
+		reassignTo string
+		wantErr    bool
+	}{
+		{"默认删除文件", "", "", false},
+		{"删除文件", "purge", "", false},
+		{"转移给存在的用户", "reassign", receiver.Username, false},
+		{"未配置接收用户", "reassign", "", true},
+		{"接收用户不存在", "reassign", "nobody", true},
+		{"不支持的处理方式", "archive", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setAccountConfig(t, 7, tt.policy, tt.reassignTo)
+			err := user.ValidateAccountConfig()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
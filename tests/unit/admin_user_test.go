@@ -0,0 +1,128 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"template/internal/middleware"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestManageableUserGuards 测试管理操作的权限：不能管理自己，管理员只能管理普通用户，超级管理员可以管理其他所有用户
+func TestManageableUserGuards(t *testing.T) {
+	db := newUserTestDB(t)
+	superAdmin := newTestUser(t, db, "manage-super", common.UserRoleSuperAdmin)
+	otherSuper := newTestUser(t, db, "manage-super-2", common.UserRoleSuperAdmin)
+	admin := newTestUser(t, db, "manage-admin", common.UserRoleAdmin)
+	otherAdmin := newTestUser(t, db, "manage-admin-2", common.UserRoleAdmin)
+	member := newTestUser(t, db, "manage-member", common.UserRoleUser)
+
+	tests := []struct {
+		name     string
+		operator string
+		target   string
+		wantCode errors.ErrorCode
+	}{
+		{"不能管理自己", admin.ID.String(), admin.ID.String(), errors.CodeForbidden},
+		{"管理员不能管理超级管理员", admin.ID.String(), superAdmin.ID.String(), errors.CodeForbidden},
+		{"管理员不能管理同级管理员", admin.ID.String(), otherAdmin.ID.String(), errors.CodeForbidden},
+		{"普通用户不能管理其他用户", member.ID.String(), admin.ID.String(), errors.CodeForbidden},
+		{"用户不存在", superAdmin.ID.String(), common.NewUUID().String(), errors.CodeUserNotFound},
+		{"管理员可以管理普通用户", admin.ID.String(), member.ID.String(), 0},
+		{"超级管理员可以管理管理员", superAdmin.ID.String(), admin.ID.String(), 0},
+		{"超级管理员可以管理其他超级管理员", superAdmin.ID.String(), otherSuper.ID.String(), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := user.SetUserStatus(tt.operator, tt.target, common.UserStatusNormal)
+			if tt.wantCode == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.True(t, errors.Is(err, tt.wantCode), "got %v", err)
+
+			_, err = user.ForcePasswordReset(tt.operator, tt.target, "")
+			assert.True(t, errors.Is(err, tt.wantCode), "got %v", err)
+		})
+	}
+}
+
+// newPasswordChangeTestRouter 创建需要登录的测试路由，模拟修改密码、退出登录、个人信息和其他业务接口
+func newPasswordChangeTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	api := router.Group("/api/v1")
+	api.Use(middleware.RequireAuth())
+	api.GET("/info", ok)
+	api.POST("/change-password", ok)
+	api.POST("/logout", ok)
+	api.GET("/sessions", ok)
+	api.POST("/api-keys", ok)
+	return router
+}
+
+// doPasswordChangeRequest 使用 Bearer 令牌请求测试路由，返回状态码
+func doPasswordChangeRequest(router *gin.Engine, method, path, token string) int {
+	req := httptest.NewRequest(method, "/api/v1"+path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// TestForcePasswordResetEnforced 测试管理员重置密码后吊销原有令牌，修改密码前只能访问修改密码、退出登录和个人信息接口
+func TestForcePasswordResetEnforced(t *testing.T) {
+	db := newUserTestDB(t)
+	admin := newTestUser(t, db, "reset-admin", common.UserRoleAdmin)
+	member := newTestUser(t, db, "reset-member", common.UserRoleUser)
+	router := newPasswordChangeTestRouter()
+
+	before := loginTestUser(t, member, user.ClientInfo{IP: "198.51.100.1"})
+	_, apiKey, err := user.CreateAPIKey(member.ID.String(), "ci", []string{common.ScopeFilesRead}, 0)
+	require.NoError(t, err)
+	scoped := newAuthTestRouter(middleware.RequireAuth(common.ScopeFilesRead))
+	require.Equal(t, http.StatusOK, doAuthRequest(scoped, apiKey))
+
+	tempPassword, err := user.ForcePasswordReset(admin.ID.String(), member.ID.String(), "")
+	require.NoError(t, err)
+	require.NotEmpty(t, tempPassword)
+
+	// 原有令牌和会话立即失效
+	assert.Equal(t, http.StatusUnauthorized, doPasswordChangeRequest(router, http.MethodGet, "/info", before.AccessToken))
+	_, err = user.RefreshToken(before.RefreshToken)
+	assert.Error(t, err)
+
+	// 使用临时密码登录后，只能访问少数接口
+	result, err := user.Login(member.Username, tempPassword, user.ClientInfo{IP: "198.51.100.1"})
+	require.NoError(t, err)
+	assert.Equal(t, true, result.UserInfo["password_change_required"])
+	token := result.Tokens.AccessToken
+
+	assert.Equal(t, http.StatusOK, doPasswordChangeRequest(router, http.MethodGet, "/info", token))
+	assert.Equal(t, http.StatusOK, doPasswordChangeRequest(router, http.MethodPost, "/change-password", token))
+	assert.Equal(t, http.StatusOK, doPasswordChangeRequest(router, http.MethodPost, "/logout", token))
+	assert.Equal(t, http.StatusForbidden, doPasswordChangeRequest(router, http.MethodGet, "/sessions", token))
+	assert.Equal(t, http.StatusForbidden, doPasswordChangeRequest(router, http.MethodPost, "/api-keys", token))
+	assert.Equal(t, http.StatusForbidden, doAuthRequest(scoped, apiKey), "修改密码前API密钥也不能使用")
+
+	// 刷新得到的令牌同样受限
+	refreshed, err := user.RefreshToken(result.Tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, doPasswordChangeRequest(router, http.MethodGet, "/sessions", refreshed.AccessToken))
+
+	// 修改密码后重新登录，恢复正常访问
+	require.NoError(t, user.ChangePassword(member.ID.String(), tempPassword, testUserPassword))
+	after := loginTestUser(t, member, user.ClientInfo{IP: "198.51.100.1"})
+	assert.Equal(t, http.StatusOK, doPasswordChangeRequest(router, http.MethodGet, "/sessions", after.AccessToken))
+	assert.Equal(t, http.StatusOK, doAuthRequest(scoped, apiKey))
+}
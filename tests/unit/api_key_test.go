@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"template/internal/middleware"
+	"template/internal/models"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAPIKeyHashAndLookup 测试密钥只保存哈希，使用明文可以查到所属用户和授权范围
+func TestAPIKeyHashAndLookup(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "apikey-lookup", common.UserRoleUser)
+
+	key, plaintext, err := user.CreateAPIKey(u.ID.String(), "ci", []string{common.ScopeFilesRead, common.ScopeFilesRead}, 0)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plaintext, common.APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(plaintext, key.Prefix))
+
+	var stored models.APIKey
+	require.NoError(t, db.Where("id = ?", key.ID.String()).First(&stored).Error)
+	sum := sha256.Sum256([]byte(plaintext))
+	assert.Equal(t, hex.EncodeToString(sum[:]), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, plaintext)
+	assert.Equal(t, []string{common.ScopeFilesRead}, stored.ScopeList(), "重复的授权范围只保存一次")
+
+	claims, err := user.AuthenticateAPIKey(plaintext, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, u.ID.String(), claims.UserID)
+	assert.True(t, claims.IsAPIKey())
+	assert.True(t, claims.HasScope(common.ScopeFilesRead))
+	assert.False(t, claims.HasScope(common.ScopeUploadWrite))
+
+	_, err = user.AuthenticateAPIKey(plaintext+"0", "192.0.2.1")
+	assert.True(t, errors.Is(err, errors.CodeInvalidAuthToken))
+	_, err = user.AuthenticateAPIKey(strings.TrimPrefix(plaintext, common.APIKeyPrefix), "192.0.2.1")
+	assert.True(t, errors.Is(err, errors.CodeInvalidAuthToken))
+
+	_, _, err = user.CreateAPIKey(u.ID.String(), "bad", []string{"admin:all"}, 0)
+	assert.True(t, errors.Is(err, errors.CodeInvalidParameter))
+}
+
+// TestAPIKeyExpiryAndRevocation 测试过期、删除的密钥以及被禁用用户的密钥都不能使用
+func TestAPIKeyExpiryAndRevocation(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "apikey-revoke", common.UserRoleUser)
+
+	expiring, expiringText, err := user.CreateAPIKey(u.ID.String(), "expiring", []string{common.ScopeFilesRead}, 1)
+	require.NoError(t, err)
+	_, err = user.AuthenticateAPIKey(expiringText, "")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Model(&models.APIKey{}).Where("id = ?", expiring.ID.String()).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, err = user.AuthenticateAPIKey(expiringText, "")
+	assert.True(t, errors.Is(err, errors.CodeExpiredAuthToken))
+
+	revoked, revokedText, err := user.CreateAPIKey(u.ID.String(), "revoked", []string{common.ScopeFilesRead}, 0)
+	require.NoError(t, err)
+
+	// 只能删除自己的密钥
+	other := newTestUser(t, db, "apikey-other", common.UserRoleUser)
+	err = user.RevokeAPIKey(other.ID.String(), revoked.ID.String())
+	assert.True(t, errors.Is(err, errors.CodeNotFound))
+	_, err = user.AuthenticateAPIKey(revokedText, "")
+	require.NoError(t, err)
+
+	require.NoError(t, user.RevokeAPIKey(u.ID.String(), revoked.ID.String()))
+	_, err = user.AuthenticateAPIKey(revokedText, "")
+	assert.True(t, errors.Is(err, errors.CodeInvalidAuthToken))
+
+	_, activeText, err := user.CreateAPIKey(u.ID.String(), "active", []string{common.ScopeFilesRead}, 0)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", u.ID.String()).Update("status", common.UserStatusDisabled).Error)
+	_, err = user.AuthenticateAPIKey(activeText, "")
+	assert.True(t, errors.Is(err, errors.CodeUserDisabled))
+}
+
+// TestAPIKeyScopeEnforcement 测试认证中间件按授权范围限制API密钥，登录令牌不受限制
+func TestAPIKeyScopeEnforcement(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "apikey-scope", common.UserRoleSuperAdmin)
+	_, tokens := issueTestTokens(t, db, u)
+
+	_, uploadKey, err := user.CreateAPIKey(u.ID.String(), "upload", []string{common.ScopeUploadWrite}, 0)
+	require.NoError(t, err)
+	_, readKey, err := user.CreateAPIKey(u.ID.String(), "read", []string{common.ScopeFilesRead}, 0)
+	require.NoError(t, err)
+
+	scoped := newAuthTestRouter(middleware.RequireAuth(common.ScopeFilesRead))
+	assert.Equal(t, http.StatusForbidden, doAuthRequest(scoped, uploadKey), "缺少授权范围")
+	assert.Equal(t, http.StatusOK, doAuthRequest(scoped, readKey))
+	assert.Equal(t, http.StatusOK, doAuthRequest(scoped, tokens.AccessToken))
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(scoped, common.APIKeyPrefix+"unknown"))
+
+	// X-API-Key 请求头与 Bearer 方式等价
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("X-API-Key", uploadKey)
+	w := httptest.NewRecorder()
+	scoped.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 没有声明授权范围的路由不接受API密钥
+	unscoped := newAuthTestRouter(middleware.RequireAuth())
+	assert.Equal(t, http.StatusForbidden, doAuthRequest(unscoped, readKey))
+	assert.Equal(t, http.StatusOK, doAuthRequest(unscoped, tokens.AccessToken))
+
+	// 管理权限只能通过登录令牌使用，超级管理员的密钥同样被拒绝
+	permission := newAuthTestRouter(middleware.RequirePermission(common.PermFileRead))
+	assert.Equal(t, http.StatusForbidden, doAuthRequest(permission, readKey))
+	assert.Equal(t, http.StatusOK, doAuthRequest(permission, tokens.AccessToken))
+}
@@ -0,0 +1,30 @@
+package unit
+
+import (
+	"template/pkg/cache"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMemCacheIncr 测试内存缓存计数器
+func TestMemCacheIncr(t *testing.T) {
+	c := cache.InitMemCache()
+
+	count, err := c.Incr("counter", 50*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	count, _ = c.Incr("counter", 50*time.Millisecond)
+	assert.Equal(t, int64(2), count)
+
+	// 后续递增不会延长过期时间
+	time.Sleep(60 * time.Millisecond)
+	count, _ = c.Incr("counter", 50*time.Millisecond)
+	assert.Equal(t, int64(1), count)
+
+	_ = c.Set("text", "abc", 0)
+	_, err = c.Incr("text", 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,273 @@
+package unit
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	stderrors "errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	uploadController "template/internal/controllers/upload"
+	"template/internal/middleware"
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	uploadService "template/internal/services/upload"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/constants"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// chunkTestDigest 计算内容的MD5和SHA-256
+func chunkTestDigest(content []byte) (string, string) {
+	md5Sum := md5.Sum(content)
+	sha256Sum := sha256.Sum256(content)
+	return hex.EncodeToString(md5Sum[:]), hex.EncodeToString(sha256Sum[:])
+}
+
+// uploadTestChunks 以单个分片上传内容并合并，内容的哈希按声明的值初始化
+func uploadTestChunks(t *testing.T, userID, md5Hash, sha256Hash string, content []byte) (string, error) {
+	init, err := uploadService.InitChunkUpload("secret.txt", int64(len(content)), md5Hash, sha256Hash, 1024, userID, "")
+	require.NoError(t, err)
+
+	_, err = uploadService.UploadChunk(init.FileID, 0, "", newTestFileHeader(t, "chunk", content))
+	require.NoError(t, err)
+
+	_, err = uploadService.MergeChunks(init.FileID)
+	return init.FileID, err
+}
+
+// findTestUploadFile 查询文件记录
+func findTestUploadFile(t *testing.T, db *gorm.DB, fileID string) *models.UploadFile {
+	var file models.UploadFile
+	require.NoError(t, db.Where("id = ?", fileID).First(&file).Error)
+	return &file
+}
+
+// TestChunkUploadClaimedHashCannotReadContent 测试只声明他人文件的哈希不能获得该文件的内容，合并校验通过后才按实际内容去重
+func TestChunkUploadClaimedHashCannotReadContent(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	owner := newTestUser(t, db, "chunk-owner", common.UserRoleUser)
+	attacker := newTestUser(t, db, "chunk-attacker", common.UserRoleUser)
+	honest := newTestUser(t, db, "chunk-honest", common.UserRoleUser)
+
+	secret := []byte("owner's private notes")
+	uploaded, err := uploadService.SimpleUpload(newTestFileHeader(t, "secret.txt", secret), owner.ID.String(), "")
+	require.NoError(t, err)
+	ownerFile := findTestUploadFile(t, db, uploaded.FileID)
+	md5Hash, sha256Hash := chunkTestDigest(secret)
+
+	// 声明相同的哈希和大小只会得到待上传的记录，不会引用已有内容
+	init, err := uploadService.InitChunkUpload("stolen.txt", int64(len(secret)), md5Hash, sha256Hash, 1024, attacker.ID.String(), "")
+	require.NoError(t, err)
+	claimed := findTestUploadFile(t, db, init.FileID)
+	assert.Equal(t, constants.UploadStatusUploading, claimed.UploadStatus)
+	assert.Empty(t, claimed.BlobID)
+	_, err = uploadService.OpenStoredFile(claimed)
+	assert.Error(t, err, "未上传内容的记录不能读取")
+
+	// 上传的内容与声明的哈希不符时合并失败
+	forged := []byte(strings.Repeat("x", len(secret)))
+	fileID, err := uploadTestChunks(t, attacker.ID.String(), md5Hash, sha256Hash, forged)
+	require.Error(t, err)
+	failed := findTestUploadFile(t, db, fileID)
+	assert.Equal(t, constants.UploadStatusFailed, failed.UploadStatus)
+	assert.Empty(t, failed.BlobID)
+
+	var blob models.FileBlob
+	require.NoError(t, db.Where("id = ?", ownerFile.BlobID).First(&blob).Error)
+	assert.Equal(t, 1, blob.RefCount)
+
+	// 实际持有相同内容时，合并校验通过后引用已有内容
+	fileID, err = uploadTestChunks(t, honest.ID.String(), md5Hash, sha256Hash, secret)
+	require.NoError(t, err)
+	deduplicated := findTestUploadFile(t, db, fileID)
+	assert.Equal(t, constants.UploadStatusCompleted, deduplicated.UploadStatus)
+	assert.Equal(t, ownerFile.BlobID, deduplicated.BlobID)
+	assert.Equal(t, ownerFile.FilePath, deduplicated.FilePath)
+}
+
+// newChunkUploadTestRouter 创建挂载分片上传接口的测试路由
+func newChunkUploadTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+
+	chunk := router.Group("/upload/chunk", middleware.RequireAuth(common.ScopeUploadWrite))
+	chunk.POST("/init", uploadController.InitChunkUpload)
+	chunk.POST("", uploadController.UploadChunk)
+	chunk.POST("/merge", uploadController.MergeChunks)
+	return router
+}
+
+// postChunkJSON 以JSON请求体调用分片上传接口，返回状态码
+func postChunkJSON(t *testing.T, router *gin.Engine, path, token string, body interface{}) int {
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// postTestChunk 上传一个分片，返回状态码
+func postTestChunk(t *testing.T, router *gin.Engine, token, fileID, md5Hash string, content []byte) int {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	require.NoError(t, writer.WriteField("fileID", fileID))
+	require.NoError(t, writer.WriteField("chunkIndex", strconv.Itoa(0)))
+	require.NoError(t, writer.WriteField("md5Hash", md5Hash))
+	part, err := writer.CreateFormFile("chunk", "chunk")
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload/chunk", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// TestChunkUploadClientErrorStatus 测试文件不合要求和内容校验失败时返回400而不是500
+func TestChunkUploadClientErrorStatus(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	u := newTestUser(t, db, "chunk-status", common.UserRoleUser)
+	token := loginTestUser(t, u, user.ClientInfo{IP: "198.51.100.20"}).AccessToken
+	router := newChunkUploadTestRouter()
+
+	content := []byte("declared content")
+	md5Hash, sha256Hash := chunkTestDigest(content)
+	initBody := func(filename string, size int64) gin.H {
+		return gin.H{"filename": filename, "fileSize": size, "md5Hash": md5Hash, "sha256Hash": sha256Hash, "chunkSize": 1024}
+	}
+
+	assert.Equal(t, http.StatusBadRequest, postChunkJSON(t, router, "/upload/chunk/init", token, initBody("huge.txt", constants.MaxFileSize+1)), "文件过大")
+	assert.Equal(t, http.StatusBadRequest, postChunkJSON(t, router, "/upload/chunk/init", token, initBody("tool.exe", int64(len(content)))), "不支持的文件类型")
+
+	init, err := uploadService.InitChunkUpload("notes.txt", int64(len(content)), md5Hash, sha256Hash, 1024, u.ID.String(), "")
+	require.NoError(t, err)
+
+	// 分片尚未上传时合并返回409
+	assert.Equal(t, http.StatusConflict, postChunkJSON(t, router, "/upload/chunk/merge", token, gin.H{"fileID": init.FileID}))
+
+	// 分片内容与声明的分片MD5不符
+	forged := []byte(strings.Repeat("x", len(content)))
+	assert.Equal(t, http.StatusBadRequest, postTestChunk(t, router, token, init.FileID, md5Hash, forged))
+
+	// 分片本身完整，但合并后的内容与初始化时声明的哈希不符
+	forgedMD5, _ := chunkTestDigest(forged)
+	require.Equal(t, http.StatusOK, postTestChunk(t, router, token, init.FileID, forgedMD5, forged))
+	assert.Equal(t, http.StatusBadRequest, postChunkJSON(t, router, "/upload/chunk/merge", token, gin.H{"fileID": init.FileID}))
+	assert.Equal(t, constants.UploadStatusFailed, findTestUploadFile(t, db, init.FileID).UploadStatus)
+}
+
+// TestMergeChunksOnce 测试同一文件只能合并一次，并发合并时只有一个请求成功，合并出的内容不会被其他请求删除
+func TestMergeChunksOnce(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	u := newTestUser(t, db, "merge-once", common.UserRoleUser)
+
+	// 合并完成后再次合并返回冲突
+	content := []byte("merged exactly once")
+	md5Hash, sha256Hash := chunkTestDigest(content)
+	fileID, err := uploadTestChunks(t, u.ID.String(), md5Hash, sha256Hash, content)
+	require.NoError(t, err)
+	_, err = uploadService.MergeChunks(fileID)
+	assert.ErrorIs(t, err, uploadService.ErrUploadNotMerging)
+
+	// 并发合并同一文件
+	content = []byte("merged concurrently")
+	md5Hash, sha256Hash = chunkTestDigest(content)
+	init, err := uploadService.InitChunkUpload("race.txt", int64(len(content)), md5Hash, sha256Hash, 1024, u.ID.String(), "")
+	require.NoError(t, err)
+	_, err = uploadService.UploadChunk(init.FileID, 0, "", newTestFileHeader(t, "chunk", content))
+	require.NoError(t, err)
+
+	const workers = 8
+	var (
+		wg                  sync.WaitGroup
+		mu                  sync.Mutex
+		succeeded, rejected int
+	)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, err := uploadService.MergeChunks(init.FileID)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case stderrors.Is(err, uploadService.ErrUploadNotMerging):
+				rejected++
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, workers-1, rejected)
+
+	merged := findTestUploadFile(t, db, init.FileID)
+	assert.Equal(t, constants.UploadStatusCompleted, merged.UploadStatus)
+	stored, err := os.ReadFile(merged.FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, content, stored)
+
+	var blob models.FileBlob
+	require.NoError(t, db.Where("id = ?", merged.BlobID).First(&blob).Error)
+	assert.Equal(t, 1, blob.RefCount)
+}
+
+// TestCompleteFileWithBlobRequiresMerging 测试合并期间已被标记失败的文件不会再被改为完成，也不会占用文件内容的引用
+func TestCompleteFileWithBlobRequiresMerging(t *testing.T) {
+	db := newUploadTestDB(t)
+	repo := uploadRepo.NewUploadRepository(db)
+
+	file := newTestUploadFile("user-a")
+	file.UploadStatus = constants.UploadStatusMerging
+	require.NoError(t, repo.CreateUploadFile(file))
+
+	// 清理任务在合并期间将文件标记为失败
+	marked, err := repo.MarkUploadFailed(file.ID.String())
+	require.NoError(t, err)
+	require.True(t, marked)
+
+	file.UploadStatus = constants.UploadStatusCompleted
+	blob := &models.FileBlob{SHA256Hash: "merged", StoredName: file.StoredName, FilePath: "merged", FileSize: file.FileSize}
+	completed, err := repo.CompleteFileWithBlob(file, blob)
+	require.NoError(t, err)
+	assert.False(t, completed)
+	assert.Equal(t, constants.UploadStatusFailed, findTestUploadFile(t, db, file.ID.String()).UploadStatus)
+
+	var count int64
+	require.NoError(t, db.Model(&models.FileBlob{}).Count(&count).Error)
+	assert.Zero(t, count, "未完成的文件不创建内容记录")
+}
@@ -0,0 +1,142 @@
+package unit
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+	"template/pkg/constants"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+func newUploadTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.UploadFile{},
+		&models.FileBlob{},
+		&models.ChunkInfo{},
+		&models.TusUpload{},
+		&models.FileDerivative{},
+		&models.UserQuota{},
+		&models.Folder{},
+		&models.FileVersion{},
+		&models.FileShare{},
+		&models.FilePermission{},
+		&models.TemporaryAccess{},
+	))
+	return db
+}
+
+// useTestUploadService 在临时目录中初始化使用当前测试数据库的上传服务
+func useTestUploadService(t *testing.T, db *gorm.DB) {
+	require.NoError(t, db.AutoMigrate(
+		&models.UploadFile{},
+		&models.FileBlob{},
+		&models.ChunkInfo{},
+		&models.TusUpload{},
+		&models.FileDerivative{},
+		&models.UserQuota{},
+		&models.Folder{},
+		&models.FileVersion{},
+		&models.FileShare{},
+		&models.FilePermission{},
+		&models.TemporaryAccess{},
+		&models.WebhookEndpoint{},
+	))
+
+	// 本地存储使用相对路径，切换到临时目录避免写入仓库
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+
+	uploadService.InitUploadService()
+}
+
+// newTestFileHeader 构造上传表单中的文件
+func newTestFileHeader(t *testing.T, filename string, content []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	return req.MultipartForm.File["file"][0]
+}
+
+func newTestUploadFile(userID string) *models.UploadFile {
+	return &models.UploadFile{
+		Filename:     "a.txt",
+		StoredName:   common.NewUUID().String() + ".txt",
+		FilePath:     "uploads/blob.txt",
+		FileSize:     3,
+		MimeType:     "text/plain",
+		Extension:    ".txt",
+		MD5Hash:      "md5",
+		SHA256Hash:   "hash",
+		UploadStatus: constants.UploadStatusCompleted,
+		UserID:       userID,
+	}
+}
+
+// TestFileBlobRefCount 测试相同内容的文件记录共享内容，最后一个引用释放时才返回待删除的内容
+func TestFileBlobRefCount(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+
+	first := newTestUploadFile("user-a")
+	blob := &models.FileBlob{SHA256Hash: "hash", MD5Hash: "md5", StoredName: "blob.txt", FilePath: "uploads/blob.txt", FileSize: 3}
+	require.NoError(t, repo.CreateFileWithBlob(first, blob))
+
+	// 其他用户上传相同内容时获得自己的文件记录
+	existing, err := repo.GetBlobByHash("hash")
+	require.NoError(t, err)
+	second := newTestUploadFile("user-b")
+	require.NoError(t, repo.CreateFileWithBlob(second, existing))
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, first.BlobID, second.BlobID)
+
+	existing, _ = repo.GetBlobByHash("hash")
+	assert.Equal(t, 2, existing.RefCount)
+
+	// 删除其中一个记录，内容仍被引用
+	orphans, err := repo.PurgeFileRecords(first)
+	require.NoError(t, err)
+	assert.Empty(t, orphans)
+	_, err = repo.GetUploadFileByID(second.ID.String())
+	assert.NoError(t, err)
+
+	// 删除最后一个记录，返回需要删除存储的内容
+	orphans, err = repo.PurgeFileRecords(second)
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "uploads/blob.txt", orphans[0].FilePath)
+	_, err = repo.GetBlobByHash("hash")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, err = repo.GetBlobByHash("")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	// 已释放的内容不能再被引用
+	third := newTestUploadFile("user-c")
+	assert.ErrorIs(t, repo.CreateFileWithBlob(third, existing), gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,57 @@
+package unit
+
+import (
+	"testing"
+
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	"template/pkg/constants"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSaveFileVersion 测试上传新版本时当前内容保存为历史版本，删除历史版本后释放内容引用和存储用量
+func TestSaveFileVersion(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+
+	file := newTestUploadFile("user-a")
+	v1 := &models.FileBlob{SHA256Hash: "hash", MD5Hash: "md5", StoredName: "v1.txt", FilePath: "uploads/v1.txt", FileSize: 3}
+	require.NoError(t, repo.CreateFileWithBlob(file, v1))
+	file.Version = 1
+	stale := *file
+
+	v2 := &models.FileBlob{SHA256Hash: "hash2", MD5Hash: "md52", StoredName: "v2.txt", FilePath: "uploads/v2.txt", FileSize: 5}
+	replaced, err := repo.SaveFileVersion(file, v2, "text/plain", constants.UploadStatusCompleted)
+	require.NoError(t, err)
+	assert.True(t, replaced)
+	assert.Equal(t, 2, file.Version)
+	assert.Equal(t, "uploads/v2.txt", file.FilePath)
+
+	// 基于旧记录的并发修改不生效
+	v3 := &models.FileBlob{SHA256Hash: "hash3", MD5Hash: "md53", StoredName: "v3.txt", FilePath: "uploads/v3.txt", FileSize: 7}
+	replaced, err = repo.SaveFileVersion(&stale, v3, "text/plain", constants.UploadStatusCompleted)
+	require.NoError(t, err)
+	assert.False(t, replaced)
+	_, err = repo.GetBlobByHash("hash3")
+	assert.Error(t, err)
+
+	versions, err := repo.GetFileVersions(file.ID.String())
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, 1, versions[0].Version)
+	assert.Equal(t, v1.ID.String(), versions[0].BlobID)
+
+	quota, err := repo.RecomputeQuotaUsage("user-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), quota.UsedBytes)
+
+	orphans, err := repo.DeleteFileVersions(file, versions)
+	require.NoError(t, err)
+	require.Len(t, orphans, 1)
+	assert.Equal(t, "uploads/v1.txt", orphans[0].FilePath)
+
+	quota, err = repo.GetUserQuota("user-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), quota.UsedBytes)
+}
@@ -0,0 +1,123 @@
+package unit
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMoveFolder 测试移动文件夹时同步更新子孙文件夹的路径，并按路径继承可见性
+func TestMoveFolder(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+
+	create := func(name string, parent *models.Folder) *models.Folder {
+		folder := &models.Folder{UserID: "user-a", Name: name, IsPublic: true}
+		folder.ID = common.NewUUID()
+		folder.Path = "/" + folder.ID.String() + "/"
+		if parent != nil {
+			folder.ParentID = parent.ID.String()
+			folder.Path = parent.Path + folder.ID.String() + "/"
+		}
+		require.NoError(t, repo.CreateFolder(folder))
+		return folder
+	}
+	docs := create("docs", nil)
+	work := create("work", docs)
+	reports := create("reports", work)
+	archive := create("archive", nil)
+
+	require.NoError(t, repo.MoveFolder(work, archive, nil))
+	assert.Equal(t, archive.ID.String(), work.ParentID)
+
+	moved, err := repo.GetFolderByID(reports.ID.String())
+	require.NoError(t, err)
+	assert.Equal(t, []string{archive.ID.String(), work.ID.String(), reports.ID.String()}, moved.PathIDs())
+
+	subtree, err := repo.GetFolderSubtree(archive)
+	require.NoError(t, err)
+	assert.Len(t, subtree, 3)
+	subtree, err = repo.GetFolderSubtree(docs)
+	require.NoError(t, err)
+	assert.Len(t, subtree, 1)
+
+	assert.True(t, repo.IsFolderPathPublic(moved.PathIDs()))
+	require.NoError(t, repo.UpdateFolderVisibility(archive.ID.String(), false))
+	assert.False(t, repo.IsFolderPathPublic(moved.PathIDs()))
+	assert.True(t, repo.IsFolderPathPublic(docs.PathIDs()))
+}
+
+// TestMoveFolderIntoDescendant 测试不能将文件夹移到自身或子孙文件夹中
+func TestMoveFolderIntoDescendant(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	u := newTestUser(t, db, "folder-cycle", common.UserRoleUser)
+	userID := u.ID.String()
+
+	docs, err := uploadService.CreateFolder(userID, "docs", "")
+	require.NoError(t, err)
+	work, err := uploadService.CreateFolder(userID, "work", docs.ID.String())
+	require.NoError(t, err)
+	reports, err := uploadService.CreateFolder(userID, "reports", work.ID.String())
+	require.NoError(t, err)
+
+	_, err = uploadService.MoveFolder(docs.ID.String(), userID, docs.ID.String())
+	assert.ErrorIs(t, err, uploadService.ErrFolderCycle)
+	_, err = uploadService.MoveFolder(docs.ID.String(), userID, reports.ID.String())
+	assert.ErrorIs(t, err, uploadService.ErrFolderCycle)
+
+	unchanged, err := uploadService.GetUserFolder(reports.ID.String(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{docs.ID.String(), work.ID.String(), reports.ID.String()}, unchanged.PathIDs())
+}
+
+// TestMoveFolderUsesCurrentPaths 测试移动时按事务中重新读取的路径检查和更新，读取后已被其他请求移动的文件夹不会形成循环或写回旧路径
+func TestMoveFolderUsesCurrentPaths(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+	errCycle := errors.New("cycle")
+	checkCycle := func(folder, parent *models.Folder, subtree []models.Folder) error {
+		if parent != nil && strings.HasPrefix(parent.Path, folder.Path) {
+			return errCycle
+		}
+		return nil
+	}
+
+	create := func(name string) *models.Folder {
+		folder := &models.Folder{UserID: "user-a", Name: name}
+		folder.ID = common.NewUUID()
+		folder.Path = "/" + folder.ID.String() + "/"
+		require.NoError(t, repo.CreateFolder(folder))
+		return folder
+	}
+	reload := func(folder *models.Folder) *models.Folder {
+		current, err := repo.GetFolderByID(folder.ID.String())
+		require.NoError(t, err)
+		return current
+	}
+
+	// 两个请求分别读取了 a 和 b，其中一个先把 b 移到 a 下
+	a, b := create("a"), create("b")
+	staleA, staleB := *a, *b
+	require.NoError(t, repo.MoveFolder(b, a, checkCycle))
+
+	// 另一个请求按读取时的路径把 a 移到 b 下会形成循环
+	assert.ErrorIs(t, repo.MoveFolder(&staleA, &staleB, checkCycle), errCycle)
+	assert.Equal(t, []string{a.ID.String()}, reload(a).PathIDs())
+	assert.Equal(t, []string{a.ID.String(), b.ID.String()}, reload(b).PathIDs())
+
+	// 按旧路径读取的文件夹移动时，子树按当前路径更新
+	c := create("c")
+	staleA = *reload(a)
+	require.NoError(t, repo.MoveFolder(a, c, checkCycle))
+	require.NoError(t, repo.MoveFolder(&staleA, nil, checkCycle))
+	assert.Equal(t, []string{a.ID.String()}, staleA.PathIDs())
+	assert.Equal(t, []string{a.ID.String(), b.ID.String()}, reload(b).PathIDs())
+	assert.Empty(t, reload(a).ParentID)
+}
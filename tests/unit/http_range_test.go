@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"template/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseRange 测试 Range 请求头的解析
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		header string
+		start  int64
+		length int64
+	}{
+		{"bytes=0-99", 0, 100},
+		{"bytes=100-", 100, 900},
+		{"bytes=-100", 900, 100},
+		{"bytes=-5000", 0, 1000},
+		{"bytes=990-5000", 990, 10},
+	}
+	for _, tt := range tests {
+		r, err := utils.ParseRange(tt.header, 1000)
+		assert.NoError(t, err, tt.header)
+		if assert.NotNil(t, r, tt.header) {
+			assert.Equal(t, tt.start, r.Start, tt.header)
+			assert.Equal(t, tt.length, r.Length, tt.header)
+		}
+	}
+
+	// 格式错误或多个范围时返回完整内容
+	for _, header := range []string{"", "items=0-1", "bytes=5-1", "bytes=a-b", "bytes=0-1,5-6"} {
+		r, err := utils.ParseRange(header, 1000)
+		assert.NoError(t, err, header)
+		assert.Nil(t, r, header)
+	}
+
+	// 超出文件大小
+	for _, header := range []string{"bytes=1000-", "bytes=-0"} {
+		_, err := utils.ParseRange(header, 1000)
+		assert.ErrorIs(t, err, utils.ErrRangeNotSatisfiable, header)
+	}
+}
+
+// TestConditionalRequest 测试 If-None-Match、If-Modified-Since 和 If-Range 的判断
+func TestConditionalRequest(t *testing.T) {
+	etag := `"abc"`
+	modified := time.Date(2024, 5, 1, 8, 0, 0, 500, time.UTC)
+	newRequest := func(headers map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	assert.False(t, utils.NotModified(newRequest(nil), etag, modified))
+	assert.True(t, utils.NotModified(newRequest(map[string]string{"If-None-Match": `"x", W/"abc"`}), etag, modified))
+	assert.True(t, utils.NotModified(newRequest(map[string]string{"If-None-Match": "*"}), etag, modified))
+	assert.False(t, utils.NotModified(newRequest(map[string]string{"If-None-Match": `"x"`}), etag, modified))
+	assert.True(t, utils.NotModified(newRequest(map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}), etag, modified))
+	assert.False(t, utils.NotModified(newRequest(map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}), etag, modified))
+	// ETag不匹配时忽略 If-Modified-Since
+	assert.False(t, utils.NotModified(newRequest(map[string]string{
+		"If-None-Match":     `"x"`,
+		"If-Modified-Since": modified.Format(http.TimeFormat),
+	}), etag, modified))
+
+	assert.True(t, utils.IfRangeMatches(newRequest(nil), etag, modified))
+	assert.True(t, utils.IfRangeMatches(newRequest(map[string]string{"If-Range": etag}), etag, modified))
+	assert.False(t, utils.IfRangeMatches(newRequest(map[string]string{"If-Range": `W/"abc"`}), etag, modified))
+	assert.True(t, utils.IfRangeMatches(newRequest(map[string]string{"If-Range": modified.Format(http.TimeFormat)}), etag, modified))
+	assert.False(t, utils.IfRangeMatches(newRequest(map[string]string{"If-Range": modified.Add(time.Hour).Format(http.TimeFormat)}), etag, modified))
+}
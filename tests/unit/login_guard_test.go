@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	userController "template/internal/controllers/user"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/config"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setLoginLimits 临时修改登录防护参数，测试结束后恢复
+func setLoginLimits(t *testing.T, accountMax, ipMax, lockMinutes int) {
+	security := &config.GetConfig().Security
+	previous := *security
+	security.LoginMaxAttempts = accountMax
+	security.LoginIPMaxAttempts = ipMax
+	security.LoginLockMinutes = lockMinutes
+	t.Cleanup(func() { *security = previous })
+}
+
+// TestLoginAccountLockout 测试账号连续失败达到上限后锁定，锁定期间正确的密码也无法登录
+func TestLoginAccountLockout(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "guard-account", common.UserRoleUser)
+	setLoginLimits(t, 3, 100, 10)
+
+	client := user.ClientInfo{IP: "192.0.2.10"}
+	for i := 0; i < 3; i++ {
+		_, err := user.Login(u.Username, "wrong-password", client)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, errors.CodeInvalidCredential), "达到上限的那次失败仍然提示账号或密码错误")
+	}
+
+	// 用户名和邮箱共享计数
+	_, err := user.Login(u.Email, testUserPassword, client)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.CodeRateLimited))
+
+	appErr, ok := err.(*errors.Error)
+	require.True(t, ok)
+	retryAfter, ok := appErr.Metadata["retry_after"].(int)
+	require.True(t, ok)
+	assert.Greater(t, retryAfter, 590)
+	assert.LessOrEqual(t, retryAfter, 601)
+}
+
+// TestLoginIPLockout 测试同一IP失败次数达到上限后封禁，不影响其他IP
+func TestLoginIPLockout(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "guard-ip", common.UserRoleUser)
+	setLoginLimits(t, 100, 3, 10)
+
+	// 使用不同的不存在账号，避免触发账号锁定
+	client := user.ClientInfo{IP: "192.0.2.20"}
+	for i := 0; i < 3; i++ {
+		_, err := user.Login("guard-ip-missing-"+strconv.Itoa(i), "wrong-password", client)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, errors.CodeInvalidCredential))
+	}
+
+	_, err := user.Login(u.Username, testUserPassword, client)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.CodeRateLimited))
+
+	_, err = user.Login(u.Username, testUserPassword, user.ClientInfo{IP: "192.0.2.21"})
+	assert.NoError(t, err)
+}
+
+// TestLoginRetryAfterHeader 测试登录被限制时返回 Retry-After 响应头
+func TestLoginRetryAfterHeader(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "guard-header", common.UserRoleUser)
+	setLoginLimits(t, 1, 100, 2)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+	router.POST("/login", userController.Login)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		body := `{"account":"` + u.Username + `","password":"` + password + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := login("wrong-password")
+	assert.Empty(t, w.Header().Get("Retry-After"))
+
+	w = login(testUserPassword)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.Greater(t, retryAfter, 110)
+	assert.LessOrEqual(t, retryAfter, 121)
+}
+
+// TestLoginSuccessResetsFailures 测试登录成功后清除账号的失败计数
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "guard-reset", common.UserRoleUser)
+	setLoginLimits(t, 3, 100, 10)
+
+	client := user.ClientInfo{IP: "192.0.2.30"}
+	for round := 0; round < 2; round++ {
+		for i := 0; i < 2; i++ {
+			_, err := user.Login(u.Username, "wrong-password", client)
+			require.True(t, errors.Is(err, errors.CodeInvalidCredential))
+		}
+		_, err := user.Login(u.Username, testUserPassword, client)
+		require.NoError(t, err, "第 %d 轮失败次数未达到上限，可以正常登录", round+1)
+	}
+}
+
+// TestUnlockAccount 测试管理员解除登录锁定，且只能解锁自己有权管理的用户
+func TestUnlockAccount(t *testing.T) {
+	db := newUserTestDB(t)
+	superAdmin := newTestUser(t, db, "guard-super", common.UserRoleSuperAdmin)
+	admin := newTestUser(t, db, "guard-admin", common.UserRoleAdmin)
+	member := newTestUser(t, db, "guard-member", common.UserRoleUser)
+	setLoginLimits(t, 1, 100, 10)
+
+	client := user.ClientInfo{IP: "192.0.2.40"}
+	for _, u := range []string{superAdmin.Username, member.Username} {
+		_, _ = user.Login(u, "wrong-password", client)
+		_, err := user.Login(u, testUserPassword, client)
+		require.True(t, errors.Is(err, errors.CodeRateLimited))
+	}
+
+	// 管理员不能解锁超级管理员，也不能解锁自己
+	err := user.UnlockAccount(admin.ID.String(), superAdmin.ID.String())
+	assert.True(t, errors.Is(err, errors.CodeForbidden))
+	err = user.UnlockAccount(admin.ID.String(), admin.ID.String())
+	assert.True(t, errors.Is(err, errors.CodeForbidden))
+	_, err = user.Login(superAdmin.Username, testUserPassword, client)
+	assert.True(t, errors.Is(err, errors.CodeRateLimited), "解锁失败时仍然保持锁定")
+
+	require.NoError(t, user.UnlockAccount(admin.ID.String(), member.ID.String()))
+	_, err = user.Login(member.Username, testUserPassword, client)
+	assert.NoError(t, err)
+
+	err = user.UnlockAccount(member.ID.String(), admin.ID.String())
+	assert.True(t, errors.Is(err, errors.CodeForbidden), "普通用户不能解锁管理员")
+	err = user.UnlockAccount(admin.ID.String(), common.NewUUID().String())
+	assert.True(t, errors.Is(err, errors.CodeUserNotFound))
+}
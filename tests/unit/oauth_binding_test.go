@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	userController "template/internal/controllers/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+	"template/pkg/oauth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubOAuthProvider 不访问网络的第三方登录提供方，授权码固定返回同一身份
+type stubOAuthProvider struct {
+	name     string
+	identity oauth.Identity
+}
+
+func (p *stubOAuthProvider) Name() string        { return p.name }
+func (p *stubOAuthProvider) DisplayName() string { return p.name }
+
+func (p *stubOAuthProvider) AuthCodeURL(ctx context.Context, req oauth.AuthRequest) (string, error) {
+	return "https://idp.example/authorize?" + url.Values{"state": {req.State}}.Encode(), nil
+}
+
+func (p *stubOAuthProvider) Exchange(ctx context.Context, code string, req oauth.AuthRequest) (*oauth.Identity, error) {
+	identity := p.identity
+	return &identity, nil
+}
+
+// beginTestOAuth 发起授权，返回授权状态和浏览器绑定Cookie
+func beginTestOAuth(t *testing.T, router *gin.Engine, provider string) (string, *http.Cookie) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth/"+provider, nil))
+	require.Equal(t, http.StatusFound, w.Code)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+
+	var binding *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "oauth_binding" {
+			binding = cookie
+		}
+	}
+	require.NotNil(t, binding)
+	assert.True(t, binding.HttpOnly)
+	assert.Equal(t, http.SameSiteLaxMode, binding.SameSite)
+	return location.Query().Get("state"), binding
+}
+
+// callbackTestOAuth 模拟第三方授权页面跳转回回调地址
+func callbackTestOAuth(router *gin.Engine, provider, state string, cookie *http.Cookie) int {
+	req := httptest.NewRequest(http.MethodGet, "/oauth/"+provider+"/callback?"+url.Values{"state": {state}, "code": {"code-1"}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// TestOAuthCallbackRequiresBrowserBinding 测试回调必须携带发起授权时写入的浏览器绑定Cookie
+func TestOAuthCallbackRequiresBrowserBinding(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "oauth-binding", common.UserRoleUser)
+
+	provider := &stubOAuthProvider{name: "stub-binding", identity: oauth.Identity{
+		Provider:      "stub-binding",
+		Subject:       "subject-1",
+		Email:         u.Email,
+		EmailVerified: true,
+	}}
+	oauth.RegisterProvider(provider)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+	router.GET("/oauth/:provider", userController.OAuthLogin)
+	router.GET("/oauth/:provider/callback", userController.OAuthCallback)
+
+	// 攻击者发起授权后把回调地址交给受害者的浏览器，受害者没有对应的Cookie
+	state, _ := beginTestOAuth(t, router, provider.name)
+	assert.Equal(t, http.StatusBadRequest, callbackTestOAuth(router, provider.name, state, nil))
+
+	// 另一次授权的Cookie同样不匹配
+	state, _ = beginTestOAuth(t, router, provider.name)
+	_, otherCookie := beginTestOAuth(t, router, provider.name)
+	assert.Equal(t, http.StatusBadRequest, callbackTestOAuth(router, provider.name, state, otherCookie))
+
+	state, cookie := beginTestOAuth(t, router, provider.name)
+	assert.Equal(t, http.StatusOK, callbackTestOAuth(router, provider.name, state, cookie))
+
+	// 授权状态只能使用一次
+	assert.Equal(t, http.StatusBadRequest, callbackTestOAuth(router, provider.name, state, cookie))
+}
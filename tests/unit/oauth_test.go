@@ -0,0 +1,235 @@
+package unit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"template/pkg/config"
+	"template/pkg/oauth"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockOIDCServer 本地模拟的OIDC提供方
+type mockOIDCServer struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	signKey  *rsa.PrivateKey // 签发ID令牌使用的私钥，默认与公布的公钥一致
+	clientID string
+
+	mu    sync.Mutex
+	codes map[string]mockAuthorization // 授权码 -> 授权请求
+}
+
+// mockAuthorization 模拟授权页面记录的请求参数
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockOIDCServer(t *testing.T, clientID string) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	m := &mockOIDCServer{key: key, signKey: key, clientID: clientID, codes: make(map[string]mockAuthorization)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test-key",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+
+		m.mu.Lock()
+		auth, ok := m.codes[r.PostForm.Get("code")]
+		delete(m.codes, r.PostForm.Get("code"))
+		m.mu.Unlock()
+
+		if !ok || oauth.CodeChallengeS256(r.PostForm.Get("code_verifier")) != auth.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		now := time.Now()
+		idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            m.URL,
+			"aud":            m.clientID,
+			"sub":            "user-123",
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"name":           "Alice",
+			"nonce":          auth.nonce,
+			"iat":            now.Unix(),
+			"exp":            now.Add(time.Minute).Unix(),
+		})
+		idToken.Header["kid"] = "test-key"
+		signed, _ := idToken.SignedString(m.signKey)
+
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"id_token":     signed,
+		})
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// authorize 模拟用户在授权页面同意授权，返回授权码
+func (m *mockOIDCServer) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	query := u.Query()
+	assert.Equal(t, m.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.codes["code-1"] = mockAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	return "code-1"
+}
+
+// TestOIDCProviderExchange 测试OIDC授权码+PKCE流程
+func TestOIDCProviderExchange(t *testing.T) {
+	server := newMockOIDCServer(t, "test-client")
+	provider := oauth.NewOIDCProvider("mock", config.OAuthProviderConfig{
+		Issuer:   server.URL,
+		ClientID: "test-client",
+	})
+	ctx := context.Background()
+
+	t.Run("登录成功", func(t *testing.T) {
+		req, err := oauth.NewAuthRequest("http://localhost/callback")
+		require.NoError(t, err)
+
+		authURL, err := provider.AuthCodeURL(ctx, *req)
+		require.NoError(t, err)
+		code := server.authorize(t, authURL)
+
+		identity, err := provider.Exchange(ctx, code, *req)
+		require.NoError(t, err)
+		assert.Equal(t, "mock", identity.Provider)
+		assert.Equal(t, "user-123", identity.Subject)
+		assert.Equal(t, "alice@example.com", identity.Email)
+		assert.True(t, identity.EmailVerified)
+	})
+
+	t.Run("PKCE校验值错误", func(t *testing.T) {
+		req, _ := oauth.NewAuthRequest("http://localhost/callback")
+		authURL, _ := provider.AuthCodeURL(ctx, *req)
+		code := server.authorize(t, authURL)
+
+		req.CodeVerifier = "wrong-verifier"
+		_, err := provider.Exchange(ctx, code, *req)
+		assert.Error(t, err)
+	})
+
+	t.Run("nonce不匹配", func(t *testing.T) {
+		req, _ := oauth.NewAuthRequest("http://localhost/callback")
+		authURL, _ := provider.AuthCodeURL(ctx, *req)
+		code := server.authorize(t, authURL)
+
+		req.Nonce = "another-nonce"
+		_, err := provider.Exchange(ctx, code, *req)
+		assert.Error(t, err)
+	})
+
+	t.Run("签名无效", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		server.signKey = otherKey
+		defer func() { server.signKey = server.key }()
+
+		req, _ := oauth.NewAuthRequest("http://localhost/callback")
+		authURL, _ := provider.AuthCodeURL(ctx, *req)
+		code := server.authorize(t, authURL)
+
+		_, err = provider.Exchange(ctx, code, *req)
+		assert.Error(t, err)
+	})
+}
+
+// TestOAuth2ProviderExchange 测试GitHub风格的OAuth2提供方
+func TestOAuth2ProviderExchange(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("code") != "gh-code" || r.PostForm.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "gh-token", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer gh-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id": 42, "login": "octocat", "name": "The Octocat", "email": null}`))
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"email": "old@example.com", "primary": false, "verified": true},
+			{"email": "octocat@example.com", "primary": true, "verified": true}]`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider, err := oauth.NewProvider("github", config.OAuthProviderConfig{
+		Type:        oauth.ProviderTypeOAuth2,
+		ClientID:    "gh-client",
+		AuthURL:     server.URL + "/authorize",
+		TokenURL:    server.URL + "/token",
+		UserInfoURL: server.URL + "/user",
+		EmailsURL:   server.URL + "/user/emails",
+	})
+	require.NoError(t, err)
+
+	req, _ := oauth.NewAuthRequest("http://localhost/callback")
+	authURL, err := provider.AuthCodeURL(context.Background(), *req)
+	require.NoError(t, err)
+	assert.Contains(t, authURL, "code_challenge=")
+
+	identity, err := provider.Exchange(context.Background(), "gh-code", *req)
+	require.NoError(t, err)
+	assert.Equal(t, "42", identity.Subject)
+	assert.Equal(t, "octocat", identity.Username)
+	assert.Equal(t, "octocat@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+}
+
+// TestCodeChallengeS256 使用RFC 7636附录B的示例验证PKCE校验码
+func TestCodeChallengeS256(t *testing.T) {
+	challenge := oauth.CodeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk")
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", challenge)
+}
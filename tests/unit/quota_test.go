@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"testing"
+
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQuotaReserveAndRelease 测试占用空间不能超过配额，删除文件和上传失败时释放空间
+func TestQuotaReserveAndRelease(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+
+	// 首次查询时按已有的文件记录统计用量
+	file := newTestUploadFile("user-a")
+	file.FileSize = 100
+	blob := &models.FileBlob{SHA256Hash: "hash", MD5Hash: "md5", StoredName: "blob.txt", FilePath: "uploads/blob.txt", FileSize: 100}
+	require.NoError(t, repo.CreateFileWithBlob(file, blob))
+	quota, err := repo.GetUserQuota("user-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), quota.UsedBytes)
+
+	ok, err := repo.ReserveQuota("user-a", 50, 150)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = repo.ReserveQuota("user-a", 1, 150)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	// 不限制配额
+	ok, err = repo.ReserveQuota("user-a", 1000, 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 释放不会减到负数
+	require.NoError(t, repo.ReleaseQuota("user-a", 2000))
+	quota, _ = repo.GetUserQuota("user-a")
+	assert.Equal(t, int64(0), quota.UsedBytes)
+
+	// 按文件记录修正用量，删除文件后释放
+	quota, err = repo.RecomputeQuotaUsage("user-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), quota.UsedBytes)
+	_, err = repo.PurgeFileRecords(file)
+	require.NoError(t, err)
+	quota, _ = repo.GetUserQuota("user-a")
+	assert.Equal(t, int64(0), quota.UsedBytes)
+}
+
+// TestQuotaReleasedOnUploadFailed 测试上传失败时释放初始化时预留的空间，且只释放一次
+func TestQuotaReleasedOnUploadFailed(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+
+	ok, err := repo.ReserveQuota("user-a", 100, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	file := &models.UploadFile{Filename: "a.txt", StoredName: "a.txt", FileSize: 100, UserID: "user-a"}
+	require.NoError(t, repo.CreateUploadFile(file))
+
+	reserved, err := repo.GetReservedBytes("user-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), reserved)
+
+	marked, err := repo.MarkUploadFailed(file.ID.String())
+	require.NoError(t, err)
+	assert.True(t, marked)
+	marked, err = repo.MarkUploadFailed(file.ID.String())
+	require.NoError(t, err)
+	assert.False(t, marked)
+	quota, _ := repo.GetUserQuota("user-a")
+	assert.Equal(t, int64(0), quota.UsedBytes)
+}
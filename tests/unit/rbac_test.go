@@ -0,0 +1,170 @@
+package unit
+
+import (
+	"net/http"
+	"testing"
+
+	"template/internal/middleware"
+	"template/internal/models"
+	"template/internal/services/rbac"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/database"
+	"template/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestLegacyRoleName 测试旧版整数角色到内置角色的映射
+func TestLegacyRoleName(t *testing.T) {
+	assert.Equal(t, common.RoleNameSuperAdmin, common.LegacyRoleName(common.UserRoleSuperAdmin))
+	assert.Equal(t, common.RoleNameAdmin, common.LegacyRoleName(common.UserRoleAdmin))
+	assert.Equal(t, common.RoleNameUser, common.LegacyRoleName(common.UserRoleUser))
+	assert.Equal(t, common.RoleNameUser, common.LegacyRoleName(0))
+}
+
+// TestAuthorization 测试权限和角色判断
+func TestAuthorization(t *testing.T) {
+	authz := &rbac.Authorization{
+		Roles:       []string{common.RoleNameAdmin},
+		Permissions: []string{common.PermFileRead, common.PermFileDelete},
+	}
+
+	assert.True(t, authz.HasPermission(common.PermFileRead))
+	assert.True(t, authz.HasPermission(common.PermFileRead, common.PermFileDelete))
+	assert.False(t, authz.HasPermission(common.PermFileRead, common.PermRoleManage))
+
+	assert.True(t, authz.HasRole(common.RoleNameSuperAdmin, common.RoleNameAdmin))
+	assert.False(t, authz.HasRole(common.RoleNameSuperAdmin))
+}
+
+// findTestRole 按标识查询角色
+func findTestRole(t *testing.T, db *gorm.DB, name string) *models.Role {
+	var role models.Role
+	require.NoError(t, db.Where("name = ?", name).First(&role).Error)
+	return &role
+}
+
+// testUserRoleIDs 查询用户关联的角色ID
+func testUserRoleIDs(t *testing.T, db *gorm.DB, userID string) []string {
+	var roleIDs []string
+	require.NoError(t, db.Model(&models.UserRole{}).Where("user_id = ?", userID).Pluck("role_id", &roleIDs).Error)
+	return roleIDs
+}
+
+// TestRequirePermission 测试拥有权限的用户可以访问，缺少权限的用户和API密钥不能访问
+func TestRequirePermission(t *testing.T) {
+	db := newUserTestDB(t)
+	admin := newTestUser(t, db, "perm-admin", common.UserRoleAdmin)
+	member := newTestUser(t, db, "perm-member", common.UserRoleUser)
+	router := newAuthTestRouter(middleware.RequirePermission(common.PermUserRead))
+
+	assert.Equal(t, http.StatusOK, doAuthRequest(router, loginTestUser(t, admin, user.ClientInfo{IP: "198.51.100.40"}).AccessToken))
+	assert.Equal(t, http.StatusForbidden, doAuthRequest(router, loginTestUser(t, member, user.ClientInfo{IP: "198.51.100.40"}).AccessToken))
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(router, ""))
+
+	// 管理权限只能通过登录令牌使用，拥有权限的用户的API密钥也不能访问
+	_, key, err := user.CreateAPIKey(admin.ID.String(), "admin-key", common.APIKeyScopes, 0)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, doAuthRequest(router, key))
+}
+
+// TestSetUserRoles 测试只有超级管理员可以变更超级管理员角色，设置角色后同步旧版整数角色字段
+func TestSetUserRoles(t *testing.T) {
+	db := newUserTestDB(t)
+	superAdmin := newTestUser(t, db, "roles-super", common.UserRoleSuperAdmin)
+	admin := newTestUser(t, db, "roles-admin", common.UserRoleAdmin)
+	member := newTestUser(t, db, "roles-member", common.UserRoleUser)
+	superRole := findTestRole(t, db, common.RoleNameSuperAdmin)
+	adminRole := findTestRole(t, db, common.RoleNameAdmin)
+	userRole := findTestRole(t, db, common.RoleNameUser)
+
+	// 管理员不能授予超级管理员角色
+	err := rbac.SetUserRoles(admin.ID.String(), member.ID.String(), []string{superRole.ID.String()})
+	assert.True(t, errors.Is(err, errors.CodeForbidden), "got %v", err)
+	assert.Empty(t, testUserRoleIDs(t, db, member.ID.String()))
+
+	// 管理员可以分配其他角色，旧版角色字段随之更新
+	require.NoError(t, rbac.SetUserRoles(admin.ID.String(), member.ID.String(), []string{adminRole.ID.String(), userRole.ID.String()}))
+	assert.ElementsMatch(t, []string{adminRole.ID.String(), userRole.ID.String()}, testUserRoleIDs(t, db, member.ID.String()))
+	assert.Equal(t, common.UserRoleAdmin, findUnscopedUser(t, db, member.ID.String()).Role)
+
+	// 超级管理员可以授予超级管理员角色
+	require.NoError(t, rbac.SetUserRoles(superAdmin.ID.String(), member.ID.String(), []string{superRole.ID.String()}))
+	assert.Equal(t, common.UserRoleSuperAdmin, findUnscopedUser(t, db, member.ID.String()).Role)
+
+	// 管理员不能移除超级管理员角色，超级管理员不能移除自己的超级管理员角色
+	err = rbac.SetUserRoles(admin.ID.String(), member.ID.String(), []string{userRole.ID.String()})
+	assert.True(t, errors.Is(err, errors.CodeForbidden), "got %v", err)
+	err = rbac.SetUserRoles(superAdmin.ID.String(), superAdmin.ID.String(), []string{adminRole.ID.String()})
+	assert.True(t, errors.Is(err, errors.CodeForbidden), "got %v", err)
+	assert.Equal(t, common.UserRoleSuperAdmin, findUnscopedUser(t, db, superAdmin.ID.String()).Role)
+
+	require.NoError(t, rbac.SetUserRoles(superAdmin.ID.String(), member.ID.String(), []string{userRole.ID.String()}))
+	assert.Equal(t, common.UserRoleUser, findUnscopedUser(t, db, member.ID.String()).Role)
+
+	// 不存在的角色
+	err = rbac.SetUserRoles(superAdmin.ID.String(), member.ID.String(), []string{common.NewUUID().String()})
+	assert.True(t, errors.Is(err, errors.CodeNotFound), "got %v", err)
+}
+
+// TestSeedRBACLegacyRoles 测试同步内置角色时，还没有角色的用户按旧版整数角色获得对应的内置角色，已有角色的用户不受影响
+func TestSeedRBACLegacyRoles(t *testing.T) {
+	db := newUserTestDB(t)
+	superAdmin := newTestUser(t, db, "seed-super", common.UserRoleSuperAdmin)
+	admin := newTestUser(t, db, "seed-admin", common.UserRoleAdmin)
+	member := newTestUser(t, db, "seed-member", common.UserRoleUser)
+	assigned := newTestUser(t, db, "seed-assigned", common.UserRoleAdmin)
+	userRole := findTestRole(t, db, common.RoleNameUser)
+	require.NoError(t, db.Create(&models.UserRole{UserID: assigned.ID.String(), RoleID: userRole.ID.String()}).Error)
+
+	require.NoError(t, database.SeedRBAC())
+	// 重复执行不会重复分配
+	require.NoError(t, database.SeedRBAC())
+
+	assert.Equal(t, []string{findTestRole(t, db, common.RoleNameSuperAdmin).ID.String()}, testUserRoleIDs(t, db, superAdmin.ID.String()))
+	assert.Equal(t, []string{findTestRole(t, db, common.RoleNameAdmin).ID.String()}, testUserRoleIDs(t, db, admin.ID.String()))
+	assert.Equal(t, []string{userRole.ID.String()}, testUserRoleIDs(t, db, member.ID.String()))
+	assert.Equal(t, []string{userRole.ID.String()}, testUserRoleIDs(t, db, assigned.ID.String()), "已分配角色的用户保持原样")
+
+	// 超级管理员角色拥有全部权限
+	var superRole models.Role
+	require.NoError(t, db.Preload("Permissions").Where("name = ?", common.RoleNameSuperAdmin).First(&superRole).Error)
+	assert.Len(t, superRole.Permissions, len(common.Permissions))
+}
+
+// TestAuthorizationCacheInvalidation 测试用户权限有缓存，通过角色管理接口变更角色或角色权限后立即生效
+func TestAuthorizationCacheInvalidation(t *testing.T) {
+	db := newUserTestDB(t)
+	superAdmin := newTestUser(t, db, "cache-super", common.UserRoleSuperAdmin)
+	member := newTestUser(t, db, "cache-member", common.UserRoleUser)
+	memberID := member.ID.String()
+	adminRole := findTestRole(t, db, common.RoleNameAdmin)
+
+	assert.False(t, rbac.HasPermission(memberID, common.PermUserRead))
+
+	// 绕过角色管理接口直接修改数据库时，缓存的权限不变
+	require.NoError(t, db.Create(&models.UserRole{UserID: memberID, RoleID: adminRole.ID.String()}).Error)
+	assert.False(t, rbac.HasPermission(memberID, common.PermUserRead), "读取缓存的权限")
+
+	// 设置用户角色后清除缓存
+	require.NoError(t, rbac.SetUserRoles(superAdmin.ID.String(), memberID, []string{adminRole.ID.String()}))
+	assert.True(t, rbac.HasPermission(memberID, common.PermUserRead))
+
+	// 修改角色的权限后清除角色成员的缓存
+	auditor, err := rbac.CreateRole("auditor", "审计员", "", []string{common.PermFileRead})
+	require.NoError(t, err)
+	require.NoError(t, rbac.SetUserRoles(superAdmin.ID.String(), memberID, []string{auditor.ID.String()}))
+	assert.False(t, rbac.HasPermission(memberID, common.PermUserRead))
+	assert.True(t, rbac.HasPermission(memberID, common.PermFileRead))
+
+	_, err = rbac.UpdateRole(auditor.ID.String(), "审计员", "", []string{common.PermFileRead, common.PermUserRead})
+	require.NoError(t, err)
+	assert.True(t, rbac.HasPermission(memberID, common.PermUserRead))
+
+	// 删除角色后成员失去该角色的权限
+	require.NoError(t, rbac.DeleteRole(auditor.ID.String()))
+	assert.False(t, rbac.HasPermission(memberID, common.PermFileRead))
+}
@@ -0,0 +1,132 @@
+package unit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"template/pkg/config"
+	"template/pkg/upload"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClamdSignature 模拟病毒库中的特征内容
+const fakeClamdSignature = "X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR"
+
+// startFakeClamd 启动模拟的clamd服务：解析 INSTREAM 数据块，内容包含特征时返回 FOUND
+// 内容超过 maxLength 时与clamd一样返回大小超限错误
+func startFakeClamd(t *testing.T, maxLength int) (string, <-chan []byte) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []byte, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			content, reply := handleFakeClamd(conn, maxLength)
+			received <- content
+			conn.Write([]byte(reply + "\x00"))
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func handleFakeClamd(conn net.Conn, maxLength int) ([]byte, string) {
+	reader := bufio.NewReader(conn)
+	command, err := reader.ReadString(0)
+	if err != nil || command != "zINSTREAM\x00" {
+		return nil, "UNKNOWN COMMAND"
+	}
+
+	var content []byte
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return content, "stream: read error ERROR"
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size == 0 {
+			break
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return content, "stream: read error ERROR"
+		}
+		content = append(content, chunk...)
+		if len(content) > maxLength {
+			return content, "INSTREAM size limit exceeded. ERROR"
+		}
+	}
+
+	if bytes.Contains(content, []byte(fakeClamdSignature)) {
+		return content, "stream: Eicar-Test-Signature FOUND"
+	}
+	return content, "stream: OK"
+}
+
+// TestClamdScanner 测试通过 INSTREAM 协议发送内容并解析扫描结果
+func TestClamdScanner(t *testing.T) {
+	address, received := startFakeClamd(t, 1024*1024)
+	scanner := upload.NewClamdScanner(address, 5*time.Second)
+
+	// 超过一个数据块的内容完整送达
+	clean := strings.Repeat("hello clamd ", 10000)
+	result, err := scanner.Scan(strings.NewReader(clean))
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+	assert.Equal(t, clean, string(<-received))
+
+	result, err = scanner.Scan(strings.NewReader("prefix " + fakeClamdSignature))
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+
+	// 空文件
+	result, err = scanner.Scan(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+}
+
+// TestClamdScannerErrors 测试clamd返回错误或不可用时扫描失败，而不是视为安全
+func TestClamdScannerErrors(t *testing.T) {
+	address, _ := startFakeClamd(t, 100)
+	scanner := upload.NewClamdScanner(address, 5*time.Second)
+
+	_, err := scanner.Scan(strings.NewReader(strings.Repeat("x", 200*1024)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "size limit exceeded")
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddress := listener.Addr().String()
+	listener.Close()
+
+	_, err = upload.NewClamdScanner(closedAddress, time.Second).Scan(strings.NewReader("data"))
+	assert.Error(t, err)
+}
+
+// TestNewScanner 测试按配置选择扫描器
+func TestNewScanner(t *testing.T) {
+	scanner, err := upload.NewScanner(config.ScannerConfig{})
+	require.NoError(t, err)
+	assert.True(t, upload.IsNoopScanner(scanner))
+
+	scanner, err = upload.NewScanner(config.ScannerConfig{Driver: upload.ScannerDriverClamd})
+	require.NoError(t, err)
+	assert.False(t, upload.IsNoopScanner(scanner))
+
+	_, err = upload.NewScanner(config.ScannerConfig{Driver: "unknown"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"template/internal/middleware"
+	"template/internal/models"
+	"template/internal/services/user"
+	"template/pkg/common"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuthTestRouter 创建挂载了指定中间件的测试路由，GET /protected 通过认证时返回200
+func newAuthTestRouter(handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handlers = append(handlers, func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/protected", handlers...)
+	return router
+}
+
+// doAuthRequest 使用 Bearer 令牌请求测试路由，返回状态码
+func doAuthRequest(router *gin.Engine, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w.Code
+}
+
+// loginTestUser 使用测试密码登录，返回签发的令牌
+func loginTestUser(t *testing.T, u *models.User, client user.ClientInfo) *common.TokenPair {
+	result, err := user.Login(u.Username, testUserPassword, client)
+	require.NoError(t, err)
+	require.NotNil(t, result.Tokens)
+	return result.Tokens
+}
+
+// sessionIDOf 解析令牌所属的会话ID
+func sessionIDOf(t *testing.T, token string) string {
+	claims, err := common.ParseToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.SessionID)
+	return claims.SessionID
+}
+
+// TestLoginCreatesSession 测试每次登录创建一个设备会话，会话列表按最后活跃时间排序
+func TestLoginCreatesSession(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "session-create", common.UserRoleUser)
+
+	first := loginTestUser(t, u, user.ClientInfo{
+		IP:        "10.0.0.1",
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36",
+	})
+	time.Sleep(10 * time.Millisecond)
+	second := loginTestUser(t, u, user.ClientInfo{IP: "10.0.0.2", Device: "工作电脑"})
+
+	sessions, err := user.ListSessions(u.ID.String())
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, sessionIDOf(t, second.AccessToken), sessions[0].ID.String())
+	assert.Equal(t, "工作电脑", sessions[0].Device)
+	assert.Equal(t, sessionIDOf(t, first.AccessToken), sessions[1].ID.String())
+	assert.Equal(t, "Chrome on Windows", sessions[1].Device)
+	assert.Equal(t, "10.0.0.1", sessions[1].IP)
+
+	other, err := user.ListSessions(common.NewUUID().String())
+	require.NoError(t, err)
+	assert.Empty(t, other)
+}
+
+// TestRevokeSession 测试吊销单个会话后该会话的令牌立即失效，其他会话不受影响
+func TestRevokeSession(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "session-revoke", common.UserRoleUser)
+	router := newAuthTestRouter(middleware.RequireAuth())
+
+	first := loginTestUser(t, u, user.ClientInfo{IP: "10.0.0.1"})
+	second := loginTestUser(t, u, user.ClientInfo{IP: "10.0.0.2"})
+	require.Equal(t, http.StatusOK, doAuthRequest(router, first.AccessToken))
+
+	// 其他用户不能吊销
+	intruder := newTestUser(t, db, "session-intruder", common.UserRoleUser)
+	assert.Error(t, user.RevokeSession(intruder.ID.String(), sessionIDOf(t, first.AccessToken)))
+
+	require.NoError(t, user.RevokeSession(u.ID.String(), sessionIDOf(t, first.AccessToken)))
+	assert.Error(t, user.RevokeSession(u.ID.String(), sessionIDOf(t, first.AccessToken)), "已吊销的会话不能重复吊销")
+
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(router, first.AccessToken))
+	assert.Equal(t, http.StatusOK, doAuthRequest(router, second.AccessToken))
+
+	_, err := user.RefreshToken(first.RefreshToken)
+	assert.Error(t, err, "已吊销会话的刷新令牌不能换取新令牌")
+
+	sessions, err := user.ListSessions(u.ID.String())
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, sessionIDOf(t, second.AccessToken), sessions[0].ID.String())
+}
+
+// TestRevokeOtherSessions 测试吊销其他会话时保留当前会话
+func TestRevokeOtherSessions(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "session-others", common.UserRoleUser)
+	router := newAuthTestRouter(middleware.RequireAuth())
+
+	current := loginTestUser(t, u, user.ClientInfo{IP: "10.0.0.1"})
+	others := []*common.TokenPair{
+		loginTestUser(t, u, user.ClientInfo{IP: "10.0.0.2"}),
+		loginTestUser(t, u, user.ClientInfo{IP: "10.0.0.3"}),
+	}
+
+	count, err := user.RevokeOtherSessions(u.ID.String(), sessionIDOf(t, current.AccessToken))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	assert.Equal(t, http.StatusOK, doAuthRequest(router, current.AccessToken))
+	for _, tokens := range others {
+		assert.Equal(t, http.StatusUnauthorized, doAuthRequest(router, tokens.AccessToken))
+	}
+
+	count, err = user.RevokeOtherSessions(u.ID.String(), sessionIDOf(t, current.AccessToken))
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// 不指定当前会话时吊销全部
+	count, err = user.RevokeOtherSessions(u.ID.String(), "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(router, current.AccessToken))
+}
+
+// TestSessionStateCache 测试认证中间件缓存会话的有效状态，通过接口吊销时立即更新缓存
+func TestSessionStateCache(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "session-cache", common.UserRoleUser)
+	router := newAuthTestRouter(middleware.RequireAuth())
+
+	tokens := loginTestUser(t, u, user.ClientInfo{IP: "10.0.0.1"})
+	sessionID := sessionIDOf(t, tokens.AccessToken)
+	require.Equal(t, http.StatusOK, doAuthRequest(router, tokens.AccessToken))
+
+	// 有效状态已缓存，绕过服务直接修改数据库时在缓存过期前仍然有效
+	require.NoError(t, db.Model(&models.UserSession{}).Where("id = ?", sessionID).Update("revoked_at", time.Now()).Error)
+	assert.Equal(t, http.StatusOK, doAuthRequest(router, tokens.AccessToken))
+
+	// 通过服务吊销时同步更新缓存，立即生效
+	require.NoError(t, db.Model(&models.UserSession{}).Where("id = ?", sessionID).Update("revoked_at", nil).Error)
+	require.NoError(t, user.RevokeSession(u.ID.String(), sessionID))
+	assert.Equal(t, http.StatusUnauthorized, doAuthRequest(router, tokens.AccessToken))
+}
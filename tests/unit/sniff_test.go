@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"template/pkg/upload"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDetectContentType 测试根据文件头识别文件类型
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF\x00", "image/jpeg"},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"gif", "GIF89a\x01\x00\x01\x00", "image/gif"},
+		{"webp", "RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"pdf", "%PDF-1.7\n", "application/pdf"},
+		{"zip", "PK\x03\x04\x14\x00\x00\x00", "application/zip"},
+		{"empty zip", "PK\x05\x06\x00\x00\x00\x00", "application/zip"},
+		{"mp4", "\x00\x00\x00\x18ftypisom\x00\x00\x02\x00isomiso2", "video/mp4"},
+		{"mov", "\x00\x00\x00\x14ftypqt  \x00\x00\x02\x00qt  ", "video/mov"},
+		{"text", "hello world", "text/plain"},
+		{"exe", "MZ\x90\x00\x03\x00\x00\x00\x04\x00", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, upload.DetectContentType([]byte(tt.head)), tt.name)
+	}
+}
+
+// TestValidateContentType 测试文件内容与扩展名的一致性检查
+func TestValidateContentType(t *testing.T) {
+	mimeType, err := upload.ValidateContentType("photo.JPG", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"))
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", mimeType)
+
+	// HTML内容的文本文件按纯文本记录
+	mimeType, err = upload.ValidateContentType("page.txt", []byte("<html><script>alert(1)</script></html>"))
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", mimeType)
+
+	_, err = upload.ValidateContentType("setup.jpg", []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00"))
+	assert.ErrorIs(t, err, upload.ErrContentTypeMismatch)
+
+	_, err = upload.ValidateContentType("image.png", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"))
+	assert.ErrorIs(t, err, upload.ErrContentTypeMismatch)
+}
@@ -0,0 +1,62 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	"template/pkg/constants"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetStaleUploads 测试超过保留时间没有写入的上传和已过期的tus上传被找出，已完成的文件不受影响
+func TestGetStaleUploads(t *testing.T) {
+	db := newUploadTestDB(t)
+	repo := uploadRepo.NewUploadRepository(db)
+	now := time.Now()
+
+	newUpload := func(status int, updatedAt time.Time) *models.UploadFile {
+		file := newTestUploadFile("user-a")
+		file.UploadStatus = status
+		require.NoError(t, repo.CreateUploadFile(file))
+		require.NoError(t, db.Model(file).UpdateColumn("updated_at", updatedAt).Error)
+		return file
+	}
+
+	stale := newUpload(constants.UploadStatusUploading, now.Add(-48*time.Hour))
+	active := newUpload(constants.UploadStatusUploading, now)
+	newUpload(constants.UploadStatusCompleted, now.Add(-48*time.Hour))
+
+	// 仍在写入但已过期的tus上传
+	expiredTus := newUpload(constants.UploadStatusUploading, now)
+	require.NoError(t, db.Create(&models.TusUpload{FileID: expiredTus.ID.String(), ExpiresAt: now.Add(-time.Minute)}).Error)
+
+	// 合并中断的文件按开始合并的时间判断，tus上传过期不影响正在合并的文件
+	staleMerging := newUpload(constants.UploadStatusMerging, now.Add(-48*time.Hour))
+	mergingTus := newUpload(constants.UploadStatusMerging, now)
+	require.NoError(t, db.Create(&models.TusUpload{FileID: mergingTus.ID.String(), ExpiresAt: now.Add(-time.Minute)}).Error)
+
+	files, err := repo.GetStaleUploads(now.Add(-24*time.Hour), now, 10)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID.String())
+	}
+	assert.ElementsMatch(t, []string{stale.ID.String(), expiredTus.ID.String(), staleMerging.ID.String()}, ids)
+
+	// 标记失败后不再被找出，已完成的文件不会被标记失败
+	marked, err := repo.MarkUploadFailed(stale.ID.String())
+	require.NoError(t, err)
+	assert.True(t, marked)
+	files, err = repo.GetStaleUploads(now.Add(-24*time.Hour), now, 10)
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	require.NoError(t, db.Model(active).UpdateColumn("upload_status", constants.UploadStatusCompleted).Error)
+	marked, err = repo.MarkUploadFailed(active.ID.String())
+	require.NoError(t, err)
+	assert.False(t, marked)
+}
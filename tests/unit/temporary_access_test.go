@@ -0,0 +1,256 @@
+package unit
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+	"template/internal/routes"
+	uploadService "template/internal/services/upload"
+	"template/pkg/common"
+	"template/pkg/constants"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestConsumeTemporaryAccess 测试临时访问链接按使用次数限制占用，停用或过期后不能再使用
+func TestConsumeTemporaryAccess(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+	now := time.Now()
+
+	limited := &models.TemporaryAccess{FileID: "file-a", AccessToken: "token-a", Purpose: "test", ExpiresAt: now.Add(time.Hour), UsageLimit: 2, IsActive: true}
+	require.NoError(t, repo.CreateTemporaryAccess(limited))
+	for i, want := range []bool{true, true, false} {
+		consumed, err := repo.ConsumeTemporaryAccess(limited.ID.String(), now)
+		require.NoError(t, err)
+		assert.Equal(t, want, consumed, "第 %d 次使用", i+1)
+	}
+
+	// 使用次数为0表示不限制，不会被字段默认值覆盖
+	unlimited := &models.TemporaryAccess{FileID: "file-a", AccessToken: "token-b", Purpose: "test", ExpiresAt: now.Add(time.Hour), UsageLimit: 0, IsActive: true}
+	require.NoError(t, repo.CreateTemporaryAccess(unlimited))
+	stored, err := repo.GetTemporaryAccessByToken("token-b")
+	require.NoError(t, err)
+	assert.Equal(t, 0, stored.UsageLimit)
+	for i := 0; i < 5; i++ {
+		consumed, err := repo.ConsumeTemporaryAccess(unlimited.ID.String(), now)
+		require.NoError(t, err)
+		assert.True(t, consumed)
+	}
+
+	consumed, err := repo.ConsumeTemporaryAccess(unlimited.ID.String(), now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.False(t, consumed)
+
+	revoked, err := repo.DeactivateTemporaryAccess(unlimited.ID.String(), "file-b")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = repo.DeactivateTemporaryAccess(unlimited.ID.String(), "file-a")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	consumed, err = repo.ConsumeTemporaryAccess(unlimited.ID.String(), now)
+	require.NoError(t, err)
+	assert.False(t, consumed)
+
+	count, err := repo.CountValidTemporaryAccesses("file-a", now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+// newTemporaryAccessTestFile 上传一个私有文件，返回文件记录
+func newTemporaryAccessTestFile(t *testing.T, db *gorm.DB) *models.UploadFile {
+	owner := newTestUser(t, db, "access-owner", common.UserRoleUser)
+	uploaded, err := uploadService.SimpleUpload(newTestFileHeader(t, "report.txt", []byte("quarterly report")), owner.ID.String(), "")
+	require.NoError(t, err)
+	_, err = uploadService.SetUserFileVisibility(uploaded.FileID, owner.ID.String(), false)
+	require.NoError(t, err)
+	file, err := uploadService.GetUserFile(uploaded.FileID, owner.ID.String())
+	require.NoError(t, err)
+	return file
+}
+
+// createTestAccess 创建临时访问链接，返回链接记录和链接中的查询参数
+func createTestAccess(t *testing.T, file *models.UploadFile, opts *uploadService.TemporaryAccessOptions) (*models.TemporaryAccess, url.Values) {
+	access, err := uploadService.CreateTemporaryAccess(file.ID.String(), file.UserID, opts)
+	require.NoError(t, err)
+	link, err := url.Parse(uploadService.TemporaryAccessURL(file, access))
+	require.NoError(t, err)
+	return access, link.Query()
+}
+
+// TestTemporaryAccessDefaultExpiry 测试不指定过期时间时使用默认有效期，超出最长有效期时拒绝
+func TestTemporaryAccessDefaultExpiry(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	file := newTemporaryAccessTestFile(t, db)
+
+	access, _ := createTestAccess(t, file, &uploadService.TemporaryAccessOptions{Purpose: "default"})
+	assert.WithinDuration(t, time.Now().Add(constants.TemporaryAccessDefaultTTL), access.ExpiresAt, time.Minute)
+	assert.Equal(t, constants.TemporaryAccessDefaultUsage, access.UsageLimit)
+
+	_, err := uploadService.CreateTemporaryAccess(file.ID.String(), file.UserID, &uploadService.TemporaryAccessOptions{
+		Purpose:   "too-long",
+		ExpiresAt: time.Now().Add(constants.TemporaryAccessMaxTTL + time.Hour),
+	})
+	assert.ErrorIs(t, err, uploadService.ErrInvalidAccessExpiry)
+}
+
+// TestUseTemporaryAccessRejections 测试签名无效或被篡改、过期、停用、IP不允许和次数用完的链接都被拒绝
+func TestUseTemporaryAccessRejections(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	file := newTemporaryAccessTestFile(t, db)
+	fileID := file.ID.String()
+	unlimited := 0
+
+	_, query := createTestAccess(t, file, &uploadService.TemporaryAccessOptions{Purpose: "tamper", UsageLimit: &unlimited})
+	tampered := func(key, value string) url.Values {
+		changed := url.Values{}
+		for k, v := range query {
+			changed[k] = v
+		}
+		changed.Set(key, value)
+		return changed
+	}
+	require.NoError(t, uploadService.UseTemporaryAccess(fileID, query, "192.0.2.1", true))
+	for name, changed := range map[string]url.Values{
+		"修改签名":   tampered("signature", query.Get("signature")[1:]+"0"),
+		"修改过期时间": tampered("expires", "9999999999"),
+		"修改访问令牌": tampered("access", "0"+query.Get("access")[1:]),
+		"缺少过期时间": tampered("expires", ""),
+		"缺少签名":   tampered("signature", ""),
+	} {
+		err := uploadService.UseTemporaryAccess(fileID, changed, "192.0.2.1", true)
+		assert.ErrorIs(t, err, uploadService.ErrAccessSignatureInvalid, name)
+	}
+	err := uploadService.UseTemporaryAccess(common.NewUUID().String(), query, "192.0.2.1", true)
+	assert.ErrorIs(t, err, uploadService.ErrAccessSignatureInvalid, "链接只能访问签发时的文件")
+
+	// 数据库中的过期时间已过时拒绝
+	expired, expiredQuery := createTestAccess(t, file, &uploadService.TemporaryAccessOptions{Purpose: "expired", UsageLimit: &unlimited})
+	require.NoError(t, db.Model(&models.TemporaryAccess{}).Where("id = ?", expired.ID.String()).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	assert.ErrorIs(t, uploadService.UseTemporaryAccess(fileID, expiredQuery, "192.0.2.1", true), uploadService.ErrAccessExpired)
+
+	// 停用后立即失效
+	revoked, revokedQuery := createTestAccess(t, file, &uploadService.TemporaryAccessOptions{Purpose: "revoked", UsageLimit: &unlimited})
+	require.NoError(t, uploadService.UseTemporaryAccess(fileID, revokedQuery, "192.0.2.1", true))
+	require.NoError(t, uploadService.RevokeTemporaryAccess(fileID, file.UserID, revoked.ID.String()))
+	assert.ErrorIs(t, uploadService.UseTemporaryAccess(fileID, revokedQuery, "192.0.2.1", true), uploadService.ErrAccessExpired)
+	assert.ErrorIs(t, uploadService.UseTemporaryAccess(fileID, revokedQuery, "192.0.2.1", false), uploadService.ErrAccessExpired)
+
+	// 只允许指定的IP地址和网段
+	_, ipQuery := createTestAccess(t, file, &uploadService.TemporaryAccessOptions{
+		Purpose:    "ip",
+		UsageLimit: &unlimited,
+		AllowedIPs: []string{"203.0.113.7", "10.0.0.0/8"},
+	})
+	assert.NoError(t, uploadService.UseTemporaryAccess(fileID, ipQuery, "203.0.113.7", true))
+	assert.NoError(t, uploadService.UseTemporaryAccess(fileID, ipQuery, "10.20.30.40", true))
+	assert.ErrorIs(t, uploadService.UseTemporaryAccess(fileID, ipQuery, "203.0.113.8", true), uploadService.ErrAccessIPNotAllowed)
+	assert.ErrorIs(t, uploadService.UseTemporaryAccess(fileID, ipQuery, "11.0.0.1", false), uploadService.ErrAccessIPNotAllowed)
+	assert.ErrorIs(t, uploadService.UseTemporaryAccess(fileID, ipQuery, "", true), uploadService.ErrAccessIPNotAllowed)
+
+	_, err = uploadService.CreateTemporaryAccess(fileID, file.UserID, &uploadService.TemporaryAccessOptions{Purpose: "bad-ip", AllowedIPs: []string{"10.0.0.0/33"}})
+	assert.ErrorIs(t, err, uploadService.ErrInvalidAllowedIP)
+
+	// 只校验不占用次数，次数用完后不能重新下载，但可以继续断点续传
+	once, onceQuery := createTestAccess(t, file, &uploadService.TemporaryAccessOptions{Purpose: "once"})
+	require.NoError(t, uploadService.UseTemporaryAccess(fileID, onceQuery, "192.0.2.1", false))
+	require.NoError(t, uploadService.UseTemporaryAccess(fileID, onceQuery, "192.0.2.1", true))
+	assert.ErrorIs(t, uploadService.UseTemporaryAccess(fileID, onceQuery, "192.0.2.1", true), uploadService.ErrAccessUsedUp)
+	assert.NoError(t, uploadService.UseTemporaryAccess(fileID, onceQuery, "192.0.2.1", false))
+	var stored models.TemporaryAccess
+	require.NoError(t, db.Where("id = ?", once.ID.String()).First(&stored).Error)
+	assert.Equal(t, 1, stored.UsageCount)
+}
+
+// TestUseTemporaryAccessConcurrent 测试并发使用同一链接时，成功的次数恰好等于使用次数限制
+func TestUseTemporaryAccessConcurrent(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	file := newTemporaryAccessTestFile(t, db)
+
+	const limit, workers = 5, 20
+	usageLimit := limit
+	_, query := createTestAccess(t, file, &uploadService.TemporaryAccessOptions{Purpose: "concurrent", UsageLimit: &usageLimit})
+
+	var (
+		wg                sync.WaitGroup
+		mu                sync.Mutex
+		succeeded, usedUp int
+	)
+	start := make(chan struct{})
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			err := uploadService.UseTemporaryAccess(file.ID.String(), query, "192.0.2.1", true)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case stderrors.Is(err, uploadService.ErrAccessUsedUp):
+				usedUp++
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	assert.Equal(t, limit, succeeded)
+	assert.Equal(t, workers-limit, usedUp)
+}
+
+// TestTemporaryAccessDownloadConsumesOnce 测试通过临时访问链接下载时，HEAD 请求和断点续传的后续分段不占用使用次数
+func TestTemporaryAccessDownloadConsumesOnce(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	file := newTemporaryAccessTestFile(t, db)
+	access, err := uploadService.CreateTemporaryAccess(file.ID.String(), file.UserID, &uploadService.TemporaryAccessOptions{Purpose: "download"})
+	require.NoError(t, err)
+	link := uploadService.TemporaryAccessURL(file, access)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+	routes.RegisterFileRoutes(router)
+
+	request := func(method, rangeHeader string) int {
+		req := httptest.NewRequest(method, link, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 下载工具先用 HEAD 获取文件大小，再分段下载
+	assert.Equal(t, http.StatusOK, request(http.MethodHead, ""))
+	assert.Equal(t, http.StatusOK, request(http.MethodHead, ""))
+	assert.Equal(t, http.StatusPartialContent, request(http.MethodGet, "bytes=0-7"))
+	assert.Equal(t, http.StatusPartialContent, request(http.MethodGet, "bytes=8-"))
+
+	// 唯一的一次使用次数已被从头开始的请求占用，不能重新下载
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, ""))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "bytes=0-"))
+
+	var stored models.TemporaryAccess
+	require.NoError(t, db.Where("id = ?", access.ID.String()).First(&stored).Error)
+	assert.Equal(t, 1, stored.UsageCount)
+}
@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"template/pkg/upload"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerateThumbnail 测试缩略图的尺寸计算和输出格式
+func TestGenerateThumbnail(t *testing.T) {
+	opaque := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	for i := range opaque.Pix {
+		opaque.Pix[i] = 0xff
+	}
+
+	thumb, err := upload.GenerateThumbnail(opaque, 200, 85)
+	require.NoError(t, err)
+	assert.Equal(t, "image/jpeg", thumb.MimeType)
+	assert.Equal(t, 200, thumb.Width)
+	assert.Equal(t, 50, thumb.Height)
+
+	decoded, err := upload.DecodeImage(bytes.NewReader(thumb.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 200, 50), decoded.Bounds())
+
+	// 带透明区域的图片输出PNG，小图不放大
+	transparent := image.NewNRGBA(image.Rect(0, 0, 30, 60))
+	transparent.Set(0, 0, color.NRGBA{R: 255, A: 128})
+	thumb, err = upload.GenerateThumbnail(transparent, 200, 85)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", thumb.MimeType)
+	assert.Equal(t, 30, thumb.Width)
+	assert.Equal(t, 60, thumb.Height)
+}
+
+// TestCheckImageSize 测试像素数上限检查
+func TestCheckImageSize(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 100, 100))))
+
+	assert.NoError(t, upload.CheckImageSize(bytes.NewReader(buf.Bytes()), 10000))
+	assert.ErrorIs(t, upload.CheckImageSize(bytes.NewReader(buf.Bytes()), 9999), upload.ErrImageTooLarge)
+	assert.Error(t, upload.CheckImageSize(strings.NewReader("not an image"), 10000))
+}
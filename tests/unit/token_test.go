@@ -0,0 +1,184 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"template/internal/models"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/config"
+	"template/pkg/database"
+	"template/pkg/errors"
+	"template/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// newUserTestDB 创建用户相关测试使用的内存数据库，并替换全局数据库连接
+func newUserTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		NamingStrategy: schema.NamingStrategy{SingularTable: true},
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.User{},
+		&models.UserSession{},
+		&models.UserRecoveryCode{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		&models.Role{},
+		&models.Permission{},
+		&models.UserRole{},
+	))
+	seedTestRoles(t, db)
+
+	previous := database.GetDB()
+	database.SetDB(db)
+	t.Cleanup(func() { database.SetDB(previous) })
+
+	if config.GetConfig().JWT.SecretKey == "" {
+		config.GetConfig().JWT.SecretKey = "unit-test-secret"
+	}
+	return db
+}
+
+// seedTestRoles 创建内置权限和角色，用户按旧版整数角色获得对应的内置角色
+func seedTestRoles(t *testing.T, db *gorm.DB) {
+	permByCode := make(map[string]models.Permission, len(common.Permissions))
+	for _, def := range common.Permissions {
+		perm := models.Permission{Code: def.Code, Name: def.Name}
+		require.NoError(t, db.Create(&perm).Error)
+		permByCode[def.Code] = perm
+	}
+	for _, def := range common.SystemRoles {
+		role := models.Role{Name: def.Name, DisplayName: def.DisplayName, IsSystem: true}
+		for _, code := range def.Permissions {
+			role.Permissions = append(role.Permissions, permByCode[code])
+		}
+		require.NoError(t, db.Create(&role).Error)
+	}
+}
+
+// testUserPassword 测试用户的登录密码
+const testUserPassword = "Passw0rd!"
+
+// newTestUser 创建测试用户
+func newTestUser(t *testing.T, db *gorm.DB, username string, role int) *models.User {
+	hashed, err := utils.HashPassword(testUserPassword)
+	require.NoError(t, err)
+	u := &models.User{Username: username, Password: hashed, Email: username + "@example.com", Role: role}
+	require.NoError(t, db.Create(u).Error)
+	return u
+}
+
+// issueTestTokens 为用户创建会话并签发令牌对
+func issueTestTokens(t *testing.T, db *gorm.DB, u *models.User) (*models.UserSession, *common.TokenPair) {
+	session := &models.UserSession{
+		UserID:     u.ID.String(),
+		Device:     "test",
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	require.NoError(t, db.Create(session).Error)
+
+	tokens, err := common.GenerateTokenPair(common.TokenSubject{
+		UserID:    u.ID.String(),
+		Username:  u.Username,
+		Role:      u.Role,
+		SessionID: session.ID.String(),
+	})
+	require.NoError(t, err)
+	return session, tokens
+}
+
+// TestRefreshTokenRotation 测试刷新令牌轮换，换取新令牌后旧的刷新令牌失效
+func TestRefreshTokenRotation(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "rotate", common.UserRoleUser)
+	_, tokens := issueTestTokens(t, db, u)
+
+	rotated, err := user.RefreshToken(tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+
+	claims, err := common.ParseToken(rotated.AccessToken)
+	require.NoError(t, err)
+	assert.False(t, common.IsTokenRevoked(claims))
+
+	_, err = user.RefreshToken(rotated.RefreshToken)
+	assert.NoError(t, err, "新的刷新令牌可以继续使用")
+}
+
+// TestRefreshTokenReuse 测试重复使用已轮换的刷新令牌时吊销该用户的全部令牌
+func TestRefreshTokenReuse(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "reuse", common.UserRoleUser)
+	_, tokens := issueTestTokens(t, db, u)
+
+	rotated, err := user.RefreshToken(tokens.RefreshToken)
+	require.NoError(t, err)
+
+	// 等待时钟前进，使重用检测的吊销时间晚于新令牌的签发时间
+	time.Sleep(2 * time.Millisecond)
+	_, err = user.RefreshToken(tokens.RefreshToken)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.CodeInvalidAuthToken))
+
+	claims, err := common.ParseToken(rotated.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, common.IsTokenRevoked(claims), "检测到重用后之前签发的令牌全部失效")
+
+	_, err = user.RefreshToken(rotated.RefreshToken)
+	assert.Error(t, err)
+}
+
+// TestLogoutRevokesTokens 测试退出登录后访问令牌、刷新令牌和会话都失效
+func TestLogoutRevokesTokens(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "logout", common.UserRoleUser)
+	session, tokens := issueTestTokens(t, db, u)
+
+	accessClaims, err := common.ParseToken(tokens.AccessToken)
+	require.NoError(t, err)
+	require.NoError(t, user.Logout(accessClaims, tokens.RefreshToken))
+
+	assert.True(t, common.IsTokenRevoked(accessClaims))
+	refreshClaims, err := common.ParseToken(tokens.RefreshToken)
+	require.NoError(t, err)
+	assert.True(t, common.IsTokenRevoked(refreshClaims))
+	assert.False(t, user.ValidateSession(session.ID.String()))
+
+	_, err = user.RefreshToken(tokens.RefreshToken)
+	assert.Error(t, err)
+}
+
+// TestRevokeUserTokensSameSecond 测试吊销后同一秒内重新登录签发的令牌仍然有效
+func TestRevokeUserTokensSameSecond(t *testing.T) {
+	db := newUserTestDB(t)
+	u := newTestUser(t, db, "relogin", common.UserRoleUser)
+
+	// 从整秒开始，保证吊销和重新登录发生在同一秒内
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+
+	_, before := issueTestTokens(t, db, u)
+	time.Sleep(2 * time.Millisecond)
+	require.NoError(t, common.RevokeUserTokens(u.ID.String()))
+	time.Sleep(2 * time.Millisecond)
+	_, after := issueTestTokens(t, db, u)
+
+	beforeClaims, err := common.ParseToken(before.AccessToken)
+	require.NoError(t, err)
+	afterClaims, err := common.ParseToken(after.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, beforeClaims.IssuedAt.Unix(), afterClaims.IssuedAt.Unix())
+
+	assert.True(t, common.IsTokenRevoked(beforeClaims))
+	assert.False(t, common.IsTokenRevoked(afterClaims))
+}
@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"strings"
+	"template/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6238Secret RFC 6238 附录B中SHA1测试向量使用的密钥 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestGenerateTOTPCode 使用RFC 6238测试向量验证TOTP计算（取低6位）
+func TestGenerateTOTPCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		unixTime int64
+		wantCode string
+	}{
+		{name: "T=59", unixTime: 59, wantCode: "287082"},
+		{name: "T=1111111109", unixTime: 1111111109, wantCode: "081804"},
+		{name: "T=1234567890", unixTime: 1234567890, wantCode: "005924"},
+		{name: "T=2000000000", unixTime: 2000000000, wantCode: "279037"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := utils.GenerateTOTPCode(rfc6238Secret, time.Unix(tt.unixTime, 0))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+}
+
+// TestValidateTOTPCode 测试验证码校验及时间偏移容忍
+func TestValidateTOTPCode(t *testing.T) {
+	secret, err := utils.GenerateTOTPSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, err := utils.GenerateTOTPCode(secret, now)
+	assert.NoError(t, err)
+
+	counter, ok := utils.ValidateTOTPCode(secret, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/utils.TOTPPeriod, counter)
+
+	// 上一个时间步的验证码仍在容忍范围内
+	_, ok = utils.ValidateTOTPCode(secret, code, now.Add(utils.TOTPPeriod*time.Second))
+	assert.True(t, ok)
+
+	// 超出容忍范围
+	_, ok = utils.ValidateTOTPCode(secret, code, now.Add(3*utils.TOTPPeriod*time.Second))
+	assert.False(t, ok)
+
+	// 格式错误
+	_, ok = utils.ValidateTOTPCode(secret, "12345", now)
+	assert.False(t, ok)
+}
+
+// TestBuildTOTPURI 测试otpauth URI格式
+func TestBuildTOTPURI(t *testing.T) {
+	uri := utils.BuildTOTPURI("template", "root", rfc6238Secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/template:root?"))
+	assert.Contains(t, uri, "secret="+rfc6238Secret)
+	assert.Contains(t, uri, "issuer=template")
+}
+
+// TestRecoveryCodes 测试恢复码生成与规范化
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := utils.GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Len(t, codes[0], 11)
+	assert.Equal(t, utils.NormalizeRecoveryCode(codes[0]), utils.NormalizeRecoveryCode(" "+strings.ToUpper(codes[0])+" "))
+}
@@ -0,0 +1,59 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"template/internal/models"
+	uploadRepo "template/internal/repositories/upload"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTrashRestore 测试删除的文件进入回收站并继续占用存储用量，恢复后重新可见
+func TestTrashRestore(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+
+	file := newTestUploadFile("user-a")
+	file.FolderID = "folder-a"
+	blob := &models.FileBlob{SHA256Hash: "hash", MD5Hash: "md5", StoredName: "blob.txt", FilePath: "uploads/blob.txt", FileSize: 3}
+	require.NoError(t, repo.CreateFileWithBlob(file, blob))
+	fileID := file.ID.String()
+
+	require.NoError(t, repo.DeleteUploadFile(fileID))
+	_, err := repo.GetUploadFileByID(fileID)
+	assert.Error(t, err)
+
+	trashed, total, err := repo.GetTrashedFiles("user-a", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, trashed, 1)
+	assert.Equal(t, fileID, trashed[0].ID.String())
+
+	quota, err := repo.RecomputeQuotaUsage("user-a")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), quota.UsedBytes)
+
+	// 只有超过保留时间的文件才会被定时任务清理
+	expired, err := repo.GetExpiredTrashedFiles(time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+	expired, err = repo.GetExpiredTrashedFiles(time.Now().Add(time.Second), 10)
+	require.NoError(t, err)
+	assert.Len(t, expired, 1)
+
+	restored, err := repo.RestoreFile(fileID, "")
+	require.NoError(t, err)
+	assert.True(t, restored)
+	restored, err = repo.RestoreFile(fileID, "")
+	require.NoError(t, err)
+	assert.False(t, restored)
+
+	file, err = repo.GetUploadFileByID(fileID)
+	require.NoError(t, err)
+	assert.Equal(t, "", file.FolderID)
+	_, total, err = repo.GetTrashedFiles("user-a", 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
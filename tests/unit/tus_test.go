@@ -0,0 +1,50 @@
+package unit
+
+import (
+	"io"
+	"strings"
+	"template/pkg/upload"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseTusMetadata 测试 Upload-Metadata 请求头的解析
+func TestParseTusMetadata(t *testing.T) {
+	metadata, err := upload.ParseTusMetadata("filename d29ybGRfZG9taW5hdGlvbl9wbGFuLnBkZg==,is_confidential, filetype YXBwbGljYXRpb24vcGRm")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"filename":        "world_domination_plan.pdf",
+		"is_confidential": "",
+		"filetype":        "application/pdf",
+	}, metadata)
+
+	metadata, err = upload.ParseTusMetadata("")
+	require.NoError(t, err)
+	assert.Empty(t, metadata)
+
+	for _, header := range []string{"filename !!!", "a YQ==,a Yg==", "a b c", "a YQ==,,b Yg=="} {
+		_, err := upload.ParseTusMetadata(header)
+		assert.Error(t, err, header)
+	}
+}
+
+// TestTusChecksum 测试 Upload-Checksum 请求头的解析和校验
+func TestTusChecksum(t *testing.T) {
+	// sha1("hello world")
+	checksum, err := upload.ParseTusChecksum("sha1 Kq5sNclPz7QV2+lfQIuc6R7oRu0=")
+	require.NoError(t, err)
+	io.Copy(checksum.Writer(), strings.NewReader("hello world"))
+	assert.True(t, checksum.Verify())
+
+	checksum, err = upload.ParseTusChecksum("sha1 Kq5sNclPz7QV2+lfQIuc6R7oRu0=")
+	require.NoError(t, err)
+	io.Copy(checksum.Writer(), strings.NewReader("hello"))
+	assert.False(t, checksum.Verify())
+
+	for _, header := range []string{"crc32 AAAAAA==", "sha1", "sha1 !!!", "md5 Kq5sNclPz7QV2+lfQIuc6R7oRu0="} {
+		_, err := upload.ParseTusChecksum(header)
+		assert.Error(t, err, header)
+	}
+}
@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"template/internal/routes"
+	"template/internal/services/rbac"
+	"template/internal/services/user"
+	"template/pkg/common"
+	"template/pkg/errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUploadRoutesRequireFileScopes 测试文件管理接口按操作要求 files:read、files:write 或 files:delete 授权范围，只有上传权限的密钥不能使用
+func TestUploadRoutesRequireFileScopes(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	u := newTestUser(t, db, "scope-routes", common.UserRoleUser)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+	routes.RegisterUploadRoutes(router.Group("/api/v1"))
+
+	keys := make(map[string]string)
+	for _, scope := range common.APIKeyScopes {
+		_, key, err := user.CreateAPIKey(u.ID.String(), scope, []string{scope}, 0)
+		require.NoError(t, err)
+		keys[scope] = key
+	}
+	token := loginTestUser(t, u, user.ClientInfo{IP: "198.51.100.30"}).AccessToken
+
+	request := func(method, path, credential string) int {
+		req := httptest.NewRequest(method, "/api/v1/upload"+path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+credential)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	fileID := common.NewUUID().String()
+	tests := []struct {
+		method string
+		path   string
+		scope  string
+	}{
+		{http.MethodGet, "/files", common.ScopeFilesRead},
+		{http.MethodGet, "/files/" + fileID, common.ScopeFilesRead},
+		{http.MethodGet, "/files/" + fileID + "/versions", common.ScopeFilesRead},
+		{http.MethodGet, "/files/" + fileID + "/temp-access", common.ScopeFilesRead},
+		{http.MethodGet, "/folders/children", common.ScopeFilesRead},
+		{http.MethodGet, "/trash", common.ScopeFilesRead},
+		{http.MethodPut, "/files/" + fileID + "/name", common.ScopeFilesWrite},
+		{http.MethodPut, "/files/" + fileID + "/settings", common.ScopeFilesWrite},
+		{http.MethodPut, "/files/" + fileID + "/folder", common.ScopeFilesWrite},
+		{http.MethodPost, "/files/" + fileID + "/versions/1/restore", common.ScopeFilesWrite},
+		{http.MethodPost, "/files/" + fileID + "/temp-access", common.ScopeFilesWrite},
+		{http.MethodPost, "/folders", common.ScopeFilesWrite},
+		{http.MethodPost, "/trash/restore", common.ScopeFilesWrite},
+		{http.MethodPost, "/files/batch-delete", common.ScopeFilesDelete},
+		{http.MethodPost, "/files/" + fileID + "/versions/prune", common.ScopeFilesDelete},
+		{http.MethodDelete, "/folders/" + fileID, common.ScopeFilesDelete},
+		{http.MethodDelete, "/trash/" + fileID, common.ScopeFilesDelete},
+		{http.MethodDelete, "/trash", common.ScopeFilesDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			for scope, key := range keys {
+				status := request(tt.method, tt.path, key)
+				if scope == tt.scope {
+					assert.NotEqual(t, http.StatusForbidden, status, "%s 的密钥可以访问", scope)
+				} else {
+					assert.Equal(t, http.StatusForbidden, status, "%s 的密钥不能访问", scope)
+				}
+			}
+			assert.NotEqual(t, http.StatusForbidden, request(tt.method, tt.path, token), "登录令牌不受授权范围限制")
+		})
+	}
+
+	// 上传接口仍然只需要 upload:write
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/quota", keys[common.ScopeUploadWrite]))
+	assert.Equal(t, http.StatusForbidden, request(http.MethodGet, "/quota", keys[common.ScopeFilesRead]))
+}
+
+// TestUploadRoutesRequireUploadPermission 测试上传接口要求用户拥有 upload:file 权限，API密钥按其所属用户的权限检查
+func TestUploadRoutesRequireUploadPermission(t *testing.T) {
+	db := newUserTestDB(t)
+	useTestUploadService(t, db)
+	u := newTestUser(t, db, "upload-perm", common.UserRoleUser)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(errors.ErrorHandler())
+	routes.RegisterUploadRoutes(router.Group("/api/v1"))
+
+	_, key, err := user.CreateAPIKey(u.ID.String(), "uploader", []string{common.ScopeUploadWrite}, 0)
+	require.NoError(t, err)
+	token := loginTestUser(t, u, user.ClientInfo{IP: "198.51.100.31"}).AccessToken
+
+	request := func(credential string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/upload/quota", nil)
+		req.Header.Set("Authorization", "Bearer "+credential)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 普通用户角色默认拥有上传权限
+	assert.Equal(t, http.StatusOK, request(token))
+	assert.Equal(t, http.StatusOK, request(key))
+
+	// 改为没有上传权限的角色后，登录令牌和API密钥都不能上传
+	viewer, err := rbac.CreateRole("viewer", "只读用户", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, rbac.SetUserRoles(u.ID.String(), u.ID.String(), []string{viewer.ID.String()}))
+	assert.Equal(t, http.StatusForbidden, request(token))
+	assert.Equal(t, http.StatusForbidden, request(key))
+}
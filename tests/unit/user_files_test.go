@@ -0,0 +1,61 @@
+package unit
+
+import (
+	"testing"
+
+	uploadRepo "template/internal/repositories/upload"
+	"template/pkg/constants"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetUserFiles 测试文件列表按用户、类型和文件名筛选并排序，不包括上传中和上传失败的文件
+func TestGetUserFiles(t *testing.T) {
+	repo := uploadRepo.NewUploadRepository(newUploadTestDB(t))
+
+	create := func(userID, filename, mimeType string, size int64, status int) {
+		file := newTestUploadFile(userID)
+		file.Filename = filename
+		file.MimeType = mimeType
+		file.FileSize = size
+		file.UploadStatus = status
+		require.NoError(t, repo.CreateUploadFile(file))
+	}
+	create("user-a", "report.txt", "text/plain", 30, constants.UploadStatusCompleted)
+	create("user-a", "photo.png", "image/png", 10, constants.UploadStatusCompleted)
+	create("user-a", "avatar.jpg", "image/jpeg", 20, constants.UploadStatusPendingScan)
+	create("user-a", "partial.png", "image/png", 40, constants.UploadStatusUploading)
+	create("user-a", "broken.png", "image/png", 50, constants.UploadStatusFailed)
+	create("user-b", "other.png", "image/png", 60, constants.UploadStatusCompleted)
+
+	filenames := func(query *uploadRepo.FileQuery) ([]string, int64) {
+		query.UserID = "user-a"
+		query.Limit = 10
+		files, total, err := repo.GetUserFiles(query)
+		require.NoError(t, err)
+		names := make([]string, 0, len(files))
+		for _, file := range files {
+			names = append(names, file.Filename)
+		}
+		return names, total
+	}
+
+	names, total := filenames(&uploadRepo.FileQuery{SortBy: "file_size", SortOrder: "asc"})
+	assert.Equal(t, []string{"photo.png", "avatar.jpg", "report.txt"}, names)
+	assert.Equal(t, int64(3), total)
+
+	names, _ = filenames(&uploadRepo.FileQuery{MimeType: "image/*", SortBy: "filename", SortOrder: "asc"})
+	assert.Equal(t, []string{"avatar.jpg", "photo.png"}, names)
+
+	names, _ = filenames(&uploadRepo.FileQuery{MimeType: "image/png"})
+	assert.Equal(t, []string{"photo.png"}, names)
+
+	names, _ = filenames(&uploadRepo.FileQuery{Keyword: "port"})
+	assert.Equal(t, []string{"report.txt"}, names)
+
+	// 分页时总数不受影响，不支持的排序字段按创建时间排序
+	names, total = filenames(&uploadRepo.FileQuery{SortBy: "user_id; DROP TABLE upload_file", Offset: 2})
+	assert.Len(t, names, 1)
+	assert.Equal(t, int64(3), total)
+}
@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"template/internal/models"
+	"template/internal/services/webhook"
+	"template/pkg/common"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWebhookSignature 测试签名计算和校验
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"1","type":"user.registered"}`)
+	signature := webhook.Sign("secret", 1700000000, body)
+
+	assert.Contains(t, signature, "sha256=")
+	assert.True(t, webhook.VerifySignature("secret", 1700000000, body, signature))
+	assert.False(t, webhook.VerifySignature("other", 1700000000, body, signature))
+	assert.False(t, webhook.VerifySignature("secret", 1700000001, body, signature))
+	assert.False(t, webhook.VerifySignature("secret", 1700000000, []byte(`{}`), signature))
+}
+
+// TestWebhookRetryDelay 测试指数退避的重试间隔
+func TestWebhookRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, webhook.RetryDelay(1))
+	assert.Equal(t, 2*time.Minute, webhook.RetryDelay(2))
+	assert.Equal(t, 8*time.Minute, webhook.RetryDelay(4))
+	assert.Equal(t, 6*time.Hour, webhook.RetryDelay(20))
+}
+
+// TestWebhookSend 测试向接收方发送带签名的投递请求
+func TestWebhookSend(t *testing.T) {
+	endpoint := &models.WebhookEndpoint{Secret: "whsec_test"}
+	delivery := &models.WebhookDelivery{
+		BaseModel: models.BaseModel{ID: common.NewUUID()},
+		EventType: common.WebhookEventFileUploaded,
+		Payload:   `{"type":"file.uploaded"}`,
+	}
+
+	var verified bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(webhook.TimestampHeader), 10, 64)
+		verified = webhook.VerifySignature(endpoint.Secret, timestamp, body, r.Header.Get(webhook.SignatureHeader))
+
+		assert.Equal(t, common.WebhookEventFileUploaded, r.Header.Get(webhook.EventHeader))
+		assert.Equal(t, delivery.ID.String(), r.Header.Get(webhook.DeliveryHeader))
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	endpoint.URL = receiver.URL
+	result := webhook.Send(receiver.Client(), endpoint, delivery)
+	assert.True(t, result.Succeeded())
+	assert.True(t, verified)
+	assert.Equal(t, "ok", result.ResponseBody)
+
+	// 非2xx响应视为投递失败
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	endpoint.URL = failing.URL
+	result = webhook.Send(failing.Client(), endpoint, delivery)
+	assert.False(t, result.Succeeded())
+	assert.Equal(t, http.StatusInternalServerError, result.StatusCode)
+}